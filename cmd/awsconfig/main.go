@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/configservice"
+	"github.com/fergalhk/llm-cloud-discovery/internal/cmd"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/configquery"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/parsearn"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/dns"
+)
+
+func main() {
+	awsConfig, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	// queries run against the current account & region unless an aggregator is set
+	aggregatorName := os.Getenv("AWS_CONFIG_AGGREGATOR")
+
+	configClient := configservice.NewFromConfig(awsConfig)
+
+	cmd.Run(
+		`You are a helpful assistant that can answer questions about infrastructure resources, particularly but not exclusively those in AWS cloud.
+
+You have been provided with tools that query AWS Config, which records the configuration of resources across AWS accounts & regions.
+
+To use the tools, you should follow this process:
+
+1. If you don't know which resource types are available, call the get_aws_config_schema tool without a resource type to list them.
+2. Get the fields of the resource type you need using the get_aws_config_schema tool.
+3. Construct a query, and run it using the aws_config_query tool.
+
+AWS Config queries are a subset of SQL SELECT, with these rules:
+
+* There is no FROM clause. Every query runs against a single table of resources, so filter by resource type instead, e.g. SELECT resourceId, resourceName WHERE resourceType = 'AWS::EC2::Instance'.
+* Resource types are in the format AWS::Service::Resource, e.g. AWS::S3::Bucket or AWS::Lambda::Function.
+* Every resource has the fields resourceId, resourceName, resourceType, accountId, awsRegion, availabilityZone, arn, resourceCreationTime, tags & relationships. Resource specific properties are nested under configuration, e.g. configuration.instanceType, and some resource types also have supplementaryConfiguration.
+* Fields in arrays are referenced by the path of their elements, e.g. configuration.securityGroups.groupId. A condition on an array field matches if any element matches.
+* Tags are queried with tags.key & tags.value, or with tags.tag, e.g. tags.tag = 'Environment:production'.
+* Supported operators are =, !=, <, <=, >, >=, IN, NOT IN, BETWEEN, LIKE (with % as a wildcard), AND, OR & NOT. String comparisons are case sensitive.
+* COUNT, SUM, MIN, MAX & AVG are supported along with GROUP BY & ORDER BY, e.g. SELECT resourceType, COUNT(*) GROUP BY resourceType.
+* Joins & subqueries are not supported. Select only the fields you need, as whole configuration objects can be very large.
+
+Resources refer to each other by ID or ARN, so answering a question about how resources are connected often needs more than one query. For example, to find the instances in a security group, query the group's ID first, then query instances WHERE configuration.securityGroups.groupId = '<ID>'.
+
+If you have an ARN, use the parse_arn tool to find out the resource type & identifier it refers to.
+
+The tools provided should be called multiple times if necessary to answer the question.
+
+Pay particular attention to the names of the properties & parameters provided to you for each tool. If you get these wrong, the tool will fail. You must also ensure that any required parameters are passed to the tool.
+`,
+		configquery.NewQueryTool(configClient, aggregatorName),
+		configquery.NewSchemaTool(configClient, aggregatorName),
+		parsearn.Tool{},
+		dns.New(dns.WithServer(os.Getenv("DNS_RESOLVER"))),
+	)
+}
@@ -2,13 +2,40 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/fergalhk/llm-cloud-discovery/internal/cmd"
+	"github.com/fergalhk/llm-cloud-discovery/internal/inventory"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/domain"
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/get"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/iamaccess"
+	inventorytools "github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/inventory"
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/list"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/netpath"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/parsearn"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/related"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/s3objects"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/stacks"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/trail"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/dns"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/terraform"
+	"github.com/fergalhk/llm-cloud-discovery/internal/tfstate"
 )
 
 func main() {
@@ -18,13 +45,20 @@ func main() {
 	}
 
 	// create service & tools
-	awsListTool, err := list.NewTool(cloudformation.NewFromConfig(awsConfig), cloudcontrol.NewFromConfig(awsConfig))
+	cloudformationClient := cloudformation.NewFromConfig(awsConfig)
+	awsListTool, err := list.NewTool(cloudformationClient, cloudcontrol.NewFromConfig(awsConfig))
 	if err != nil {
 		panic(err)
 	}
 
+	inventoryPrompt, inventoryTools, err := openInventory()
+	if err != nil {
+		panic(err)
+	}
+	terraformPrompt, terraformTools := terraformStates(s3.NewFromConfig(awsConfig))
+
 	cmd.Run(
-		`You are a helpful assistant that can answer questions about infrastructure resources, particularly but not exclusively those in AWS cloud.
+		fmt.Sprintf(`You are a helpful assistant that can answer questions about infrastructure resources, particularly but not exclusively those in AWS cloud.
 
 The tools provided should be called multiple times if necessary to answer the question.
 
@@ -32,9 +66,95 @@ For example, if the user asks for details about all EC2 instances, you should fi
 
 The tools do not have any context about the previous tool calls, so you must make sure to pass the correct parameters to each tool. For example, if you have already called the list_aws_resources tool, you must pass the list of resource identifiers to the get_aws_resource tool as they were returned by the list_aws_resources tool.
 
-Pay particular attention to the names of the properties & parameters provided to you for each tool. If you get these wrong, the tool will fail. You must also ensure that any required parameters are passed to the tool.
-`,
-		awsListTool,
-		get.NewTool(cloudcontrol.NewFromConfig(awsConfig)),
+If you have an ARN, or you're unsure which resource type an ID belongs to, use the parse_arn tool to get the resource type & identifier to pass to get_aws_resource.
+
+To answer questions about how resources are connected, for example which services use a particular table, use the find_related_resources tool rather than fetching every resource yourself. It returns the related resources along with the property that links them, which you can then inspect with the get_aws_resource tool.
+
+To find out which roles or users can perform an action on a resource, use the evaluate_iam_access tool rather than reading IAM policies yourself.
+
+To find out whether one resource can connect to another over the network, use the check_network_reachability tool. It needs network interface IDs, instance IDs or private IP addresses, so look these up first, for example from the resource's properties or by resolving its endpoint with the dns_record tool.
+
+To find out which AWS resource serves a domain, for example the CloudFront distribution or load balancer behind a website, use the resolve_domain_to_aws tool. It follows the domain's CNAMEs & Route 53 aliases, and returns the resource type & identifier to pass to get_aws_resource.
+
+S3 objects are not available through list_aws_resources. To look inside a bucket, use the list_s3_objects tool, and use the head_s3_object tool to get the metadata of a specific object.
+
+To find out which CloudFormation stack created a resource, use the find_cloudformation_stack tool with the resource's ID or ARN. Use the list_cloudformation_stacks & list_cloudformation_stack_resources tools to see what each stack contains, and the get_cloudformation_drift tool to find out whether a stack's resources have been changed outside CloudFormation.
+
+To find out who changed a resource and when, use the lookup_cloudtrail_events tool. CloudTrail records resources by name or ID rather than by Cloud Control identifier, so you may need to try the resource's ARN as well as its name.
+
+%s%sPay particular attention to the names of the properties & parameters provided to you for each tool. If you get these wrong, the tool will fail. You must also ensure that any required parameters are passed to the tool.
+`, inventoryPrompt, terraformPrompt),
+		append([]tools.Function{
+			awsListTool,
+			get.NewTool(cloudcontrol.NewFromConfig(awsConfig)),
+			parsearn.Tool{},
+			iamaccess.NewTool(cloudcontrol.NewFromConfig(awsConfig)),
+			netpath.NewTool(ec2.NewFromConfig(awsConfig)),
+			dns.New(dns.WithServer(os.Getenv("DNS_RESOLVER"))),
+			domain.NewTool(
+				dns.NewResolver(os.Getenv("DNS_RESOLVER")),
+				route53.NewFromConfig(awsConfig),
+				cloudfront.NewFromConfig(awsConfig),
+				elasticloadbalancingv2.NewFromConfig(awsConfig),
+				elasticloadbalancing.NewFromConfig(awsConfig),
+				globalaccelerator.NewFromConfig(awsConfig),
+			),
+			related.NewTool(cloudcontrol.NewFromConfig(awsConfig)),
+			s3objects.NewListTool(s3.NewFromConfig(awsConfig)),
+			s3objects.NewHeadTool(s3.NewFromConfig(awsConfig)),
+			trail.NewTool(cloudtrail.NewFromConfig(awsConfig)),
+			stacks.NewListTool(cloudformationClient),
+			stacks.NewResourcesTool(cloudformationClient),
+			stacks.NewFindTool(cloudformationClient),
+			stacks.NewDriftTool(cloudformationClient),
+		}, append(inventoryTools, terraformTools...)...)...,
 	)
 }
+
+// openInventory opens the local inventory synced by the inventory-sync command, if there is one,
+// returning a paragraph for the prompt along with the tools to query it. The inventory is read from
+// INVENTORY_DB, or the default path.
+func openInventory() (string, []tools.Function, error) {
+	path := os.Getenv("INVENTORY_DB")
+	if path == "" {
+		var err error
+		path, err = inventory.DefaultPath()
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return "", nil, nil
+	}
+
+	store, err := inventory.Open(path, true)
+	if err != nil {
+		return "", nil, err
+	}
+
+	prompt := `A local inventory of resources is available, which can be queried with SQL using the query_inventory tool. It's much faster than the list_aws_resources & get_aws_resource tools, and can join resources of different types, so use it first for the resource types it holds. Use the list_inventory_tables tool to find out which types it holds & when they were last synced. If the inventory doesn't hold a resource type, or the question needs up to date data, use the other tools instead.
+
+`
+	return prompt, []tools.Function{inventorytools.NewTablesTool(store), inventorytools.NewQueryTool(store)}, nil
+}
+
+// terraformStates returns a paragraph for the prompt along with the tools to query the Terraform
+// state files listed in TF_STATE, separated by commas, if it's set. Each can be a local file or
+// directory, or an s3:// URL of a state file or a prefix holding them.
+func terraformStates(s3Client *s3.Client) (string, []tools.Function) {
+	locations := []string{}
+	for _, location := range strings.Split(os.Getenv("TF_STATE"), ",") {
+		if location = strings.TrimSpace(location); location != "" {
+			locations = append(locations, location)
+		}
+	}
+	if len(locations) == 0 {
+		return "", nil
+	}
+
+	source := tfstate.NewSource(s3Client, locations...)
+	prompt := `Some resources are managed by Terraform. To find out whether a resource is managed by Terraform, and the address & module of the Terraform resource managing it, use the find_terraform_resources tool with the resource's ID or ARN. To find the resources of a type that aren't managed by Terraform, list them with the list_aws_resources tool, then pass all of their identifiers to find_terraform_resources at once. To check a resource for drift, get it with the get_aws_resource tool and compare it with the attributes returned by find_terraform_resources. Use the list_terraform_resources tool to list what Terraform manages.
+
+`
+	return prompt, []tools.Function{terraform.NewListTool(source), terraform.NewFindTool(source)}
+}
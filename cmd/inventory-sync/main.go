@@ -0,0 +1,92 @@
+// Command inventory-sync copies AWS resources from the Cloud Control API into the local inventory
+// used by the cloudcontrol agent. Only resources that are new, have changed since the last sync or
+// were last fetched longer ago than -refetch-age are fetched, so it can be run often.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/fergalhk/llm-cloud-discovery/internal/inventory"
+)
+
+func main() {
+	defaultPath, err := inventory.DefaultPath()
+	if err != nil {
+		panic(err)
+	}
+
+	path := flag.String("db", defaultPath, "The inventory database file")
+	resourceTypes := flag.String("types", "", "Comma separated resource types to sync, e.g. AWS::EC2::Instance,AWS::EC2::VPC. Defaults to the types already in the inventory")
+	maxAge := flag.Duration("max-age", 0, "Skip resource types synced more recently than this, e.g. 1h")
+	full := flag.Bool("full", false, "Fetch every resource, rather than only those that are new or changed")
+	refetchAge := flag.Duration("refetch-age", 24*time.Hour, "Fetch resources again once they were last fetched longer ago than this, as many types only list their identifiers, so their changes aren't seen otherwise. 0 disables it")
+	concurrency := flag.Int("concurrency", 5, "The number of resources fetched at once")
+	flag.Parse()
+
+	ctx := context.Background()
+	store, err := inventory.Open(*path, false)
+	if err != nil {
+		panic(err)
+	}
+	defer store.Close()
+
+	types := []string{}
+	for _, t := range strings.Split(*resourceTypes, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		synced, err := store.Types(ctx)
+		if err != nil {
+			panic(err)
+		}
+		for _, t := range synced {
+			types = append(types, t.ResourceType)
+		}
+	}
+	if len(types) == 0 {
+		fmt.Fprintln(os.Stderr, "The inventory is empty, so the resource types to sync must be given with -types")
+		os.Exit(2)
+	}
+
+	types, err = store.Stale(ctx, types, *maxAge)
+	if err != nil {
+		panic(err)
+	}
+
+	awsConfig, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		panic(err)
+	}
+
+	opts := []inventory.Opt{inventory.WithConcurrency(*concurrency), inventory.WithRefetchAge(*refetchAge)}
+	if *full {
+		opts = append(opts, inventory.WithFullSync())
+	}
+	syncer := inventory.NewSyncer(store, cloudcontrol.NewFromConfig(awsConfig), opts...)
+
+	// a type that fails to sync doesn't stop the others, but makes the command fail
+	failed := false
+	for _, t := range types {
+		result, err := syncer.Sync(ctx, t)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", t, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: %d listed, %d fetched, %d unchanged, %d deleted, %d failed in %s\n",
+			t, result.Listed, result.Fetched, result.Unchanged, result.Deleted, result.Failed, result.Duration.Round(time.Millisecond))
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/fergalhk/llm-cloud-discovery/internal/cmd"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/get"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/iamaccess"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/list"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/parsearn"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/aws/related"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/dns"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/k8s"
+)
+
+func main() {
+	awsConfig, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	// the kubeconfig is found the same way as kubectl finds it, from KUBECONFIG or ~/.kube/config
+	clients, err := k8s.NewKubeconfigClients("")
+	if err != nil {
+		panic(err)
+	}
+
+	awsListTool, err := list.NewTool(cloudformation.NewFromConfig(awsConfig), cloudcontrol.NewFromConfig(awsConfig))
+	if err != nil {
+		panic(err)
+	}
+
+	cmd.Run(
+		`You are a helpful assistant that can answer questions about infrastructure resources, in Kubernetes clusters running on AWS & in AWS itself.
+
+The tools provided should be called multiple times if necessary to answer the question.
+
+To find resources in a Kubernetes cluster, use the list_k8s_resources tool, then use the get_k8s_resource tool to get the details of a particular resource. Both tools take a context parameter to choose the cluster, which defaults to the current kubeconfig context. Use the fields parameter to get only the fields you need, e.g. spec.serviceAccountName for pods.
+
+Kubernetes workloads are connected to AWS in these ways:
+
+* Pods assume IAM roles through IAM roles for service accounts (IRSA). A pod runs as the service account in its spec.serviceAccountName field, and the role is in the service account's eks.amazonaws.com/role-arn annotation. To find the pods that use a role, list the service accounts with that annotation, then list the pods in the same namespace & match their spec.serviceAccountName.
+* Nodes are EC2 instances. The instance ID is at the end of the node's spec.providerID field, e.g. i-0abc123 in aws:///eu-west-1a/i-0abc123.
+* Services of type LoadBalancer & ingresses are fronted by AWS load balancers, whose DNS names are in status.loadBalancer.ingress.hostname.
+* Persistent volumes are EBS volumes, whose volume IDs are in spec.csi.volumeHandle.
+
+To find resources in AWS, use the list_aws_resources tool to get a list of identifiers, and the get_aws_resource tool to get the details of each one. If you have an ARN, use the parse_arn tool to get the resource type & identifier to pass to get_aws_resource. To find out which actions an IAM role can perform on a resource, use the evaluate_iam_access tool.
+
+The tools do not have any context about the previous tool calls, so you must make sure to pass the correct parameters to each tool.
+
+Pay particular attention to the names of the properties & parameters provided to you for each tool. If you get these wrong, the tool will fail. You must also ensure that any required parameters are passed to the tool.
+`,
+		k8s.NewListTool(clients),
+		k8s.NewGetTool(clients),
+		awsListTool,
+		get.NewTool(cloudcontrol.NewFromConfig(awsConfig)),
+		parsearn.Tool{},
+		iamaccess.NewTool(cloudcontrol.NewFromConfig(awsConfig)),
+		related.NewTool(cloudcontrol.NewFromConfig(awsConfig)),
+		dns.New(dns.WithServer(os.Getenv("DNS_RESOLVER"))),
+	)
+}
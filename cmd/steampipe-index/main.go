@@ -0,0 +1,60 @@
+// Command steampipe-index builds the vector index the steampipe agent uses to find tables by
+// meaning. It should be run again whenever plugins are installed or updated.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/catalog"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/tableindex"
+	"github.com/ollama/ollama/api"
+)
+
+func main() {
+	defaultPath, err := tableindex.DefaultPath()
+	if err != nil {
+		panic(err)
+	}
+
+	ollamaURL := flag.String("ollama-url", "http://localhost:11434", "The URL of the Ollama server")
+	model := flag.String("model", tableindex.DefaultModel, "The embedding model to use")
+	path := flag.String("index", defaultPath, "The file to write the index to")
+	flag.Parse()
+
+	dbConnStr := os.Getenv("STEAMPIPE_DB")
+	if dbConnStr == "" {
+		panic("STEAMPIPE_DB is not set")
+	}
+
+	ctx := context.Background()
+	db, err := steampipe.NewPool(ctx, dbConnStr)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	apiURL, err := url.Parse(*ollamaURL)
+	if err != nil {
+		panic(fmt.Errorf("error parsing ollama URL: %w", err))
+	}
+	embedder := tableindex.NewOllamaEmbedder(api.NewClient(apiURL, new(http.Client)), *model)
+
+	idx, err := tableindex.Build(ctx, catalog.New(db), embedder, *model, func(done, total int) {
+		fmt.Fprintf(os.Stderr, "\rEmbedded %d/%d tables", done, total)
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := idx.Save(*path); err != nil {
+		panic(err)
+	}
+	fmt.Printf("Wrote index of %d tables to %s\n", len(idx.Entries), *path)
+}
@@ -2,13 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"strings"
 
 	"github.com/fergalhk/llm-cloud-discovery/internal/cmd"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/catalog"
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/dml"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/explain"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/join"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/saved"
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/schema"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/tableindex"
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/tables"
-	"github.com/jackc/pgx/v5"
 )
 
 func main() {
@@ -17,32 +26,103 @@ func main() {
 		panic("STEAMPIPE_DB is not set")
 	}
 
-	db, err := pgx.Connect(context.Background(), dbConnStr)
+	db, err := steampipe.NewPool(context.Background(), dbConnStr)
 	if err != nil {
 		panic(err)
 	}
+	defer db.Close()
 
-	err = db.Ping(context.Background())
+	// saved queries are read from STEAMPIPE_SAVED_QUERIES in addition to the built in ones
+	library, err := saved.Load(os.Getenv("STEAMPIPE_SAVED_QUERIES"))
 	if err != nil {
 		panic(err)
 	}
+	execute := dml.New(db)
+
+	tablesOpts, err := indexOpts()
+	if err != nil {
+		panic(err)
+	}
+
+	// the prompt describes the plugins that are actually installed
+	schemas, err := catalog.New(db).Schemas(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	if len(schemas) == 0 {
+		panic("no steampipe plugins are installed")
+	}
 
 	cmd.Run(
-		`You are a helpful assistant that can answer questions about infrastructure resources, particularly but not exclusively those in AWS cloud.
+		fmt.Sprintf(`You are a helpful assistant that can answer questions about infrastructure resources, particularly but not exclusively those in AWS cloud.
+
+You have been provided with tools that allow you to query a PostgreSQL database containing resource data. Each installed steampipe plugin connection has its own schema, and its tables are prefixed with the plugin's name:
 
-You have been provided with 3 tools that allow you to query a PostgreSQL database containing AWS resource data. The tables are always prefixed with "aws_".
+%s
 
-To use the tools, you should follow this process:
+Unqualified table names refer to the first schema listed that has the table. To query another schema, qualify the table name with it, e.g. schema.table.
 
-1. Use the list_aws_tables tool to get a list of all the tables in the database. The number of tables is very large, so you should filter the results to narrow down the list. For example, if you're looking for EC2 data, you should use a resource_type_filter of ec2.
-2. Get the schema of the table you need using the get_aws_table_schema tool.
-3. Construct a SQL query, and run it using the execute_aws_query tool. Note that many resources need joins between multiple tables to return the correct data, so you should use these if necessary.
+Before writing your own query, use the list_saved_queries tool to check for a saved query that answers the question, and run it with the run_saved_query tool. Saved queries are vetted, so should be preferred.
+
+Otherwise, to use the tools, you should follow this process:
+
+1. Use the list_aws_tables tool to get a list of all the tables in the database. The number of tables is very large, so you should filter the results to narrow down the list. For example, if you're looking for EC2 data, you should use a resource_type_filter of ec2, and a schema to only list the tables of one plugin.
+2. Get the schema of the table you need using the get_aws_table_schema tool. Any columns listed in required_quals must be used in the WHERE clause of your query.
+3. Construct a SQL query, and run it using the execute_aws_query tool. Only a single read only SELECT, WITH or EXPLAIN statement can be run at a time. Note that many resources need joins between multiple tables to return the correct data, so you should use these if necessary. If you're unsure how to join two tables, use the suggest_join tool to find the join condition. Before running a large or complex query, check it with the explain_aws_query tool, which reports errors & missing required quals without calling any AWS APIs. If a query fails, fix it using the error's did_you_mean names & schema, and run it again.
 
 The tools provided should be called multiple times if necessary to answer the question.
 
-`,
-		dml.New(db),
+`, schemaList(schemas)),
+		execute,
 		schema.New(db),
-		tables.New(db),
+		tables.New(db, tablesOpts...),
+		join.New(db),
+		explain.New(db),
+		saved.NewListTool(library),
+		saved.NewRunTool(library, execute),
 	)
 }
+
+// schemaList describes each plugin schema on its own line, e.g. "- aws: 520 tables prefixed aws_".
+func schemaList(schemas []catalog.Schema) string {
+	lines := make([]string, len(schemas))
+	for i, s := range schemas {
+		line := fmt.Sprintf("- %s: %d tables", s.Name, s.Tables)
+		if s.TablePrefix != "" {
+			line += fmt.Sprintf(" prefixed %s", s.TablePrefix)
+		}
+		if s.Plugin != "" {
+			line += fmt.Sprintf(", from the %s plugin", s.Plugin)
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indexOpts uses the table index built by the steampipe-index command, if there is one, to search
+// tables by meaning. The index is read from STEAMPIPE_TABLE_INDEX, or the default path, and
+// embeddings use the same Ollama server as the chat.
+func indexOpts() ([]tables.Opt, error) {
+	path := os.Getenv("STEAMPIPE_TABLE_INDEX")
+	if path == "" {
+		var err error
+		path, err = tableindex.DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	idx, err := tableindex.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	client, err := cmd.OllamaClient()
+	if err != nil {
+		return nil, fmt.Errorf("error creating ollama client: %w", err)
+	}
+
+	return []tables.Opt{tables.WithIndex(idx, tableindex.NewOllamaEmbedder(client, idx.Model))}, nil
+}
@@ -0,0 +1,107 @@
+// Package arn parses AWS ARNs and converts between ARNs and the CloudFormation type
+// names & primary identifiers used by the CloudControl API.
+package arn
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	prefix = "arn:"
+
+	DefaultPartition = "aws"
+)
+
+// ARN is a parsed Amazon Resource Name.
+type ARN struct {
+	Partition string `json:"partition"`
+	Service   string `json:"service"`
+	Region    string `json:"region,omitempty"`
+	AccountID string `json:"account_id,omitempty"`
+	// Resource is everything after the account ID, e.g. table/users.
+	Resource string `json:"resource"`
+	// ResourceType is the part of Resource before the first "/" or ":", e.g. table. It is
+	// empty for services that don't qualify their resources, such as S3 buckets and SQS queues.
+	ResourceType string `json:"resource_type,omitempty"`
+	// ResourceID is the rest of Resource, e.g. users.
+	ResourceID string `json:"resource_id"`
+}
+
+// IsARN returns true if s looks like an ARN.
+func IsARN(s string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+// Parse parses an ARN of the form arn:partition:service:region:account-id:resource.
+func Parse(s string) (ARN, error) {
+	if !IsARN(s) {
+		return ARN{}, fmt.Errorf("%q is not an ARN: ARNs begin with %q", s, prefix)
+	}
+
+	sections := strings.SplitN(s, ":", 6)
+	if len(sections) != 6 {
+		return ARN{}, fmt.Errorf("%q is not an ARN: expected 6 colon separated sections, got %d", s, len(sections))
+	}
+
+	a := ARN{
+		Partition: sections[1],
+		Service:   sections[2],
+		Region:    sections[3],
+		AccountID: sections[4],
+		Resource:  sections[5],
+	}
+	if a.Partition == "" || a.Service == "" || a.Resource == "" {
+		return ARN{}, fmt.Errorf("%q is not an ARN: partition, service and resource must not be empty", s)
+	}
+
+	a.ResourceType, a.ResourceID = splitResource(a.Service, a.Resource)
+
+	return a, nil
+}
+
+// PartitionOf returns the partition a region is in, e.g. aws-us-gov for us-gov-west-1. Unknown
+// regions are assumed to be in the default partition.
+func PartitionOf(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-isob-"):
+		return "aws-iso-b"
+	case strings.HasPrefix(region, "us-isof-"):
+		return "aws-iso-f"
+	case strings.HasPrefix(region, "us-iso-"):
+		return "aws-iso"
+	case strings.HasPrefix(region, "eu-isoe-"):
+		return "aws-iso-e"
+	default:
+		return DefaultPartition
+	}
+}
+
+// String formats the ARN.
+func (a ARN) String() string {
+	return strings.Join([]string{"arn", a.Partition, a.Service, a.Region, a.AccountID, a.Resource}, ":")
+}
+
+// splitResource splits the resource section into a type and ID. Services whose resources
+// aren't qualified by a type keep the whole section as the ID.
+func splitResource(service, resource string) (string, string) {
+	switch service {
+	case "s3", "sqs", "sns":
+		return "", resource
+	case "apigateway":
+		// e.g. /restapis/abc123/stages/prod
+		trimmed := strings.TrimPrefix(resource, "/")
+		resourceType, id, _ := strings.Cut(trimmed, "/")
+		return resourceType, id
+	}
+
+	i := strings.IndexAny(resource, "/:")
+	if i < 0 {
+		return "", resource
+	}
+	return resource[:i], resource[i+1:]
+}
@@ -0,0 +1,83 @@
+package arn
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		arn     string
+		want    ARN
+		wantErr bool
+	}{
+		{
+			name: "resource type & ID",
+			arn:  "arn:aws:dynamodb:eu-west-1:111111111111:table/users",
+			want: ARN{Partition: "aws", Service: "dynamodb", Region: "eu-west-1", AccountID: "111111111111", Resource: "table/users", ResourceType: "table", ResourceID: "users"},
+		},
+		{
+			name: "colon separated",
+			arn:  "arn:aws:rds:eu-west-1:111111111111:db:orders",
+			want: ARN{Partition: "aws", Service: "rds", Region: "eu-west-1", AccountID: "111111111111", Resource: "db:orders", ResourceType: "db", ResourceID: "orders"},
+		},
+		{
+			name: "ID with a path",
+			arn:  "arn:aws:ecs:eu-west-1:111111111111:service/prod/web",
+			want: ARN{Partition: "aws", Service: "ecs", Region: "eu-west-1", AccountID: "111111111111", Resource: "service/prod/web", ResourceType: "service", ResourceID: "prod/web"},
+		},
+		{
+			name: "unqualified resource",
+			arn:  "arn:aws:s3:::assets/logo.png",
+			want: ARN{Partition: "aws", Service: "s3", Resource: "assets/logo.png", ResourceID: "assets/logo.png"},
+		},
+		{
+			name: "API Gateway path",
+			arn:  "arn:aws:apigateway:eu-west-1::/restapis/abc123/stages/prod",
+			want: ARN{Partition: "aws", Service: "apigateway", Region: "eu-west-1", Resource: "/restapis/abc123/stages/prod", ResourceType: "restapis", ResourceID: "abc123/stages/prod"},
+		},
+		{
+			name: "other partition",
+			arn:  "arn:aws-us-gov:ec2:us-gov-west-1:111111111111:vpc/vpc-1",
+			want: ARN{Partition: "aws-us-gov", Service: "ec2", Region: "us-gov-west-1", AccountID: "111111111111", Resource: "vpc/vpc-1", ResourceType: "vpc", ResourceID: "vpc-1"},
+		},
+		{name: "not an ARN", arn: "users", wantErr: true},
+		{name: "too few sections", arn: "arn:aws:dynamodb:eu-west-1", wantErr: true},
+		{name: "no partition", arn: "arn::dynamodb:eu-west-1:111111111111:table/users", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.arn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.arn {
+				t.Errorf("got string %q, want %q", got.String(), tt.arn)
+			}
+		})
+	}
+}
+
+func TestPartitionOf(t *testing.T) {
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{region: "eu-west-1", want: "aws"},
+		{region: "us-gov-west-1", want: "aws-us-gov"},
+		{region: "cn-north-1", want: "aws-cn"},
+		{region: "us-iso-east-1", want: "aws-iso"},
+		{region: "us-isob-east-1", want: "aws-iso-b"},
+		{region: "", want: "aws"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			if got := PartitionOf(tt.region); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package arn
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type (
+	// mapping describes how ARNs of one resource type relate to a CloudFormation type.
+	mapping struct {
+		service      string
+		resourceType string
+		typeName     string
+		// identifier returns the CloudControl primary identifier for the ARN.
+		identifier func(a ARN) string
+		// arn builds an ARN from the CloudControl primary identifier. The partition, region and
+		// account ID of the template are already filled in.
+		arn func(template ARN, identifier string) (ARN, error)
+	}
+
+	// CloudControlResource is the CloudFormation type name & CloudControl primary identifier of a resource.
+	CloudControlResource struct {
+		TypeName   string `json:"type_name"`
+		Identifier string `json:"identifier"`
+	}
+)
+
+var (
+	mappings = []mapping{
+		named("acm", "certificate", "AWS::CertificateManager::Certificate", "/").identifiedByARN(),
+		{service: "apigateway", resourceType: "restapis", typeName: "AWS::ApiGateway::RestApi", identifier: firstSegment, arn: func(t ARN, id string) (ARN, error) {
+			t.AccountID = ""
+			t.Resource = "/restapis/" + id
+			return t, nil
+		}},
+		named("cloudfront", "distribution", "AWS::CloudFront::Distribution", "/").global(),
+		named("dynamodb", "table", "AWS::DynamoDB::Table", "/").withIdentifier(firstSegment, nil),
+		named("ec2", "instance", "AWS::EC2::Instance", "/"),
+		named("ec2", "internet-gateway", "AWS::EC2::InternetGateway", "/"),
+		named("ec2", "launch-template", "AWS::EC2::LaunchTemplate", "/"),
+		named("ec2", "natgateway", "AWS::EC2::NatGateway", "/"),
+		named("ec2", "network-acl", "AWS::EC2::NetworkAcl", "/"),
+		named("ec2", "network-interface", "AWS::EC2::NetworkInterface", "/"),
+		named("ec2", "route-table", "AWS::EC2::RouteTable", "/"),
+		named("ec2", "security-group", "AWS::EC2::SecurityGroup", "/"),
+		named("ec2", "subnet", "AWS::EC2::Subnet", "/"),
+		named("ec2", "volume", "AWS::EC2::Volume", "/"),
+		named("ec2", "vpc", "AWS::EC2::VPC", "/"),
+		named("ecr", "repository", "AWS::ECR::Repository", "/"),
+		named("ecs", "cluster", "AWS::ECS::Cluster", "/"),
+		{service: "ecs", resourceType: "service", typeName: "AWS::ECS::Service", identifier: func(a ARN) string {
+			// services are identified by their ARN & cluster name. Old format service ARNs don't include the cluster.
+			parts := strings.Split(a.ResourceID, "/")
+			if len(parts) < 2 {
+				return a.String()
+			}
+			return a.String() + "|" + parts[0]
+		}, arn: func(t ARN, id string) (ARN, error) {
+			serviceARN, _, _ := strings.Cut(id, "|")
+			return Parse(serviceARN)
+		}},
+		named("ecs", "task-definition", "AWS::ECS::TaskDefinition", "/").identifiedByARN(),
+		named("eks", "cluster", "AWS::EKS::Cluster", "/"),
+		named("elasticloadbalancing", "listener", "AWS::ElasticLoadBalancingV2::Listener", "/").identifiedByARN(),
+		named("elasticloadbalancing", "loadbalancer", "AWS::ElasticLoadBalancingV2::LoadBalancer", "/").identifiedByARN(),
+		named("elasticloadbalancing", "targetgroup", "AWS::ElasticLoadBalancingV2::TargetGroup", "/").identifiedByARN(),
+		named("events", "rule", "AWS::Events::Rule", "/").identifiedByARN(),
+		named("iam", "instance-profile", "AWS::IAM::InstanceProfile", "/").global().withIdentifier(lastSegment, nil),
+		named("iam", "policy", "AWS::IAM::ManagedPolicy", "/").identifiedByARN(),
+		named("iam", "role", "AWS::IAM::Role", "/").global().withIdentifier(lastSegment, nil),
+		named("iam", "user", "AWS::IAM::User", "/").global().withIdentifier(lastSegment, nil),
+		named("kms", "alias", "AWS::KMS::Alias", "/").withIdentifier(func(a ARN) string { return a.Resource }, func(t ARN, id string) (ARN, error) {
+			t.Resource = id
+			return t, nil
+		}),
+		named("kms", "key", "AWS::KMS::Key", "/"),
+		{service: "lambda", resourceType: "function", typeName: "AWS::Lambda::Function", identifier: func(a ARN) string {
+			// strip any version or alias qualifier
+			name, _, _ := strings.Cut(a.ResourceID, ":")
+			return name
+		}, arn: func(t ARN, id string) (ARN, error) {
+			t.Resource = "function:" + id
+			return t, nil
+		}},
+		{service: "logs", resourceType: "log-group", typeName: "AWS::Logs::LogGroup", identifier: func(a ARN) string {
+			return strings.TrimSuffix(a.ResourceID, ":*")
+		}, arn: func(t ARN, id string) (ARN, error) {
+			t.Resource = "log-group:" + id
+			return t, nil
+		}},
+		named("rds", "cluster", "AWS::RDS::DBCluster", ":"),
+		named("rds", "db", "AWS::RDS::DBInstance", ":"),
+		named("rds", "subgrp", "AWS::RDS::DBSubnetGroup", ":"),
+		named("route53", "hostedzone", "AWS::Route53::HostedZone", "/").global().withoutAccount(),
+		named("s3", "", "AWS::S3::Bucket", "").global().withoutAccount(),
+		named("secretsmanager", "secret", "AWS::SecretsManager::Secret", ":").identifiedByARN(),
+		{service: "sns", typeName: "AWS::SNS::Topic", identifier: func(a ARN) string { return a.String() }, arn: func(t ARN, id string) (ARN, error) {
+			return Parse(id)
+		}},
+		{service: "sqs", typeName: "AWS::SQS::Queue", identifier: func(a ARN) string {
+			return fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/%s", a.Region, a.AccountID, a.ResourceID)
+		}, arn: func(t ARN, id string) (ARN, error) {
+			// e.g. https://sqs.eu-west-1.amazonaws.com/123456789012/my-queue
+			parts := strings.Split(strings.TrimPrefix(id, "https://"), "/")
+			if len(parts) != 3 {
+				return ARN{}, fmt.Errorf("%q is not a queue URL", id)
+			}
+			host := strings.Split(parts[0], ".")
+			if len(host) > 1 {
+				t.Region = host[1]
+			}
+			t.AccountID = parts[1]
+			t.Resource = parts[2]
+			return t, nil
+		}},
+		named("states", "stateMachine", "AWS::StepFunctions::StateMachine", ":").identifiedByARN(),
+	}
+
+	// idPrefixes maps the prefixes of EC2 style resource IDs to their CloudFormation types.
+	idPrefixes = map[string]string{
+		"eni-":    "AWS::EC2::NetworkInterface",
+		"i-":      "AWS::EC2::Instance",
+		"igw-":    "AWS::EC2::InternetGateway",
+		"lt-":     "AWS::EC2::LaunchTemplate",
+		"nat-":    "AWS::EC2::NatGateway",
+		"acl-":    "AWS::EC2::NetworkAcl",
+		"rtb-":    "AWS::EC2::RouteTable",
+		"sg-":     "AWS::EC2::SecurityGroup",
+		"subnet-": "AWS::EC2::Subnet",
+		"vol-":    "AWS::EC2::Volume",
+		"vpc-":    "AWS::EC2::VPC",
+	}
+)
+
+// named returns a mapping for a resource identified by the part of its ARN after the resource type.
+func named(service, resourceType, typeName, separator string) mapping {
+	return mapping{
+		service:      service,
+		resourceType: resourceType,
+		typeName:     typeName,
+		identifier:   func(a ARN) string { return a.ResourceID },
+		arn: func(t ARN, id string) (ARN, error) {
+			t.Resource = resourceType + separator + id
+			return t, nil
+		},
+	}
+}
+
+// global changes the mapping to build ARNs without a region, e.g. for IAM & CloudFront.
+func (m mapping) global() mapping {
+	arn := m.arn
+	m.arn = func(t ARN, id string) (ARN, error) {
+		t.Region = ""
+		return arn(t, id)
+	}
+	return m
+}
+
+// withoutAccount changes the mapping to build ARNs without an account ID, e.g. for S3 buckets.
+func (m mapping) withoutAccount() mapping {
+	arn := m.arn
+	m.arn = func(t ARN, id string) (ARN, error) {
+		t.AccountID = ""
+		return arn(t, id)
+	}
+	return m
+}
+
+// identifiedByARN changes the mapping so that the CloudControl identifier is the full ARN.
+func (m mapping) identifiedByARN() mapping {
+	m.identifier = func(a ARN) string { return a.String() }
+	m.arn = func(_ ARN, id string) (ARN, error) { return Parse(id) }
+	return m
+}
+
+// withIdentifier replaces how the identifier is derived from the ARN and, if arn is non-nil,
+// how the ARN is built from the identifier.
+func (m mapping) withIdentifier(identifier func(a ARN) string, arn func(t ARN, id string) (ARN, error)) mapping {
+	m.identifier = identifier
+	if arn != nil {
+		m.arn = arn
+	}
+	return m
+}
+
+// firstSegment identifies resources with sub-resources by the first path segment, e.g. table/users/index/by-email gives users.
+func firstSegment(a ARN) string {
+	id, _, _ := strings.Cut(a.ResourceID, "/")
+	return id
+}
+
+// lastSegment identifies resources with paths by the final path segment, e.g. role/service-role/my-role gives my-role.
+func lastSegment(a ARN) string {
+	return a.ResourceID[strings.LastIndex(a.ResourceID, "/")+1:]
+}
+
+// ToCloudControl returns the CloudFormation type name & CloudControl primary identifier for the ARN.
+func (a ARN) ToCloudControl() (CloudControlResource, error) {
+	if a.Service == "s3" && strings.Contains(a.ResourceID, "/") {
+		return CloudControlResource{}, fmt.Errorf("%s is an S3 object, which is not a CloudControl resource", a)
+	}
+
+	for _, m := range mappings {
+		if m.service == a.Service && m.resourceType == a.ResourceType {
+			return CloudControlResource{TypeName: m.typeName, Identifier: m.identifier(a)}, nil
+		}
+	}
+
+	return CloudControlResource{}, fmt.Errorf("no known CloudFormation type for %s resources of type %q", a.Service, a.ResourceType)
+}
+
+// FromCloudControl builds the ARN for a CloudControl resource. Region & account ID are
+// ignored for global resources, and for resources whose identifier is already an ARN.
+func FromCloudControl(partition, region, accountID string, r CloudControlResource) (ARN, error) {
+	if IsARN(r.Identifier) {
+		// composite identifiers such as ECS services append other properties after a "|"
+		identifierARN, _, _ := strings.Cut(r.Identifier, "|")
+		return Parse(identifierARN)
+	}
+	if partition == "" {
+		partition = DefaultPartition
+	}
+
+	for _, m := range mappings {
+		if m.typeName != r.TypeName {
+			continue
+		}
+
+		a, err := m.arn(ARN{Partition: partition, Service: m.service, Region: region, AccountID: accountID}, r.Identifier)
+		if err != nil {
+			return ARN{}, err
+		}
+		a.ResourceType, a.ResourceID = splitResource(a.Service, a.Resource)
+		return a, nil
+	}
+
+	return ARN{}, fmt.Errorf("no known ARN format for %s", r.TypeName)
+}
+
+// GuessTypeFromID returns the CloudFormation type of an EC2 style resource ID, e.g. sg-0123 is a
+// security group. The second return value is false if the ID isn't recognised.
+func GuessTypeFromID(id string) (string, bool) {
+	for prefix, typeName := range idPrefixes {
+		if strings.HasPrefix(id, prefix) {
+			return typeName, true
+		}
+	}
+	return "", false
+}
+
+// SupportedTypes returns the CloudFormation types that can be converted to & from ARNs.
+func SupportedTypes() []string {
+	typeNames := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		typeNames = append(typeNames, m.typeName)
+	}
+	sort.Strings(typeNames)
+	return typeNames
+}
+
+// NormalizeIdentifier converts an ARN into the CloudControl primary identifier for resourceType.
+// Identifiers that aren't ARNs, or ARNs of a different type, are returned unchanged.
+func NormalizeIdentifier(resourceType, identifier string) string {
+	if !IsARN(identifier) {
+		return identifier
+	}
+
+	parsed, err := Parse(identifier)
+	if err != nil {
+		return identifier
+	}
+
+	cc, err := parsed.ToCloudControl()
+	if err != nil || cc.TypeName != resourceType {
+		return identifier
+	}
+
+	return cc.Identifier
+}
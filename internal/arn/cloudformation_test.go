@@ -0,0 +1,244 @@
+package arn
+
+import "testing"
+
+func TestCloudControl(t *testing.T) {
+	tests := []struct {
+		name           string
+		arn            string
+		wantType       string
+		wantIdentifier string
+		// wantARN is the ARN built back from the identifier, if it isn't the same as arn.
+		wantARN string
+	}{
+		{
+			name:           "named",
+			arn:            "arn:aws:ec2:eu-west-1:111111111111:security-group/sg-0123",
+			wantType:       "AWS::EC2::SecurityGroup",
+			wantIdentifier: "sg-0123",
+		},
+		{
+			name:           "colon separated",
+			arn:            "arn:aws:rds:eu-west-1:111111111111:cluster:orders",
+			wantType:       "AWS::RDS::DBCluster",
+			wantIdentifier: "orders",
+		},
+		{
+			name:           "sub-resource",
+			arn:            "arn:aws:dynamodb:eu-west-1:111111111111:table/users/index/by-email",
+			wantType:       "AWS::DynamoDB::Table",
+			wantIdentifier: "users",
+			wantARN:        "arn:aws:dynamodb:eu-west-1:111111111111:table/users",
+		},
+		{
+			name:           "IAM role with a path",
+			arn:            "arn:aws:iam::111111111111:role/service-role/app",
+			wantType:       "AWS::IAM::Role",
+			wantIdentifier: "app",
+			wantARN:        "arn:aws:iam::111111111111:role/app",
+		},
+		{
+			name:           "global",
+			arn:            "arn:aws:cloudfront::111111111111:distribution/E2QWRUHAPOMQZL",
+			wantType:       "AWS::CloudFront::Distribution",
+			wantIdentifier: "E2QWRUHAPOMQZL",
+		},
+		{
+			name:           "S3 bucket",
+			arn:            "arn:aws:s3:::assets",
+			wantType:       "AWS::S3::Bucket",
+			wantIdentifier: "assets",
+		},
+		{
+			name:           "identified by ARN",
+			arn:            "arn:aws:ecs:eu-west-1:111111111111:task-definition/web:3",
+			wantType:       "AWS::ECS::TaskDefinition",
+			wantIdentifier: "arn:aws:ecs:eu-west-1:111111111111:task-definition/web:3",
+		},
+		{
+			name:           "ECS service",
+			arn:            "arn:aws:ecs:eu-west-1:111111111111:service/prod/web",
+			wantType:       "AWS::ECS::Service",
+			wantIdentifier: "arn:aws:ecs:eu-west-1:111111111111:service/prod/web|prod",
+		},
+		{
+			name:           "old format ECS service",
+			arn:            "arn:aws:ecs:eu-west-1:111111111111:service/web",
+			wantType:       "AWS::ECS::Service",
+			wantIdentifier: "arn:aws:ecs:eu-west-1:111111111111:service/web",
+		},
+		{
+			name:           "Lambda alias",
+			arn:            "arn:aws:lambda:eu-west-1:111111111111:function:api:live",
+			wantType:       "AWS::Lambda::Function",
+			wantIdentifier: "api",
+			wantARN:        "arn:aws:lambda:eu-west-1:111111111111:function:api",
+		},
+		{
+			name:           "log group",
+			arn:            "arn:aws:logs:eu-west-1:111111111111:log-group:/ecs/web:*",
+			wantType:       "AWS::Logs::LogGroup",
+			wantIdentifier: "/ecs/web",
+			wantARN:        "arn:aws:logs:eu-west-1:111111111111:log-group:/ecs/web",
+		},
+		{
+			name:           "KMS alias",
+			arn:            "arn:aws:kms:eu-west-1:111111111111:alias/app",
+			wantType:       "AWS::KMS::Alias",
+			wantIdentifier: "alias/app",
+		},
+		{
+			name:           "API Gateway stage",
+			arn:            "arn:aws:apigateway:eu-west-1::/restapis/abc123/stages/prod",
+			wantType:       "AWS::ApiGateway::RestApi",
+			wantIdentifier: "abc123",
+			wantARN:        "arn:aws:apigateway:eu-west-1::/restapis/abc123",
+		},
+		{
+			name:           "SQS queue",
+			arn:            "arn:aws:sqs:eu-west-1:111111111111:jobs",
+			wantType:       "AWS::SQS::Queue",
+			wantIdentifier: "https://sqs.eu-west-1.amazonaws.com/111111111111/jobs",
+		},
+		{
+			name:           "SNS topic",
+			arn:            "arn:aws:sns:eu-west-1:111111111111:alerts",
+			wantType:       "AWS::SNS::Topic",
+			wantIdentifier: "arn:aws:sns:eu-west-1:111111111111:alerts",
+		},
+		{
+			name:           "other partition",
+			arn:            "arn:aws-cn:ec2:cn-north-1:111111111111:vpc/vpc-1",
+			wantType:       "AWS::EC2::VPC",
+			wantIdentifier: "vpc-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := Parse(tt.arn)
+			if err != nil {
+				t.Fatalf("error parsing ARN: %v", err)
+			}
+			cc, err := a.ToCloudControl()
+			if err != nil {
+				t.Fatalf("error converting to CloudControl: %v", err)
+			}
+			if cc.TypeName != tt.wantType || cc.Identifier != tt.wantIdentifier {
+				t.Errorf("got %s %q, want %s %q", cc.TypeName, cc.Identifier, tt.wantType, tt.wantIdentifier)
+			}
+
+			wantARN := tt.wantARN
+			if wantARN == "" {
+				wantARN = tt.arn
+			}
+			got, err := FromCloudControl(a.Partition, a.Region, a.AccountID, cc)
+			if err != nil {
+				t.Fatalf("error building ARN: %v", err)
+			}
+			if got.String() != wantARN {
+				t.Errorf("got ARN %s, want %s", got, wantARN)
+			}
+			if parsed, _ := Parse(wantARN); got != parsed {
+				t.Errorf("got %+v, want %+v", got, parsed)
+			}
+		})
+	}
+}
+
+func TestToCloudControlErrors(t *testing.T) {
+	for _, s := range []string{
+		"arn:aws:s3:::assets/logo.png",
+		"arn:aws:ec2:eu-west-1:111111111111:image/ami-0123",
+	} {
+		a, err := Parse(s)
+		if err != nil {
+			t.Fatalf("error parsing %s: %v", s, err)
+		}
+		if cc, err := a.ToCloudControl(); err == nil {
+			t.Errorf("got %+v for %s, want an error", cc, s)
+		}
+	}
+}
+
+func TestFromCloudControl(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource CloudControlResource
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "default partition",
+			resource: CloudControlResource{TypeName: "AWS::EC2::VPC", Identifier: "vpc-1"},
+			want:     "arn:aws:ec2:eu-west-1:111111111111:vpc/vpc-1",
+		},
+		{
+			name:     "without account",
+			resource: CloudControlResource{TypeName: "AWS::Route53::HostedZone", Identifier: "Z0123"},
+			want:     "arn:aws:route53:::hostedzone/Z0123",
+		},
+		{
+			name:     "invalid queue URL",
+			resource: CloudControlResource{TypeName: "AWS::SQS::Queue", Identifier: "jobs"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown type",
+			resource: CloudControlResource{TypeName: "AWS::Example::Widget", Identifier: "widget"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromCloudControl("", "eu-west-1", "111111111111", tt.resource)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeIdentifier(t *testing.T) {
+	tests := []struct {
+		resourceType string
+		identifier   string
+		want         string
+	}{
+		{resourceType: "AWS::DynamoDB::Table", identifier: "arn:aws:dynamodb:eu-west-1:111111111111:table/users", want: "users"},
+		{resourceType: "AWS::DynamoDB::Table", identifier: "users", want: "users"},
+		{resourceType: "AWS::IAM::Role", identifier: "arn:aws:dynamodb:eu-west-1:111111111111:table/users", want: "arn:aws:dynamodb:eu-west-1:111111111111:table/users"},
+		{resourceType: "AWS::DynamoDB::Table", identifier: "arn:aws:dynamodb", want: "arn:aws:dynamodb"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeIdentifier(tt.resourceType, tt.identifier); got != tt.want {
+			t.Errorf("NormalizeIdentifier(%s, %s) = %s, want %s", tt.resourceType, tt.identifier, got, tt.want)
+		}
+	}
+}
+
+func TestGuessTypeFromID(t *testing.T) {
+	tests := []struct {
+		id     string
+		want   string
+		wantOK bool
+	}{
+		{id: "sg-0123456789abcdef0", want: "AWS::EC2::SecurityGroup", wantOK: true},
+		{id: "subnet-0123", want: "AWS::EC2::Subnet", wantOK: true},
+		{id: "i-0123", want: "AWS::EC2::Instance", wantOK: true},
+		{id: "igw-0123", want: "AWS::EC2::InternetGateway", wantOK: true},
+		{id: "ami-0123"},
+	}
+
+	for _, tt := range tests {
+		got, ok := GuessTypeFromID(tt.id)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GuessTypeFromID(%s) = %s, %v, want %s, %v", tt.id, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
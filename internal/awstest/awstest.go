@@ -0,0 +1,178 @@
+// Package awstest has local stand-ins for AWS APIs, so tools can be tested without calling AWS.
+// Each stand-in is an http.Handler, and Config returns the configuration for an SDK client that
+// sends its requests to one.
+package awstest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const (
+	// Region is the region of the clients returned by Config.
+	Region = "eu-west-1"
+
+	// ec2Version is the API version sent by EC2 clients, which have their own error format.
+	ec2Version = "2016-11-15"
+)
+
+type (
+	// Error is an AWS API error, returned by an operation to send an error response.
+	Error struct {
+		Code    string
+		Message string
+	}
+
+	// JSONOperation handles a request to an API using the AWS JSON protocol, returning the
+	// response to encode as JSON.
+	JSONOperation func(body []byte) (any, error)
+
+	// QueryOperation handles a request to an API using the AWS query protocol, returning the XML
+	// response.
+	QueryOperation func(form url.Values) (string, error)
+
+	// RESTOperation handles a request to an API using the AWS REST XML protocol, returning the XML
+	// response.
+	RESTOperation func(r *http.Request) (string, error)
+)
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Config starts a server for handler, returning the configuration for SDK clients to use it,
+// e.g. with ec2.NewFromConfig. Requests aren't signed or retried.
+func Config(t testing.TB, handler http.Handler) aws.Config {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return aws.Config{
+		Region:           Region,
+		BaseEndpoint:     aws.String(server.URL),
+		Credentials:      aws.AnonymousCredentials{},
+		RetryMaxAttempts: 1,
+	}
+}
+
+// JSON decodes the body of a JSON protocol request into a request of type T for fn.
+func JSON[T any](fn func(req T) (any, error)) JSONOperation {
+	return func(body []byte) (any, error) {
+		var req T
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, &Error{Code: "SerializationException", Message: err.Error()}
+		}
+		return fn(req)
+	}
+}
+
+// JSONHandler serves an API using the AWS JSON protocol, with operations keyed by their target,
+// e.g. CloudApiService.GetResource. An *Error returned by an operation is sent as an error
+// response with a 400 status, & any other error with a 500 status.
+func JSONHandler(operations map[string]JSONOperation) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.Header.Get("X-Amz-Target")
+		op, ok := operations[target]
+		if !ok {
+			http.Error(w, "unexpected operation "+target, http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := op(body)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		if err != nil {
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				apiErr = &Error{Code: "InternalFailure", Message: err.Error()}
+				w.WriteHeader(http.StatusInternalServerError)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			json.NewEncoder(w).Encode(map[string]string{"__type": apiErr.Code, "message": apiErr.Message})
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// QueryHandler serves an API using the AWS query protocol, such as EC2 or CloudFormation, with
+// operations keyed by their action. An *Error returned by an operation is sent as an error
+// response with a 400 status, & any other error with a 500 status.
+func QueryHandler(operations map[string]QueryOperation) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		action := r.Form.Get("Action")
+		op, ok := operations[action]
+		if !ok {
+			http.Error(w, "unexpected action "+action, http.StatusBadRequest)
+			return
+		}
+
+		resp, err := op(r.Form)
+		writeXML(w, r.Form.Get("Version"), resp, err)
+	})
+}
+
+// RESTHandler serves an API using the AWS REST XML protocol, such as Route 53 or CloudFront, with
+// operations keyed by their method & path as http.ServeMux patterns, e.g.
+// "GET /2013-04-01/hostedzone/{id}/rrset". Errors are sent as by QueryHandler.
+func RESTHandler(operations map[string]RESTOperation) http.Handler {
+	mux := http.NewServeMux()
+	for pattern, op := range operations {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			resp, err := op(r)
+			writeXML(w, "", resp, err)
+		})
+	}
+	return mux
+}
+
+// writeXML writes the XML response of an operation, or its error as a 400 if it's an *Error, else a
+// 500.
+func writeXML(w http.ResponseWriter, version, resp string, err error) {
+	w.Header().Set("Content-Type", "text/xml")
+	if err != nil {
+		var apiErr *Error
+		if !errors.As(err, &apiErr) {
+			apiErr = &Error{Code: "InternalFailure", Message: err.Error()}
+			w.WriteHeader(http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		fmt.Fprint(w, queryError(version, apiErr))
+		return
+	}
+	io.WriteString(w, resp)
+}
+
+// queryError returns the XML of an error response. EC2 has its own format, & the other query &
+// REST XML APIs share one.
+func queryError(version string, err *Error) string {
+	var code, message strings.Builder
+	xml.EscapeText(&code, []byte(err.Code))
+	xml.EscapeText(&message, []byte(err.Message))
+
+	if version == ec2Version {
+		return fmt.Sprintf("<Response><Errors><Error><Code>%s</Code><Message>%s</Message></Error></Errors></Response>", code.String(), message.String())
+	}
+	return fmt.Sprintf("<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>%s</Message></Error></ErrorResponse>", code.String(), message.String())
+}
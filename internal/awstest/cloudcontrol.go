@@ -0,0 +1,171 @@
+package awstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+)
+
+type (
+	// CloudControl is a stand-in for the Cloud Control API, serving fixture resources.
+	CloudControl struct {
+		mu        sync.Mutex
+		resources map[string]map[string]Resource
+		// listModels are the properties a resource model must give to list a type, e.g. Cluster
+		// for ECS services.
+		listModels map[string]string
+		gets       int
+	}
+
+	// Resource is a fixture resource.
+	Resource struct {
+		// Properties are returned by GetResource. Getting a resource without properties fails.
+		Properties string
+		// ListProperties are returned by ListResources. Only the identifier is listed without them,
+		// as with most resource types.
+		ListProperties string
+	}
+
+	resourceRequest struct {
+		TypeName      string
+		Identifier    string
+		ResourceModel string
+		NextToken     string
+	}
+)
+
+// NewCloudControl returns a stand-in without any resources.
+func NewCloudControl() *CloudControl {
+	return &CloudControl{resources: map[string]map[string]Resource{}, listModels: map[string]string{}}
+}
+
+// LoadCloudControl returns a stand-in serving the resources in a JSON fixture, which has the
+// properties of each resource by type name & identifier.
+func LoadCloudControl(t testing.TB, path string) *CloudControl {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading fixture: %v", err)
+	}
+	fixture := map[string]map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("error unmarshalling fixture %s: %v", path, err)
+	}
+
+	c := NewCloudControl()
+	for typeName, resources := range fixture {
+		for identifier, properties := range resources {
+			c.Set(typeName, identifier, Resource{Properties: string(properties)})
+		}
+	}
+	return c
+}
+
+// Client returns a Cloud Control client using the stand-in.
+func (c *CloudControl) Client(t testing.TB) *cloudcontrol.Client {
+	t.Helper()
+	return cloudcontrol.NewFromConfig(Config(t, c))
+}
+
+// Set adds or replaces a resource.
+func (c *CloudControl) Set(typeName, identifier string, r Resource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.resources[typeName] == nil {
+		c.resources[typeName] = map[string]Resource{}
+	}
+	c.resources[typeName][identifier] = r
+}
+
+// Delete removes a resource.
+func (c *CloudControl) Delete(typeName, identifier string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.resources[typeName], identifier)
+}
+
+// RequireListModel makes listing a type fail unless the resource model gives property, and only
+// lists the resources whose property has the same value, as for ECS services & their Cluster.
+func (c *CloudControl) RequireListModel(typeName, property string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listModels[typeName] = property
+}
+
+// Gets returns the number of GetResource requests, & resets it.
+func (c *CloudControl) Gets() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	gets := c.gets
+	c.gets = 0
+	return gets
+}
+
+func (c *CloudControl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	JSONHandler(map[string]JSONOperation{
+		"CloudApiService.GetResource":   JSON(c.getResource),
+		"CloudApiService.ListResources": JSON(c.listResources),
+	}).ServeHTTP(w, r)
+}
+
+func (c *CloudControl) getResource(req resourceRequest) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gets++
+	resource, ok := c.resources[req.TypeName][req.Identifier]
+	if !ok || resource.Properties == "" {
+		return nil, &Error{Code: "ResourceNotFoundException", Message: "Resource of type '" + req.TypeName + "' with identifier '" + req.Identifier + "' was not found."}
+	}
+
+	return map[string]any{
+		"TypeName":            req.TypeName,
+		"ResourceDescription": map[string]string{"Identifier": req.Identifier, "Properties": resource.Properties},
+	}, nil
+}
+
+func (c *CloudControl) listResources(req resourceRequest) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var want any
+	property, modelRequired := c.listModels[req.TypeName]
+	if modelRequired {
+		model := map[string]any{}
+		json.Unmarshal([]byte(req.ResourceModel), &model)
+		if want = model[property]; want == nil {
+			return nil, &Error{Code: "InvalidRequestException", Message: "Missing Or Invalid ResourceModel property in " + req.TypeName + " list handler request input. Required property: [" + property + "]"}
+		}
+	}
+
+	identifiers := make([]string, 0, len(c.resources[req.TypeName]))
+	for identifier, resource := range c.resources[req.TypeName] {
+		if modelRequired {
+			properties := map[string]any{}
+			json.Unmarshal([]byte(resource.Properties), &properties)
+			if properties[property] != want {
+				continue
+			}
+		}
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+
+	descriptions := []map[string]string{}
+	for _, identifier := range identifiers {
+		d := map[string]string{"Identifier": identifier}
+		if props := c.resources[req.TypeName][identifier].ListProperties; props != "" {
+			d["Properties"] = props
+		}
+		descriptions = append(descriptions, d)
+	}
+	return map[string]any{"TypeName": req.TypeName, "ResourceDescriptions": descriptions}, nil
+}
@@ -18,6 +18,11 @@ import (
 )
 
 var (
+	ollamaURL = flag.String("ollama-url", "http://localhost:11434", "The URL of the Ollama server")
+	modelName = flag.String("model", constants.DefaultModel, "The model to use for the LLM")
+	debug     = flag.Bool("debug", false, "Enable debug logging")
+	prompt    = flag.String("prompt", "", "The prompt to ask the LLM")
+
 	exitMessages = map[string]struct{}{
 		"exit":   {},
 		"quit":   {},
@@ -34,17 +39,13 @@ var (
 )
 
 func Run(systemPrompt string, toolFunctions ...tools.Function) {
-	ollamaURL := flag.String("ollama-url", "http://localhost:11434", "The URL of the Ollama server")
-	modelName := flag.String("model", constants.DefaultModel, "The model to use for the LLM")
-	debug := flag.Bool("debug", false, "Enable debug logging")
-	prompt := flag.String("prompt", "", "The prompt to ask the LLM")
-	flag.Parse()
+	parseFlags()
 
 	log := newLogger(*debug)
 	defer log.Sync()
 
 	// Create Ollama client
-	ollamaClient, err := newOllamaClient(*ollamaURL)
+	ollamaClient, err := OllamaClient()
 	if err != nil {
 		log.Panic("Error creating Ollama client", zap.Error(err))
 	}
@@ -98,8 +99,12 @@ func Run(systemPrompt string, toolFunctions ...tools.Function) {
 	}
 }
 
-func newOllamaClient(ollamaURL string) (*api.Client, error) {
-	apiURL, err := url.Parse(ollamaURL)
+// OllamaClient returns a client for the Ollama server given by the -ollama-url flag, for tools that
+// need the same server as the chat, e.g. for embeddings. It can be called before Run.
+func OllamaClient() (*api.Client, error) {
+	parseFlags()
+
+	apiURL, err := url.Parse(*ollamaURL)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing ollama URL: %w", err)
 	}
@@ -107,6 +112,12 @@ func newOllamaClient(ollamaURL string) (*api.Client, error) {
 	return api.NewClient(apiURL, new(http.Client)), nil
 }
 
+func parseFlags() {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+}
+
 func newLogger(debug bool) *zap.Logger {
 	cfg := zap.NewProductionConfig()
 	if debug {
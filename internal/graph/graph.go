@@ -0,0 +1,249 @@
+// Package graph builds a relationship graph between cloud resources by finding
+// references to one resource inside the properties of another.
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// minTokenLength is the shortest string that is considered a reference. Shorter
+	// values (e.g. "true", "tcp") match far too many resources to be useful.
+	minTokenLength = 5
+)
+
+type (
+	// NodeID uniquely identifies a resource in the graph.
+	NodeID struct {
+		Type       string `json:"resource_type"`
+		Identifier string `json:"resource_identifier"`
+	}
+
+	// Node is a single resource and its properties.
+	Node struct {
+		ID         NodeID
+		Properties map[string]any
+	}
+
+	// Edge records that the properties of From reference To.
+	Edge struct {
+		From NodeID `json:"from"`
+		To   NodeID `json:"to"`
+		// Path is the location of the reference within the properties of From, e.g. SecurityGroupIds[0].
+		Path string `json:"path"`
+		Kind Kind   `json:"kind"`
+	}
+
+	// Kind is a coarse classification of an edge, derived from where the reference was found.
+	Kind string
+
+	// Related is a resource reachable from the starting resource.
+	Related struct {
+		NodeID
+		Hops int `json:"hops"`
+		// Via is the chain of edges from the starting resource to this one.
+		Via []Edge `json:"via"`
+	}
+
+	// Graph holds resources and the references between them. Edges are computed lazily,
+	// so resources can be added in any order. Graph is not safe for concurrent use.
+	Graph struct {
+		nodes map[NodeID]*Node
+		edges map[NodeID][]Edge
+		dirty bool
+	}
+)
+
+const (
+	KindReference     Kind = "reference"
+	KindSecurityGroup Kind = "security_group"
+	KindIAMPolicy     Kind = "iam_policy"
+	KindEnvironment   Kind = "environment_variable"
+	KindNetwork       Kind = "network"
+)
+
+func (n NodeID) String() string {
+	return n.Type + " " + n.Identifier
+}
+
+func New() *Graph {
+	return &Graph{
+		nodes: make(map[NodeID]*Node),
+		edges: make(map[NodeID][]Edge),
+	}
+}
+
+// AddResource adds a resource to the graph from its JSON properties, replacing any existing
+// resource with the same ID.
+func (g *Graph) AddResource(resourceType, identifier string, propertiesJSON string) error {
+	properties := map[string]any{}
+	if propertiesJSON != "" {
+		err := json.Unmarshal([]byte(propertiesJSON), &properties)
+		if err != nil {
+			return fmt.Errorf("error unmarshalling properties of %s %s: %w", resourceType, identifier, err)
+		}
+	}
+
+	id := NodeID{Type: resourceType, Identifier: identifier}
+	g.nodes[id] = &Node{ID: id, Properties: properties}
+	g.dirty = true
+
+	return nil
+}
+
+// Has returns true if the resource is in the graph.
+func (g *Graph) Has(id NodeID) bool {
+	_, ok := g.nodes[id]
+	return ok
+}
+
+// Identifiers returns the identifiers of the resources of the given type, in order.
+func (g *Graph) Identifiers(resourceType string) []string {
+	identifiers := []string{}
+	for id := range g.nodes {
+		if id.Type == resourceType {
+			identifiers = append(identifiers, id.Identifier)
+		}
+	}
+	sort.Strings(identifiers)
+	return identifiers
+}
+
+// Len returns the number of resources in the graph.
+func (g *Graph) Len() int {
+	return len(g.nodes)
+}
+
+// Edges returns the edges to and from the given resource.
+func (g *Graph) Edges(id NodeID) []Edge {
+	g.build()
+	return g.edges[id]
+}
+
+// Related walks the graph outwards from the given resource, returning every resource
+// within maxHops edges of it. References are followed in both directions, so a task
+// definition referencing a table and a table referenced by a task definition are both related.
+func (g *Graph) Related(id NodeID, maxHops int) ([]Related, error) {
+	if !g.Has(id) {
+		return nil, fmt.Errorf("resource %s is not in the graph", id)
+	}
+	g.build()
+
+	visited := map[NodeID]struct{}{id: {}}
+	frontier := []Related{{NodeID: id}}
+	related := []Related{}
+	for hop := 1; hop <= maxHops && len(frontier) > 0; hop++ {
+		next := []Related{}
+		for _, current := range frontier {
+			for _, edge := range g.edges[current.NodeID] {
+				other := edge.To
+				if other == current.NodeID {
+					other = edge.From
+				}
+				if _, ok := visited[other]; ok {
+					continue
+				}
+				visited[other] = struct{}{}
+
+				r := Related{
+					NodeID: other,
+					Hops:   hop,
+					Via:    append(append([]Edge{}, current.Via...), edge),
+				}
+				next = append(next, r)
+				related = append(related, r)
+			}
+		}
+		frontier = next
+	}
+
+	sort.SliceStable(related, func(i, j int) bool {
+		if related[i].Hops != related[j].Hops {
+			return related[i].Hops < related[j].Hops
+		}
+		return related[i].NodeID.String() < related[j].NodeID.String()
+	})
+
+	return related, nil
+}
+
+// build recomputes every edge in the graph if resources have been added since the last build.
+func (g *Graph) build() {
+	if !g.dirty {
+		return
+	}
+
+	index := g.buildIndex()
+	g.edges = make(map[NodeID][]Edge)
+	for _, node := range g.nodes {
+		seen := map[NodeID]struct{}{}
+		walkStrings(node.Properties, "", func(path, value string) {
+			for _, target := range index.lookup(value) {
+				if target == node.ID {
+					continue
+				}
+				if _, ok := seen[target]; ok {
+					continue
+				}
+				seen[target] = struct{}{}
+
+				edge := Edge{From: node.ID, To: target, Path: path, Kind: classify(path)}
+				g.edges[node.ID] = append(g.edges[node.ID], edge)
+				g.edges[target] = append(g.edges[target], edge)
+			}
+		})
+	}
+
+	for id := range g.edges {
+		sort.SliceStable(g.edges[id], func(i, j int) bool {
+			return g.edges[id][i].Path < g.edges[id][j].Path
+		})
+	}
+
+	g.dirty = false
+}
+
+// classify derives an edge kind from the property path the reference was found at.
+func classify(path string) Kind {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.Contains(lower, "securitygroup"):
+		return KindSecurityGroup
+	case strings.Contains(lower, "policy") || strings.Contains(lower, "policies"):
+		return KindIAMPolicy
+	case strings.Contains(lower, "environment"):
+		return KindEnvironment
+	case strings.Contains(lower, "subnet") || strings.Contains(lower, "vpc"):
+		return KindNetwork
+	default:
+		return KindReference
+	}
+}
+
+// walkStrings calls fn for every string value in v, along with its path.
+func walkStrings(v any, path string, fn func(path, value string)) {
+	switch val := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			walkStrings(val[k], childPath, fn)
+		}
+	case []any:
+		for i, elem := range val {
+			walkStrings(elem, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	case string:
+		fn(path, val)
+	}
+}
@@ -0,0 +1,165 @@
+package graph
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const fixturePath = "testdata/resources.json"
+
+var (
+	testTable     = NodeID{Type: "AWS::DynamoDB::Table", Identifier: "users"}
+	testFunction  = NodeID{Type: "AWS::Lambda::Function", Identifier: "worker"}
+	testQueue     = NodeID{Type: "AWS::SQS::Queue", Identifier: "https://sqs.eu-west-1.amazonaws.com/111111111111/jobs"}
+	testRole      = NodeID{Type: "AWS::IAM::Role", Identifier: "app"}
+	testService   = NodeID{Type: "AWS::ECS::Service", Identifier: "arn:aws:ecs:eu-west-1:111111111111:service/prod/web|prod"}
+	testTaskDef   = NodeID{Type: "AWS::ECS::TaskDefinition", Identifier: "arn:aws:ecs:eu-west-1:111111111111:task-definition/web:3"}
+	testWebGroup  = NodeID{Type: "AWS::EC2::SecurityGroup", Identifier: "sg-web"}
+	testWebSubnet = NodeID{Type: "AWS::EC2::Subnet", Identifier: "subnet-a"}
+)
+
+// newTestGraph returns a graph of the fixture resources, which has the properties of each
+// resource by type name & identifier.
+func newTestGraph(t *testing.T) *Graph {
+	t.Helper()
+
+	data, err := os.ReadFile(fixturePath)
+	if err != nil {
+		t.Fatalf("error reading fixture: %v", err)
+	}
+	fixture := map[string]map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("error unmarshalling fixture: %v", err)
+	}
+
+	g := New()
+	for resourceType, resources := range fixture {
+		for identifier, properties := range resources {
+			if err := g.AddResource(resourceType, identifier, string(properties)); err != nil {
+				t.Fatalf("error adding %s %s: %v", resourceType, identifier, err)
+			}
+		}
+	}
+	return g
+}
+
+func TestRelated(t *testing.T) {
+	type want struct {
+		id   NodeID
+		hops int
+		// path & kind are of the last edge on the way to the resource.
+		path string
+		kind Kind
+	}
+
+	tests := []struct {
+		name string
+		from NodeID
+		hops int
+		want []want
+	}{
+		{
+			name: "one hop",
+			from: testTable,
+			hops: 1,
+			want: []want{
+				{id: testTaskDef, hops: 1, path: "ContainerDefinitions[0].Environment[0].Value", kind: KindEnvironment},
+				{id: testRole, hops: 1, path: "Policies[0].PolicyDocument.Statement[0].Resource", kind: KindIAMPolicy},
+			},
+		},
+		{
+			name: "references followed in both directions",
+			from: testTable,
+			hops: 3,
+			want: []want{
+				{id: testTaskDef, hops: 1, path: "ContainerDefinitions[0].Environment[0].Value", kind: KindEnvironment},
+				{id: testRole, hops: 1, path: "Policies[0].PolicyDocument.Statement[0].Resource", kind: KindIAMPolicy},
+				{id: testService, hops: 2, path: "TaskDefinition", kind: KindReference},
+				{id: testWebGroup, hops: 3, path: "NetworkConfiguration.AwsvpcConfiguration.SecurityGroups[0]", kind: KindSecurityGroup},
+				{id: testWebSubnet, hops: 3, path: "NetworkConfiguration.AwsvpcConfiguration.Subnets[0]", kind: KindNetwork},
+			},
+		},
+		{
+			name: "unique names are references, but shared & short values aren't",
+			from: testFunction,
+			hops: 3,
+			want: []want{
+				{id: testQueue, hops: 1, path: "Environment.Variables.QUEUE", kind: KindEnvironment},
+			},
+		},
+	}
+
+	g := newTestGraph(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			related, err := g.Related(tt.from, tt.hops)
+			if err != nil {
+				t.Fatalf("error getting related resources: %v", err)
+			}
+
+			got := []want{}
+			for _, r := range related {
+				if len(r.Via) != r.Hops {
+					t.Errorf("got %d edges to %s, want %d", len(r.Via), r.NodeID, r.Hops)
+				}
+				last := r.Via[len(r.Via)-1]
+				got = append(got, want{id: r.NodeID, hops: r.Hops, path: last.Path, kind: last.Kind})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelatedMissingResource(t *testing.T) {
+	_, err := newTestGraph(t).Related(NodeID{Type: "AWS::DynamoDB::Table", Identifier: "orders"}, 1)
+	if err == nil || !strings.Contains(err.Error(), "is not in the graph") {
+		t.Errorf("got error %v, want one for the missing resource", err)
+	}
+}
+
+func TestAddResourceReplaces(t *testing.T) {
+	g := newTestGraph(t)
+	if len(g.Edges(testFunction)) != 1 {
+		t.Fatalf("got edges %+v, want the queue", g.Edges(testFunction))
+	}
+
+	// edges are rebuilt once a resource changes
+	if err := g.AddResource(testFunction.Type, testFunction.Identifier, `{"FunctionName": "worker"}`); err != nil {
+		t.Fatalf("error replacing resource: %v", err)
+	}
+	if edges := g.Edges(testFunction); len(edges) != 0 {
+		t.Errorf("got edges %+v, want none", edges)
+	}
+
+	if err := g.AddResource(testFunction.Type, testFunction.Identifier, "{"); err == nil {
+		t.Error("got no error for invalid properties")
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	got := newTestGraph(t).Identifiers("AWS::EC2::SecurityGroup")
+	if want := []string{"sg-db", "sg-web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSplitCamelCase(t *testing.T) {
+	tests := map[string][]string{
+		"Table":                         {"Table"},
+		"SecurityGroup":                 {"Security", "Group"},
+		"DBInstance":                    {"DB", "Instance"},
+		"LoadBalancer":                  {"Load", "Balancer"},
+		"VPCEndpointServicePermissions": {"VPC", "Endpoint", "Service", "Permissions"},
+	}
+
+	for in, want := range tests {
+		if got := splitCamelCase(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitCamelCase(%s) = %v, want %v", in, got, want)
+		}
+	}
+}
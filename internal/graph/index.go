@@ -0,0 +1,149 @@
+package graph
+
+import (
+	"strings"
+	"unicode"
+)
+
+var (
+	// identityKeys are top-level property names that identify the resource they belong to,
+	// regardless of its type.
+	identityKeys = []string{"arn", "id", "dnsname", "domainname", "endpoint.address", "queueurl"}
+	// identitySuffixes are combined with the words of a resource type name to find further
+	// identifying properties, e.g. AWS::DynamoDB::Table gives TableName & TableArn.
+	identitySuffixes = []string{"arn", "id", "identifier", "url"}
+	// weakIdentitySuffixes identify a resource but are often reused between resources (e.g. a
+	// security group named "default"), so they're only used when they're unique in the graph.
+	weakIdentitySuffixes = []string{"name"}
+)
+
+type index struct {
+	strong map[string][]NodeID
+	weak   map[string][]NodeID
+}
+
+func (g *Graph) buildIndex() index {
+	idx := index{
+		strong: make(map[string][]NodeID),
+		weak:   make(map[string][]NodeID),
+	}
+
+	for _, node := range g.nodes {
+		idx.add(idx.strong, node.ID.Identifier, node.ID)
+
+		strongKeys, weakKeys := identityKeysFor(node.ID.Type)
+		for key, value := range topLevelStrings(node.Properties) {
+			lower := strings.ToLower(key)
+			if _, ok := strongKeys[lower]; ok {
+				idx.add(idx.strong, value, node.ID)
+			} else if _, ok := weakKeys[lower]; ok {
+				idx.add(idx.weak, value, node.ID)
+			}
+		}
+	}
+
+	return idx
+}
+
+func (idx index) add(m map[string][]NodeID, token string, id NodeID) {
+	if len(token) < minTokenLength {
+		return
+	}
+	for _, existing := range m[token] {
+		if existing == id {
+			return
+		}
+	}
+	m[token] = append(m[token], id)
+}
+
+// lookup returns the resources referenced by value.
+func (idx index) lookup(value string) []NodeID {
+	if len(value) < minTokenLength {
+		return nil
+	}
+
+	if ids, ok := idx.strong[value]; ok {
+		return ids
+	}
+	if ids := idx.weak[value]; len(ids) == 1 {
+		return ids
+	}
+
+	// ARNs in policies & config often point below or across the resource, e.g.
+	// arn:aws:s3:::bucket/* or arn:aws:dynamodb:...:table/users/index/by-email, so trim
+	// the ARN back one component at a time until it matches.
+	if strings.HasPrefix(value, "arn:") {
+		trimmed := strings.TrimRight(value, "*")
+		for {
+			i := strings.LastIndexAny(trimmed, "/:")
+			if i <= 0 {
+				break
+			}
+			trimmed = trimmed[:i]
+			if ids, ok := idx.strong[trimmed]; ok {
+				return ids
+			}
+		}
+	}
+
+	return nil
+}
+
+// identityKeysFor returns the lower case property names that identify a resource of the given type.
+func identityKeysFor(resourceType string) (strong, weak map[string]struct{}) {
+	strong = make(map[string]struct{})
+	weak = make(map[string]struct{})
+	for _, k := range identityKeys {
+		strong[k] = struct{}{}
+	}
+	weak["name"] = struct{}{}
+
+	segments := strings.Split(resourceType, "::")
+	words := splitCamelCase(segments[len(segments)-1])
+	for i := range words {
+		prefix := strings.ToLower(strings.Join(words[i:], ""))
+		for _, suffix := range identitySuffixes {
+			strong[prefix+suffix] = struct{}{}
+		}
+		for _, suffix := range weakIdentitySuffixes {
+			weak[prefix+suffix] = struct{}{}
+		}
+	}
+
+	return strong, weak
+}
+
+// topLevelStrings returns the string properties at the top level of a resource, along with
+// the addresses of any endpoint objects.
+func topLevelStrings(properties map[string]any) map[string]string {
+	out := make(map[string]string)
+	for k, v := range properties {
+		switch val := v.(type) {
+		case string:
+			out[k] = val
+		case map[string]any:
+			if address, ok := val["Address"].(string); ok {
+				out[k+".Address"] = address
+			}
+		}
+	}
+	return out
+}
+
+// splitCamelCase splits a type name into words, keeping acronyms together,
+// e.g. DBInstance gives [DB Instance] and SecurityGroup gives [Security Group].
+func splitCamelCase(s string) []string {
+	runes := []rune(s)
+	words := []string{}
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+		acronymEnd := unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if lowerToUpper || acronymEnd {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
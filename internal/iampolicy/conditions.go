@@ -0,0 +1,167 @@
+package iampolicy
+
+import (
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// comparison compares a value from the request context with a value from the policy.
+	comparison func(contextValue, policyValue string) bool
+
+	operator struct {
+		compare comparison
+		// negated operators (e.g. StringNotEquals) hold when no policy value matches.
+		negated bool
+	}
+)
+
+var operators = map[string]operator{
+	"StringEquals":              {compare: func(c, p string) bool { return c == p }},
+	"StringNotEquals":           {compare: func(c, p string) bool { return c == p }, negated: true},
+	"StringEqualsIgnoreCase":    {compare: strings.EqualFold},
+	"StringNotEqualsIgnoreCase": {compare: strings.EqualFold, negated: true},
+	"StringLike":                {compare: func(c, p string) bool { return wildcardMatch(p, c, false) }},
+	"StringNotLike":             {compare: func(c, p string) bool { return wildcardMatch(p, c, false) }, negated: true},
+	"ArnEquals":                 {compare: func(c, p string) bool { return wildcardMatch(p, c, false) }},
+	"ArnLike":                   {compare: func(c, p string) bool { return wildcardMatch(p, c, false) }},
+	"ArnNotEquals":              {compare: func(c, p string) bool { return wildcardMatch(p, c, false) }, negated: true},
+	"ArnNotLike":                {compare: func(c, p string) bool { return wildcardMatch(p, c, false) }, negated: true},
+	"Bool":                      {compare: strings.EqualFold},
+	"NumericEquals":             {compare: numeric(func(c, p float64) bool { return c == p })},
+	"NumericNotEquals":          {compare: numeric(func(c, p float64) bool { return c == p }), negated: true},
+	"NumericLessThan":           {compare: numeric(func(c, p float64) bool { return c < p })},
+	"NumericLessThanEquals":     {compare: numeric(func(c, p float64) bool { return c <= p })},
+	"NumericGreaterThan":        {compare: numeric(func(c, p float64) bool { return c > p })},
+	"NumericGreaterThanEquals":  {compare: numeric(func(c, p float64) bool { return c >= p })},
+	"IpAddress":                 {compare: ipInPrefix},
+	"NotIpAddress":              {compare: ipInPrefix, negated: true},
+}
+
+// matchConditions evaluates a statement's condition block. Every operator & key must hold
+// for the statement to apply. Keys missing from the request context make the result
+// uncertain, and are returned so the caller can explain what would need to be known.
+func matchConditions(context map[string][]string, conditions map[string]map[string]StringList) (tristate, []string) {
+	result := yes
+	unresolved := []string{}
+
+	for operatorName, keys := range conditions {
+		name, _ := strings.CutSuffix(operatorName, "IfExists")
+		forAll := false
+		if rest, ok := strings.CutPrefix(name, "ForAllValues:"); ok {
+			name, forAll = rest, true
+		} else if rest, ok := strings.CutPrefix(name, "ForAnyValue:"); ok {
+			name = rest
+		}
+
+		for key, policyValues := range keys {
+			contextValues, ok := lookupContext(context, key)
+
+			var m tristate
+			switch {
+			case name == "Null":
+				m = matchNull(ok, policyValues)
+			case !ok:
+				// the key may well be present on a real request, even though it wasn't given to us.
+				// IfExists & ForAllValues operators hold vacuously when it isn't, but as we can't
+				// tell, they're as uncertain as the others.
+				m = maybe
+				unresolved = append(unresolved, key)
+			default:
+				op, known := operators[name]
+				if !known {
+					m = maybe
+					unresolved = append(unresolved, key)
+					break
+				}
+				m = matchOperator(op, contextValues, policyValues, forAll)
+			}
+
+			result = and(result, m)
+		}
+	}
+
+	sort.Strings(unresolved)
+	return result, unresolved
+}
+
+func matchOperator(op operator, contextValues, policyValues []string, forAll bool) tristate {
+	matchesAny := func(c string) bool {
+		for _, p := range policyValues {
+			if op.compare(c, p) {
+				return !op.negated
+			}
+		}
+		return op.negated
+	}
+
+	if forAll {
+		for _, c := range contextValues {
+			if !matchesAny(c) {
+				return no
+			}
+		}
+		return yes
+	}
+
+	for _, c := range contextValues {
+		if matchesAny(c) {
+			return yes
+		}
+	}
+	return no
+}
+
+// matchNull evaluates the Null operator, which checks for the presence of a key rather than its value.
+func matchNull(present bool, policyValues []string) tristate {
+	if !present {
+		// the caller may simply not have told us the key's value
+		return maybe
+	}
+	for _, p := range policyValues {
+		if strings.EqualFold(p, "false") {
+			return yes
+		}
+	}
+	return no
+}
+
+// lookupContext finds a condition key in the request context. Condition keys are case insensitive.
+func lookupContext(context map[string][]string, key string) ([]string, bool) {
+	for k, v := range context {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func numeric(compare func(c, p float64) bool) comparison {
+	return func(c, p string) bool {
+		cf, err := strconv.ParseFloat(c, 64)
+		if err != nil {
+			return false
+		}
+		pf, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return false
+		}
+		return compare(cf, pf)
+	}
+}
+
+func ipInPrefix(c, p string) bool {
+	addr, err := netip.ParseAddr(c)
+	if err != nil {
+		return false
+	}
+	prefix, err := netip.ParsePrefix(p)
+	if err != nil {
+		// single addresses are allowed without a prefix length
+		single, err := netip.ParseAddr(p)
+		return err == nil && single == addr
+	}
+	return prefix.Contains(addr)
+}
@@ -0,0 +1,323 @@
+package iampolicy
+
+import (
+	"strings"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/arn"
+)
+
+type (
+	// Request is an API call to evaluate.
+	Request struct {
+		// Principal is the ARN of the calling role or user, or a service principal such as lambda.amazonaws.com.
+		Principal string
+		// Action is the IAM action, e.g. dynamodb:PutItem.
+		Action string
+		// Resource is the ARN of the resource being accessed.
+		Resource string
+		// Context holds the values of any known condition keys, e.g. aws:SourceVpc.
+		Context map[string][]string
+	}
+
+	// NamedPolicy is a policy along with a description of where it came from.
+	NamedPolicy struct {
+		Name   string
+		Policy Policy
+	}
+
+	// PolicySet is every policy that applies to a request.
+	PolicySet struct {
+		Identity []NamedPolicy
+		Resource []NamedPolicy
+		// Boundary is the principal's permissions boundary. A nil boundary means there's no boundary.
+		Boundary []NamedPolicy
+	}
+
+	Decision   string
+	PolicyKind string
+
+	// MatchedStatement is a statement that applies to the request.
+	MatchedStatement struct {
+		Policy    string     `json:"policy"`
+		Kind      PolicyKind `json:"kind"`
+		Statement Statement  `json:"statement"`
+		// Conditional is true if the statement only applies when its conditions hold, and the
+		// request context didn't include the condition keys needed to decide.
+		Conditional bool `json:"conditional,omitempty"`
+		// UnresolvedConditionKeys are the condition keys that were missing from the request context.
+		UnresolvedConditionKeys []string `json:"unresolved_condition_keys,omitempty"`
+	}
+
+	// Result is the outcome of evaluating a request.
+	Result struct {
+		Decision   Decision           `json:"decision"`
+		Statements []MatchedStatement `json:"statements"`
+	}
+
+	// tristate is the result of matching part of a statement: IAM conditions can't always be
+	// decided offline, so a match can be unknown as well as true or false.
+	tristate int
+)
+
+const (
+	// DecisionAllow means the request is allowed.
+	DecisionAllow Decision = "Allow"
+	// DecisionConditionalAllow means the request is allowed only if conditions that couldn't be evaluated hold.
+	DecisionConditionalAllow Decision = "ConditionalAllow"
+	// DecisionDeny means the request is explicitly denied.
+	DecisionDeny Decision = "ExplicitDeny"
+	// DecisionImplicitDeny means no statement allows the request.
+	DecisionImplicitDeny Decision = "ImplicitDeny"
+
+	PolicyKindIdentity PolicyKind = "identity"
+	PolicyKindResource PolicyKind = "resource"
+	PolicyKindBoundary PolicyKind = "permissions_boundary"
+)
+
+const (
+	no tristate = iota
+	maybe
+	yes
+)
+
+// Evaluate evaluates a request against a set of policies.
+func Evaluate(req Request, policies PolicySet) Result {
+	result := Result{Statements: []MatchedStatement{}}
+
+	evaluateKind := func(kind PolicyKind, named []NamedPolicy) (allow, deny tristate) {
+		for _, np := range named {
+			for _, s := range np.Policy.Statement {
+				m, unresolved := matchStatement(req, s, kind == PolicyKindResource)
+				if m == no {
+					continue
+				}
+				result.Statements = append(result.Statements, MatchedStatement{
+					Policy:                  np.Name,
+					Kind:                    kind,
+					Statement:               s,
+					Conditional:             m == maybe,
+					UnresolvedConditionKeys: unresolved,
+				})
+				if s.Effect == EffectDeny {
+					deny = or(deny, m)
+				} else {
+					allow = or(allow, m)
+				}
+			}
+		}
+		return allow, deny
+	}
+
+	identityAllow, identityDeny := evaluateKind(PolicyKindIdentity, policies.Identity)
+	resourceAllow, resourceDeny := evaluateKind(PolicyKindResource, policies.Resource)
+	deny := or(identityDeny, resourceDeny)
+
+	if policies.Boundary != nil {
+		boundaryAllow, boundaryDeny := evaluateKind(PolicyKindBoundary, policies.Boundary)
+		deny = or(deny, boundaryDeny)
+		identityAllow = and(identityAllow, boundaryAllow)
+	}
+
+	// within an account, either an identity or a resource policy can grant access. Across
+	// accounts, both must. Some ARNs (e.g. S3 buckets) don't include an account, so are
+	// assumed to be in the principal's account.
+	var allow tristate
+	if isCrossAccount(req.Principal, req.Resource) {
+		allow = and(identityAllow, resourceAllow)
+	} else {
+		allow = or(identityAllow, resourceAllow)
+	}
+
+	switch {
+	case deny == yes:
+		result.Decision = DecisionDeny
+	case allow == yes && deny == no:
+		result.Decision = DecisionAllow
+	case allow != no:
+		result.Decision = DecisionConditionalAllow
+	default:
+		result.Decision = DecisionImplicitDeny
+	}
+
+	return result
+}
+
+// matchStatement returns whether a statement applies to the request, along with the condition
+// keys that couldn't be resolved from the request context.
+func matchStatement(req Request, s Statement, isResourcePolicy bool) (tristate, []string) {
+	if !matchAction(req.Action, s) {
+		return no, nil
+	}
+
+	resourceMatch := matchResource(req.Resource, s)
+	if resourceMatch == no {
+		return no, nil
+	}
+
+	principalMatch := yes
+	if isResourcePolicy {
+		principalMatch = matchPrincipalElement(req, s)
+		if principalMatch == no {
+			return no, nil
+		}
+	}
+
+	conditionMatch, unresolved := matchConditions(req.Context, s.Condition)
+
+	return and(resourceMatch, and(principalMatch, conditionMatch)), unresolved
+}
+
+func matchAction(action string, s Statement) bool {
+	if len(s.NotAction) > 0 {
+		return !anyWildcardMatch(s.NotAction, action, true)
+	}
+	return anyWildcardMatch(s.Action, action, true)
+}
+
+func matchResource(resource string, s Statement) tristate {
+	patterns, negate := s.Resource, false
+	if len(s.NotResource) > 0 {
+		patterns, negate = s.NotResource, true
+	}
+	// resource policies may omit the resource element, in which case they apply to the resource they're attached to
+	if len(patterns) == 0 {
+		return yes
+	}
+
+	m := no
+	for _, pattern := range patterns {
+		// policy variables such as ${aws:username} can't be resolved offline, so treat them
+		// as wildcards & the match as uncertain
+		substituted := substitutePolicyVariables(pattern)
+		if wildcardMatch(substituted, resource, false) {
+			if substituted != pattern {
+				m = or(m, maybe)
+			} else {
+				m = yes
+			}
+		}
+	}
+
+	if negate {
+		return not(m)
+	}
+	return m
+}
+
+func matchPrincipalElement(req Request, s Statement) tristate {
+	if s.NotPrincipal != nil {
+		return not(matchPrincipal(req, *s.NotPrincipal))
+	}
+	if s.Principal == nil {
+		return no
+	}
+	return matchPrincipal(req, *s.Principal)
+}
+
+func matchPrincipal(req Request, p Principal) tristate {
+	if p.Wildcard {
+		return yes
+	}
+
+	for principalType, values := range p.Values {
+		for _, v := range values {
+			switch {
+			case v == "*" && principalType == "AWS":
+				return yes
+			case v == req.Principal:
+				return yes
+			case principalType == "AWS" && accountOf(req.Principal) != "" && isAccountPrincipal(v, accountOf(req.Principal)):
+				// naming an account delegates the decision to that account's identity policies,
+				// so it only grants access on its own when crossing accounts
+				if isCrossAccount(req.Principal, req.Resource) {
+					return yes
+				}
+			}
+		}
+	}
+
+	return no
+}
+
+func isAccountPrincipal(value, accountID string) bool {
+	return value == accountID || value == "arn:aws:iam::"+accountID+":root"
+}
+
+func isCrossAccount(principal, resource string) bool {
+	principalAccount, resourceAccount := accountOf(principal), accountOf(resource)
+	return principalAccount != "" && resourceAccount != "" && principalAccount != resourceAccount
+}
+
+func accountOf(s string) string {
+	a, err := arn.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return a.AccountID
+}
+
+func substitutePolicyVariables(pattern string) string {
+	for {
+		start := strings.Index(pattern, "${")
+		if start < 0 {
+			return pattern
+		}
+		end := strings.Index(pattern[start:], "}")
+		if end < 0 {
+			return pattern
+		}
+		pattern = pattern[:start] + "*" + pattern[start+end+1:]
+	}
+}
+
+func anyWildcardMatch(patterns []string, value string, foldCase bool) bool {
+	for _, p := range patterns {
+		if wildcardMatch(p, value, foldCase) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch matches value against an IAM pattern, where * matches any sequence of
+// characters & ? matches any single character.
+func wildcardMatch(pattern, value string, foldCase bool) bool {
+	if foldCase {
+		pattern, value = strings.ToLower(pattern), strings.ToLower(value)
+	}
+
+	p, v := 0, 0
+	starP, starV := -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case p < len(pattern) && pattern[p] == '*':
+			starP, starV = p, v
+			p++
+		case starP >= 0:
+			starV++
+			p, v = starP+1, starV
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+func or(a, b tristate) tristate {
+	return max(a, b)
+}
+
+func and(a, b tristate) tristate {
+	return min(a, b)
+}
+
+func not(a tristate) tristate {
+	return yes - a
+}
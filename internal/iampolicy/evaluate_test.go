@@ -0,0 +1,316 @@
+package iampolicy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const (
+	testRole        = "arn:aws:iam::111111111111:role/app"
+	testUsers       = "arn:aws:dynamodb:eu-west-1:111111111111:table/users"
+	testSharedTable = "arn:aws:dynamodb:eu-west-1:222222222222:table/shared"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity []string
+		resource []string
+		boundary []string
+		action   string
+		arn      string
+		context  map[string][]string
+
+		want           Decision
+		wantUnresolved []string
+	}{
+		{
+			name:     "wildcard action and ARN allow",
+			identity: []string{"allow_users_table.json"},
+			action:   "dynamodb:PutItem",
+			arn:      testUsers,
+			want:     DecisionAllow,
+		},
+		{
+			name:     "actions ignore case",
+			identity: []string{"allow_users_table.json"},
+			action:   "DynamoDB:getitem",
+			arn:      testUsers,
+			want:     DecisionAllow,
+		},
+		{
+			name:     "wildcard action doesn't match other actions",
+			identity: []string{"allow_users_table.json"},
+			action:   "dynamodb:Scan",
+			arn:      testUsers,
+			want:     DecisionImplicitDeny,
+		},
+		{
+			name:     "? matches a single character only",
+			identity: []string{"allow_users_table.json"},
+			action:   "dynamodb:GetItem",
+			arn:      "arn:aws:dynamodb:eu-west-1:111111111111:table/users-archive",
+			want:     DecisionImplicitDeny,
+		},
+		{
+			name:     "ARNs are case sensitive",
+			identity: []string{"allow_users_table.json"},
+			action:   "dynamodb:GetItem",
+			arn:      "arn:aws:dynamodb:eu-west-1:111111111111:table/Users",
+			want:     DecisionImplicitDeny,
+		},
+		{
+			name:     "explicit deny beats allow",
+			identity: []string{"allow_users_table.json", "deny_deletes.json"},
+			action:   "dynamodb:DeleteItem",
+			arn:      testUsers,
+			want:     DecisionDeny,
+		},
+		{
+			name:     "deny in a resource policy beats an identity allow",
+			identity: []string{"allow_users_table.json"},
+			resource: []string{"table_policy_deny_deletes.json"},
+			action:   "dynamodb:DeleteItem",
+			arn:      testUsers,
+			want:     DecisionDeny,
+		},
+		{
+			name:     "NotAction allows other actions",
+			identity: []string{"allow_all_but_iam.json"},
+			action:   "sqs:SendMessage",
+			arn:      "arn:aws:sqs:eu-west-1:111111111111:jobs",
+			want:     DecisionAllow,
+		},
+		{
+			name:     "NotAction excludes its actions",
+			identity: []string{"allow_all_but_iam.json"},
+			action:   "iam:CreateUser",
+			arn:      "arn:aws:iam::111111111111:user/mallory",
+			want:     DecisionImplicitDeny,
+		},
+		{
+			name:     "NotResource deny skips its resources",
+			identity: []string{"deny_outside_public_bucket.json"},
+			action:   "s3:GetObject",
+			arn:      "arn:aws:s3:::public/index.html",
+			want:     DecisionAllow,
+		},
+		{
+			name:     "NotResource deny applies to other resources",
+			identity: []string{"deny_outside_public_bucket.json"},
+			action:   "s3:GetObject",
+			arn:      "arn:aws:s3:::private/secrets.txt",
+			want:     DecisionDeny,
+		},
+		{
+			name:           "IfExists with a missing key is conditional",
+			identity:       []string{"allow_if_vpc.json"},
+			action:         "s3:GetObject",
+			arn:            "arn:aws:s3:::reports/q1.csv",
+			want:           DecisionConditionalAllow,
+			wantUnresolved: []string{"aws:SourceVpc"},
+		},
+		{
+			name:     "IfExists with a matching key allows",
+			identity: []string{"allow_if_vpc.json"},
+			action:   "s3:GetObject",
+			arn:      "arn:aws:s3:::reports/q1.csv",
+			context:  map[string][]string{"aws:sourcevpc": {"vpc-0a1b2c3d"}},
+			want:     DecisionAllow,
+		},
+		{
+			name:     "IfExists with another value doesn't allow",
+			identity: []string{"allow_if_vpc.json"},
+			action:   "s3:GetObject",
+			arn:      "arn:aws:s3:::reports/q1.csv",
+			context:  map[string][]string{"aws:SourceVpc": {"vpc-99999999"}},
+			want:     DecisionImplicitDeny,
+		},
+		{
+			name:           "conditional deny makes an allow conditional",
+			identity:       []string{"allow_if_vpc.json", "deny_outside_vpc.json"},
+			action:         "s3:GetObject",
+			arn:            "arn:aws:s3:::reports/q1.csv",
+			want:           DecisionConditionalAllow,
+			wantUnresolved: []string{"aws:SourceVpc", "aws:SourceVpc"},
+		},
+		{
+			name:     "negated IfExists deny applies to other values",
+			identity: []string{"allow_if_vpc.json", "deny_outside_vpc.json"},
+			action:   "s3:GetObject",
+			arn:      "arn:aws:s3:::reports/q1.csv",
+			context:  map[string][]string{"aws:SourceVpc": {"vpc-99999999"}},
+			want:     DecisionDeny,
+		},
+		{
+			name:           "ForAllValues with a missing key is conditional",
+			identity:       []string{"allow_tag_keys.json"},
+			action:         "ec2:CreateTags",
+			arn:            "arn:aws:ec2:eu-west-1:111111111111:instance/i-0123456789abcdef0",
+			want:           DecisionConditionalAllow,
+			wantUnresolved: []string{"aws:TagKeys"},
+		},
+		{
+			name:     "ForAllValues with every value allowed",
+			identity: []string{"allow_tag_keys.json"},
+			action:   "ec2:CreateTags",
+			arn:      "arn:aws:ec2:eu-west-1:111111111111:instance/i-0123456789abcdef0",
+			context:  map[string][]string{"aws:TagKeys": {"team", "env"}},
+			want:     DecisionAllow,
+		},
+		{
+			name:     "ForAllValues with a value not allowed",
+			identity: []string{"allow_tag_keys.json"},
+			action:   "ec2:CreateTags",
+			arn:      "arn:aws:ec2:eu-west-1:111111111111:instance/i-0123456789abcdef0",
+			context:  map[string][]string{"aws:TagKeys": {"team", "owner"}},
+			want:     DecisionImplicitDeny,
+		},
+		{
+			name:           "ForAnyValue with a missing key is conditional",
+			identity:       []string{"allow_tag_keys.json"},
+			action:         "ec2:DeleteTags",
+			arn:            "arn:aws:ec2:eu-west-1:111111111111:instance/i-0123456789abcdef0",
+			want:           DecisionConditionalAllow,
+			wantUnresolved: []string{"aws:TagKeys"},
+		},
+		{
+			name:     "ForAnyValue with one value allowed",
+			identity: []string{"allow_tag_keys.json"},
+			action:   "ec2:DeleteTags",
+			arn:      "arn:aws:ec2:eu-west-1:111111111111:instance/i-0123456789abcdef0",
+			context:  map[string][]string{"aws:TagKeys": {"owner", "team"}},
+			want:     DecisionAllow,
+		},
+		{
+			name:     "ForAnyValue with no value allowed",
+			identity: []string{"allow_tag_keys.json"},
+			action:   "ec2:DeleteTags",
+			arn:      "arn:aws:ec2:eu-west-1:111111111111:instance/i-0123456789abcdef0",
+			context:  map[string][]string{"aws:TagKeys": {"owner"}},
+			want:     DecisionImplicitDeny,
+		},
+		{
+			name:           "unknown operator is conditional even with the key",
+			identity:       []string{"allow_before_date.json"},
+			action:         "s3:GetObject",
+			arn:            "arn:aws:s3:::reports/q1.csv",
+			context:        map[string][]string{"aws:CurrentTime": {"2025-01-01T00:00:00Z"}},
+			want:           DecisionConditionalAllow,
+			wantUnresolved: []string{"aws:CurrentTime"},
+		},
+		{
+			name:     "resource policy statements need a principal",
+			identity: []string{"allow_users_table.json"},
+			resource: []string{"deny_deletes.json"},
+			action:   "dynamodb:DeleteItem",
+			arn:      testUsers,
+			want:     DecisionAllow,
+		},
+		{
+			name:     "cross account access needs a resource policy",
+			identity: []string{"allow_all_but_iam.json"},
+			action:   "dynamodb:GetItem",
+			arn:      testSharedTable,
+			want:     DecisionImplicitDeny,
+		},
+		{
+			name:     "cross account access with a resource policy",
+			identity: []string{"allow_all_but_iam.json"},
+			resource: []string{"table_policy_other_account.json"},
+			action:   "dynamodb:GetItem",
+			arn:      testSharedTable,
+			want:     DecisionAllow,
+		},
+		{
+			name:     "permissions boundary limits identity policies",
+			identity: []string{"allow_users_table.json"},
+			boundary: []string{"boundary_s3_only.json"},
+			action:   "dynamodb:GetItem",
+			arn:      testUsers,
+			want:     DecisionImplicitDeny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies := PolicySet{
+				Identity: loadPolicies(t, tt.identity),
+				Resource: loadPolicies(t, tt.resource),
+			}
+			if tt.boundary != nil {
+				policies.Boundary = loadPolicies(t, tt.boundary)
+			}
+
+			got := Evaluate(Request{Principal: testRole, Action: tt.action, Resource: tt.arn, Context: tt.context}, policies)
+			if got.Decision != tt.want {
+				t.Errorf("got decision %s, want %s", got.Decision, tt.want)
+			}
+
+			unresolved := []string{}
+			for _, s := range got.Statements {
+				unresolved = append(unresolved, s.UnresolvedConditionKeys...)
+			}
+			if !slices.Equal(unresolved, tt.wantUnresolved) {
+				t.Errorf("got unresolved condition keys %v, want %v", unresolved, tt.wantUnresolved)
+			}
+		})
+	}
+}
+
+func TestEvaluateReportsMatchedStatements(t *testing.T) {
+	policies := PolicySet{Identity: loadPolicies(t, []string{"allow_users_table.json", "deny_deletes.json"})}
+
+	got := Evaluate(Request{Principal: testRole, Action: "dynamodb:DeleteItem", Resource: testUsers}, policies)
+
+	sids := []string{}
+	for _, s := range got.Statements {
+		sids = append(sids, s.Statement.Sid)
+	}
+	if !slices.Equal(sids, []string{"UsersTable", "NoDeletes"}) {
+		t.Errorf("got statements %v, want [UsersTable NoDeletes]", sids)
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	tests := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"*", "anything", true},
+		{"arn:aws:s3:::bucket/*", "arn:aws:s3:::bucket/a/b", true},
+		{"arn:aws:s3:::bucket/*", "arn:aws:s3:::bucket", false},
+		{"arn:aws:s3:::bucket*", "arn:aws:s3:::bucket", true},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+		{"table/user?", "table/users", true},
+		{"table/user?", "table/user", false},
+	}
+
+	for _, tt := range tests {
+		if got := wildcardMatch(tt.pattern, tt.value, false); got != tt.want {
+			t.Errorf("wildcardMatch(%q, %q) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+// loadPolicies parses fixture policies from testdata, naming each after its file.
+func loadPolicies(t *testing.T, files []string) []NamedPolicy {
+	t.Helper()
+
+	out := []NamedPolicy{}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatalf("error reading fixture: %v", err)
+		}
+		policy, err := Parse(data)
+		if err != nil {
+			t.Fatalf("error parsing %s: %v", file, err)
+		}
+		out = append(out, NamedPolicy{Name: file, Policy: policy})
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+// Package iampolicy parses IAM policy documents and evaluates requests against them offline.
+//
+// The evaluator implements the parts of the IAM evaluation logic needed to answer
+// "who can access what" questions: explicit denies, allows from identity & resource
+// policies, permissions boundaries, wildcards, NotAction/NotResource/NotPrincipal and
+// the common condition operators. Service control policies and session policies are not
+// evaluated.
+package iampolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+type (
+	// Policy is an IAM policy document.
+	Policy struct {
+		Version   string      `json:"Version,omitempty"`
+		ID        string      `json:"Id,omitempty"`
+		Statement []Statement `json:"Statement"`
+	}
+
+	// Statement is a single statement of a policy document.
+	Statement struct {
+		Sid          string     `json:"Sid,omitempty"`
+		Effect       Effect     `json:"Effect"`
+		Principal    *Principal `json:"Principal,omitempty"`
+		NotPrincipal *Principal `json:"NotPrincipal,omitempty"`
+		Action       StringList `json:"Action,omitempty"`
+		NotAction    StringList `json:"NotAction,omitempty"`
+		Resource     StringList `json:"Resource,omitempty"`
+		NotResource  StringList `json:"NotResource,omitempty"`
+		// Condition maps operators (e.g. StringEquals) to condition keys and their values.
+		Condition map[string]map[string]StringList `json:"Condition,omitempty"`
+	}
+
+	Effect string
+
+	// StringList is a policy element that can be written as either a single string or a list.
+	// Numbers & booleans, which are valid in condition values, are converted to strings.
+	StringList []string
+
+	// Principal is the principal element of a resource policy. A principal of "*" is stored
+	// as Wildcard, otherwise principals are keyed by their type, e.g. AWS or Service.
+	Principal struct {
+		Wildcard bool
+		Values   map[string]StringList
+	}
+)
+
+const (
+	EffectAllow Effect = "Allow"
+	EffectDeny  Effect = "Deny"
+)
+
+// Parse parses a policy document. CloudControl returns policy documents as JSON objects for
+// some resource types and as JSON encoded strings for others, so both are accepted.
+func Parse(document any) (Policy, error) {
+	var raw []byte
+	switch doc := document.(type) {
+	case nil:
+		return Policy{}, fmt.Errorf("policy document is empty")
+	case string:
+		raw = []byte(doc)
+	case []byte:
+		raw = doc
+	case json.RawMessage:
+		raw = doc
+	default:
+		var err error
+		raw, err = json.Marshal(doc)
+		if err != nil {
+			return Policy{}, fmt.Errorf("error marshalling policy document: %w", err)
+		}
+	}
+
+	// policies with a single statement may give it as an object rather than a list
+	var envelope struct {
+		Version   string          `json:"Version"`
+		ID        string          `json:"Id"`
+		Statement json.RawMessage `json:"Statement"`
+	}
+	err := json.Unmarshal(raw, &envelope)
+	if err != nil {
+		return Policy{}, fmt.Errorf("error unmarshalling policy document: %w", err)
+	}
+
+	p := Policy{Version: envelope.Version, ID: envelope.ID}
+	if len(envelope.Statement) == 0 {
+		return p, nil
+	}
+	if envelope.Statement[0] == '{' {
+		var s Statement
+		err = json.Unmarshal(envelope.Statement, &s)
+		p.Statement = []Statement{s}
+	} else {
+		err = json.Unmarshal(envelope.Statement, &p.Statement)
+	}
+	if err != nil {
+		return Policy{}, fmt.Errorf("error unmarshalling policy statements: %w", err)
+	}
+
+	return p, nil
+}
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var raw any
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	switch v := raw.(type) {
+	case []any:
+		out := make(StringList, 0, len(v))
+		for _, elem := range v {
+			s, err := scalarString(elem)
+			if err != nil {
+				return err
+			}
+			out = append(out, s)
+		}
+		*l = out
+	default:
+		s, err := scalarString(v)
+		if err != nil {
+			return err
+		}
+		*l = StringList{s}
+	}
+
+	return nil
+}
+
+func scalarString(v any) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unexpected policy value %v", v)
+	}
+}
+
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var wildcard string
+	if json.Unmarshal(data, &wildcard) == nil {
+		if wildcard != "*" {
+			return fmt.Errorf("unexpected principal %q", wildcard)
+		}
+		p.Wildcard = true
+		return nil
+	}
+
+	return json.Unmarshal(data, &p.Values)
+}
+
+func (p Principal) MarshalJSON() ([]byte, error) {
+	if p.Wildcard {
+		return json.Marshal("*")
+	}
+	return json.Marshal(p.Values)
+}
@@ -0,0 +1,231 @@
+// Package inventory is a local SQLite cache of AWS resources fetched through the Cloud Control
+// API. Each resource type has its own table, holding each resource's properties as JSON along
+// with indexed columns extracted from its top level properties, so that resources can be queried &
+// joined with SQL without calling AWS.
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// typesTable records the resource types that have been synced.
+	typesTable = "inventory_types"
+
+	ColumnIdentifier = "identifier"
+	ColumnProperties = "properties"
+	ColumnSyncedAt   = "synced_at"
+	// columnListHash is the hash of the properties returned when the resource was listed, used to
+	// tell whether it has changed since it was last fetched.
+	columnListHash = "list_hash"
+
+	createTypesTable = `
+create table if not exists ` + typesTable + ` (
+  resource_type text primary key,
+  table_name text not null,
+  resources integer not null,
+  synced_at text not null
+)`
+)
+
+type (
+	Store struct {
+		db *sql.DB
+	}
+
+	// Type is a resource type that has been synced.
+	Type struct {
+		ResourceType string    `json:"resource_type"`
+		Table        string    `json:"table"`
+		Resources    int       `json:"resources"`
+		SyncedAt     time.Time `json:"synced_at"`
+		// Columns are the table's columns, including those extracted from the resources' properties.
+		Columns []string `json:"columns"`
+	}
+)
+
+// DefaultPath returns the path of the inventory if no other is given, in the user's cache directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding cache directory: %w", err)
+	}
+	return filepath.Join(dir, "llm-cloud-discovery", "inventory.db"), nil
+}
+
+// Open opens the inventory at path, creating it if it doesn't exist. A read only inventory
+// refuses any statement that would change it, so it can be queried with SQL written by the model.
+func Open(path string, readOnly bool) (*Store, error) {
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?_pragma=busy_timeout(5000)"
+	if readOnly {
+		dsn += "&mode=ro&_pragma=query_only(1)"
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("error creating inventory directory: %w", err)
+		}
+		dsn += "&_pragma=journal_mode(wal)"
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening inventory: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error opening inventory %s: %w", path, err)
+	}
+
+	if !readOnly {
+		if _, err := db.Exec(createTypesTable); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("error creating inventory types table: %w", err)
+		}
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DB returns the inventory's database, for running queries against it.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// Types lists the resource types that have been synced, along with the columns of their tables.
+func (s *Store) Types(ctx context.Context) ([]Type, error) {
+	rows, err := s.db.QueryContext(ctx, "select resource_type, table_name, resources, synced_at from "+typesTable+" order by resource_type")
+	if err != nil {
+		return nil, fmt.Errorf("error querying inventory types: %w", err)
+	}
+	defer rows.Close()
+
+	types := []Type{}
+	for rows.Next() {
+		var (
+			t        Type
+			syncedAt string
+		)
+		if err := rows.Scan(&t.ResourceType, &t.Table, &t.Resources, &syncedAt); err != nil {
+			return nil, fmt.Errorf("error scanning inventory type: %w", err)
+		}
+		t.SyncedAt, _ = time.Parse(time.RFC3339, syncedAt)
+		types = append(types, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading inventory types: %w", err)
+	}
+
+	for i, t := range types {
+		columns, err := s.columns(ctx, t.Table)
+		if err != nil {
+			return nil, err
+		}
+		types[i].Columns = columns
+	}
+
+	return types, nil
+}
+
+// Type returns the sync status of a resource type, and false if it has never been synced.
+func (s *Store) Type(ctx context.Context, resourceType string) (Type, bool, error) {
+	types, err := s.Types(ctx)
+	if err != nil {
+		return Type{}, false, err
+	}
+	for _, t := range types {
+		if t.ResourceType == resourceType {
+			return t, true, nil
+		}
+	}
+	return Type{}, false, nil
+}
+
+// Stale returns the resource types that haven't been synced within maxAge, or have never been synced.
+func (s *Store) Stale(ctx context.Context, resourceTypes []string, maxAge time.Duration) ([]string, error) {
+	out := []string{}
+	for _, resourceType := range resourceTypes {
+		t, ok, err := s.Type(ctx, resourceType)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || time.Since(t.SyncedAt) >= maxAge {
+			out = append(out, resourceType)
+		}
+	}
+	return out, nil
+}
+
+// columns returns the columns of a table, including generated columns, except the list hash which
+// is only used when syncing.
+func (s *Store) columns(ctx context.Context, table string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "select name from pragma_table_xinfo(?) order by cid", table)
+	if err != nil {
+		return nil, fmt.Errorf("error querying columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning column: %w", err)
+		}
+		if name != columnListHash {
+			columns = append(columns, name)
+		}
+	}
+	return columns, rows.Err()
+}
+
+// TableName returns the table a resource type is stored in, e.g. aws_ec2_security_group for
+// AWS::EC2::SecurityGroup.
+func TableName(resourceType string) string {
+	parts := strings.Split(resourceType, "::")
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			parts[i] = SnakeCase(part)
+		} else {
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// SnakeCase converts a property name to snake case, keeping acronyms together, e.g.
+// DBInstanceIdentifier to db_instance_identifier.
+func SnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			r = '_'
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// quoteIdentifier quotes a table or column name for use in SQL.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
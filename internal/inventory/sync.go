@@ -0,0 +1,413 @@
+package inventory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultConcurrency = 5
+	// defaultRefetchAge is how long a resource is kept before it's fetched again, even if its listed
+	// properties haven't changed. Many types only list identifiers, so this is the only way their
+	// changes are picked up.
+	defaultRefetchAge = 24 * time.Hour
+	// maxExtractedColumns is the most properties of a type extracted into their own columns.
+	maxExtractedColumns = 64
+	// baseColumns is the number of columns every table has: identifier, properties, list hash & sync time.
+	baseColumns = 4
+)
+
+// indexedSuffixes are the suffixes of extracted columns that are indexed, as they're likely to be
+// used to look up or join resources.
+var indexedSuffixes = []string{"arn", "_id", "_name", "_identifier"}
+
+type (
+	Opt func(*Syncer)
+
+	// Syncer copies resources from the Cloud Control API into the inventory.
+	Syncer struct {
+		store              *Store
+		cloudcontrolClient *cloudcontrol.Client
+		concurrency        int
+		full               bool
+		refetchAge         time.Duration
+		now                func() time.Time
+	}
+
+	// SyncResult describes what a sync of a resource type changed.
+	SyncResult struct {
+		ResourceType string
+		Table        string
+		Listed       int
+		// Fetched is the number of resources fetched because they're new, have changed or are due to be
+		// fetched again.
+		Fetched   int
+		Unchanged int
+		Deleted   int
+		// Failed is the number of resources that couldn't be fetched, and weren't stored.
+		Failed   int
+		Duration time.Duration
+	}
+
+	// resource is a listed resource, with its properties once fetched.
+	resource struct {
+		identifier string
+		listHash   string
+		// listProperties are the properties returned by ListResources, which may be incomplete.
+		listProperties string
+		properties     string
+	}
+
+	// storedResource is what's recorded about a resource when it was last fetched.
+	storedResource struct {
+		listHash string
+		syncedAt time.Time
+	}
+)
+
+// WithConcurrency sets how many resources are fetched at once.
+func WithConcurrency(n int) Opt {
+	return func(s *Syncer) {
+		s.concurrency = n
+	}
+}
+
+// WithRefetchAge sets how long a resource is kept before it's fetched again, even if its listed
+// properties haven't changed.
+func WithRefetchAge(d time.Duration) Opt {
+	return func(s *Syncer) {
+		s.refetchAge = d
+	}
+}
+
+// WithFullSync fetches every resource, rather than only those that are new or changed since the
+// last sync.
+func WithFullSync() Opt {
+	return func(s *Syncer) {
+		s.full = true
+	}
+}
+
+func NewSyncer(store *Store, cloudcontrolClient *cloudcontrol.Client, opts ...Opt) *Syncer {
+	s := &Syncer{
+		store:              store,
+		cloudcontrolClient: cloudcontrolClient,
+		concurrency:        defaultConcurrency,
+		refetchAge:         defaultRefetchAge,
+		now:                time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Sync brings a resource type's table up to date. Every resource is listed, but only those that
+// are new, whose listed properties have changed, that couldn't be fetched last time or that were
+// fetched longer ago than the refetch age are fetched in full. Resources that no longer exist are
+// deleted.
+func (s *Syncer) Sync(ctx context.Context, resourceType string) (SyncResult, error) {
+	start := time.Now()
+	result := SyncResult{ResourceType: resourceType, Table: TableName(resourceType)}
+
+	if err := s.createTable(ctx, result.Table); err != nil {
+		return result, err
+	}
+
+	listed, err := s.list(ctx, resourceType)
+	if err != nil {
+		return result, err
+	}
+	result.Listed = len(listed)
+
+	stored, err := s.stored(ctx, result.Table)
+	if err != nil {
+		return result, err
+	}
+
+	changed := []*resource{}
+	for _, r := range listed {
+		if s.needsFetch(r, stored) {
+			changed = append(changed, r)
+		}
+	}
+	result.Unchanged = len(listed) - len(changed)
+
+	fetched, failed := s.fetch(ctx, resourceType, changed)
+	result.Fetched, result.Failed = len(fetched), failed
+
+	exists := make(map[string]bool, len(listed))
+	for _, r := range listed {
+		exists[r.identifier] = true
+	}
+	deleted := []string{}
+	for id := range stored {
+		if !exists[id] {
+			deleted = append(deleted, id)
+		}
+	}
+	result.Deleted = len(deleted)
+
+	if err := s.write(ctx, result, fetched, deleted); err != nil {
+		return result, err
+	}
+	if err := s.extractColumns(ctx, result.Table); err != nil {
+		return result, err
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+func (s *Syncer) createTable(ctx context.Context, table string) error {
+	_, err := s.store.db.ExecContext(ctx, fmt.Sprintf(`
+create table if not exists %s (
+  %s text primary key,
+  %s text not null,
+  %s text not null,
+  %s text not null
+)`, quoteIdentifier(table), ColumnIdentifier, ColumnProperties, columnListHash, ColumnSyncedAt))
+	if err != nil {
+		return fmt.Errorf("error creating table %s: %w", table, err)
+	}
+	return nil
+}
+
+func (s *Syncer) list(ctx context.Context, resourceType string) ([]*resource, error) {
+	paginator := cloudcontrol.NewListResourcesPaginator(s.cloudcontrolClient, &cloudcontrol.ListResourcesInput{
+		TypeName: &resourceType,
+	})
+
+	out := []*resource{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing %s resources: %w", resourceType, err)
+		}
+
+		for _, r := range page.ResourceDescriptions {
+			if r.Identifier == nil {
+				continue
+			}
+			props := ""
+			if r.Properties != nil {
+				props = *r.Properties
+			}
+			sum := sha256.Sum256([]byte(props))
+			out = append(out, &resource{identifier: *r.Identifier, listProperties: props, listHash: hex.EncodeToString(sum[:])})
+		}
+	}
+	return out, nil
+}
+
+// stored returns the list hash & sync time of each stored resource by identifier.
+func (s *Syncer) stored(ctx context.Context, table string) (map[string]storedResource, error) {
+	rows, err := s.store.db.QueryContext(ctx, fmt.Sprintf("select %s, %s, %s from %s", ColumnIdentifier, columnListHash, ColumnSyncedAt, quoteIdentifier(table)))
+	if err != nil {
+		return nil, fmt.Errorf("error querying %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	out := map[string]storedResource{}
+	for rows.Next() {
+		var id, hash, syncedAt string
+		if err := rows.Scan(&id, &hash, &syncedAt); err != nil {
+			return nil, fmt.Errorf("error scanning %s: %w", table, err)
+		}
+		// a sync time that can't be parsed is the zero time, so the resource is fetched again
+		t, _ := time.Parse(time.RFC3339, syncedAt)
+		out[id] = storedResource{listHash: hash, syncedAt: t}
+	}
+	return out, rows.Err()
+}
+
+// needsFetch returns whether a listed resource should be fetched in full.
+func (s *Syncer) needsFetch(r *resource, stored map[string]storedResource) bool {
+	prev, ok := stored[r.identifier]
+	switch {
+	case s.full || !ok:
+		return true
+	case prev.listHash == "" || prev.listHash != r.listHash:
+		// an empty hash means only the listed properties were stored, as the resource couldn't be fetched
+		return true
+	default:
+		return s.refetchAge > 0 && s.now().Sub(prev.syncedAt) >= s.refetchAge
+	}
+}
+
+// fetch gets the full properties of resources. If a resource can't be fetched, its listed
+// properties are used instead if there are any, but without a list hash so it's fetched again next
+// sync. Otherwise it's counted as failed.
+func (s *Syncer) fetch(ctx context.Context, resourceType string, resources []*resource) ([]*resource, int) {
+	var (
+		mu      sync.Mutex
+		fetched = []*resource{}
+		failed  int
+	)
+
+	var g errgroup.Group
+	g.SetLimit(s.concurrency)
+	for _, r := range resources {
+		g.Go(func() error {
+			resp, err := s.cloudcontrolClient.GetResource(ctx, &cloudcontrol.GetResourceInput{
+				TypeName:   &resourceType,
+				Identifier: &r.identifier,
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil && resp.ResourceDescription != nil && resp.ResourceDescription.Properties != nil:
+				r.properties = *resp.ResourceDescription.Properties
+			case r.listProperties != "":
+				r.properties = r.listProperties
+				r.listHash = ""
+			default:
+				failed++
+				return nil
+			}
+			fetched = append(fetched, r)
+			return nil
+		})
+	}
+	g.Wait()
+
+	return fetched, failed
+}
+
+// write stores fetched resources & removes deleted ones in a single transaction, so the table is
+// never seen half synced.
+func (s *Syncer) write(ctx context.Context, result SyncResult, fetched []*resource, deleted []string) error {
+	tx, err := s.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := s.now().UTC().Format(time.RFC3339)
+	table := quoteIdentifier(result.Table)
+
+	upsert := fmt.Sprintf(`insert into %s (%s, %s, %s, %s) values (?, ?, ?, ?)
+on conflict (%s) do update set %s = excluded.%s, %s = excluded.%s, %s = excluded.%s`,
+		table, ColumnIdentifier, ColumnProperties, columnListHash, ColumnSyncedAt,
+		ColumnIdentifier, ColumnProperties, ColumnProperties, columnListHash, columnListHash, ColumnSyncedAt, ColumnSyncedAt)
+	for _, r := range fetched {
+		if _, err := tx.ExecContext(ctx, upsert, r.identifier, r.properties, r.listHash, now); err != nil {
+			return fmt.Errorf("error storing %s: %w", r.identifier, err)
+		}
+	}
+
+	del := fmt.Sprintf("delete from %s where %s = ?", table, ColumnIdentifier)
+	for _, id := range deleted {
+		if _, err := tx.ExecContext(ctx, del, id); err != nil {
+			return fmt.Errorf("error deleting %s: %w", id, err)
+		}
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, "select count(*) from "+table).Scan(&count); err != nil {
+		return fmt.Errorf("error counting %s: %w", result.Table, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `insert into `+typesTable+` (resource_type, table_name, resources, synced_at) values (?, ?, ?, ?)
+on conflict (resource_type) do update set table_name = excluded.table_name, resources = excluded.resources, synced_at = excluded.synced_at`,
+		result.ResourceType, result.Table, count, now)
+	if err != nil {
+		return fmt.Errorf("error recording sync of %s: %w", result.ResourceType, err)
+	}
+
+	return tx.Commit()
+}
+
+// extractColumns adds a virtual column for each top level scalar property of the table's
+// resources that doesn't have one yet, e.g. vpc_id for VpcId. Columns that look like references
+// to other resources are indexed.
+func (s *Syncer) extractColumns(ctx context.Context, table string) error {
+	existing, err := s.store.columns(ctx, table)
+	if err != nil {
+		return err
+	}
+	existing = append(existing, columnListHash)
+	added := len(existing) - baseColumns
+
+	quoted := quoteIdentifier(table)
+	keys, err := s.scalarKeys(ctx, quoted)
+	if err != nil {
+		return fmt.Errorf("error finding properties of %s: %w", table, err)
+	}
+
+	for _, key := range keys {
+		column := SnakeCase(key)
+		if added >= maxExtractedColumns || slices.Contains(existing, column) || strings.ContainsAny(key, `"'`) {
+			continue
+		}
+
+		_, err := s.store.db.ExecContext(ctx, fmt.Sprintf(`alter table %s add column %s generated always as (json_extract(%s, '$."%s"')) virtual`,
+			quoted, quoteIdentifier(column), ColumnProperties, key))
+		if err != nil {
+			return fmt.Errorf("error adding column %s to %s: %w", column, table, err)
+		}
+		existing = append(existing, column)
+		added++
+
+		if isIndexed(column) {
+			_, err := s.store.db.ExecContext(ctx, fmt.Sprintf("create index if not exists %s on %s (%s)",
+				quoteIdentifier(table+"_"+column), quoted, quoteIdentifier(column)))
+			if err != nil {
+				return fmt.Errorf("error indexing %s.%s: %w", table, column, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// scalarKeys returns the top level properties of a table's resources that hold strings, numbers or
+// booleans, most common first.
+func (s *Syncer) scalarKeys(ctx context.Context, quotedTable string) ([]string, error) {
+	rows, err := s.store.db.QueryContext(ctx, fmt.Sprintf(`
+select
+  p.key
+from
+  %s t,
+  json_each(t.%s) p
+where
+  p.type in ('text', 'integer', 'real', 'true', 'false')
+group by
+  p.key
+order by
+  count(*) desc,
+  p.key`, quotedTable, ColumnProperties))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func isIndexed(column string) bool {
+	for _, suffix := range indexedSuffixes {
+		if strings.HasSuffix(column, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package inventory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/awstest"
+)
+
+const testType = "AWS::EC2::VPC"
+
+func newTestSyncer(t *testing.T, opts ...Opt) (*Syncer, *awstest.CloudControl, *time.Time) {
+	t.Helper()
+
+	fake := awstest.NewCloudControl()
+
+	store, err := Open(filepath.Join(t.TempDir(), "inventory.db"), false)
+	if err != nil {
+		t.Fatalf("error opening inventory: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := NewSyncer(store, fake.Client(t), opts...)
+	s.now = func() time.Time { return now }
+	return s, fake, &now
+}
+
+func runSync(t *testing.T, s *Syncer) SyncResult {
+	t.Helper()
+
+	result, err := s.Sync(context.Background(), testType)
+	if err != nil {
+		t.Fatalf("error syncing: %v", err)
+	}
+	return result
+}
+
+func storedProperties(t *testing.T, s *Syncer, id string) string {
+	t.Helper()
+
+	var props string
+	err := s.store.db.QueryRow("select "+ColumnProperties+" from "+quoteIdentifier(TableName(testType))+" where "+ColumnIdentifier+" = ?", id).Scan(&props)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", id, err)
+	}
+	return props
+}
+
+func TestSyncOnlyFetchesChanges(t *testing.T) {
+	s, fake, _ := newTestSyncer(t)
+	fake.Set(testType, "vpc-1", awstest.Resource{ListProperties: `{"VpcId":"vpc-1"}`, Properties: `{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}`})
+	fake.Set(testType, "vpc-2", awstest.Resource{ListProperties: `{"VpcId":"vpc-2"}`, Properties: `{"VpcId":"vpc-2","CidrBlock":"10.1.0.0/16"}`})
+
+	if r := runSync(t, s); r.Listed != 2 || r.Fetched != 2 {
+		t.Errorf("got %+v on the first sync, want both fetched", r)
+	}
+
+	fake.Set(testType, "vpc-2", awstest.Resource{ListProperties: `{"VpcId":"vpc-2","Tags":[]}`, Properties: `{"VpcId":"vpc-2","CidrBlock":"10.2.0.0/16"}`})
+	fake.Delete(testType, "vpc-1")
+	r := runSync(t, s)
+	if r.Fetched != 1 || r.Unchanged != 0 || r.Deleted != 1 {
+		t.Errorf("got %+v, want the changed resource fetched & the missing one deleted", r)
+	}
+	if got := storedProperties(t, s, "vpc-2"); got != `{"VpcId":"vpc-2","CidrBlock":"10.2.0.0/16"}` {
+		t.Errorf("got properties %s, want the changed properties", got)
+	}
+}
+
+func TestSyncRefetchesOldResources(t *testing.T) {
+	s, fake, now := newTestSyncer(t, WithRefetchAge(time.Hour))
+	// the list handler only returns identifiers, so changes can't be seen in the list
+	fake.Set(testType, "vpc-1", awstest.Resource{Properties: `{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}`})
+	runSync(t, s)
+
+	fake.Set(testType, "vpc-1", awstest.Resource{Properties: `{"VpcId":"vpc-1","CidrBlock":"10.9.0.0/16"}`})
+	*now = now.Add(30 * time.Minute)
+	if r := runSync(t, s); r.Fetched != 0 || r.Unchanged != 1 {
+		t.Errorf("got %+v before the refetch age, want the resource unchanged", r)
+	}
+
+	*now = now.Add(30 * time.Minute)
+	if r := runSync(t, s); r.Fetched != 1 {
+		t.Errorf("got %+v after the refetch age, want the resource fetched", r)
+	}
+	if got := storedProperties(t, s, "vpc-1"); got != `{"VpcId":"vpc-1","CidrBlock":"10.9.0.0/16"}` {
+		t.Errorf("got properties %s, want the changed properties", got)
+	}
+}
+
+func TestSyncRefetchesListedProperties(t *testing.T) {
+	s, fake, _ := newTestSyncer(t)
+	fake.Set(testType, "vpc-1", awstest.Resource{ListProperties: `{"VpcId":"vpc-1"}`})
+	fake.Set(testType, "vpc-2", awstest.Resource{})
+
+	r := runSync(t, s)
+	if r.Fetched != 1 || r.Failed != 1 {
+		t.Errorf("got %+v, want the listed properties stored & the resource without any failed", r)
+	}
+	if got := storedProperties(t, s, "vpc-1"); got != `{"VpcId":"vpc-1"}` {
+		t.Errorf("got properties %s, want the listed properties", got)
+	}
+
+	// the list hash is unchanged, but the listed properties were all that was stored
+	fake.Set(testType, "vpc-1", awstest.Resource{ListProperties: `{"VpcId":"vpc-1"}`, Properties: `{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}`})
+	fake.Gets()
+	runSync(t, s)
+	if gets := fake.Gets(); gets != 2 {
+		t.Errorf("got %d resources fetched, want both fetched again", gets)
+	}
+	if got := storedProperties(t, s, "vpc-1"); got != `{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}` {
+		t.Errorf("got properties %s, want the fetched properties", got)
+	}
+
+	runSync(t, s)
+	if gets := fake.Gets(); gets != 1 {
+		t.Errorf("got %d resources fetched once vpc-1 was fetched, want only the failed one", gets)
+	}
+}
+
+func TestSyncFull(t *testing.T) {
+	s, fake, _ := newTestSyncer(t, WithFullSync())
+	fake.Set(testType, "vpc-1", awstest.Resource{ListProperties: `{"VpcId":"vpc-1"}`, Properties: `{"VpcId":"vpc-1","CidrBlock":"10.0.0.0/16"}`})
+
+	runSync(t, s)
+	if r := runSync(t, s); r.Fetched != 1 || r.Unchanged != 0 {
+		t.Errorf("got %+v, want every resource fetched", r)
+	}
+}
@@ -64,6 +64,7 @@ func (c *ollamaService) Chat(ctx context.Context, prompt string) (string, error)
 func (c *ollamaService) Reset() {
 	c.log.Debug("Resetting history")
 	c.resetMessages()
+	c.toolRegistry.Reset()
 }
 
 func (c *ollamaService) doChatWithTools(ctx context.Context) (api.ChatResponse, error) {
@@ -0,0 +1,87 @@
+package configquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/configservice"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+const (
+	parameterExpression = "expression"
+	parameterMaxResults = "max_results"
+
+	defaultMaxResults = 100
+	maxMaxResults     = 500
+)
+
+type QueryTool struct {
+	querier
+}
+
+// NewQueryTool creates the aws_config_query tool. If aggregatorName is set, queries run against
+// that configuration aggregator, otherwise they run against the current account & region.
+func NewQueryTool(configClient *configservice.Client, aggregatorName string) tools.Function {
+	return &QueryTool{
+		querier: querier{
+			configClient:   configClient,
+			aggregatorName: aggregatorName,
+		},
+	}
+}
+
+func (t *QueryTool) Name() string {
+	return "aws_config_query"
+}
+
+func (t *QueryTool) Description() string {
+	return `This tool runs an AWS Config advanced query, to find resources by their configuration.
+Queries use the AWS Config query dialect, which is a subset of SQL SELECT. There is no FROM clause, as every query runs against the single table of resource configuration items.
+For example: SELECT resourceId, resourceName, configuration.instanceType WHERE resourceType = 'AWS::EC2::Instance' AND configuration.state.name = 'running'
+The response is a JSON object containing the selected fields and a list of results, each of which is a JSON document.`
+}
+
+func (t *QueryTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterExpression,
+			Description: "The AWS Config query to run, e.g. SELECT resourceId, resourceName WHERE resourceType = 'AWS::S3::Bucket'",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterMaxResults,
+			Description: fmt.Sprintf("The maximum number of results to return, up to %d. Defaults to %d.", maxMaxResults, defaultMaxResults),
+			Type:        tools.ParameterTypeInteger,
+		},
+	}
+}
+
+func (t *QueryTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	expression, _ := parameters[parameterExpression].(string)
+	if expression == "" {
+		return "", fmt.Errorf("%s is required", parameterExpression)
+	}
+
+	maxResults, err := tools.IntParameter(parameters, parameterMaxResults, defaultMaxResults)
+	if err != nil {
+		return "", err
+	}
+	if maxResults < 1 || maxResults > maxMaxResults {
+		return "", fmt.Errorf("%s must be between 1 and %d", parameterMaxResults, maxMaxResults)
+	}
+
+	out, err := t.query(ctx, expression, maxResults)
+	if err != nil {
+		return "", err
+	}
+
+	outJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling results to JSON: %w", err)
+	}
+
+	return string(outJSON), nil
+}
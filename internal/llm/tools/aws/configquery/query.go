@@ -0,0 +1,86 @@
+package configquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/configservice"
+	"github.com/aws/aws-sdk-go-v2/service/configservice/types"
+)
+
+// pageSize is the largest page size AWS Config allows for queries.
+const pageSize = 100
+
+type (
+	// querier runs advanced queries, against an aggregator if one is configured and otherwise
+	// against the current account & region.
+	querier struct {
+		configClient   *configservice.Client
+		aggregatorName string
+	}
+
+	queryResult struct {
+		Fields  []string          `json:"fields"`
+		Results []json.RawMessage `json:"results"`
+		// Truncated is true if the query returned more results than were requested.
+		Truncated bool `json:"truncated"`
+	}
+)
+
+// query runs an advanced query, returning at most maxResults results.
+func (q querier) query(ctx context.Context, expression string, maxResults int) (queryResult, error) {
+	out := queryResult{Fields: []string{}, Results: []json.RawMessage{}}
+
+	var nextToken *string
+	for {
+		limit := int32(min(pageSize, maxResults-len(out.Results)))
+
+		var (
+			results   []string
+			queryInfo *types.QueryInfo
+		)
+		if q.aggregatorName != "" {
+			resp, err := q.configClient.SelectAggregateResourceConfig(ctx, &configservice.SelectAggregateResourceConfigInput{
+				ConfigurationAggregatorName: &q.aggregatorName,
+				Expression:                  &expression,
+				Limit:                       limit,
+				NextToken:                   nextToken,
+			})
+			if err != nil {
+				return queryResult{}, fmt.Errorf("error querying aggregator %s: %w", q.aggregatorName, err)
+			}
+			results, queryInfo, nextToken = resp.Results, resp.QueryInfo, resp.NextToken
+		} else {
+			resp, err := q.configClient.SelectResourceConfig(ctx, &configservice.SelectResourceConfigInput{
+				Expression: &expression,
+				Limit:      limit,
+				NextToken:  nextToken,
+			})
+			if err != nil {
+				return queryResult{}, fmt.Errorf("error querying resource configuration: %w", err)
+			}
+			results, queryInfo, nextToken = resp.Results, resp.QueryInfo, resp.NextToken
+		}
+
+		if queryInfo != nil && len(out.Fields) == 0 {
+			for _, f := range queryInfo.SelectFields {
+				out.Fields = append(out.Fields, aws.ToString(f.Name))
+			}
+		}
+
+		// each result is a JSON document, so is passed through as is rather than double encoded
+		for _, r := range results {
+			out.Results = append(out.Results, json.RawMessage(r))
+		}
+
+		if nextToken == nil {
+			return out, nil
+		}
+		if len(out.Results) >= maxResults {
+			out.Truncated = true
+			return out, nil
+		}
+	}
+}
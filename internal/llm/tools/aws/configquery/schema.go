@@ -0,0 +1,234 @@
+package configquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/configservice"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+const (
+	parameterResourceType = "resource_type"
+
+	// sampleSize is the number of configuration items inspected to infer a schema. Optional
+	// properties are often missing from any single item.
+	sampleSize = 5
+	// maxFields bounds the number of fields returned for resource types with very large configurations.
+	maxFields = 300
+	// maxExampleLength is the longest example value returned before it's elided.
+	maxExampleLength = 60
+	// maxResourceTypes is the most resource types listed when no resource type is given.
+	maxResourceTypes = 500
+)
+
+// commonFields are the fields every configuration item has, regardless of resource type.
+var commonFields = []field{
+	{Path: "accountId", Type: "string"},
+	{Path: "arn", Type: "string"},
+	{Path: "awsRegion", Type: "string"},
+	{Path: "availabilityZone", Type: "string"},
+	{Path: "resourceId", Type: "string"},
+	{Path: "resourceName", Type: "string"},
+	{Path: "resourceType", Type: "string"},
+	{Path: "resourceCreationTime", Type: "date"},
+	{Path: "configurationItemCaptureTime", Type: "date"},
+	{Path: "configurationItemStatus", Type: "string"},
+	{Path: "tags.key", Type: "string"},
+	{Path: "tags.value", Type: "string"},
+	{Path: "relationships.resourceId", Type: "string"},
+	{Path: "relationships.resourceType", Type: "string"},
+	{Path: "relationships.relationshipName", Type: "string"},
+}
+
+type (
+	SchemaTool struct {
+		querier
+	}
+
+	schema struct {
+		ResourceType string  `json:"resource_type"`
+		CommonFields []field `json:"common_fields"`
+		// Fields are the configuration & supplementary configuration fields seen on the sampled items.
+		Fields   []field `json:"fields"`
+		Sampled  int     `json:"sampled"`
+		Complete bool    `json:"complete"`
+	}
+
+	field struct {
+		Path    string `json:"path"`
+		Type    string `json:"type"`
+		Example string `json:"example,omitempty"`
+	}
+
+	resourceTypeCount struct {
+		ResourceType string `json:"resourceType"`
+		Count        int    `json:"COUNT(*)"`
+	}
+)
+
+func NewSchemaTool(configClient *configservice.Client, aggregatorName string) tools.Function {
+	return &SchemaTool{
+		querier: querier{
+			configClient:   configClient,
+			aggregatorName: aggregatorName,
+		},
+	}
+}
+
+func (t *SchemaTool) Name() string {
+	return "get_aws_config_schema"
+}
+
+func (t *SchemaTool) Description() string {
+	return fmt.Sprintf(`This tool describes the fields that can be used in an AWS Config query for a resource type, e.g. AWS::EC2::Instance.
+The fields are inferred from a sample of the resources recorded by AWS Config, so fields that are never set on any sampled resource are not listed. Arrays are queried using the path of their elements, e.g. configuration.securityGroups.groupId.
+If %q is not given, the resource types recorded by AWS Config are listed along with the number of resources of each type.
+The response is a JSON object.`, parameterResourceType)
+}
+
+func (t *SchemaTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterResourceType,
+			Description: "The resource type to describe, in the format AWS::Service::Resource, e.g. AWS::RDS::DBInstance.",
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t *SchemaTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	resourceType, _ := parameters[parameterResourceType].(string)
+
+	var (
+		out any
+		err error
+	)
+	if resourceType == "" {
+		out, err = t.resourceTypes(ctx)
+	} else {
+		out, err = t.schema(ctx, resourceType)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	outJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling schema to JSON: %w", err)
+	}
+
+	return string(outJSON), nil
+}
+
+func (t *SchemaTool) resourceTypes(ctx context.Context) ([]resourceTypeCount, error) {
+	res, err := t.query(ctx, "SELECT resourceType, COUNT(*) GROUP BY resourceType ORDER BY COUNT(*) DESC", maxResourceTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]resourceTypeCount, 0, len(res.Results))
+	for _, r := range res.Results {
+		var c resourceTypeCount
+		if err := json.Unmarshal(r, &c); err != nil {
+			return nil, fmt.Errorf("error unmarshalling resource type count: %w", err)
+		}
+		out = append(out, c)
+	}
+
+	return out, nil
+}
+
+func (t *SchemaTool) schema(ctx context.Context, resourceType string) (schema, error) {
+	// resource types are quoted in the query, so must not contain quotes themselves
+	if strings.ContainsAny(resourceType, `'"`) {
+		return schema{}, fmt.Errorf("invalid resource type %q", resourceType)
+	}
+
+	res, err := t.query(ctx, fmt.Sprintf("SELECT configuration, supplementaryConfiguration WHERE resourceType = '%s'", resourceType), sampleSize)
+	if err != nil {
+		return schema{}, err
+	}
+	if len(res.Results) == 0 {
+		return schema{}, fmt.Errorf("no resources of type %s are recorded by AWS Config. Call this tool without %s to list the recorded resource types", resourceType, parameterResourceType)
+	}
+
+	fields := map[string]field{}
+	for _, r := range res.Results {
+		var item any
+		if err := json.Unmarshal(r, &item); err != nil {
+			return schema{}, fmt.Errorf("error unmarshalling configuration item: %w", err)
+		}
+		collectFields("", item, fields)
+	}
+
+	out := schema{
+		ResourceType: resourceType,
+		CommonFields: commonFields,
+		Fields:       make([]field, 0, len(fields)),
+		Sampled:      len(res.Results),
+		Complete:     len(fields) <= maxFields,
+	}
+	for _, path := range slices.Sorted(maps.Keys(fields)) {
+		if len(out.Fields) == maxFields {
+			break
+		}
+		out.Fields = append(out.Fields, fields[path])
+	}
+
+	return out, nil
+}
+
+// collectFields records the path & type of every leaf value in v. Array elements share their
+// array's path, as that's how they're referenced in queries.
+func collectFields(path string, v any, fields map[string]field) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			collectFields(childPath, child, fields)
+		}
+	case []any:
+		for _, child := range v {
+			collectFields(path, child, fields)
+		}
+	case nil:
+		if _, ok := fields[path]; !ok && path != "" {
+			fields[path] = field{Path: path, Type: "null"}
+		}
+	default:
+		// prefer a non-null type & example if the field is null on other items
+		if f, ok := fields[path]; ok && f.Type != "null" {
+			return
+		}
+		fields[path] = field{Path: path, Type: jsonType(v), Example: example(v)}
+	}
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func example(v any) string {
+	s := fmt.Sprint(v)
+	if len(s) > maxExampleLength {
+		return s[:maxExampleLength] + "..."
+	}
+	return s
+}
@@ -0,0 +1,360 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/fergalhk/llm-cloud-discovery/internal/arn"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/dns"
+)
+
+const (
+	parameterHostname = "hostname"
+
+	viaCNAME = "cname"
+	viaAlias = "alias"
+	viaPTR   = "ptr"
+
+	// maxAliases is the most Route 53 aliases followed, in case of a loop.
+	maxAliases = 10
+	// maxAddresses is the most addresses whose domain names are looked up, if the chain doesn't
+	// end in a known endpoint.
+	maxAddresses = 3
+)
+
+// globalAcceleratorRegions are the regions Global Accelerator's API is in by partition, whichever
+// region the accelerator's endpoints are in. Other partitions use the client's region.
+var globalAcceleratorRegions = map[string]string{
+	arn.DefaultPartition: "us-west-2",
+}
+
+type (
+	Tool struct {
+		resolver                *dns.Resolver
+		route53Client           *route53.Client
+		cloudfrontClient        *cloudfront.Client
+		elbv2Client             *elasticloadbalancingv2.Client
+		elbClient               *elasticloadbalancing.Client
+		globalacceleratorClient *globalaccelerator.Client
+	}
+
+	result struct {
+		Hostname string `json:"hostname"`
+		Status   string `json:"status"`
+		// Chain is each CNAME, Route 53 alias & reverse DNS name followed from the hostname, in order.
+		Chain     []hop      `json:"chain"`
+		Addresses []string   `json:"addresses"`
+		Resources []resource `json:"resources"`
+		Notes     []string   `json:"notes,omitempty"`
+	}
+
+	hop struct {
+		Name   string `json:"name"`
+		Target string `json:"target"`
+		Via    string `json:"via"`
+	}
+
+	resource struct {
+		// Name is the DNS name the resource was recognised from.
+		Name string `json:"name"`
+		endpoint
+	}
+)
+
+func NewTool(
+	resolver *dns.Resolver,
+	route53Client *route53.Client,
+	cloudfrontClient *cloudfront.Client,
+	elbv2Client *elasticloadbalancingv2.Client,
+	elbClient *elasticloadbalancing.Client,
+	globalacceleratorClient *globalaccelerator.Client,
+) tools.Function {
+	return &Tool{
+		resolver:                resolver,
+		route53Client:           route53Client,
+		cloudfrontClient:        cloudfrontClient,
+		elbv2Client:             elbv2Client,
+		elbClient:               elbClient,
+		globalacceleratorClient: globalacceleratorClient,
+	}
+}
+
+func (t *Tool) Name() string {
+	return "resolve_domain_to_aws"
+}
+
+func (t *Tool) Description() string {
+	return `This tool finds the AWS resources a hostname points at, e.g. the CloudFront distribution or load balancer serving a website.
+The hostname's CNAMEs & Route 53 alias records are followed, and each name in the chain is matched against the DNS names of AWS endpoints: CloudFront distributions, load balancers, S3 buckets & website endpoints, API Gateway APIs & custom domain names, Global Accelerator accelerators, RDS databases & EC2 instances. If the chain doesn't end in a known endpoint, the reverse DNS names of its addresses are matched too.
+The response is a JSON object with the chain, the addresses at the end of it, and each resource found with its resource type & identifier, which can be passed straight to the get_aws_resource tool. If the identifier could be of more than one resource type, the other types are listed as alternatives. A resource without an identifier was recognised, but not found in this account.`
+}
+
+func (t *Tool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterHostname,
+			Description: "The hostname to resolve, e.g. www.example.com. A URL's hostname is used if a URL is given.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t *Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	hostname, ok := parameters[parameterHostname].(string)
+	if !ok || strings.TrimSpace(hostname) == "" {
+		return "", fmt.Errorf("%s is not a valid string", parameterHostname)
+	}
+	hostname = normalize(hostname)
+
+	out := result{Hostname: hostname, Chain: []hop{}, Addresses: []string{}, Resources: []resource{}}
+	current := hostname
+	for range maxAliases {
+		answer, err := t.resolver.Lookup(ctx, current, "A")
+		if err != nil {
+			return "", err
+		}
+		out.Status = answer.Status
+		for _, link := range answer.CNAMEChain {
+			out.Chain = append(out.Chain, hop{Name: normalize(link.Name), Target: normalize(link.Target), Via: viaCNAME})
+			current = normalize(link.Target)
+		}
+		out.Addresses = out.Addresses[:0]
+		for _, r := range answer.Records {
+			out.Addresses = append(out.Addresses, r.Value)
+		}
+
+		// AWS endpoints aren't in hosted zones of this account
+		if _, ok := matchEndpoint(current, ""); ok {
+			break
+		}
+		target, err := t.alias(ctx, current)
+		if err != nil {
+			out.Notes = append(out.Notes, fmt.Sprintf("Route 53 alias records couldn't be checked, so the chain may be incomplete: %s", err))
+			break
+		}
+		if target == "" {
+			break
+		}
+		out.Chain = append(out.Chain, hop{Name: current, Target: target, Via: viaAlias})
+		current = target
+	}
+
+	// the hostname may itself be an endpoint, e.g. a load balancer's DNS name
+	if e, ok := matchEndpoint(hostname, ""); ok {
+		out.Resources = append(out.Resources, resource{Name: hostname, endpoint: e})
+	}
+	for _, h := range out.Chain {
+		if e, ok := matchEndpoint(h.Target, h.Name); ok {
+			out.Resources = append(out.Resources, resource{Name: h.Target, endpoint: e})
+		}
+	}
+
+	// addresses such as elastic IPs & CloudFront's edge servers have AWS reverse DNS names
+	if len(out.Resources) == 0 {
+		for i, address := range out.Addresses {
+			if i == maxAddresses || net.ParseIP(address) == nil {
+				break
+			}
+			answer, err := t.resolver.LookupAddr(ctx, address)
+			if err != nil {
+				out.Notes = append(out.Notes, err.Error())
+				break
+			}
+			for _, r := range answer.Records {
+				name := normalize(r.Value)
+				out.Chain = append(out.Chain, hop{Name: address, Target: name, Via: viaPTR})
+				if e, ok := matchEndpoint(name, current); ok {
+					out.Resources = append(out.Resources, resource{Name: name, endpoint: e})
+				}
+			}
+		}
+	}
+
+	for i := range out.Resources {
+		t.identify(ctx, &out.Resources[i])
+	}
+	if len(out.Resources) == 0 {
+		out.Notes = append(out.Notes, "The hostname doesn't point at a known AWS endpoint. It may be served from outside AWS, or by an EC2 instance or elastic IP address, which can be found by its address.")
+	}
+
+	outJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling resources to JSON: %w", err)
+	}
+
+	return string(outJSON), nil
+}
+
+// alias returns the target of the Route 53 alias record for name, or an empty string if there
+// isn't one. The record is looked for in the most specific hosted zones the name is in, which
+// may be both public & private.
+func (t *Tool) alias(ctx context.Context, name string) (string, error) {
+	fqdn := name + "."
+	labels := strings.Split(name, ".")
+	for i := range len(labels) - 1 {
+		zone := strings.Join(labels[i:], ".") + "."
+		zones, err := t.route53Client.ListHostedZonesByName(ctx, &route53.ListHostedZonesByNameInput{DNSName: &zone})
+		if err != nil {
+			return "", fmt.Errorf("error listing hosted zones named %s: %w", zone, err)
+		}
+
+		found := false
+		for _, z := range zones.HostedZones {
+			if !strings.EqualFold(aws.ToString(z.Name), zone) {
+				continue
+			}
+			found = true
+
+			records, err := t.route53Client.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+				HostedZoneId:    z.Id,
+				StartRecordName: &fqdn,
+			})
+			if err != nil {
+				return "", fmt.Errorf("error listing records of hosted zone %s: %w", zone, err)
+			}
+			for _, r := range records.ResourceRecordSets {
+				if strings.EqualFold(aws.ToString(r.Name), fqdn) && r.AliasTarget != nil {
+					return normalize(aws.ToString(r.AliasTarget.DNSName)), nil
+				}
+			}
+		}
+		if found {
+			return "", nil
+		}
+	}
+	return "", nil
+}
+
+// identify looks up the identifier of a resource whose DNS name doesn't contain it. Errors are
+// noted on the resource rather than returned, as the rest of the result is still useful.
+func (t *Tool) identify(ctx context.Context, r *resource) {
+	var err error
+	switch r.lookup {
+	case lookupCloudFront:
+		r.Identifier, err = t.distribution(ctx, r.Name)
+	case lookupLoadBalancer:
+		err = t.loadBalancer(ctx, r)
+	case lookupGlobalAccelerator:
+		r.Identifier, err = t.accelerator(ctx, r.Name)
+	default:
+		return
+	}
+
+	switch {
+	case err != nil:
+		r.Note = fmt.Sprintf("The identifier couldn't be looked up: %s", err)
+	case r.Identifier == "":
+		r.Note = "No resource with this DNS name was found in this account, so it may belong to another account."
+	}
+}
+
+// distribution returns the ID of the CloudFront distribution with a domain name.
+func (t *Tool) distribution(ctx context.Context, name string) (string, error) {
+	paginator := cloudfront.NewListDistributionsPaginator(t.cloudfrontClient, &cloudfront.ListDistributionsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("error listing CloudFront distributions: %w", err)
+		}
+		if page.DistributionList == nil {
+			continue
+		}
+		for _, d := range page.DistributionList.Items {
+			if strings.EqualFold(aws.ToString(d.DomainName), name) {
+				return aws.ToString(d.Id), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// loadBalancer sets the identifier of a load balancer, which is its ARN for application, network &
+// gateway load balancers, or its name for classic load balancers.
+func (t *Tool) loadBalancer(ctx context.Context, r *resource) error {
+	name := strings.TrimPrefix(r.Name, "dualstack.")
+	v2, err := t.elbv2Client.DescribeLoadBalancers(ctx, &elasticloadbalancingv2.DescribeLoadBalancersInput{
+		Names: []string{r.name},
+	}, func(o *elasticloadbalancingv2.Options) {
+		o.Region = r.Region
+	})
+	var notFound *elbv2types.LoadBalancerNotFoundException
+	if err != nil && !errors.As(err, &notFound) {
+		return fmt.Errorf("error describing load balancer %s: %w", r.name, err)
+	}
+	if err == nil {
+		for _, lb := range v2.LoadBalancers {
+			if strings.EqualFold(aws.ToString(lb.DNSName), name) {
+				r.Identifier = aws.ToString(lb.LoadBalancerArn)
+				return nil
+			}
+		}
+	}
+
+	classic, err := t.elbClient.DescribeLoadBalancers(ctx, &elasticloadbalancing.DescribeLoadBalancersInput{
+		LoadBalancerNames: []string{r.name},
+	}, func(o *elasticloadbalancing.Options) {
+		o.Region = r.Region
+	})
+	var classicNotFound *elbtypes.AccessPointNotFoundException
+	if errors.As(err, &classicNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error describing classic load balancer %s: %w", r.name, err)
+	}
+	for _, lb := range classic.LoadBalancerDescriptions {
+		if strings.EqualFold(aws.ToString(lb.DNSName), name) {
+			r.ResourceType = "AWS::ElasticLoadBalancing::LoadBalancer"
+			r.Identifier = aws.ToString(lb.LoadBalancerName)
+			return nil
+		}
+	}
+	return nil
+}
+
+// accelerator returns the ARN of the Global Accelerator accelerator with a DNS name.
+func (t *Tool) accelerator(ctx context.Context, name string) (string, error) {
+	paginator := globalaccelerator.NewListAcceleratorsPaginator(t.globalacceleratorClient, &globalaccelerator.ListAcceleratorsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx, func(o *globalaccelerator.Options) {
+			if region, ok := globalAcceleratorRegions[arn.PartitionOf(o.Region)]; ok {
+				o.Region = region
+			}
+		})
+		if err != nil {
+			return "", fmt.Errorf("error listing Global Accelerator accelerators: %w", err)
+		}
+		for _, a := range page.Accelerators {
+			if strings.EqualFold(aws.ToString(a.DnsName), name) || strings.EqualFold(aws.ToString(a.DualStackDnsName), name) {
+				return aws.ToString(a.AcceleratorArn), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// normalize returns a DNS name in lower case without the trailing dot, taking the hostname of a URL.
+func normalize(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, rest, ok := strings.Cut(name, "://"); ok {
+		name, _, _ = strings.Cut(rest, "/")
+		if host, _, err := net.SplitHostPort(name); err == nil {
+			name = host
+		}
+	}
+	return strings.TrimSuffix(name, ".")
+}
@@ -0,0 +1,284 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/fergalhk/llm-cloud-discovery/internal/awstest"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/dns"
+	mdns "github.com/miekg/dns"
+)
+
+const (
+	testDistributionID  = "E2QWRUHAPOMQZL"
+	testLoadBalancerARN = "arn:aws:elasticloadbalancing:eu-west-1:111111111111:loadbalancer/app/web/50dc6c495c0c9188"
+	testAcceleratorARN  = "arn:aws:globalaccelerator::111111111111:accelerator/1234abcd-abcd-1234-abcd-1234abcdefgh"
+)
+
+// testZone is served by the test DNS server, which follows CNAMEs as a recursive resolver would.
+// Route 53 aliases are answered with their target's addresses, as they are by Route 53.
+var testZone = map[string][]string{
+	"www.example.com.":               {"www.example.com. 300 IN CNAME d111111abcdef8.cloudfront.net."},
+	"d111111abcdef8.cloudfront.net.": {"d111111abcdef8.cloudfront.net. 60 IN A 192.0.2.1"},
+	"app.example.com.":               {"app.example.com. 60 IN A 10.0.1.5"},
+	"dualstack.internal-web-1234567890.eu-west-1.elb.amazonaws.com.": {"dualstack.internal-web-1234567890.eu-west-1.elb.amazonaws.com. 60 IN A 10.0.1.5"},
+	"static.example.com.":                            {"static.example.com. 60 IN A 192.0.2.20"},
+	"s3-website-eu-west-1.amazonaws.com.":            {"s3-website-eu-west-1.amazonaws.com. 60 IN A 192.0.2.20"},
+	"legacy.example.com.":                            {"legacy.example.com. 300 IN CNAME legacy-1234567890.eu-west-1.elb.amazonaws.com."},
+	"legacy-1234567890.eu-west-1.elb.amazonaws.com.": {"legacy-1234567890.eu-west-1.elb.amazonaws.com. 60 IN A 192.0.2.30"},
+	"global.example.com.":                            {"global.example.com. 300 IN CNAME a1234567890abcdef.awsglobalaccelerator.com."},
+	"a1234567890abcdef.awsglobalaccelerator.com.":    {"a1234567890abcdef.awsglobalaccelerator.com. 60 IN A 192.0.2.40"},
+	"mail.example.com.":                              {"mail.example.com. 300 IN A 192.0.2.50"},
+	"50.2.0.192.in-addr.arpa.":                       {"50.2.0.192.in-addr.arpa. 300 IN PTR ec2-192-0-2-50.eu-west-1.compute.amazonaws.com."},
+}
+
+// testAliases are the Route 53 alias records in the example.com hosted zone, by name.
+var testAliases = map[string]string{
+	"app.example.com.":    "dualstack.internal-web-1234567890.eu-west-1.elb.amazonaws.com.",
+	"static.example.com.": "s3-website-eu-west-1.amazonaws.com.",
+}
+
+// newTestDNSServer starts a DNS server on a local UDP port serving testZone, returning its address.
+func newTestDNSServer(t *testing.T) string {
+	t.Helper()
+
+	handler := mdns.HandlerFunc(func(w mdns.ResponseWriter, req *mdns.Msg) {
+		m := new(mdns.Msg)
+		m.SetReply(req)
+		m.RecursionAvailable = true
+		q := req.Question[0]
+		name := strings.ToLower(q.Name)
+
+		for range 10 {
+			records, ok := testZone[name]
+			if !ok {
+				if len(m.Answer) == 0 {
+					m.Rcode = mdns.RcodeNameError
+				}
+				break
+			}
+			next := ""
+			for _, s := range records {
+				rr, err := mdns.NewRR(s)
+				if err != nil {
+					t.Errorf("error parsing %q: %v", s, err)
+					continue
+				}
+				if cname, ok := rr.(*mdns.CNAME); ok {
+					m.Answer = append(m.Answer, rr)
+					next = cname.Target
+				} else if rr.Header().Rrtype == q.Qtype {
+					m.Answer = append(m.Answer, rr)
+				}
+			}
+			if next == "" {
+				break
+			}
+			name = next
+		}
+		w.WriteMsg(m)
+	})
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	started := make(chan struct{})
+	server := &mdns.Server{PacketConn: conn, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+
+	return conn.LocalAddr().String()
+}
+
+// newTestTool returns a tool backed by the test DNS server & local stand-ins for the AWS APIs. The
+// example.com hosted zone has testAliases, & the account has a CloudFront distribution, an
+// application load balancer named web, a classic load balancer named legacy & an accelerator.
+func newTestTool(t *testing.T) *Tool {
+	t.Helper()
+
+	route53Handler := awstest.RESTHandler(map[string]awstest.RESTOperation{
+		"GET /2013-04-01/hostedzonesbyname": func(r *http.Request) (string, error) {
+			// as with the real API, zones are listed from the name, whether or not it's a zone
+			return `<ListHostedZonesByNameResponse><HostedZones><HostedZone><Id>/hostedzone/Z1</Id><Name>example.com.</Name>` +
+				`<CallerReference>1</CallerReference></HostedZone></HostedZones><IsTruncated>false</IsTruncated><MaxItems>100</MaxItems>` +
+				`</ListHostedZonesByNameResponse>`, nil
+		},
+		"GET /2013-04-01/hostedzone/{id}/rrset": func(r *http.Request) (string, error) {
+			if id := r.PathValue("id"); id != "Z1" {
+				return "", &awstest.Error{Code: "NoSuchHostedZone", Message: "No hosted zone found with ID: " + id}
+			}
+			// records are listed from the name, so the next record is listed if there's none
+			name := r.URL.Query().Get("name")
+			record := `<ResourceRecordSet><Name>zz.example.com.</Name><Type>A</Type><TTL>300</TTL>` +
+				`<ResourceRecords><ResourceRecord><Value>192.0.2.99</Value></ResourceRecord></ResourceRecords></ResourceRecordSet>`
+			if target, ok := testAliases[name]; ok {
+				record = fmt.Sprintf(`<ResourceRecordSet><Name>%s</Name><Type>A</Type><AliasTarget><HostedZoneId>Z32O12XQLNTSW2</HostedZoneId>`+
+					`<DNSName>%s</DNSName><EvaluateTargetHealth>false</EvaluateTargetHealth></AliasTarget></ResourceRecordSet>`, name, target)
+			}
+			return `<ListResourceRecordSetsResponse><ResourceRecordSets>` + record +
+				`</ResourceRecordSets><IsTruncated>false</IsTruncated><MaxItems>100</MaxItems></ListResourceRecordSetsResponse>`, nil
+		},
+	})
+
+	cloudfrontHandler := awstest.RESTHandler(map[string]awstest.RESTOperation{
+		"GET /2020-05-31/distribution": func(*http.Request) (string, error) {
+			return `<DistributionList><Marker></Marker><MaxItems>100</MaxItems><IsTruncated>false</IsTruncated><Quantity>1</Quantity>` +
+				`<Items><DistributionSummary><Id>` + testDistributionID + `</Id><DomainName>d111111abcdef8.cloudfront.net</DomainName>` +
+				`</DistributionSummary></Items></DistributionList>`, nil
+		},
+	})
+
+	elbv2Handler := awstest.QueryHandler(map[string]awstest.QueryOperation{
+		"DescribeLoadBalancers": func(form url.Values) (string, error) {
+			if name := form.Get("Names.member.1"); name != "web" {
+				return "", &awstest.Error{Code: "LoadBalancerNotFound", Message: "One or more load balancers not found"}
+			}
+			return `<DescribeLoadBalancersResponse><DescribeLoadBalancersResult><LoadBalancers><member>` +
+				`<LoadBalancerArn>` + testLoadBalancerARN + `</LoadBalancerArn><LoadBalancerName>web</LoadBalancerName>` +
+				`<DNSName>internal-web-1234567890.eu-west-1.elb.amazonaws.com</DNSName></member></LoadBalancers>` +
+				`</DescribeLoadBalancersResult></DescribeLoadBalancersResponse>`, nil
+		},
+	})
+
+	elbHandler := awstest.QueryHandler(map[string]awstest.QueryOperation{
+		"DescribeLoadBalancers": func(form url.Values) (string, error) {
+			if name := form.Get("LoadBalancerNames.member.1"); name != "legacy" {
+				return "", &awstest.Error{Code: "LoadBalancerNotFound", Message: "There is no ACTIVE Load Balancer named '" + name + "'"}
+			}
+			return `<DescribeLoadBalancersResponse><DescribeLoadBalancersResult><LoadBalancerDescriptions><member>` +
+				`<LoadBalancerName>legacy</LoadBalancerName><DNSName>legacy-1234567890.eu-west-1.elb.amazonaws.com</DNSName>` +
+				`</member></LoadBalancerDescriptions></DescribeLoadBalancersResult></DescribeLoadBalancersResponse>`, nil
+		},
+	})
+
+	acceleratorHandler := awstest.JSONHandler(map[string]awstest.JSONOperation{
+		"GlobalAccelerator_V20180706.ListAccelerators": func([]byte) (any, error) {
+			return map[string]any{"Accelerators": []map[string]string{{
+				"AcceleratorArn": testAcceleratorARN,
+				"DnsName":        "a1234567890abcdef.awsglobalaccelerator.com",
+			}}}, nil
+		},
+	})
+
+	return NewTool(
+		dns.NewResolver(newTestDNSServer(t)),
+		route53.NewFromConfig(awstest.Config(t, route53Handler)),
+		cloudfront.NewFromConfig(awstest.Config(t, cloudfrontHandler)),
+		elasticloadbalancingv2.NewFromConfig(awstest.Config(t, elbv2Handler)),
+		elasticloadbalancing.NewFromConfig(awstest.Config(t, elbHandler)),
+		globalaccelerator.NewFromConfig(awstest.Config(t, acceleratorHandler)),
+	).(*Tool)
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname string
+		want     result
+	}{
+		{
+			name:     "CNAME to a CloudFront distribution",
+			hostname: "https://www.example.com/index.html",
+			want: result{
+				Hostname:  "www.example.com",
+				Status:    "NOERROR",
+				Chain:     []hop{{Name: "www.example.com", Target: "d111111abcdef8.cloudfront.net", Via: viaCNAME}},
+				Addresses: []string{"192.0.2.1"},
+				Resources: []resource{{Name: "d111111abcdef8.cloudfront.net", endpoint: endpoint{Service: "cloudfront", ResourceType: "AWS::CloudFront::Distribution", Identifier: testDistributionID}}},
+			},
+		},
+		{
+			name:     "alias to a load balancer",
+			hostname: "app.example.com",
+			want: result{
+				Hostname:  "app.example.com",
+				Status:    "NOERROR",
+				Chain:     []hop{{Name: "app.example.com", Target: "dualstack.internal-web-1234567890.eu-west-1.elb.amazonaws.com", Via: viaAlias}},
+				Addresses: []string{"10.0.1.5"},
+				Resources: []resource{{Name: "dualstack.internal-web-1234567890.eu-west-1.elb.amazonaws.com", endpoint: endpoint{Service: "elasticloadbalancing", ResourceType: "AWS::ElasticLoadBalancingV2::LoadBalancer", Identifier: testLoadBalancerARN, Region: "eu-west-1"}}},
+			},
+		},
+		{
+			name:     "alias to an S3 website endpoint",
+			hostname: "static.example.com",
+			want: result{
+				Hostname:  "static.example.com",
+				Status:    "NOERROR",
+				Chain:     []hop{{Name: "static.example.com", Target: "s3-website-eu-west-1.amazonaws.com", Via: viaAlias}},
+				Addresses: []string{"192.0.2.20"},
+				Resources: []resource{{Name: "s3-website-eu-west-1.amazonaws.com", endpoint: endpoint{Service: "s3", ResourceType: "AWS::S3::Bucket", Identifier: "static.example.com", Region: "eu-west-1"}}},
+			},
+		},
+		{
+			name:     "CNAME to a classic load balancer",
+			hostname: "legacy.example.com",
+			want: result{
+				Hostname:  "legacy.example.com",
+				Status:    "NOERROR",
+				Chain:     []hop{{Name: "legacy.example.com", Target: "legacy-1234567890.eu-west-1.elb.amazonaws.com", Via: viaCNAME}},
+				Addresses: []string{"192.0.2.30"},
+				Resources: []resource{{Name: "legacy-1234567890.eu-west-1.elb.amazonaws.com", endpoint: endpoint{Service: "elasticloadbalancing", ResourceType: "AWS::ElasticLoadBalancing::LoadBalancer", Identifier: "legacy", Region: "eu-west-1"}}},
+			},
+		},
+		{
+			name:     "CNAME to a Global Accelerator accelerator",
+			hostname: "global.example.com",
+			want: result{
+				Hostname:  "global.example.com",
+				Status:    "NOERROR",
+				Chain:     []hop{{Name: "global.example.com", Target: "a1234567890abcdef.awsglobalaccelerator.com", Via: viaCNAME}},
+				Addresses: []string{"192.0.2.40"},
+				Resources: []resource{{Name: "a1234567890abcdef.awsglobalaccelerator.com", endpoint: endpoint{Service: "globalaccelerator", ResourceType: "AWS::GlobalAccelerator::Accelerator", Identifier: testAcceleratorARN}}},
+			},
+		},
+		{
+			name:     "reverse DNS name of an address",
+			hostname: "mail.example.com",
+			want: result{
+				Hostname:  "mail.example.com",
+				Status:    "NOERROR",
+				Chain:     []hop{{Name: "192.0.2.50", Target: "ec2-192-0-2-50.eu-west-1.compute.amazonaws.com", Via: viaPTR}},
+				Addresses: []string{"192.0.2.50"},
+				Resources: []resource{{Name: "ec2-192-0-2-50.eu-west-1.compute.amazonaws.com", endpoint: endpoint{Service: "ec2", ResourceType: "AWS::EC2::Instance", Region: "eu-west-1", Note: "The name is the public DNS name of an EC2 instance, or of an elastic IP address, with the public IP address 192.0.2.50. Look for the instance whose PublicIp is 192.0.2.50."}}},
+			},
+		},
+	}
+
+	tool := newTestTool(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tool.Call(context.Background(), map[string]any{parameterHostname: tt.hostname})
+			if err != nil {
+				t.Fatalf("error calling tool: %v", err)
+			}
+			var got result
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("error unmarshalling %s: %v", out, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	tool := newTestTool(t)
+	if _, err := tool.Call(context.Background(), map[string]any{parameterHostname: " "}); err == nil {
+		t.Error("got no error for an empty hostname")
+	}
+}
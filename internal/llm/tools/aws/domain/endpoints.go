@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+)
+
+// lookup is how the identifier of an endpoint's resource is found, for endpoints whose names
+// don't contain it.
+type lookup int
+
+const (
+	lookupNone lookup = iota
+	lookupCloudFront
+	lookupLoadBalancer
+	lookupGlobalAccelerator
+)
+
+type (
+	// endpoint is an AWS resource recognised from its DNS name.
+	endpoint struct {
+		Service      string `json:"service"`
+		ResourceType string `json:"resource_type,omitempty"`
+		Identifier   string `json:"identifier,omitempty"`
+		Region       string `json:"region,omitempty"`
+		// AlternativeTypes are other resource types the identifier may belong to, when the name
+		// doesn't say which, e.g. REST & HTTP APIs share API Gateway's names.
+		AlternativeTypes []string `json:"alternative_types,omitempty"`
+		Note             string   `json:"note,omitempty"`
+
+		// lookup finds the identifier, which is looked up by name if it's set.
+		lookup lookup
+		name   string
+	}
+
+	// matcher recognises the names of a kind of endpoint. previous is the name that pointed at
+	// the endpoint, by CNAME or alias, which is needed when the endpoint is shared, e.g. an S3
+	// website endpoint is named after the bucket it serves.
+	matcher struct {
+		pattern *regexp.Regexp
+		build   func(m []string, previous string) endpoint
+	}
+)
+
+var matchers = []matcher{
+	{
+		// d111111abcdef8.cloudfront.net
+		pattern: regexp.MustCompile(`^[a-z0-9]+\.cloudfront\.net$`),
+		build: func(m []string, _ string) endpoint {
+			return endpoint{Service: "cloudfront", ResourceType: "AWS::CloudFront::Distribution", lookup: lookupCloudFront}
+		},
+	},
+	{
+		// the reverse DNS name of a CloudFront edge server, e.g. server-1-2-3-4.fra50.r.cloudfront.net
+		pattern: regexp.MustCompile(`\.r\.cloudfront\.net$`),
+		build: func(m []string, _ string) endpoint {
+			return endpoint{Service: "cloudfront", ResourceType: "AWS::CloudFront::Distribution", Note: "The address belongs to a CloudFront edge server, which doesn't say which distribution serves the domain. Look for a distribution with the domain in its Aliases."}
+		},
+	},
+	{
+		// my-alb-1234567890.eu-west-1.elb.amazonaws.com, internal-my-alb-1234567890.eu-west-1.elb.amazonaws.com
+		// or my-nlb-0123456789abcdef.elb.eu-west-1.amazonaws.com
+		pattern: regexp.MustCompile(`^(?:dualstack\.)?(?:internal-)?(.+)-[a-z0-9]+\.(?:([a-z0-9-]+)\.elb|elb\.([a-z0-9-]+))\.amazonaws\.com$`),
+		build: func(m []string, _ string) endpoint {
+			return endpoint{Service: "elasticloadbalancing", ResourceType: "AWS::ElasticLoadBalancingV2::LoadBalancer", Region: m[2] + m[3], lookup: lookupLoadBalancer, name: m[1]}
+		},
+	},
+	{
+		// my-bucket.s3-website-us-east-1.amazonaws.com or my-bucket.s3-website.eu-west-1.amazonaws.com.
+		// Aliases point at the endpoint without the bucket, which is named after the domain.
+		pattern: regexp.MustCompile(`^(?:(.+)\.)?s3-website[.-]([a-z0-9-]+)\.amazonaws\.com$`),
+		build: func(m []string, previous string) endpoint {
+			bucket := m[1]
+			if bucket == "" {
+				bucket = previous
+			}
+			return endpoint{Service: "s3", ResourceType: "AWS::S3::Bucket", Identifier: bucket, Region: m[2]}
+		},
+	},
+	{
+		// my-bucket.s3.amazonaws.com or my-bucket.s3.eu-west-1.amazonaws.com
+		pattern: regexp.MustCompile(`^(.+)\.s3(?:[.-]([a-z0-9-]+))?\.amazonaws\.com$`),
+		build: func(m []string, _ string) endpoint {
+			return endpoint{Service: "s3", ResourceType: "AWS::S3::Bucket", Identifier: m[1], Region: m[2]}
+		},
+	},
+	{
+		// a1b2c3d4e5.execute-api.eu-west-1.amazonaws.com, or d-a1b2c3d4e5.execute-api.eu-west-1.amazonaws.com
+		// for a regional custom domain name
+		pattern: regexp.MustCompile(`^([a-z0-9-]+)\.execute-api\.([a-z0-9-]+)\.amazonaws\.com$`),
+		build: func(m []string, previous string) endpoint {
+			if strings.HasPrefix(m[1], "d-") {
+				return endpoint{Service: "apigateway", ResourceType: "AWS::ApiGateway::DomainName", Identifier: previous, Region: m[2], AlternativeTypes: []string{"AWS::ApiGatewayV2::DomainName"}}
+			}
+			return endpoint{Service: "apigateway", ResourceType: "AWS::ApiGateway::RestApi", Identifier: m[1], Region: m[2], AlternativeTypes: []string{"AWS::ApiGatewayV2::Api"}}
+		},
+	},
+	{
+		// a1234567890abcdef.awsglobalaccelerator.com or a1234567890abcdef.dualstack.awsglobalaccelerator.com
+		pattern: regexp.MustCompile(`^[a-z0-9]+\.(?:dualstack\.)?awsglobalaccelerator\.com$`),
+		build: func(m []string, _ string) endpoint {
+			return endpoint{Service: "globalaccelerator", ResourceType: "AWS::GlobalAccelerator::Accelerator", lookup: lookupGlobalAccelerator}
+		},
+	},
+	{
+		// my-db.abcdefghijkl.eu-west-1.rds.amazonaws.com, my-cluster.cluster-ro-abcdefghijkl.eu-west-1.rds.amazonaws.com
+		// or my-proxy.proxy-abcdefghijkl.eu-west-1.rds.amazonaws.com
+		pattern: regexp.MustCompile(`^([a-z0-9-]+)\.(cluster-(?:ro-)?|proxy-)?[a-z0-9]+\.([a-z0-9-]+)\.rds\.amazonaws\.com$`),
+		build: func(m []string, _ string) endpoint {
+			switch {
+			case m[2] == "proxy-":
+				return endpoint{Service: "rds", ResourceType: "AWS::RDS::DBProxy", Identifier: m[1], Region: m[3]}
+			case m[2] != "":
+				return endpoint{Service: "rds", ResourceType: "AWS::RDS::DBCluster", Identifier: m[1], Region: m[3]}
+			}
+			return endpoint{Service: "rds", ResourceType: "AWS::RDS::DBInstance", Identifier: m[1], Region: m[3]}
+		},
+	},
+	{
+		// ec2-1-2-3-4.eu-west-1.compute.amazonaws.com, or ec2-1-2-3-4.compute-1.amazonaws.com in us-east-1
+		pattern: regexp.MustCompile(`^ec2-(\d+)-(\d+)-(\d+)-(\d+)\.(?:([a-z0-9-]+)\.compute|compute-1)\.amazonaws\.com$`),
+		build: func(m []string, _ string) endpoint {
+			ip := strings.Join(m[1:5], ".")
+			region := m[5]
+			if region == "" {
+				region = "us-east-1"
+			}
+			return endpoint{Service: "ec2", ResourceType: "AWS::EC2::Instance", Region: region, Note: "The name is the public DNS name of an EC2 instance, or of an elastic IP address, with the public IP address " + ip + ". Look for the instance whose PublicIp is " + ip + "."}
+		},
+	},
+}
+
+// matchEndpoint returns the AWS resource a DNS name belongs to, if it's recognised.
+func matchEndpoint(name, previous string) (endpoint, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, m := range matchers {
+		if match := m.pattern.FindStringSubmatch(name); match != nil {
+			return m.build(match, previous), true
+		}
+	}
+	return endpoint{}, false
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		dnsName  string
+		previous string
+		want     endpoint
+		wantOK   bool
+	}{
+		{
+			name:    "CloudFront distribution",
+			dnsName: "d111111abcdef8.cloudfront.net.",
+			want:    endpoint{Service: "cloudfront", ResourceType: "AWS::CloudFront::Distribution", lookup: lookupCloudFront},
+			wantOK:  true,
+		},
+		{
+			name:    "application load balancer",
+			dnsName: "my-alb-1234567890.eu-west-1.elb.amazonaws.com",
+			want:    endpoint{Service: "elasticloadbalancing", ResourceType: "AWS::ElasticLoadBalancingV2::LoadBalancer", Region: "eu-west-1", lookup: lookupLoadBalancer, name: "my-alb"},
+			wantOK:  true,
+		},
+		{
+			name:    "network load balancer",
+			dnsName: "my-nlb-0123456789abcdef.elb.us-east-2.amazonaws.com",
+			want:    endpoint{Service: "elasticloadbalancing", ResourceType: "AWS::ElasticLoadBalancingV2::LoadBalancer", Region: "us-east-2", lookup: lookupLoadBalancer, name: "my-nlb"},
+			wantOK:  true,
+		},
+		{
+			name:    "internal dualstack load balancer",
+			dnsName: "dualstack.internal-my-alb-1234567890.eu-west-1.elb.amazonaws.com",
+			want:    endpoint{Service: "elasticloadbalancing", ResourceType: "AWS::ElasticLoadBalancingV2::LoadBalancer", Region: "eu-west-1", lookup: lookupLoadBalancer, name: "my-alb"},
+			wantOK:  true,
+		},
+		{
+			name:    "S3 website endpoint",
+			dnsName: "assets.example.com.s3-website-us-east-1.amazonaws.com",
+			want:    endpoint{Service: "s3", ResourceType: "AWS::S3::Bucket", Identifier: "assets.example.com", Region: "us-east-1"},
+			wantOK:  true,
+		},
+		{
+			name:     "S3 website endpoint via an alias",
+			dnsName:  "s3-website.eu-west-1.amazonaws.com.",
+			previous: "www.example.com",
+			want:     endpoint{Service: "s3", ResourceType: "AWS::S3::Bucket", Identifier: "www.example.com", Region: "eu-west-1"},
+			wantOK:   true,
+		},
+		{
+			name:    "S3 bucket",
+			dnsName: "my-bucket.s3.eu-west-1.amazonaws.com",
+			want:    endpoint{Service: "s3", ResourceType: "AWS::S3::Bucket", Identifier: "my-bucket", Region: "eu-west-1"},
+			wantOK:  true,
+		},
+		{
+			name:    "API Gateway API",
+			dnsName: "a1b2c3d4e5.execute-api.eu-west-1.amazonaws.com",
+			want:    endpoint{Service: "apigateway", ResourceType: "AWS::ApiGateway::RestApi", Identifier: "a1b2c3d4e5", Region: "eu-west-1", AlternativeTypes: []string{"AWS::ApiGatewayV2::Api"}},
+			wantOK:  true,
+		},
+		{
+			name:     "API Gateway custom domain name",
+			dnsName:  "d-a1b2c3d4e5.execute-api.eu-west-1.amazonaws.com",
+			previous: "api.example.com",
+			want:     endpoint{Service: "apigateway", ResourceType: "AWS::ApiGateway::DomainName", Identifier: "api.example.com", Region: "eu-west-1", AlternativeTypes: []string{"AWS::ApiGatewayV2::DomainName"}},
+			wantOK:   true,
+		},
+		{
+			name:    "Global Accelerator",
+			dnsName: "a1234567890abcdef.dualstack.awsglobalaccelerator.com",
+			want:    endpoint{Service: "globalaccelerator", ResourceType: "AWS::GlobalAccelerator::Accelerator", lookup: lookupGlobalAccelerator},
+			wantOK:  true,
+		},
+		{
+			name:    "RDS instance",
+			dnsName: "orders.abcdefghijkl.eu-west-1.rds.amazonaws.com",
+			want:    endpoint{Service: "rds", ResourceType: "AWS::RDS::DBInstance", Identifier: "orders", Region: "eu-west-1"},
+			wantOK:  true,
+		},
+		{
+			name:    "RDS cluster reader",
+			dnsName: "orders.cluster-ro-abcdefghijkl.eu-west-1.rds.amazonaws.com",
+			want:    endpoint{Service: "rds", ResourceType: "AWS::RDS::DBCluster", Identifier: "orders", Region: "eu-west-1"},
+			wantOK:  true,
+		},
+		{
+			name:    "RDS proxy",
+			dnsName: "orders-proxy.proxy-abcdefghijkl.eu-west-1.rds.amazonaws.com",
+			want:    endpoint{Service: "rds", ResourceType: "AWS::RDS::DBProxy", Identifier: "orders-proxy", Region: "eu-west-1"},
+			wantOK:  true,
+		},
+		{
+			name:    "EC2 instance in us-east-1",
+			dnsName: "ec2-192-0-2-1.compute-1.amazonaws.com",
+			want:    endpoint{Service: "ec2", ResourceType: "AWS::EC2::Instance", Region: "us-east-1", Note: "The name is the public DNS name of an EC2 instance, or of an elastic IP address, with the public IP address 192.0.2.1. Look for the instance whose PublicIp is 192.0.2.1."},
+			wantOK:  true,
+		},
+		{name: "not an AWS endpoint", dnsName: "www.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchEndpoint(tt.dnsName, tt.previous)
+			if ok != tt.wantOK {
+				t.Fatalf("got match %v, want %v", ok, tt.wantOK)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
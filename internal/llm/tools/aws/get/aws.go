@@ -2,10 +2,14 @@ package get
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/fergalhk/llm-cloud-discovery/internal/arn"
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
 )
 
 const (
@@ -13,14 +17,32 @@ const (
 	parameterResourceIdentifier = "resource_identifier"
 )
 
-type Tool struct {
-	cloudcontrolClient *cloudcontrol.Client
+type (
+	Opt func(*Tool)
+
+	Tool struct {
+		cloudcontrolClient *cloudcontrol.Client
+		format             format.Format
+	}
+)
+
+// WithFormat sets the default format of the resource's properties. The model can still ask for
+// another format per call.
+func WithFormat(f format.Format) Opt {
+	return func(t *Tool) {
+		t.format = f
+	}
 }
 
-func NewTool(cloudcontrolClient *cloudcontrol.Client) tools.Function {
-	return &Tool{
+func NewTool(cloudcontrolClient *cloudcontrol.Client, opts ...Opt) tools.Function {
+	t := &Tool{
 		cloudcontrolClient: cloudcontrolClient,
+		format:             format.JSON,
+	}
+	for _, opt := range opts {
+		opt(t)
 	}
+	return t
 }
 
 func (t *Tool) Name() string {
@@ -28,11 +50,16 @@ func (t *Tool) Name() string {
 }
 
 func (t *Tool) Description() string {
+	shape := "a JSON object containing the resource's properties"
+	if t.format != format.JSON {
+		shape = fmt.Sprintf("the resource's properties as %s, with one property per row and nested properties flattened into dotted paths", t.format.Describe())
+	}
+
 	return fmt.Sprintf(`This tool allows all of the properties of a specific AWS resource to be retrieved.
-The tool returns a JSON object containing the resource's properties.
+The tool returns %s. Properties without a value are left out, and long values are shortened.
 You must provide both the %q and %q parameters.
 The %q parameter is the same resource type used for the list_aws_resources tool.`,
-		parameterResourceIdentifier, parameterResourceType, parameterResourceType)
+		shape, parameterResourceIdentifier, parameterResourceType, parameterResourceType)
 }
 
 func (t *Tool) ParameterDefinitions() []tools.ParameterDefinition {
@@ -45,10 +72,11 @@ func (t *Tool) ParameterDefinitions() []tools.ParameterDefinition {
 		},
 		{
 			Name:        parameterResourceIdentifier,
-			Description: "The identifier of the resource to retrieve.",
+			Description: "The identifier of the resource to retrieve. ARNs are converted to the identifier automatically.",
 			Required:    true,
 			Type:        tools.ParameterTypeString,
 		},
+		format.ParameterDefinition(t.format),
 	}
 }
 
@@ -62,6 +90,13 @@ func (t *Tool) Call(ctx context.Context, parameters map[string]any) (string, err
 	if !ok {
 		return "", fmt.Errorf("%s is not a valid string", parameterResourceIdentifier)
 	}
+	f, err := format.FromParameters(parameters, t.format)
+	if err != nil {
+		return "", err
+	}
+
+	// the model often passes an ARN where CloudControl expects a name or ID
+	resourceIdentifier = arn.NormalizeIdentifier(resourceType, resourceIdentifier)
 
 	resp, err := t.cloudcontrolClient.GetResource(ctx, &cloudcontrol.GetResourceInput{
 		TypeName:   &resourceType,
@@ -71,5 +106,13 @@ func (t *Tool) Call(ctx context.Context, parameters map[string]any) (string, err
 		return "", fmt.Errorf("error getting resource: %w", err)
 	}
 
-	return *resp.ResourceDescription.Properties, nil
+	// numbers are kept as written, as large IDs & sizes would lose precision as floats
+	var properties any
+	decoder := json.NewDecoder(strings.NewReader(*resp.ResourceDescription.Properties))
+	decoder.UseNumber()
+	if err := decoder.Decode(&properties); err != nil {
+		return "", fmt.Errorf("error unmarshalling resource properties: %w", err)
+	}
+
+	return f.Object(properties)
 }
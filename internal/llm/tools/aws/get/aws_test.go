@@ -0,0 +1,61 @@
+package get
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/awstest"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
+)
+
+// testProperties has numbers that can't be represented exactly as float64.
+const testProperties = `{"TableName":"orders","ProvisionedThroughput":{"ReadCapacityUnits":9007199254740993,"WriteCapacityUnits":5},"TableSizeBytes":12345678901234567890,"Ratio":0.1}`
+
+func newTestTool(t *testing.T) *Tool {
+	t.Helper()
+
+	cloudControl := awstest.NewCloudControl()
+	cloudControl.Set("AWS::DynamoDB::Table", "orders", awstest.Resource{Properties: testProperties})
+	return NewTool(cloudControl.Client(t)).(*Tool)
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name   string
+		format format.Format
+		want   string
+	}{
+		{
+			name:   "JSON",
+			format: format.JSON,
+			want:   `{"ProvisionedThroughput":{"ReadCapacityUnits":9007199254740993,"WriteCapacityUnits":5},"Ratio":0.1,"TableName":"orders","TableSizeBytes":12345678901234567890}`,
+		},
+		{
+			name:   "CSV",
+			format: format.CSV,
+			want: "property,value\n" +
+				"ProvisionedThroughput.ReadCapacityUnits,9007199254740993\n" +
+				"ProvisionedThroughput.WriteCapacityUnits,5\n" +
+				"Ratio,0.1\n" +
+				"TableName,orders\n" +
+				"TableSizeBytes,12345678901234567890\n",
+		},
+	}
+
+	tool := newTestTool(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tool.Call(context.Background(), map[string]any{
+				parameterResourceType:       "AWS::DynamoDB::Table",
+				parameterResourceIdentifier: "orders",
+				format.ParameterFormat:      string(tt.format),
+			})
+			if err != nil {
+				t.Fatalf("error calling tool: %v", err)
+			}
+			if out != tt.want {
+				t.Errorf("got %s, want %s", out, tt.want)
+			}
+		})
+	}
+}
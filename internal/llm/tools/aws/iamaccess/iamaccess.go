@@ -0,0 +1,267 @@
+package iamaccess
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/fergalhk/llm-cloud-discovery/internal/arn"
+	"github.com/fergalhk/llm-cloud-discovery/internal/iampolicy"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+const (
+	parameterAction       = "action"
+	parameterResourceARN  = "resource_arn"
+	parameterPrincipalARN = "principal_arn"
+	parameterContext      = "context"
+
+	roleTypeName = "AWS::IAM::Role"
+	userTypeName = "AWS::IAM::User"
+)
+
+type (
+	Tool struct {
+		cloudcontrolClient *cloudcontrol.Client
+
+		// mu guards the policy caches, which live until the conversation is reset.
+		mu              sync.Mutex
+		principals      map[arn.CloudControlResource]principalPolicies
+		managedPolicies map[string]iampolicy.NamedPolicy
+	}
+
+	result struct {
+		ResourceARN         string              `json:"resource_arn"`
+		ResourcePolicies    []string            `json:"resource_policies"`
+		PrincipalsEvaluated int                 `json:"principals_evaluated"`
+		Results             []principalResult   `json:"results"`
+		Unevaluated         []unevaluatedPolicy `json:"unevaluated_policies,omitempty"`
+		Note                string              `json:"note,omitempty"`
+	}
+
+	principalResult struct {
+		Principal string `json:"principal"`
+		Action    string `json:"action"`
+		iampolicy.Result
+	}
+)
+
+func NewTool(cloudcontrolClient *cloudcontrol.Client) tools.Function {
+	t := &Tool{
+		cloudcontrolClient: cloudcontrolClient,
+	}
+	t.Reset()
+
+	return t
+}
+
+func (t *Tool) Name() string {
+	return "evaluate_iam_access"
+}
+
+func (t *Tool) Description() string {
+	return fmt.Sprintf(`This tool answers "who can access what" questions by evaluating IAM policies, for example which roles can write to a DynamoDB table.
+It fetches the identity policies of the principal (inline & customer managed policies, group policies and permissions boundaries) and the resource policy of the resource, and evaluates them in the same way as IAM.
+If %q is omitted, every IAM role in the account is evaluated and only the roles that may be allowed are returned.
+Each result has a decision of Allow, ConditionalAllow (allowed only if the listed conditions hold), ExplicitDeny or ImplicitDeny, along with the policy statements that led to it.
+AWS managed policies can't be retrieved, so they're listed as unevaluated. The response is a JSON object.`,
+		parameterPrincipalARN)
+}
+
+func (t *Tool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterAction,
+			Description: "The IAM action to evaluate, e.g. dynamodb:PutItem. Multiple actions can be given as a comma separated list.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterResourceARN,
+			Description: "The ARN of the resource being accessed, e.g. arn:aws:dynamodb:eu-west-1:123456789012:table/users.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterPrincipalARN,
+			Description: "The ARN of the IAM role or user to evaluate. If omitted, every role in the account is evaluated.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterContext,
+			Description: `A JSON object of condition keys to values for the request, e.g. {"aws:SourceVpc": "vpc-0123"}. Conditions on keys that aren't given are reported rather than evaluated.`,
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t *Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	actionList, _ := parameters[parameterAction].(string)
+	actions := splitList(actionList)
+	if len(actions) == 0 {
+		return "", fmt.Errorf("%s is required", parameterAction)
+	}
+
+	resourceARN, _ := parameters[parameterResourceARN].(string)
+	if !arn.IsARN(resourceARN) {
+		return "", fmt.Errorf("%s must be an ARN", parameterResourceARN)
+	}
+
+	requestContext, err := parseContext(parameters[parameterContext])
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	principals := []arn.CloudControlResource{}
+	scanAll := false
+	if principalARN, _ := parameters[parameterPrincipalARN].(string); principalARN != "" {
+		principal, err := principalFromARN(principalARN)
+		if err != nil {
+			return "", err
+		}
+		principals = append(principals, principal)
+	} else {
+		scanAll = true
+		principals, err = t.listRoles(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	resourcePolicies, unevaluated, err := t.resourcePolicies(ctx, resourceARN)
+	if err != nil {
+		return "", err
+	}
+
+	out := result{
+		ResourceARN:      resourceARN,
+		ResourcePolicies: make([]string, 0, len(resourcePolicies)),
+		Results:          []principalResult{},
+		Unevaluated:      unevaluated,
+	}
+	for _, p := range resourcePolicies {
+		out.ResourcePolicies = append(out.ResourcePolicies, p.Name)
+	}
+
+	for _, p := range principals {
+		principal, err := t.principalPolicies(ctx, p)
+		if err != nil {
+			if !scanAll {
+				return "", err
+			}
+			out.Unevaluated = append(out.Unevaluated, unevaluatedPolicy{Name: p.Identifier, Error: err.Error()})
+			continue
+		}
+		out.PrincipalsEvaluated++
+		out.Unevaluated = append(out.Unevaluated, principal.unevaluated...)
+
+		for _, action := range actions {
+			r := iampolicy.Evaluate(iampolicy.Request{
+				Principal: principal.arn,
+				Action:    action,
+				Resource:  resourceARN,
+				Context:   requestContext,
+			}, iampolicy.PolicySet{
+				Identity: principal.identity,
+				Resource: resourcePolicies,
+				Boundary: principal.boundary,
+			})
+			if scanAll && r.Decision == iampolicy.DecisionImplicitDeny {
+				continue
+			}
+			out.Results = append(out.Results, principalResult{Principal: principal.arn, Action: action, Result: r})
+		}
+	}
+
+	if scanAll {
+		out.Note = "Roles with an ImplicitDeny decision are omitted."
+	}
+
+	outJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling result to JSON: %w", err)
+	}
+
+	return string(outJSON), nil
+}
+
+// Reset discards the cached policies, so the next call sees fresh data.
+func (t *Tool) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.principals = make(map[arn.CloudControlResource]principalPolicies)
+	t.managedPolicies = make(map[string]iampolicy.NamedPolicy)
+}
+
+func (t *Tool) listRoles(ctx context.Context) ([]arn.CloudControlResource, error) {
+	paginator := cloudcontrol.NewListResourcesPaginator(t.cloudcontrolClient, &cloudcontrol.ListResourcesInput{
+		TypeName: aws.String(roleTypeName),
+	})
+
+	roles := []arn.CloudControlResource{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing roles: %w", err)
+		}
+
+		for _, r := range page.ResourceDescriptions {
+			roles = append(roles, arn.CloudControlResource{TypeName: roleTypeName, Identifier: aws.ToString(r.Identifier)})
+		}
+	}
+
+	return roles, nil
+}
+
+func principalFromARN(principalARN string) (arn.CloudControlResource, error) {
+	parsed, err := arn.Parse(principalARN)
+	if err != nil {
+		return arn.CloudControlResource{}, err
+	}
+
+	cc, err := parsed.ToCloudControl()
+	if err != nil || (cc.TypeName != roleTypeName && cc.TypeName != userTypeName) {
+		return arn.CloudControlResource{}, fmt.Errorf("%s is not the ARN of an IAM role or user", principalARN)
+	}
+
+	return cc, nil
+}
+
+// parseContext parses the request context parameter. Values may be strings or lists of strings.
+func parseContext(parameter any) (map[string][]string, error) {
+	contextJSON, _ := parameter.(string)
+	if contextJSON == "" {
+		return nil, nil
+	}
+
+	raw := map[string]iampolicy.StringList{}
+	err := json.Unmarshal([]byte(contextJSON), &raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid JSON object of condition keys: %w", parameterContext, err)
+	}
+
+	out := make(map[string][]string, len(raw))
+	for k, v := range raw {
+		out[k] = v
+	}
+
+	return out, nil
+}
+
+func splitList(s string) []string {
+	out := []string{}
+	for _, elem := range strings.Split(s, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			out = append(out, elem)
+		}
+	}
+	return out
+}
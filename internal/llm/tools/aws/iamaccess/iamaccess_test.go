@@ -0,0 +1,175 @@
+package iamaccess
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/awstest"
+	"github.com/fergalhk/llm-cloud-discovery/internal/iampolicy"
+)
+
+const usersTable = "arn:aws:dynamodb:eu-west-1:111111111111:table/users"
+
+// newTestTool returns a tool backed by a local stand-in for the CloudControl API, serving the
+// fixture resources in testdata/resources.json.
+func newTestTool(t *testing.T) *Tool {
+	t.Helper()
+	return NewTool(awstest.LoadCloudControl(t, "testdata/resources.json").Client(t)).(*Tool)
+}
+
+func call(t *testing.T, tool *Tool, parameters map[string]any) result {
+	t.Helper()
+
+	out, err := tool.Call(context.Background(), parameters)
+	if err != nil {
+		t.Fatalf("error calling tool: %v", err)
+	}
+	var r result
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("error unmarshalling result: %v", err)
+	}
+	return r
+}
+
+func decisions(r result) map[string]iampolicy.Decision {
+	out := map[string]iampolicy.Decision{}
+	for _, pr := range r.Results {
+		out[pr.Principal+" "+pr.Action] = pr.Decision
+	}
+	return out
+}
+
+func TestCallWithPrincipal(t *testing.T) {
+	r := call(t, newTestTool(t), map[string]any{
+		parameterAction:       "dynamodb:PutItem, dynamodb:DeleteTable",
+		parameterResourceARN:  usersTable,
+		parameterPrincipalARN: "arn:aws:iam::111111111111:role/app",
+	})
+
+	want := map[string]iampolicy.Decision{
+		"arn:aws:iam::111111111111:role/app dynamodb:PutItem":     iampolicy.DecisionAllow,
+		"arn:aws:iam::111111111111:role/app dynamodb:DeleteTable": iampolicy.DecisionDeny,
+	}
+	got := decisions(r)
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("got %s for %s, want %s", got[k], k, v)
+		}
+	}
+
+	if len(r.ResourcePolicies) != 1 || r.ResourcePolicies[0] != usersTable+" resource policy" {
+		t.Errorf("got resource policies %v, want the table's policy", r.ResourcePolicies)
+	}
+	if len(r.Unevaluated) != 1 || r.Unevaluated[0].Name != "arn:aws:iam::aws:policy/ReadOnlyAccess" {
+		t.Errorf("got unevaluated policies %v, want the AWS managed policy", r.Unevaluated)
+	}
+}
+
+func TestCallWithGroupPolicies(t *testing.T) {
+	tool := newTestTool(t)
+	parameters := map[string]any{
+		parameterAction:       "dynamodb:GetItem",
+		parameterResourceARN:  usersTable,
+		parameterPrincipalARN: "arn:aws:iam::111111111111:user/alice",
+	}
+
+	r := call(t, tool, parameters)
+	if len(r.Results) != 1 || r.Results[0].Decision != iampolicy.DecisionConditionalAllow {
+		t.Fatalf("got results %+v, want a conditional allow", r.Results)
+	}
+	if keys := r.Results[0].Statements[0].UnresolvedConditionKeys; len(keys) != 1 || keys[0] != "aws:SecureTransport" {
+		t.Errorf("got unresolved keys %v, want [aws:SecureTransport]", keys)
+	}
+
+	parameters[parameterContext] = `{"aws:SecureTransport": "true"}`
+	r = call(t, tool, parameters)
+	if r.Results[0].Decision != iampolicy.DecisionAllow {
+		t.Errorf("got %s with the condition key, want %s", r.Results[0].Decision, iampolicy.DecisionAllow)
+	}
+}
+
+func TestCallScanningRoles(t *testing.T) {
+	r := call(t, newTestTool(t), map[string]any{
+		parameterAction:      "dynamodb:Scan",
+		parameterResourceARN: usersTable,
+	})
+
+	if r.PrincipalsEvaluated != 2 {
+		t.Errorf("got %d principals evaluated, want 2", r.PrincipalsEvaluated)
+	}
+	// app is allowed by its own policy & ci by the table's, while ci's invalid policy is reported
+	want := map[string]iampolicy.Decision{
+		"arn:aws:iam::111111111111:role/app dynamodb:Scan": iampolicy.DecisionAllow,
+		"arn:aws:iam::111111111111:role/ci dynamodb:Scan":  iampolicy.DecisionAllow,
+	}
+	got := decisions(r)
+	if len(got) != len(want) {
+		t.Errorf("got results %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("got %s for %s, want %s", got[k], k, v)
+		}
+	}
+
+	found := false
+	for _, u := range r.Unevaluated {
+		found = found || strings.Contains(u.Name, "inline policy invalid")
+	}
+	if !found {
+		t.Errorf("got unevaluated policies %v, want ci's invalid policy", r.Unevaluated)
+	}
+}
+
+func TestCallOmitsImplicitDenies(t *testing.T) {
+	r := call(t, newTestTool(t), map[string]any{
+		parameterAction:      "dynamodb:PutItem",
+		parameterResourceARN: usersTable,
+	})
+
+	got := decisions(r)
+	if len(got) != 1 || got["arn:aws:iam::111111111111:role/app dynamodb:PutItem"] != iampolicy.DecisionAllow {
+		t.Errorf("got results %v, want only app", got)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]any
+		want       string
+	}{
+		{
+			name:       "no action",
+			parameters: map[string]any{parameterResourceARN: usersTable},
+			want:       "action is required",
+		},
+		{
+			name:       "resource isn't an ARN",
+			parameters: map[string]any{parameterAction: "dynamodb:GetItem", parameterResourceARN: "users"},
+			want:       "resource_arn must be an ARN",
+		},
+		{
+			name:       "principal isn't a role or user",
+			parameters: map[string]any{parameterAction: "dynamodb:GetItem", parameterResourceARN: usersTable, parameterPrincipalARN: usersTable},
+			want:       "is not the ARN of an IAM role or user",
+		},
+		{
+			name:       "invalid context",
+			parameters: map[string]any{parameterAction: "dynamodb:GetItem", parameterResourceARN: usersTable, parameterContext: "{"},
+			want:       "is not a valid JSON object of condition keys",
+		},
+	}
+
+	tool := newTestTool(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tool.Call(context.Background(), tt.parameters)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
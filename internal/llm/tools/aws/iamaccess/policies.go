@@ -0,0 +1,229 @@
+package iamaccess
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol/types"
+	"github.com/fergalhk/llm-cloud-discovery/internal/arn"
+	"github.com/fergalhk/llm-cloud-discovery/internal/iampolicy"
+)
+
+type (
+	// principalPolicies are the identity policies & permissions boundary of a role or user.
+	principalPolicies struct {
+		arn         string
+		identity    []iampolicy.NamedPolicy
+		boundary    []iampolicy.NamedPolicy
+		unevaluated []unevaluatedPolicy
+	}
+
+	unevaluatedPolicy struct {
+		Name  string `json:"name"`
+		Error string `json:"error"`
+	}
+
+	// identityProperties are the policy related properties shared by AWS::IAM::Role, User & Group.
+	identityProperties struct {
+		Arn               string   `json:"Arn"`
+		ManagedPolicyArns []string `json:"ManagedPolicyArns"`
+		Policies          []struct {
+			PolicyName     string `json:"PolicyName"`
+			PolicyDocument any    `json:"PolicyDocument"`
+		} `json:"Policies"`
+		PermissionsBoundary string   `json:"PermissionsBoundary"`
+		Groups              []string `json:"Groups"`
+	}
+
+	// resourcePolicyLocation describes where the resource policy of a type is found.
+	resourcePolicyLocation struct {
+		// typeName is the type holding the policy, if different to the resource's own type.
+		typeName string
+		// path is the property path of the policy document.
+		path []string
+	}
+)
+
+var resourcePolicyLocations = map[string]resourcePolicyLocation{
+	"AWS::DynamoDB::Table": {path: []string{"ResourcePolicy", "PolicyDocument"}},
+	"AWS::ECR::Repository": {path: []string{"RepositoryPolicyText"}},
+	"AWS::KMS::Key":        {path: []string{"KeyPolicy"}},
+	"AWS::S3::Bucket":      {typeName: "AWS::S3::BucketPolicy", path: []string{"PolicyDocument"}},
+	"AWS::SNS::Topic":      {typeName: "AWS::SNS::TopicInlinePolicy", path: []string{"PolicyDocument"}},
+	"AWS::SQS::Queue":      {typeName: "AWS::SQS::QueueInlinePolicy", path: []string{"PolicyDocument"}},
+}
+
+// getProperties fetches a resource & unmarshals its properties into out.
+func (t *Tool) getProperties(ctx context.Context, typeName, identifier string, out any) error {
+	resp, err := t.cloudcontrolClient.GetResource(ctx, &cloudcontrol.GetResourceInput{
+		TypeName:   &typeName,
+		Identifier: &identifier,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting %s %s: %w", typeName, identifier, err)
+	}
+
+	err = json.Unmarshal([]byte(aws.ToString(resp.ResourceDescription.Properties)), out)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling properties of %s %s: %w", typeName, identifier, err)
+	}
+
+	return nil
+}
+
+// principalPolicies fetches the policies attached to a role or user, caching them for the rest of the session.
+func (t *Tool) principalPolicies(ctx context.Context, principal arn.CloudControlResource) (principalPolicies, error) {
+	if cached, ok := t.principals[principal]; ok {
+		return cached, nil
+	}
+
+	props, err := t.identityProperties(ctx, principal.TypeName, principal.Identifier)
+	if err != nil {
+		return principalPolicies{}, err
+	}
+
+	p := principalPolicies{arn: props.Arn}
+	t.addIdentityPolicies(ctx, &p, principal.Identifier, props)
+
+	// users inherit the policies of their groups
+	for _, group := range props.Groups {
+		groupProps, err := t.identityProperties(ctx, "AWS::IAM::Group", group)
+		if err != nil {
+			p.unevaluated = append(p.unevaluated, unevaluatedPolicy{Name: "group/" + group, Error: err.Error()})
+			continue
+		}
+		t.addIdentityPolicies(ctx, &p, "group/"+group, groupProps)
+	}
+
+	if props.PermissionsBoundary != "" {
+		// a boundary that can't be read is treated as no boundary, and reported as unevaluated
+		boundary, err := t.managedPolicy(ctx, props.PermissionsBoundary)
+		if err != nil {
+			p.unevaluated = append(p.unevaluated, unevaluatedPolicy{Name: props.PermissionsBoundary, Error: err.Error()})
+		} else {
+			p.boundary = []iampolicy.NamedPolicy{boundary}
+		}
+	}
+
+	t.principals[principal] = p
+
+	return p, nil
+}
+
+func (t *Tool) identityProperties(ctx context.Context, typeName, identifier string) (identityProperties, error) {
+	var props identityProperties
+	err := t.getProperties(ctx, typeName, identifier, &props)
+	return props, err
+}
+
+func (t *Tool) addIdentityPolicies(ctx context.Context, p *principalPolicies, owner string, props identityProperties) {
+	for _, inline := range props.Policies {
+		name := fmt.Sprintf("%s inline policy %s", owner, inline.PolicyName)
+		policy, err := iampolicy.Parse(inline.PolicyDocument)
+		if err != nil {
+			p.unevaluated = append(p.unevaluated, unevaluatedPolicy{Name: name, Error: err.Error()})
+			continue
+		}
+		p.identity = append(p.identity, iampolicy.NamedPolicy{Name: name, Policy: policy})
+	}
+
+	for _, policyARN := range props.ManagedPolicyArns {
+		policy, err := t.managedPolicy(ctx, policyARN)
+		if err != nil {
+			p.unevaluated = append(p.unevaluated, unevaluatedPolicy{Name: policyARN, Error: err.Error()})
+			continue
+		}
+		p.identity = append(p.identity, policy)
+	}
+}
+
+// managedPolicy fetches a customer managed policy. AWS managed policies aren't CloudControl
+// resources, so they can't be fetched and are reported as unevaluated.
+func (t *Tool) managedPolicy(ctx context.Context, policyARN string) (iampolicy.NamedPolicy, error) {
+	if cached, ok := t.managedPolicies[policyARN]; ok {
+		return cached, nil
+	}
+
+	if strings.HasPrefix(policyARN, "arn:aws:iam::aws:policy/") {
+		return iampolicy.NamedPolicy{}, fmt.Errorf("AWS managed policies can't be retrieved through CloudControl")
+	}
+
+	var properties struct {
+		PolicyDocument any `json:"PolicyDocument"`
+	}
+	err := t.getProperties(ctx, "AWS::IAM::ManagedPolicy", policyARN, &properties)
+	if err != nil {
+		return iampolicy.NamedPolicy{}, err
+	}
+
+	policy, err := iampolicy.Parse(properties.PolicyDocument)
+	if err != nil {
+		return iampolicy.NamedPolicy{}, err
+	}
+
+	named := iampolicy.NamedPolicy{Name: policyARN, Policy: policy}
+	t.managedPolicies[policyARN] = named
+
+	return named, nil
+}
+
+// resourcePolicies fetches the resource policy of the resource, if it has one. Resources of
+// types without a known policy location are assumed to have none.
+func (t *Tool) resourcePolicies(ctx context.Context, resourceARN string) ([]iampolicy.NamedPolicy, []unevaluatedPolicy, error) {
+	parsed, err := arn.Parse(resourceARN)
+	if err != nil {
+		return nil, nil, err
+	}
+	// bucket policies also govern the objects in the bucket
+	if parsed.Service == "s3" {
+		bucket, _, _ := strings.Cut(parsed.ResourceID, "/")
+		parsed.Resource, parsed.ResourceID = bucket, bucket
+	}
+
+	cc, err := parsed.ToCloudControl()
+	if err != nil {
+		return nil, nil, nil
+	}
+
+	location, ok := resourcePolicyLocations[cc.TypeName]
+	if !ok {
+		return nil, nil, nil
+	}
+
+	typeName := cc.TypeName
+	if location.typeName != "" {
+		typeName = location.typeName
+	}
+
+	name := fmt.Sprintf("%s resource policy", resourceARN)
+	properties := map[string]any{}
+	err = t.getProperties(ctx, typeName, cc.Identifier, &properties)
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return nil, nil, nil
+		}
+		return nil, []unevaluatedPolicy{{Name: name, Error: err.Error()}}, nil
+	}
+
+	var document any = properties
+	for _, key := range location.path {
+		m, _ := document.(map[string]any)
+		document = m[key]
+	}
+	if document == nil {
+		return nil, nil, nil
+	}
+
+	policy, err := iampolicy.Parse(document)
+	if err != nil {
+		return nil, []unevaluatedPolicy{{Name: name, Error: err.Error()}}, nil
+	}
+
+	return []iampolicy.NamedPolicy{{Name: name, Policy: policy}}, nil, nil
+}
@@ -0,0 +1,163 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// deniedKeywords start statements that change the database or the connection. SQLite doesn't allow
+// them inside a SELECT, but a WITH clause can be followed by any of INSERT, UPDATE, DELETE or REPLACE.
+var deniedKeywords = map[string]struct{}{
+	"alter":     {},
+	"analyze":   {},
+	"attach":    {},
+	"begin":     {},
+	"commit":    {},
+	"create":    {},
+	"delete":    {},
+	"detach":    {},
+	"drop":      {},
+	"insert":    {},
+	"pragma":    {},
+	"reindex":   {},
+	"release":   {},
+	"replace":   {},
+	"rollback":  {},
+	"savepoint": {},
+	"update":    {},
+	"vacuum":    {},
+}
+
+// token is a keyword or identifier, lower cased, or a single punctuation character. Strings &
+// quoted identifiers are empty tokens, as they can't be keywords.
+type token struct {
+	value string
+	pos   int
+}
+
+// checkReadOnly returns an error explaining why a query is rejected unless it's a single SELECT or
+// WITH statement that doesn't contain a statement that writes. The inventory is also opened read
+// only, so this is to give the model a clear error rather than to stop writes.
+func checkReadOnly(query string) error {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return err
+	}
+
+	statements := [][]token{}
+	current := []token{}
+	for _, t := range append(tokens, token{value: ";"}) {
+		if t.value != ";" {
+			current = append(current, t)
+			continue
+		}
+		if len(current) > 0 {
+			statements = append(statements, current)
+			current = []token{}
+		}
+	}
+
+	switch {
+	case len(statements) == 0:
+		return fmt.Errorf("the query is empty")
+	case len(statements) > 1:
+		return fmt.Errorf("only a single statement can be run at a time, but the query has %d", len(statements))
+	}
+
+	stmt := statements[0]
+	switch first := stmt[0].value; {
+	case first == "select" || first == "with":
+	case first != "" && isWordPart(first[0]):
+		return fmt.Errorf("only SELECT & WITH statements can be run, not %s statements", strings.ToUpper(first))
+	default:
+		return fmt.Errorf("only SELECT & WITH statements can be run, so the query must start with SELECT or WITH")
+	}
+	for i, t := range stmt {
+		if _, ok := deniedKeywords[t.value]; !ok {
+			continue
+		}
+		// a keyword followed by a parenthesis is a function call, e.g. replace(name, '-', '_')
+		if i+1 < len(stmt) && stmt[i+1].value == "(" {
+			continue
+		}
+		return fmt.Errorf("%s is not allowed (position %d), as only read only queries can be run", strings.ToUpper(t.value), t.pos+1)
+	}
+
+	return nil
+}
+
+// tokenize splits a SQLite query into tokens, dropping whitespace & comments.
+func tokenize(query string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 1
+		case strings.HasPrefix(query[i:], "/*"):
+			// comments don't nest in SQLite, and one that isn't closed runs to the end of the query
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end, ok := quotedEnd(query, i, closing)
+			if !ok {
+				return nil, fmt.Errorf("unterminated %s at position %d", quotedName(c), i+1)
+			}
+			tokens = append(tokens, token{pos: i})
+			i = end
+		case isWordPart(c):
+			start := i
+			for i < len(query) && isWordPart(query[i]) {
+				i++
+			}
+			tokens = append(tokens, token{value: strings.ToLower(query[start:i]), pos: start})
+		default:
+			tokens = append(tokens, token{value: string(c), pos: i})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// quotedEnd returns the index after the closing quote of the quoted string or identifier starting
+// at start. A doubled closing quote is an escaped quote, except in brackets.
+func quotedEnd(query string, start int, closing byte) (int, bool) {
+	for i := start + 1; i < len(query); i++ {
+		if query[i] != closing {
+			continue
+		}
+		if closing != ']' && i+1 < len(query) && query[i+1] == closing {
+			i++
+			continue
+		}
+		return i + 1, true
+	}
+	return 0, false
+}
+
+func quotedName(quote byte) string {
+	if quote == '\'' {
+		return "string"
+	}
+	return "quoted identifier"
+}
+
+// isWordPart reports whether c can be part of a keyword, identifier, number or parameter name.
+// Any non-ASCII byte is treated as part of an identifier, as SQLite does.
+func isWordPart(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
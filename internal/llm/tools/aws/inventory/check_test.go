@@ -0,0 +1,55 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		// wantErr is part of the expected error, or empty if the query is accepted.
+		wantErr string
+	}{
+		{name: "select", query: "select identifier from aws_ec2_vpc where cidr_block = '10.0.0.0/16'"},
+		{name: "trailing semicolon", query: "select 1;"},
+		{name: "with", query: "with v as (select * from aws_ec2_vpc) select count(*) from v"},
+		{name: "json functions", query: "select json_extract(properties, '$.Tags[0].Value') from aws_ec2_vpc, json_each(properties, '$.Tags')"},
+		{name: "case expression", query: "select case when is_default then 'default' else 'custom' end from aws_ec2_vpc"},
+		{name: "replace function", query: "select replace(identifier, 'vpc-', '') from aws_ec2_vpc"},
+		{name: "table valued pragma function", query: "select name from pragma_table_info('aws_ec2_vpc')"},
+		{name: "keywords in strings", query: "select 'delete from t; drop table t' from t"},
+		{name: "keywords as quoted identifiers", query: "select \"update\", [delete], `insert` from t"},
+		{name: "keywords in comments", query: "select 1 -- ; drop table t\n/* pragma writable_schema */"},
+		{name: "escaped quotes", query: "select 'it''s; delete'"},
+
+		{name: "empty", query: " -- nothing\n;", wantErr: "the query is empty"},
+		{name: "insert", query: "insert into t values (1)", wantErr: "not INSERT statements"},
+		{name: "pragma", query: "pragma query_only = 0", wantErr: "not PRAGMA statements"},
+		{name: "attach", query: "attach database '/tmp/x.db' as x", wantErr: "not ATTACH statements"},
+		{name: "explain", query: "explain select 1", wantErr: "not EXPLAIN statements"},
+		{name: "starts with a string", query: "'x'", wantErr: "must start with SELECT or WITH"},
+		{name: "multiple statements", query: "select 1; select 2", wantErr: "only a single statement"},
+		{name: "statement after a string", query: "select 'a;b'; attach 'x' as x", wantErr: "only a single statement"},
+		{name: "with then delete", query: "with old as (select identifier from t) delete from t where identifier in old", wantErr: "DELETE is not allowed (position 40)"},
+		{name: "with then insert or replace", query: "with x as (select 1) insert or replace into t select * from x", wantErr: "INSERT is not allowed"},
+		{name: "with then update", query: "WITH x AS (SELECT 1) UPDATE t SET a = 1", wantErr: "UPDATE is not allowed"},
+		{name: "unterminated string", query: "select 'abc", wantErr: "unterminated string at position 8"},
+		{name: "unterminated identifier", query: "select [abc", wantErr: "unterminated quoted identifier at position 8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkReadOnly(tt.query)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("got error %q, want none", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("got no error, want one containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("got error %q, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/inventory"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
+)
+
+const (
+	parameterQuery = "query"
+
+	defaultMaxRows = 100
+	queryTimeout   = 30 * time.Second
+)
+
+type (
+	Opt func(*QueryTool)
+
+	QueryTool struct {
+		store   *inventory.Store
+		format  format.Format
+		maxRows int
+	}
+
+	TablesTool struct {
+		store *inventory.Store
+	}
+)
+
+// WithFormat sets the default format of results. The model can still ask for another format per call.
+func WithFormat(f format.Format) Opt {
+	return func(t *QueryTool) {
+		t.format = f
+	}
+}
+
+// WithMaxRows sets the most rows returned by a single query.
+func WithMaxRows(maxRows int) Opt {
+	return func(t *QueryTool) {
+		t.maxRows = maxRows
+	}
+}
+
+// NewQueryTool returns a tool that queries the inventory with SQL. The store should be opened
+// read only, so that the model's SQL can't change it.
+func NewQueryTool(store *inventory.Store, opts ...Opt) tools.Function {
+	t := &QueryTool{
+		store:   store,
+		format:  format.JSON,
+		maxRows: defaultMaxRows,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func NewTablesTool(store *inventory.Store) tools.Function {
+	return &TablesTool{store: store}
+}
+
+func (t QueryTool) Name() string {
+	return "query_inventory"
+}
+
+func (t QueryTool) Description() string {
+	return fmt.Sprintf(`Runs a read only SQLite query against the local inventory, a copy of AWS resources synced from the Cloud Control API. It's much faster than listing & getting resources one by one, and tables can be joined, but resources are only as fresh as the last sync.
+Each resource type has its own table, e.g. aws_ec2_instance for AWS::EC2::Instance, with the resource's identifier, its properties as JSON in the %[1]s column, and a column for each top level property, e.g. vpc_id for VpcId. Use the list_inventory_tables tool to find the tables & their columns. Nested properties are read with json_extract, e.g. json_extract(%[1]s, '$.Tags[0].Value'), and arrays are expanded with json_each.
+The response is returned as %[2]s by default, with at most %[3]d rows.`, inventory.ColumnProperties, t.format.Describe(), t.maxRows)
+}
+
+func (t QueryTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterQuery,
+			Description: "The SQLite query to run. Only a single SELECT or WITH statement can be run.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		format.ParameterDefinition(t.format),
+	}
+}
+
+func (t QueryTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	query, _ := parameters[parameterQuery].(string)
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+
+	f, err := format.FromParameters(parameters, t.format)
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkReadOnly(query); err != nil {
+		return "", fmt.Errorf("query rejected: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := t.store.DB().QueryContext(ctx, query)
+	if err != nil {
+		return "", fmt.Errorf("error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("error reading columns: %w", err)
+	}
+
+	data := [][]any{}
+	omitted := 0
+	for rows.Next() {
+		if len(data) == t.maxRows {
+			omitted++
+			continue
+		}
+
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", fmt.Errorf("error scanning row: %w", err)
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		data = append(data, values)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error reading rows: %w", err)
+	}
+
+	if len(data) == 0 {
+		return "", fmt.Errorf("no rows returned")
+	}
+
+	out, err := f.Rows(columns, data)
+	if err != nil {
+		return "", err
+	}
+	if omitted > 0 {
+		out = strings.TrimRight(out, "\n") + fmt.Sprintf("\n\nThe result was truncated: %d more rows were omitted. Narrow the query with a WHERE clause or an aggregate to see them.", omitted)
+	}
+
+	return out, nil
+}
+
+func (t TablesTool) Name() string {
+	return "list_inventory_tables"
+}
+
+func (t TablesTool) Description() string {
+	return "Lists the tables of the local inventory, which can be queried with the query_inventory tool. The response is a JSON array with the resource type each table holds, the number of resources, when it was last synced, and its columns."
+}
+
+func (t TablesTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{}
+}
+
+func (t TablesTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	types, err := t.store.Types(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(types) == 0 {
+		return "", fmt.Errorf("the inventory is empty, so the list_aws_resources & get_aws_resource tools must be used instead")
+	}
+
+	dataJSON, err := json.Marshal(types)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling inventory tables to JSON: %w", err)
+	}
+
+	return string(dataJSON), nil
+}
@@ -2,7 +2,6 @@ package list
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"slices"
 
@@ -10,25 +9,44 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
 )
 
 const parameterResourceType = "resource_type"
 
-type Tool struct {
-	cloudcontrolClient *cloudcontrol.Client
-	validResourceTypes []string
+type (
+	Opt func(*Tool)
+
+	Tool struct {
+		cloudcontrolClient *cloudcontrol.Client
+		validResourceTypes []string
+		format             format.Format
+	}
+)
+
+// WithFormat sets the default format of the list. The model can still ask for another format per call.
+func WithFormat(f format.Format) Opt {
+	return func(t *Tool) {
+		t.format = f
+	}
 }
 
-func NewTool(cloudformationClient *cloudformation.Client, cloudcontrolClient *cloudcontrol.Client) (tools.Function, error) {
+func NewTool(cloudformationClient *cloudformation.Client, cloudcontrolClient *cloudcontrol.Client, opts ...Opt) (tools.Function, error) {
 	resourceTypes, err := validResourceTypes(context.Background(), cloudformationClient)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Tool{
+	t := &Tool{
 		cloudcontrolClient: cloudcontrolClient,
 		validResourceTypes: resourceTypes,
-	}, nil
+		format:             format.JSON,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t, nil
 }
 
 func (t *Tool) Name() string {
@@ -36,7 +54,10 @@ func (t *Tool) Name() string {
 }
 
 func (t *Tool) Description() string {
-	return "This tool retrieves a list of identifiers for all resources in AWS of a given type. The list is returned as a JSON array of strings, each of which is the identifier of a single resource."
+	if t.format == format.JSON {
+		return "This tool retrieves a list of identifiers for all resources in AWS of a given type. The list is returned as a JSON array of strings, each of which is the identifier of a single resource."
+	}
+	return fmt.Sprintf("This tool retrieves a list of identifiers for all resources in AWS of a given type. The list is returned as %s with a single identifier column, each row of which is the identifier of a single resource.", t.format.Describe())
 }
 
 func (t *Tool) ParameterDefinitions() []tools.ParameterDefinition {
@@ -48,6 +69,7 @@ func (t *Tool) ParameterDefinitions() []tools.ParameterDefinition {
 			Required:    true,
 			Type:        tools.ParameterTypeString,
 		},
+		format.ParameterDefinition(t.format),
 	}
 }
 
@@ -57,6 +79,11 @@ func (t *Tool) Call(ctx context.Context, parameters map[string]any) (string, err
 		return "", fmt.Errorf("%s is not a valid string", parameterResourceType)
 	}
 
+	f, err := format.FromParameters(parameters, t.format)
+	if err != nil {
+		return "", err
+	}
+
 	if !slices.Contains(t.validResourceTypes, resourceType) {
 		return "", fmt.Errorf("%s is not a valid resource type", resourceType)
 	}
@@ -77,12 +104,7 @@ func (t *Tool) Call(ctx context.Context, parameters map[string]any) (string, err
 		}
 	}
 
-	resourceIdentifiersJSON, err := json.Marshal(resourceIdentifiers)
-	if err != nil {
-		return "", fmt.Errorf("error marshalling resource identifiers to JSON: %w", err)
-	}
-
-	return string(resourceIdentifiersJSON), nil
+	return f.Column("identifier", resourceIdentifiers)
 }
 
 func validResourceTypes(ctx context.Context, cloudformationClient *cloudformation.Client) ([]string, error) {
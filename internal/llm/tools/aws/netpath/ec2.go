@@ -0,0 +1,264 @@
+package netpath
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/fergalhk/llm-cloud-discovery/internal/reachability"
+)
+
+var (
+	// networkInterfaceID & instanceID match resource IDs, which have 8 or 17 hex digits, so
+	// hostnames such as i-web.internal aren't taken for IDs.
+	networkInterfaceID = regexp.MustCompile(`^eni-[0-9a-f]{8,17}$`)
+	instanceID         = regexp.MustCompile(`^i-[0-9a-f]{8,17}$`)
+)
+
+// resolveEndpoint finds the network interface for an ENI ID, instance ID, private IP address or
+// a hostname resolving to one, such as an RDS instance's endpoint.
+func (t *Tool) resolveEndpoint(ctx context.Context, value string) (reachability.Endpoint, error) {
+	input := &ec2.DescribeNetworkInterfacesInput{}
+	addr, addrErr := netip.ParseAddr(value)
+	if addrErr != nil && isHostname(value) {
+		addrs, err := t.lookupHost(ctx, value)
+		if err != nil {
+			return reachability.Endpoint{}, fmt.Errorf("error resolving %s: %w", value, err)
+		}
+		if len(addrs) != 1 {
+			// e.g. a load balancer, which has a network interface in each subnet
+			return reachability.Endpoint{}, fmt.Errorf("%s resolves to %d addresses %v, each of which may be a different network interface, so check them one at a time", value, len(addrs), addrs)
+		}
+		addr, addrErr = addrs[0].Unmap(), nil
+	}
+
+	switch {
+	case networkInterfaceID.MatchString(value):
+		input.NetworkInterfaceIds = []string{value}
+	case instanceID.MatchString(value):
+		input.Filters = []types.Filter{
+			{Name: aws.String("attachment.instance-id"), Values: []string{value}},
+			{Name: aws.String("attachment.device-index"), Values: []string{"0"}},
+		}
+	case addrErr == nil:
+		input.Filters = []types.Filter{{Name: aws.String("addresses.private-ip-address"), Values: []string{addr.String()}}}
+	default:
+		return reachability.Endpoint{}, fmt.Errorf("%q is not a network interface ID, instance ID, private IP address or hostname", value)
+	}
+
+	resp, err := t.ec2Client.DescribeNetworkInterfaces(ctx, input)
+	if err != nil {
+		return reachability.Endpoint{}, fmt.Errorf("error describing network interfaces for %s: %w", value, err)
+	}
+	if len(resp.NetworkInterfaces) == 0 {
+		return reachability.Endpoint{}, fmt.Errorf("no network interface found for %s", value)
+	}
+	if len(resp.NetworkInterfaces) > 1 {
+		return reachability.Endpoint{}, fmt.Errorf("%d network interfaces found for %s, use a network interface ID instead", len(resp.NetworkInterfaces), value)
+	}
+
+	eni := resp.NetworkInterfaces[0]
+	endpoint := reachability.Endpoint{
+		NetworkInterfaceID: aws.ToString(eni.NetworkInterfaceId),
+		Description:        aws.ToString(eni.Description),
+		SubnetID:           aws.ToString(eni.SubnetId),
+		VpcID:              aws.ToString(eni.VpcId),
+	}
+	for _, g := range eni.Groups {
+		endpoint.SecurityGroupIDs = append(endpoint.SecurityGroupIDs, aws.ToString(g.GroupId))
+	}
+
+	// use the address that was asked about, otherwise the interface's primary address
+	endpoint.Address = addr
+	if addrErr != nil {
+		endpoint.Address, err = netip.ParseAddr(aws.ToString(eni.PrivateIpAddress))
+		if err != nil {
+			return reachability.Endpoint{}, fmt.Errorf("network interface %s has no private IP address", endpoint.NetworkInterfaceID)
+		}
+	}
+
+	return endpoint, nil
+}
+
+// isHostname returns true if value looks like a DNS name, rather than a resource ID.
+func isHostname(value string) bool {
+	return strings.Contains(strings.Trim(value, "."), ".") && !strings.ContainsAny(value, ":/ ")
+}
+
+// describeNetwork fetches the security groups of the endpoints, along with the network ACLs &
+// route tables of their subnets & VPCs.
+func (t *Tool) describeNetwork(ctx context.Context, endpoints ...reachability.Endpoint) (reachability.Network, error) {
+	groupIDs, subnetIDs, vpcIDs := []string{}, []string{}, []string{}
+	for _, e := range endpoints {
+		groupIDs = append(groupIDs, e.SecurityGroupIDs...)
+		subnetIDs = append(subnetIDs, e.SubnetID)
+		vpcIDs = append(vpcIDs, e.VpcID)
+	}
+
+	n := reachability.Network{SecurityGroups: map[string]reachability.SecurityGroup{}}
+
+	if len(groupIDs) > 0 {
+		sgs, err := t.ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{GroupIds: groupIDs})
+		if err != nil {
+			return reachability.Network{}, fmt.Errorf("error describing security groups: %w", err)
+		}
+		for _, sg := range sgs.SecurityGroups {
+			n.SecurityGroups[aws.ToString(sg.GroupId)] = convertSecurityGroup(sg)
+		}
+	}
+
+	// the default ACL & main route table of each VPC are needed for subnets without explicit associations
+	vpcFilter := []types.Filter{{Name: aws.String("vpc-id"), Values: vpcIDs}}
+
+	acls, err := t.ec2Client.DescribeNetworkAcls(ctx, &ec2.DescribeNetworkAclsInput{Filters: vpcFilter})
+	if err != nil {
+		return reachability.Network{}, fmt.Errorf("error describing network ACLs: %w", err)
+	}
+	for _, acl := range acls.NetworkAcls {
+		n.NetworkACLs = append(n.NetworkACLs, convertNetworkACL(acl))
+	}
+
+	routeTables, err := t.ec2Client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{Filters: vpcFilter})
+	if err != nil {
+		return reachability.Network{}, fmt.Errorf("error describing route tables: %w", err)
+	}
+	for _, rt := range routeTables.RouteTables {
+		n.RouteTables = append(n.RouteTables, convertRouteTable(rt))
+	}
+
+	return n, nil
+}
+
+func convertSecurityGroup(sg types.SecurityGroup) reachability.SecurityGroup {
+	out := reachability.SecurityGroup{
+		ID:    aws.ToString(sg.GroupId),
+		VpcID: aws.ToString(sg.VpcId),
+	}
+	for _, p := range sg.IpPermissions {
+		out.Ingress = append(out.Ingress, convertPermission(p))
+	}
+	for _, p := range sg.IpPermissionsEgress {
+		out.Egress = append(out.Egress, convertPermission(p))
+	}
+	return out
+}
+
+func convertPermission(p types.IpPermission) reachability.SecurityGroupRule {
+	rule := reachability.SecurityGroupRule{
+		Protocol: reachability.ParseProtocol(aws.ToString(p.IpProtocol)),
+		FromPort: aws.ToInt32(p.FromPort),
+		ToPort:   aws.ToInt32(p.ToPort),
+	}
+
+	descriptions := []string{}
+	for _, r := range p.IpRanges {
+		if prefix, err := netip.ParsePrefix(aws.ToString(r.CidrIp)); err == nil {
+			rule.CIDRs = append(rule.CIDRs, prefix)
+		}
+		if d := aws.ToString(r.Description); d != "" {
+			descriptions = append(descriptions, d)
+		}
+	}
+	for _, r := range p.Ipv6Ranges {
+		if prefix, err := netip.ParsePrefix(aws.ToString(r.CidrIpv6)); err == nil {
+			rule.CIDRs = append(rule.CIDRs, prefix)
+		}
+	}
+	for _, g := range p.UserIdGroupPairs {
+		rule.SecurityGroupIDs = append(rule.SecurityGroupIDs, aws.ToString(g.GroupId))
+		if d := aws.ToString(g.Description); d != "" {
+			descriptions = append(descriptions, d)
+		}
+	}
+	for _, pl := range p.PrefixListIds {
+		rule.PrefixListIDs = append(rule.PrefixListIDs, aws.ToString(pl.PrefixListId))
+	}
+	rule.Description = strings.Join(descriptions, "; ")
+
+	return rule
+}
+
+func convertNetworkACL(acl types.NetworkAcl) reachability.NetworkACL {
+	out := reachability.NetworkACL{
+		ID:        aws.ToString(acl.NetworkAclId),
+		VpcID:     aws.ToString(acl.VpcId),
+		IsDefault: aws.ToBool(acl.IsDefault),
+	}
+	for _, a := range acl.Associations {
+		out.SubnetIDs = append(out.SubnetIDs, aws.ToString(a.SubnetId))
+	}
+	for _, e := range acl.Entries {
+		cidr := aws.ToString(e.CidrBlock)
+		if cidr == "" {
+			cidr = aws.ToString(e.Ipv6CidrBlock)
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+
+		rule := reachability.NetworkACLRule{
+			RuleNumber: aws.ToInt32(e.RuleNumber),
+			Egress:     aws.ToBool(e.Egress),
+			Protocol:   reachability.ParseProtocol(aws.ToString(e.Protocol)),
+			Allow:      e.RuleAction == types.RuleActionAllow,
+			CIDR:       prefix,
+		}
+		if e.PortRange != nil {
+			rule.FromPort = aws.ToInt32(e.PortRange.From)
+			rule.ToPort = aws.ToInt32(e.PortRange.To)
+		}
+		out.Entries = append(out.Entries, rule)
+	}
+	return out
+}
+
+func convertRouteTable(rt types.RouteTable) reachability.RouteTable {
+	out := reachability.RouteTable{
+		ID:    aws.ToString(rt.RouteTableId),
+		VpcID: aws.ToString(rt.VpcId),
+	}
+	for _, a := range rt.Associations {
+		if aws.ToBool(a.Main) {
+			out.IsMain = true
+		}
+		if a.SubnetId != nil {
+			out.SubnetIDs = append(out.SubnetIDs, aws.ToString(a.SubnetId))
+		}
+	}
+	for _, r := range rt.Routes {
+		cidr := aws.ToString(r.DestinationCidrBlock)
+		if cidr == "" {
+			cidr = aws.ToString(r.DestinationIpv6CidrBlock)
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			// prefix list destinations aren't evaluated
+			continue
+		}
+		out.Routes = append(out.Routes, reachability.Route{
+			Destination: prefix,
+			Target:      routeTarget(r),
+			Blackhole:   r.State == types.RouteStateBlackhole,
+		})
+	}
+	return out
+}
+
+func routeTarget(r types.Route) string {
+	for _, target := range []*string{
+		r.GatewayId, r.NatGatewayId, r.TransitGatewayId, r.VpcPeeringConnectionId,
+		r.NetworkInterfaceId, r.InstanceId, r.LocalGatewayId, r.CarrierGatewayId,
+		r.EgressOnlyInternetGatewayId, r.CoreNetworkArn,
+	} {
+		if target != nil && *target != "" {
+			return *target
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,99 @@
+package netpath
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/fergalhk/llm-cloud-discovery/internal/awstest"
+)
+
+// testHosts are the names the test tool resolves.
+var testHosts = map[string][]netip.Addr{
+	"db.abc123.eu-west-1.rds.amazonaws.com":      {netip.MustParseAddr("10.0.2.10")},
+	"internal-web.eu-west-1.elb.amazonaws.com":   {netip.MustParseAddr("10.0.1.20"), netip.MustParseAddr("10.0.3.20")},
+	"mapped.abc123.eu-west-1.rds.amazonaws.com.": {netip.MustParseAddr("::ffff:10.0.2.10")},
+	"i-db.abc123.eu-west-1.rds.amazonaws.com":    {netip.MustParseAddr("10.0.2.10")},
+}
+
+// newTestTool returns a tool backed by a local stand-in for the EC2 API, with a single network
+// interface, eni-db, at 10.0.2.10.
+func newTestTool(t *testing.T) *Tool {
+	t.Helper()
+
+	handler := awstest.QueryHandler(map[string]awstest.QueryOperation{
+		"DescribeNetworkInterfaces": func(form url.Values) (string, error) {
+			items := ""
+			if form.Get("Filter.1.Name") == "addresses.private-ip-address" && form.Get("Filter.1.Value.1") == "10.0.2.10" {
+				items = `<item><networkInterfaceId>eni-db</networkInterfaceId><description>RDSNetworkInterface</description>` +
+					`<subnetId>subnet-db</subnetId><vpcId>vpc-a</vpcId><privateIpAddress>10.0.2.10</privateIpAddress>` +
+					`<groupSet><item><groupId>sg-db</groupId></item></groupSet></item>`
+			}
+			return fmt.Sprintf(`<DescribeNetworkInterfacesResponse><networkInterfaceSet>%s</networkInterfaceSet></DescribeNetworkInterfacesResponse>`, items), nil
+		},
+	})
+
+	client := ec2.NewFromConfig(awstest.Config(t, handler))
+	tool := NewTool(client).(*Tool)
+	tool.lookupHost = func(_ context.Context, host string) ([]netip.Addr, error) {
+		addrs, ok := testHosts[host]
+		if !ok {
+			return nil, fmt.Errorf("no such host")
+		}
+		return addrs, nil
+	}
+	return tool
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "private IP address", value: "10.0.2.10"},
+		{name: "RDS endpoint", value: "db.abc123.eu-west-1.rds.amazonaws.com"},
+		{name: "IPv4-mapped address", value: "mapped.abc123.eu-west-1.rds.amazonaws.com."},
+		{name: "hostname like an instance ID", value: "i-db.abc123.eu-west-1.rds.amazonaws.com"},
+	}
+
+	tool := newTestTool(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tool.resolveEndpoint(context.Background(), tt.value)
+			if err != nil {
+				t.Fatalf("error resolving %s: %v", tt.value, err)
+			}
+			if got.NetworkInterfaceID != "eni-db" || got.Address != netip.MustParseAddr("10.0.2.10") || got.SubnetID != "subnet-db" ||
+				got.VpcID != "vpc-a" || len(got.SecurityGroupIDs) != 1 || got.SecurityGroupIDs[0] != "sg-db" {
+				t.Errorf("got %+v, want eni-db", got)
+			}
+		})
+	}
+}
+
+func TestResolveEndpointErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "several addresses", value: "internal-web.eu-west-1.elb.amazonaws.com", want: "resolves to 2 addresses"},
+		{name: "unknown host", value: "missing.example.com", want: "error resolving missing.example.com: no such host"},
+		{name: "no network interface", value: "10.0.9.9", want: "no network interface found for 10.0.9.9"},
+		{name: "not an endpoint", value: "arn:aws:ecs:eu-west-1:111111111111:task/web/abc", want: "is not a network interface ID"},
+	}
+
+	tool := newTestTool(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tool.resolveEndpoint(context.Background(), tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
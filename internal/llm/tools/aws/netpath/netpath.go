@@ -0,0 +1,151 @@
+package netpath
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/reachability"
+)
+
+const (
+	parameterSource      = "source"
+	parameterDestination = "destination"
+	parameterPort        = "port"
+	parameterProtocol    = "protocol"
+
+	defaultProtocol = "tcp"
+)
+
+type (
+	Tool struct {
+		ec2Client *ec2.Client
+		// lookupHost resolves hostnames given as a source or destination.
+		lookupHost func(ctx context.Context, host string) ([]netip.Addr, error)
+	}
+
+	result struct {
+		Source      reachability.Endpoint `json:"source"`
+		Destination reachability.Endpoint `json:"destination"`
+		Protocol    reachability.Protocol `json:"protocol"`
+		Port        int32                 `json:"port,omitempty"`
+		reachability.Result
+	}
+)
+
+func NewTool(ec2Client *ec2.Client) tools.Function {
+	return &Tool{
+		ec2Client:  ec2Client,
+		lookupHost: lookupHost,
+	}
+}
+
+// lookupHost resolves IPv4 addresses only, as a dual stack endpoint would otherwise be ambiguous.
+func lookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip4", host)
+}
+
+func (t *Tool) Name() string {
+	return "check_network_reachability"
+}
+
+func (t *Tool) Description() string {
+	return `This tool checks whether network traffic can flow from a source to a destination inside AWS, for example whether an ECS task can reach an RDS instance.
+It evaluates the security groups, network ACLs and route tables on the path, and explains each hop along with the rule that allowed or blocked the traffic.
+The source and destination must be network interface IDs (eni-...), EC2 instance IDs (i-...), private IP addresses, or hostnames that resolve to a single private IP address, such as an RDS instance's endpoint address.
+Load balancers resolve to an address in each subnet, so pass one of their addresses. ECS tasks using awsvpc networking must be given by their private IP address or network interface ID, found in the task's attachment details.
+The response is a JSON object.`
+}
+
+func (t *Tool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterSource,
+			Description: "The network interface ID, instance ID, private IP address or hostname the traffic comes from.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterDestination,
+			Description: "The network interface ID, instance ID, private IP address or hostname the traffic goes to.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterPort,
+			Description: "The destination port, e.g. 5432 for PostgreSQL. Required for tcp and udp.",
+			Type:        tools.ParameterTypeInteger,
+		},
+		{
+			Name:        parameterProtocol,
+			Description: "The protocol of the traffic. Defaults to tcp.",
+			Type:        tools.ParameterTypeString,
+			Enum:        []any{"tcp", "udp", "icmp"},
+		},
+	}
+}
+
+func (t *Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	source, _ := parameters[parameterSource].(string)
+	if source == "" {
+		return "", fmt.Errorf("%s is required", parameterSource)
+	}
+
+	destination, _ := parameters[parameterDestination].(string)
+	if destination == "" {
+		return "", fmt.Errorf("%s is required", parameterDestination)
+	}
+
+	protocolName, _ := parameters[parameterProtocol].(string)
+	if protocolName == "" {
+		protocolName = defaultProtocol
+	}
+	protocol := reachability.ParseProtocol(protocolName)
+
+	port, err := tools.IntParameter(parameters, parameterPort, 0)
+	if err != nil {
+		return "", err
+	}
+	if (protocol == reachability.ProtocolTCP || protocol == reachability.ProtocolUDP) && (port < 1 || port > 65535) {
+		return "", fmt.Errorf("%s must be between 1 and 65535 for %s", parameterPort, protocol)
+	}
+
+	src, err := t.resolveEndpoint(ctx, source)
+	if err != nil {
+		return "", err
+	}
+
+	dst, err := t.resolveEndpoint(ctx, destination)
+	if err != nil {
+		return "", err
+	}
+
+	network, err := t.describeNetwork(ctx, src, dst)
+	if err != nil {
+		return "", err
+	}
+
+	out := result{
+		Source:      src,
+		Destination: dst,
+		Protocol:    protocol,
+		Port:        int32(port),
+		Result: reachability.Analyze(reachability.Query{
+			Source:      src,
+			Destination: dst,
+			Protocol:    protocol,
+			Port:        int32(port),
+		}, network),
+	}
+
+	outJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling result to JSON: %w", err)
+	}
+
+	return string(outJSON), nil
+}
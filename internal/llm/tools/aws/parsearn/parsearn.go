@@ -0,0 +1,150 @@
+package parsearn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/arn"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+const (
+	parameterValue              = "value"
+	parameterResourceType       = "resource_type"
+	parameterResourceIdentifier = "resource_identifier"
+	parameterRegion             = "region"
+	parameterAccountID          = "account_id"
+)
+
+type (
+	Tool struct{}
+
+	result struct {
+		ARN                string   `json:"arn,omitempty"`
+		Parsed             *arn.ARN `json:"parsed,omitempty"`
+		ResourceType       string   `json:"cloudformation_resource_type,omitempty"`
+		ResourceIdentifier string   `json:"cloudcontrol_resource_identifier,omitempty"`
+		Note               string   `json:"note,omitempty"`
+	}
+)
+
+func (t Tool) Name() string {
+	return "parse_arn"
+}
+
+func (t Tool) Description() string {
+	return fmt.Sprintf(`This tool converts between AWS ARNs and the resource types & identifiers used by the list_aws_resources and get_aws_resource tools.
+To parse an ARN, or to find the type of a resource ID such as sg-0123456789abcdef0, pass it as %q. The response includes the partition, service, region, account ID and resource, along with the resource type & identifier to pass to get_aws_resource.
+To build an ARN, pass %q and %q instead, along with %q and %q if the ARN needs them.
+The response is a JSON object.`,
+		parameterValue, parameterResourceType, parameterResourceIdentifier, parameterRegion, parameterAccountID)
+}
+
+func (t Tool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterValue,
+			Description: "The ARN or resource ID to parse, e.g. arn:aws:dynamodb:eu-west-1:123456789012:table/users.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterResourceType,
+			Description: "The type of the resource to build an ARN for, in the format AWS::Service::ResourceType, e.g. AWS::DynamoDB::Table.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterResourceIdentifier,
+			Description: "The identifier of the resource to build an ARN for, as returned by the list_aws_resources tool.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterRegion,
+			Description: "The region of the resource to build an ARN for, e.g. eu-west-1.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterAccountID,
+			Description: "The 12 digit account ID of the resource to build an ARN for.",
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	value, _ := parameters[parameterValue].(string)
+	resourceType, _ := parameters[parameterResourceType].(string)
+	resourceIdentifier, _ := parameters[parameterResourceIdentifier].(string)
+
+	var (
+		r   result
+		err error
+	)
+	switch {
+	case value != "":
+		r, err = parse(value)
+	case resourceType != "" && resourceIdentifier != "":
+		region, _ := parameters[parameterRegion].(string)
+		accountID, _ := parameters[parameterAccountID].(string)
+		r, err = build(resourceType, resourceIdentifier, region, accountID)
+	default:
+		err = fmt.Errorf("either %s, or both %s and %s, must be provided", parameterValue, parameterResourceType, parameterResourceIdentifier)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	resultJSON, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling result to JSON: %w", err)
+	}
+
+	return string(resultJSON), nil
+}
+
+func parse(value string) (result, error) {
+	if !arn.IsARN(value) {
+		resourceType, ok := arn.GuessTypeFromID(value)
+		if !ok {
+			return result{}, fmt.Errorf("%q is neither an ARN nor a recognised resource ID", value)
+		}
+		return result{
+			ResourceType:       resourceType,
+			ResourceIdentifier: value,
+			Note:               "This is a resource ID rather than an ARN. It can be passed to get_aws_resource as-is.",
+		}, nil
+	}
+
+	parsed, err := arn.Parse(value)
+	if err != nil {
+		return result{}, err
+	}
+
+	r := result{ARN: parsed.String(), Parsed: &parsed}
+	cc, err := parsed.ToCloudControl()
+	if err != nil {
+		r.Note = err.Error()
+	} else {
+		r.ResourceType = cc.TypeName
+		r.ResourceIdentifier = cc.Identifier
+	}
+
+	return r, nil
+}
+
+func build(resourceType, resourceIdentifier, region, accountID string) (result, error) {
+	built, err := arn.FromCloudControl(arn.DefaultPartition, region, accountID, arn.CloudControlResource{
+		TypeName:   resourceType,
+		Identifier: resourceIdentifier,
+	})
+	if err != nil {
+		return result{}, err
+	}
+
+	return result{
+		ARN:                built.String(),
+		Parsed:             &built,
+		ResourceType:       resourceType,
+		ResourceIdentifier: resourceIdentifier,
+	}, nil
+}
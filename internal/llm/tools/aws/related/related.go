@@ -0,0 +1,341 @@
+package related
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/fergalhk/llm-cloud-discovery/internal/arn"
+	"github.com/fergalhk/llm-cloud-discovery/internal/graph"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	parameterResourceType       = "resource_type"
+	parameterResourceIdentifier = "resource_identifier"
+	parameterHops               = "hops"
+	parameterResourceTypes      = "resource_types"
+
+	defaultHops = 1
+	maxHops     = 3
+	// fetchConcurrency is the number of resources fetched at once while crawling.
+	fetchConcurrency = 5
+)
+
+// DefaultResourceTypes are crawled into the graph when the model doesn't ask for specific types.
+// They cover the resources most often involved in "what talks to what" questions.
+var DefaultResourceTypes = []string{
+	"AWS::CloudFront::Distribution",
+	"AWS::DynamoDB::Table",
+	"AWS::EC2::Instance",
+	"AWS::EC2::SecurityGroup",
+	"AWS::EC2::Subnet",
+	"AWS::EC2::VPC",
+	"AWS::ECS::Cluster",
+	"AWS::ECS::Service",
+	"AWS::ECS::TaskDefinition",
+	"AWS::ElasticLoadBalancingV2::LoadBalancer",
+	"AWS::ElasticLoadBalancingV2::TargetGroup",
+	"AWS::IAM::Role",
+	"AWS::KMS::Key",
+	"AWS::Lambda::Function",
+	"AWS::RDS::DBInstance",
+	"AWS::S3::Bucket",
+	"AWS::SNS::Topic",
+	"AWS::SQS::Queue",
+}
+
+// listModels are the types that can only be listed within another resource, e.g. ECS services are
+// listed per cluster.
+var listModels = map[string]listModel{
+	"AWS::ECS::Service": {parentType: "AWS::ECS::Cluster", property: "Cluster"},
+}
+
+type (
+	Tool struct {
+		cloudcontrolClient *cloudcontrol.Client
+		defaultTypes       []string
+
+		// mu guards the cached graph, which lives until the conversation is reset.
+		mu           sync.Mutex
+		graph        *graph.Graph
+		crawledTypes map[string]struct{}
+		skippedTypes map[string]string
+	}
+
+	result struct {
+		Resource     graph.NodeID      `json:"resource"`
+		Related      []graph.Related   `json:"related"`
+		CrawledTypes []string          `json:"crawled_types"`
+		SkippedTypes map[string]string `json:"skipped_types,omitempty"`
+	}
+
+	// listModel is the resource a type is listed within, & the property of the resource model
+	// it's given in.
+	listModel struct {
+		parentType string
+		property   string
+	}
+
+	listed struct {
+		identifier string
+		properties string
+	}
+)
+
+// NewTool creates the tool. If no resource types are given, DefaultResourceTypes is used.
+func NewTool(cloudcontrolClient *cloudcontrol.Client, resourceTypes ...string) tools.Function {
+	if len(resourceTypes) == 0 {
+		resourceTypes = DefaultResourceTypes
+	}
+
+	t := &Tool{
+		cloudcontrolClient: cloudcontrolClient,
+		defaultTypes:       resourceTypes,
+	}
+	t.Reset()
+
+	return t
+}
+
+func (t *Tool) Name() string {
+	return "find_related_resources"
+}
+
+func (t *Tool) Description() string {
+	return fmt.Sprintf(`This tool finds the AWS resources related to a given resource, by looking for references to each resource (ARNs, IDs and names) in the properties of the others.
+For example, it finds the ECS task definitions whose environment variables or IAM role policies refer to a DynamoDB table, and the services using those task definitions.
+Increase %q to follow references further, e.g. 2 hops goes from a table to a role that can access it and on to the task definitions using that role.
+The response is a JSON object. Each related resource includes the chain of references ("via") that connects it to the original resource, with the property path of each reference.`,
+		parameterHops)
+}
+
+func (t *Tool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterResourceType,
+			Description: "The type of the resource to start from. This is in the format of AWS::Service::ResourceType, for example AWS::DynamoDB::Table.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterResourceIdentifier,
+			Description: "The identifier of the resource to start from, as returned by the list_aws_resources tool.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterHops,
+			Description: fmt.Sprintf("The number of references to follow from the resource, between 1 and %d. Defaults to %d.", maxHops, defaultHops),
+			Type:        tools.ParameterTypeInteger,
+		},
+		{
+			Name:        parameterResourceTypes,
+			Description: fmt.Sprintf("A comma separated list of additional resource types to search for relationships, e.g. AWS::Events::Rule. A broad set of common types is always searched: %s.", strings.Join(t.defaultTypes, ", ")),
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t *Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	resourceType, _ := parameters[parameterResourceType].(string)
+	if resourceType == "" {
+		return "", fmt.Errorf("%s is required", parameterResourceType)
+	}
+
+	resourceIdentifier, _ := parameters[parameterResourceIdentifier].(string)
+	if resourceIdentifier == "" {
+		return "", fmt.Errorf("%s is required", parameterResourceIdentifier)
+	}
+	resourceIdentifier = arn.NormalizeIdentifier(resourceType, resourceIdentifier)
+
+	hops, err := tools.IntParameter(parameters, parameterHops, defaultHops)
+	if err != nil {
+		return "", err
+	}
+	if hops < 1 || hops > maxHops {
+		return "", fmt.Errorf("%s must be between 1 and %d", parameterHops, maxHops)
+	}
+
+	resourceTypes := append([]string{resourceType}, t.defaultTypes...)
+	if extra, _ := parameters[parameterResourceTypes].(string); extra != "" {
+		for _, rt := range strings.Split(extra, ",") {
+			if rt = strings.TrimSpace(rt); rt != "" {
+				resourceTypes = append(resourceTypes, rt)
+			}
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start := graph.NodeID{Type: resourceType, Identifier: resourceIdentifier}
+	if !t.graph.Has(start) {
+		// list results can hold partial properties, so always fetch the starting resource in full
+		resp, err := t.cloudcontrolClient.GetResource(ctx, &cloudcontrol.GetResourceInput{
+			TypeName:   &resourceType,
+			Identifier: &resourceIdentifier,
+		})
+		if err != nil {
+			return "", fmt.Errorf("error getting resource: %w", err)
+		}
+		err = t.graph.AddResource(resourceType, resourceIdentifier, aws.ToString(resp.ResourceDescription.Properties))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, rt := range resourceTypes {
+		err := t.crawl(ctx, rt)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	related, err := t.graph.Related(start, hops)
+	if err != nil {
+		return "", err
+	}
+
+	out := result{
+		Resource:     start,
+		Related:      related,
+		CrawledTypes: make([]string, 0, len(t.crawledTypes)),
+		SkippedTypes: t.skippedTypes,
+	}
+	for rt := range t.crawledTypes {
+		out.CrawledTypes = append(out.CrawledTypes, rt)
+	}
+	sort.Strings(out.CrawledTypes)
+
+	outJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling related resources to JSON: %w", err)
+	}
+
+	return string(outJSON), nil
+}
+
+// Reset discards the cached graph, so the next call sees fresh data.
+func (t *Tool) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.graph = graph.New()
+	t.crawledTypes = make(map[string]struct{})
+	t.skippedTypes = make(map[string]string)
+}
+
+// crawl adds every resource of the given type to the graph, if it hasn't already been crawled.
+// List results often hold only some properties, or none, so each resource is fetched in full. Some
+// types can't be listed at all, so list failures are recorded & reported to the model rather than
+// failing the whole call.
+func (t *Tool) crawl(ctx context.Context, resourceType string) error {
+	if _, ok := t.crawledTypes[resourceType]; ok {
+		return nil
+	}
+	if _, ok := t.skippedTypes[resourceType]; ok {
+		return nil
+	}
+
+	models, err := t.resourceModels(ctx, resourceType)
+	if err != nil {
+		return err
+	}
+
+	resources := []listed{}
+	for _, model := range models {
+		paginator := cloudcontrol.NewListResourcesPaginator(t.cloudcontrolClient, &cloudcontrol.ListResourcesInput{
+			TypeName:      &resourceType,
+			ResourceModel: model,
+		})
+
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				t.skippedTypes[resourceType] = err.Error()
+				return nil
+			}
+
+			for _, r := range page.ResourceDescriptions {
+				id := graph.NodeID{Type: resourceType, Identifier: aws.ToString(r.Identifier)}
+				if !t.graph.Has(id) {
+					resources = append(resources, listed{identifier: id.Identifier, properties: aws.ToString(r.Properties)})
+				}
+			}
+		}
+	}
+
+	t.fetch(ctx, resourceType, resources)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for _, r := range resources {
+		err := t.graph.AddResource(resourceType, r.identifier, r.properties)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.crawledTypes[resourceType] = struct{}{}
+
+	return nil
+}
+
+// resourceModels returns the resource models to list a type with. Most types are listed with none,
+// but types in listModels are listed once for each of their parent resources, which are crawled
+// first.
+func (t *Tool) resourceModels(ctx context.Context, resourceType string) ([]*string, error) {
+	lm, ok := listModels[resourceType]
+	if !ok {
+		return []*string{nil}, nil
+	}
+
+	err := t.crawl(ctx, lm.parentType)
+	if err != nil {
+		return nil, err
+	}
+	if reason, ok := t.skippedTypes[lm.parentType]; ok {
+		t.skippedTypes[resourceType] = fmt.Sprintf("%s resources couldn't be listed: %s", lm.parentType, reason)
+		return nil, nil
+	}
+
+	models := []*string{}
+	for _, parent := range t.graph.Identifiers(lm.parentType) {
+		model, err := json.Marshal(map[string]string{lm.property: parent})
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling resource model: %w", err)
+		}
+		models = append(models, aws.String(string(model)))
+	}
+	return models, nil
+}
+
+// fetch replaces the listed properties of resources with their full properties. If a resource
+// can't be fetched, its listed properties are kept, as its identifier can still be referenced.
+func (t *Tool) fetch(ctx context.Context, resourceType string, resources []listed) {
+	var g errgroup.Group
+	g.SetLimit(fetchConcurrency)
+	for i := range resources {
+		g.Go(func() error {
+			resp, err := t.cloudcontrolClient.GetResource(ctx, &cloudcontrol.GetResourceInput{
+				TypeName:   &resourceType,
+				Identifier: &resources[i].identifier,
+			})
+			if err == nil && resp.ResourceDescription != nil && resp.ResourceDescription.Properties != nil {
+				resources[i].properties = *resp.ResourceDescription.Properties
+			}
+			return nil
+		})
+	}
+	g.Wait()
+}
@@ -0,0 +1,86 @@
+package related
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/awstest"
+	"github.com/fergalhk/llm-cloud-discovery/internal/graph"
+)
+
+const (
+	testTaskDefinition = "arn:aws:ecs:eu-west-1:111111111111:task-definition/web:3"
+	testService        = "arn:aws:ecs:eu-west-1:111111111111:service/prod/web|prod"
+)
+
+// newTestTool returns a tool crawling a local stand-in for the CloudControl API. The task
+// definition only lists its ARN, so the reference to the table is only seen once it's fetched,
+// & ECS services can only be listed per cluster.
+func newTestTool(t *testing.T) *Tool {
+	t.Helper()
+
+	cc := awstest.NewCloudControl()
+	cc.RequireListModel("AWS::ECS::Service", "Cluster")
+	cc.Set("AWS::DynamoDB::Table", "users", awstest.Resource{Properties: `{"TableName": "users"}`})
+	cc.Set("AWS::ECS::Cluster", "prod", awstest.Resource{Properties: `{"ClusterName": "prod"}`})
+	cc.Set("AWS::ECS::TaskDefinition", testTaskDefinition, awstest.Resource{
+		Properties:     `{"TaskDefinitionArn": "` + testTaskDefinition + `", "ContainerDefinitions": [{"Environment": [{"Name": "TABLE", "Value": "users"}]}]}`,
+		ListProperties: `{"TaskDefinitionArn": "` + testTaskDefinition + `"}`,
+	})
+	cc.Set("AWS::ECS::Service", testService, awstest.Resource{
+		Properties: `{"Cluster": "prod", "ServiceName": "web", "TaskDefinition": "` + testTaskDefinition + `"}`,
+	})
+	// services of clusters that aren't listed can't be found
+	cc.Set("AWS::ECS::Service", "arn:aws:ecs:eu-west-1:111111111111:service/staging/web|staging", awstest.Resource{
+		Properties: `{"Cluster": "staging", "ServiceName": "web", "TaskDefinition": "` + testTaskDefinition + `"}`,
+	})
+
+	return NewTool(cc.Client(t), "AWS::ECS::Cluster", "AWS::ECS::Service", "AWS::ECS::TaskDefinition").(*Tool)
+}
+
+func TestCall(t *testing.T) {
+	out, err := newTestTool(t).Call(context.Background(), map[string]any{
+		parameterResourceType:       "AWS::DynamoDB::Table",
+		parameterResourceIdentifier: "arn:aws:dynamodb:eu-west-1:111111111111:table/users",
+		parameterHops:               float64(2),
+	})
+	if err != nil {
+		t.Fatalf("error calling tool: %v", err)
+	}
+	var r result
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("error unmarshalling result: %v", err)
+	}
+
+	want := []graph.NodeID{
+		{Type: "AWS::ECS::TaskDefinition", Identifier: testTaskDefinition},
+		{Type: "AWS::ECS::Service", Identifier: testService},
+	}
+	if len(r.Related) != len(want) {
+		t.Fatalf("got related resources %+v, want %v", r.Related, want)
+	}
+	for i, id := range want {
+		if r.Related[i].NodeID != id {
+			t.Errorf("got related resource %s, want %s", r.Related[i].NodeID, id)
+		}
+	}
+	if len(r.SkippedTypes) != 0 {
+		t.Errorf("got skipped types %v, want none", r.SkippedTypes)
+	}
+	if len(r.CrawledTypes) != 4 {
+		t.Errorf("got crawled types %v, want all 4", r.CrawledTypes)
+	}
+}
+
+func TestCrawlSkipsTypesOfSkippedParents(t *testing.T) {
+	tool := NewTool(awstest.NewCloudControl().Client(t)).(*Tool)
+	tool.skippedTypes["AWS::ECS::Cluster"] = "access denied"
+
+	if err := tool.crawl(context.Background(), "AWS::ECS::Service"); err != nil {
+		t.Fatalf("error crawling: %v", err)
+	}
+	if reason := tool.skippedTypes["AWS::ECS::Service"]; reason != "AWS::ECS::Cluster resources couldn't be listed: access denied" {
+		t.Errorf("got skip reason %q", reason)
+	}
+}
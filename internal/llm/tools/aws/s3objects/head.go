@@ -0,0 +1,223 @@
+package s3objects
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+const (
+	parameterVersionID          = "version_id"
+	parameterIncludeBodyPreview = "include_body_preview"
+
+	// storageClassStandard is reported when S3 omits the storage class, which it does for STANDARD objects.
+	storageClassStandard = "STANDARD"
+)
+
+type (
+	HeadOpt func(*HeadTool)
+
+	HeadTool struct {
+		s3Client *s3.Client
+		// bodyPreviewBytes is the number of bytes of the object body the model may request.
+		// Zero disables body previews entirely.
+		bodyPreviewBytes int64
+	}
+
+	headResult struct {
+		Bucket        string            `json:"bucket"`
+		Key           string            `json:"key"`
+		VersionID     string            `json:"version_id,omitempty"`
+		Size          int64             `json:"size"`
+		ContentType   string            `json:"content_type,omitempty"`
+		ETag          string            `json:"etag,omitempty"`
+		LastModified  *time.Time        `json:"last_modified,omitempty"`
+		StorageClass  string            `json:"storage_class"`
+		Encryption    encryptionResult  `json:"encryption"`
+		Metadata      map[string]string `json:"metadata,omitempty"`
+		Tags          map[string]string `json:"tags,omitempty"`
+		TagsError     string            `json:"tags_error,omitempty"`
+		BodyPreview   *string           `json:"body_preview,omitempty"`
+		BodyTruncated bool              `json:"body_truncated,omitempty"`
+	}
+
+	encryptionResult struct {
+		ServerSideEncryption string `json:"server_side_encryption,omitempty"`
+		KMSKeyID             string `json:"kms_key_id,omitempty"`
+		BucketKeyEnabled     bool   `json:"bucket_key_enabled,omitempty"`
+		CustomerAlgorithm    string `json:"customer_algorithm,omitempty"`
+	}
+)
+
+// WithBodyPreview allows the model to request the first maxBytes bytes of an object body.
+func WithBodyPreview(maxBytes int64) HeadOpt {
+	return func(t *HeadTool) {
+		t.bodyPreviewBytes = maxBytes
+	}
+}
+
+func NewHeadTool(s3Client *s3.Client, opts ...HeadOpt) tools.Function {
+	t := &HeadTool{
+		s3Client: s3Client,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *HeadTool) Name() string {
+	return "head_s3_object"
+}
+
+func (t *HeadTool) Description() string {
+	return "This tool returns the metadata of a single S3 object, including its size, storage class, encryption settings, user metadata and tags. The object body is not downloaded. The response is a JSON object."
+}
+
+func (t *HeadTool) ParameterDefinitions() []tools.ParameterDefinition {
+	params := []tools.ParameterDefinition{
+		{
+			Name:        parameterBucket,
+			Description: "The name of the S3 bucket, e.g. my-bucket. This is not an ARN.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterKey,
+			Description: "The full key of the object, as returned by the list_s3_objects tool.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterVersionID,
+			Description: "The version of the object to inspect. Defaults to the latest version.",
+			Type:        tools.ParameterTypeString,
+		},
+	}
+
+	if t.bodyPreviewBytes > 0 {
+		params = append(params, tools.ParameterDefinition{
+			Name:        parameterIncludeBodyPreview,
+			Description: fmt.Sprintf("If true, the first %d bytes of the object body are included in the response. Only use this for small text objects.", t.bodyPreviewBytes),
+			Type:        tools.ParameterTypeBoolean,
+		})
+	}
+
+	return params
+}
+
+func (t *HeadTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	bucket, _ := parameters[parameterBucket].(string)
+	if bucket == "" {
+		return "", fmt.Errorf("%s is required", parameterBucket)
+	}
+
+	key, _ := parameters[parameterKey].(string)
+	if key == "" {
+		return "", fmt.Errorf("%s is required", parameterKey)
+	}
+
+	var versionID *string
+	if v, _ := parameters[parameterVersionID].(string); v != "" {
+		versionID = &v
+	}
+
+	includeBody, err := tools.BoolParameter(parameters, parameterIncludeBodyPreview, false)
+	if err != nil {
+		return "", err
+	}
+	if includeBody && t.bodyPreviewBytes == 0 {
+		return "", fmt.Errorf("body previews are disabled")
+	}
+
+	head, err := t.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: versionID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting object metadata: %w", err)
+	}
+
+	result := headResult{
+		Bucket:       bucket,
+		Key:          key,
+		VersionID:    aws.ToString(head.VersionId),
+		Size:         aws.ToInt64(head.ContentLength),
+		ContentType:  aws.ToString(head.ContentType),
+		ETag:         aws.ToString(head.ETag),
+		LastModified: head.LastModified,
+		StorageClass: string(head.StorageClass),
+		Encryption: encryptionResult{
+			ServerSideEncryption: string(head.ServerSideEncryption),
+			KMSKeyID:             aws.ToString(head.SSEKMSKeyId),
+			BucketKeyEnabled:     aws.ToBool(head.BucketKeyEnabled),
+			CustomerAlgorithm:    aws.ToString(head.SSECustomerAlgorithm),
+		},
+		Metadata: head.Metadata,
+	}
+	if result.StorageClass == "" {
+		result.StorageClass = storageClassStandard
+	}
+
+	// tags require a separate permission, so a failure here shouldn't hide the rest of the metadata
+	tagging, err := t.s3Client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: versionID,
+	})
+	if err != nil {
+		result.TagsError = err.Error()
+	} else if len(tagging.TagSet) > 0 {
+		result.Tags = make(map[string]string, len(tagging.TagSet))
+		for _, tag := range tagging.TagSet {
+			result.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+
+	if includeBody {
+		preview, truncated, err := t.bodyPreview(ctx, bucket, key, versionID, result.Size)
+		if err != nil {
+			return "", err
+		}
+		result.BodyPreview = &preview
+		result.BodyTruncated = truncated
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling object metadata to JSON: %w", err)
+	}
+
+	return string(resultJSON), nil
+}
+
+func (t *HeadTool) bodyPreview(ctx context.Context, bucket, key string, versionID *string, size int64) (string, bool, error) {
+	if size == 0 {
+		return "", false, nil
+	}
+
+	obj, err := t.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: versionID,
+		Range:     aws.String(fmt.Sprintf("bytes=0-%d", t.bodyPreviewBytes-1)),
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("error getting object body: %w", err)
+	}
+	defer obj.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(obj.Body, t.bodyPreviewBytes))
+	if err != nil {
+		return "", false, fmt.Errorf("error reading object body: %w", err)
+	}
+
+	return string(body), size > int64(len(body)), nil
+}
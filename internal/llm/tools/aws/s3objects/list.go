@@ -0,0 +1,154 @@
+package s3objects
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+const (
+	parameterBucket            = "bucket"
+	parameterKey               = "key"
+	parameterPrefix            = "prefix"
+	parameterDelimiter         = "delimiter"
+	parameterMaxKeys           = "max_keys"
+	parameterContinuationToken = "continuation_token"
+
+	defaultMaxKeys = 100
+	maxMaxKeys     = 1000
+)
+
+type (
+	ListTool struct {
+		s3Client *s3.Client
+	}
+
+	listResult struct {
+		Bucket                string         `json:"bucket"`
+		Prefix                string         `json:"prefix,omitempty"`
+		CommonPrefixes        []string       `json:"common_prefixes,omitempty"`
+		Objects               []objectResult `json:"objects"`
+		IsTruncated           bool           `json:"is_truncated"`
+		NextContinuationToken string         `json:"next_continuation_token,omitempty"`
+	}
+
+	objectResult struct {
+		Key          string     `json:"key"`
+		Size         int64      `json:"size"`
+		LastModified *time.Time `json:"last_modified,omitempty"`
+		StorageClass string     `json:"storage_class,omitempty"`
+	}
+)
+
+func NewListTool(s3Client *s3.Client) tools.Function {
+	return &ListTool{
+		s3Client: s3Client,
+	}
+}
+
+func (t *ListTool) Name() string {
+	return "list_s3_objects"
+}
+
+func (t *ListTool) Description() string {
+	return fmt.Sprintf(`This tool lists the objects in an S3 bucket. Object contents are never downloaded.
+Use the %q parameter to browse "directories": with a delimiter of "/", objects below the next "/" are grouped into common_prefixes, which can be passed back as the %q parameter to browse further.
+If is_truncated is true, pass next_continuation_token back as the %q parameter to get the next page.`,
+		parameterDelimiter, parameterPrefix, parameterContinuationToken)
+}
+
+func (t *ListTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterBucket,
+			Description: "The name of the S3 bucket, e.g. my-bucket. This is not an ARN.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterPrefix,
+			Description: "Only list objects whose keys begin with this prefix, e.g. logs/2024/.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterDelimiter,
+			Description: "A character used to group keys into common prefixes, usually \"/\".",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterMaxKeys,
+			Description: fmt.Sprintf("The maximum number of keys to return, between 1 and %d. Defaults to %d.", maxMaxKeys, defaultMaxKeys),
+			Type:        tools.ParameterTypeInteger,
+		},
+		{
+			Name:        parameterContinuationToken,
+			Description: "The next_continuation_token returned by a previous call, used to fetch the next page.",
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t *ListTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	bucket, _ := parameters[parameterBucket].(string)
+	if bucket == "" {
+		return "", fmt.Errorf("%s is required", parameterBucket)
+	}
+
+	maxKeys, err := tools.IntParameter(parameters, parameterMaxKeys, defaultMaxKeys)
+	if err != nil {
+		return "", err
+	}
+	if maxKeys < 1 || maxKeys > maxMaxKeys {
+		return "", fmt.Errorf("%s must be between 1 and %d", parameterMaxKeys, maxMaxKeys)
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket:  &bucket,
+		MaxKeys: aws.Int32(int32(maxKeys)),
+	}
+	if prefix, _ := parameters[parameterPrefix].(string); prefix != "" {
+		input.Prefix = &prefix
+	}
+	if delimiter, _ := parameters[parameterDelimiter].(string); delimiter != "" {
+		input.Delimiter = &delimiter
+	}
+	if token, _ := parameters[parameterContinuationToken].(string); token != "" {
+		input.ContinuationToken = &token
+	}
+
+	resp, err := t.s3Client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("error listing objects: %w", err)
+	}
+
+	result := listResult{
+		Bucket:                bucket,
+		Prefix:                aws.ToString(input.Prefix),
+		Objects:               make([]objectResult, 0, len(resp.Contents)),
+		IsTruncated:           aws.ToBool(resp.IsTruncated),
+		NextContinuationToken: aws.ToString(resp.NextContinuationToken),
+	}
+	for _, p := range resp.CommonPrefixes {
+		result.CommonPrefixes = append(result.CommonPrefixes, aws.ToString(p.Prefix))
+	}
+	for _, o := range resp.Contents {
+		result.Objects = append(result.Objects, objectResult{
+			Key:          aws.ToString(o.Key),
+			Size:         aws.ToInt64(o.Size),
+			LastModified: o.LastModified,
+			StorageClass: string(o.StorageClass),
+		})
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling objects to JSON: %w", err)
+	}
+
+	return string(resultJSON), nil
+}
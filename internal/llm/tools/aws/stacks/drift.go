@@ -0,0 +1,195 @@
+package stacks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+const (
+	parameterDetect = "detect"
+
+	// detectionTimeout is how long drift detection is waited for, which usually takes under a minute.
+	detectionTimeout = 2 * time.Minute
+	pollInterval     = 5 * time.Second
+)
+
+type (
+	DriftTool struct {
+		cloudformationClient *cloudformation.Client
+	}
+
+	driftResult struct {
+		Stack          string `json:"stack"`
+		DriftStatus    string `json:"drift_status"`
+		LastDriftCheck any    `json:"last_drift_check"`
+		// DriftedResources are the resources that have been modified or deleted outside CloudFormation.
+		DriftedResources []driftedResource `json:"drifted_resources"`
+		Note             string            `json:"note,omitempty"`
+	}
+
+	driftedResource struct {
+		LogicalID    string       `json:"logical_id"`
+		PhysicalID   string       `json:"physical_id,omitempty"`
+		ResourceType string       `json:"resource_type"`
+		DriftStatus  string       `json:"drift_status"`
+		Differences  []difference `json:"differences,omitempty"`
+	}
+
+	difference struct {
+		PropertyPath string `json:"property_path"`
+		Type         string `json:"type"`
+		Expected     string `json:"expected,omitempty"`
+		Actual       string `json:"actual,omitempty"`
+	}
+)
+
+func NewDriftTool(cloudformationClient *cloudformation.Client) tools.Function {
+	return &DriftTool{
+		cloudformationClient: cloudformationClient,
+	}
+}
+
+func (t *DriftTool) Name() string {
+	return "get_cloudformation_drift"
+}
+
+func (t *DriftTool) Description() string {
+	return fmt.Sprintf(`This tool shows whether the resources of a CloudFormation stack have drifted from its template, i.e. been modified or deleted outside CloudFormation.
+The response is a JSON object with the stack's drift status, when drift was last checked, and each drifted resource along with the properties that differ from the template.
+By default the result of the last drift check is returned, which may be out of date or missing. Set %q to true to check for drift now, which takes up to a couple of minutes.`,
+		parameterDetect)
+}
+
+func (t *DriftTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterStack,
+			Description: "The name or ID of the stack.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterDetect,
+			Description: "If true, drift is detected now rather than returning the result of the last check. Defaults to false.",
+			Type:        tools.ParameterTypeBoolean,
+		},
+	}
+}
+
+func (t *DriftTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	stack, ok := parameters[parameterStack].(string)
+	if !ok || stack == "" {
+		return "", fmt.Errorf("%s is not a valid string", parameterStack)
+	}
+	detect, err := tools.BoolParameter(parameters, parameterDetect, false)
+	if err != nil {
+		return "", err
+	}
+
+	if detect {
+		if err := t.detect(ctx, stack); err != nil {
+			return "", err
+		}
+	}
+
+	resp, err := t.cloudformationClient.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{StackName: &stack})
+	if err != nil {
+		return "", fmt.Errorf("error describing stack %s: %w", stack, err)
+	}
+	if len(resp.Stacks) == 0 {
+		return "", fmt.Errorf("stack %s not found", stack)
+	}
+
+	result := driftResult{Stack: deref(resp.Stacks[0].StackName), DriftedResources: []driftedResource{}}
+	if info := resp.Stacks[0].DriftInformation; info != nil {
+		result.DriftStatus = string(info.StackDriftStatus)
+		result.LastDriftCheck = timestamp(info.LastCheckTimestamp)
+	}
+	if result.DriftStatus == "" || result.DriftStatus == string(types.StackDriftStatusNotChecked) {
+		result.Note = fmt.Sprintf("Drift has never been checked for this stack. Call this tool again with %s set to true to check it.", parameterDetect)
+	}
+
+	paginator := cloudformation.NewDescribeStackResourceDriftsPaginator(t.cloudformationClient, &cloudformation.DescribeStackResourceDriftsInput{
+		StackName: &stack,
+		StackResourceDriftStatusFilters: []types.StackResourceDriftStatus{
+			types.StackResourceDriftStatusModified,
+			types.StackResourceDriftStatusDeleted,
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("error describing resource drift of stack %s: %w", stack, err)
+		}
+
+		for _, d := range page.StackResourceDrifts {
+			r := driftedResource{
+				LogicalID:    deref(d.LogicalResourceId),
+				PhysicalID:   deref(d.PhysicalResourceId),
+				ResourceType: deref(d.ResourceType),
+				DriftStatus:  string(d.StackResourceDriftStatus),
+			}
+			for _, p := range d.PropertyDifferences {
+				r.Differences = append(r.Differences, difference{
+					PropertyPath: deref(p.PropertyPath),
+					Type:         string(p.DifferenceType),
+					Expected:     deref(p.ExpectedValue),
+					Actual:       deref(p.ActualValue),
+				})
+			}
+			result.DriftedResources = append(result.DriftedResources, r)
+		}
+	}
+
+	outJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling drift to JSON: %w", err)
+	}
+
+	return string(outJSON), nil
+}
+
+// detect starts drift detection for a stack, and waits for it to finish.
+func (t *DriftTool) detect(ctx context.Context, stack string) error {
+	resp, err := t.cloudformationClient.DetectStackDrift(ctx, &cloudformation.DetectStackDriftInput{StackName: &stack})
+	if err != nil {
+		return fmt.Errorf("error detecting drift of stack %s: %w", stack, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, detectionTimeout)
+	defer cancel()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, err := t.cloudformationClient.DescribeStackDriftDetectionStatus(ctx, &cloudformation.DescribeStackDriftDetectionStatusInput{
+			StackDriftDetectionId: resp.StackDriftDetectionId,
+		})
+		if err != nil {
+			return fmt.Errorf("error getting drift detection status of stack %s: %w", stack, err)
+		}
+
+		switch status.DetectionStatus {
+		case types.StackDriftDetectionStatusDetectionComplete:
+			return nil
+		case types.StackDriftDetectionStatusDetectionFailed:
+			// detection fails if some resources can't be checked, but the rest are still reported
+			if status.StackDriftStatus != "" && status.StackDriftStatus != types.StackDriftStatusUnknown {
+				return nil
+			}
+			return fmt.Errorf("drift detection of stack %s failed: %s", stack, deref(status.DetectionStatusReason))
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("drift detection of stack %s didn't finish in %s, try again later without detecting drift", stack, detectionTimeout)
+		case <-ticker.C:
+		}
+	}
+}
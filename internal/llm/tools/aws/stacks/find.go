@@ -0,0 +1,241 @@
+package stacks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	"github.com/fergalhk/llm-cloud-discovery/internal/arn"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+const (
+	parameterIdentifier = "identifier"
+
+	// codeValidationError is returned by DescribeStackResources for resources that aren't in a stack.
+	codeValidationError = "ValidationError"
+)
+
+type (
+	FindTool struct {
+		cloudformationClient *cloudformation.Client
+	}
+
+	findResult struct {
+		Identifier string `json:"identifier"`
+		Managed    bool   `json:"managed"`
+		// PhysicalID is the identifier the resource was found by, which may differ from the one given.
+		PhysicalID   string `json:"physical_id,omitempty"`
+		StackName    string `json:"stack_name,omitempty"`
+		StackID      string `json:"stack_id,omitempty"`
+		LogicalID    string `json:"logical_id,omitempty"`
+		ResourceType string `json:"resource_type,omitempty"`
+		Status       string `json:"status,omitempty"`
+		DriftStatus  string `json:"drift_status,omitempty"`
+		Module       string `json:"module,omitempty"`
+		// RootStackName is the top level stack, if the stack is nested.
+		RootStackName string `json:"root_stack_name,omitempty"`
+		Note          string `json:"note,omitempty"`
+	}
+)
+
+func NewFindTool(cloudformationClient *cloudformation.Client) tools.Function {
+	return &FindTool{
+		cloudformationClient: cloudformationClient,
+	}
+}
+
+func (t *FindTool) Name() string {
+	return "find_cloudformation_stack"
+}
+
+func (t *FindTool) Description() string {
+	return fmt.Sprintf(`This tool finds the CloudFormation stack that created an AWS resource, and the resource's logical ID in the stack's template.
+Pass the resource's physical ID in the %q parameter, which is its name, ID or ARN depending on the resource type, e.g. sg-0123456789abcdef0 for a security group. ARNs are also tried as the IDs they contain.
+The response is a JSON object saying whether the resource is managed by a stack, and if so the stack's name, the resource's logical ID, type, status & drift status, and the top level stack if the stack is nested.`,
+		parameterIdentifier)
+}
+
+func (t *FindTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterIdentifier,
+			Description: "The physical ID or ARN of the resource, e.g. sg-0123456789abcdef0 or arn:aws:iam::123456789012:role/web.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t *FindTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	identifier, ok := parameters[parameterIdentifier].(string)
+	if !ok || identifier == "" {
+		return "", fmt.Errorf("%s is not a valid string", parameterIdentifier)
+	}
+
+	result := findResult{
+		Identifier: identifier,
+		Note:       "The resource isn't managed by a CloudFormation stack in this account & region. If the resource has the aws:cloudformation:stack-name tag, it was created by that stack but may have been removed from it.",
+	}
+	for _, candidate := range candidates(identifier) {
+		r, ok, err := t.describe(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			continue
+		}
+
+		result = findResult{
+			Identifier:   identifier,
+			Managed:      true,
+			PhysicalID:   candidate,
+			StackName:    deref(r.StackName),
+			StackID:      deref(r.StackId),
+			LogicalID:    deref(r.LogicalResourceId),
+			ResourceType: deref(r.ResourceType),
+			Status:       string(r.ResourceStatus),
+		}
+		if r.DriftInformation != nil {
+			result.DriftStatus = string(r.DriftInformation.StackResourceDriftStatus)
+		}
+		if r.ModuleInfo != nil {
+			result.Module = deref(r.ModuleInfo.LogicalIdHierarchy)
+		}
+		result.RootStackName, err = t.rootStackName(ctx, result.StackID)
+		if err != nil {
+			return "", err
+		}
+		break
+	}
+
+	outJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling stack to JSON: %w", err)
+	}
+
+	return string(outJSON), nil
+}
+
+// describe returns the stack resource with the physical ID, and false if it isn't in a stack.
+func (t *FindTool) describe(ctx context.Context, physicalID string) (types.StackResource, bool, error) {
+	resp, err := t.cloudformationClient.DescribeStackResources(ctx, &cloudformation.DescribeStackResourcesInput{
+		PhysicalResourceId: &physicalID,
+	})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == codeValidationError {
+		return types.StackResource{}, false, nil
+	}
+	if err != nil {
+		return types.StackResource{}, false, fmt.Errorf("error describing stack resources of %s: %w", physicalID, err)
+	}
+
+	// every resource of the stack is returned, not just the one asked for
+	for _, r := range resp.StackResources {
+		if deref(r.PhysicalResourceId) == physicalID {
+			return r, true, nil
+		}
+	}
+	if len(resp.StackResources) == 0 {
+		return types.StackResource{}, false, nil
+	}
+
+	// at most 100 resources are returned, so the resource may be in the stack but not the response
+	return t.listStackResource(ctx, resp.StackResources[0], physicalID)
+}
+
+// listStackResource pages through the resources of the stack that stackResource belongs to,
+// returning the one with the physical ID, and false if it isn't in the stack.
+func (t *FindTool) listStackResource(ctx context.Context, stackResource types.StackResource, physicalID string) (types.StackResource, bool, error) {
+	paginator := cloudformation.NewListStackResourcesPaginator(t.cloudformationClient, &cloudformation.ListStackResourcesInput{
+		StackName: stackResource.StackId,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return types.StackResource{}, false, fmt.Errorf("error listing resources of stack %s: %w", deref(stackResource.StackName), err)
+		}
+
+		for _, s := range page.StackResourceSummaries {
+			if deref(s.PhysicalResourceId) != physicalID {
+				continue
+			}
+
+			r := types.StackResource{
+				StackName:          stackResource.StackName,
+				StackId:            stackResource.StackId,
+				LogicalResourceId:  s.LogicalResourceId,
+				PhysicalResourceId: s.PhysicalResourceId,
+				ResourceType:       s.ResourceType,
+				ResourceStatus:     s.ResourceStatus,
+				Timestamp:          s.LastUpdatedTimestamp,
+				ModuleInfo:         s.ModuleInfo,
+			}
+			if s.DriftInformation != nil {
+				r.DriftInformation = &types.StackResourceDriftInformation{
+					StackResourceDriftStatus: s.DriftInformation.StackResourceDriftStatus,
+					LastCheckTimestamp:       s.DriftInformation.LastCheckTimestamp,
+				}
+			}
+			return r, true, nil
+		}
+	}
+	return types.StackResource{}, false, nil
+}
+
+// rootStackName returns the name of the top level stack a nested stack belongs to, or an empty
+// string if the stack isn't nested.
+func (t *FindTool) rootStackName(ctx context.Context, stackID string) (string, error) {
+	resp, err := t.cloudformationClient.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+		StackName: &stackID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing stack %s: %w", stackID, err)
+	}
+	if len(resp.Stacks) == 0 || resp.Stacks[0].RootId == nil {
+		return "", nil
+	}
+	return stackName(*resp.Stacks[0].RootId), nil
+}
+
+// candidates returns the physical IDs a resource may have in a stack. Depending on the type, the
+// physical ID of a resource is its name, ID or ARN, so ARNs are also tried as the IDs in them.
+func candidates(identifier string) []string {
+	out := []string{identifier}
+	if !arn.IsARN(identifier) {
+		return out
+	}
+
+	parsed, err := arn.Parse(identifier)
+	if err != nil {
+		return out
+	}
+	add := func(id string) {
+		if id != "" && !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	if cc, err := parsed.ToCloudControl(); err == nil {
+		add(cc.Identifier)
+	}
+	add(parsed.ResourceID)
+	add(parsed.ResourceID[strings.LastIndex(parsed.ResourceID, "/")+1:])
+	return out
+}
+
+// stackName returns the name in a stack ID, e.g. web in
+// arn:aws:cloudformation:eu-west-1:123456789012:stack/web/0a1b2c3d.
+func stackName(stackID string) string {
+	parsed, err := arn.Parse(stackID)
+	if err != nil {
+		return stackID
+	}
+	name, _, _ := strings.Cut(parsed.ResourceID, "/")
+	return name
+}
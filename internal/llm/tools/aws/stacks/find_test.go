@@ -0,0 +1,125 @@
+package stacks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/fergalhk/llm-cloud-discovery/internal/awstest"
+)
+
+const (
+	testStackID = "arn:aws:cloudformation:eu-west-1:111111111111:stack/web/0a1b2c3d"
+	// testStackSize is more than the 100 resources DescribeStackResources returns.
+	testStackSize = 150
+	// testPageSize is the number of resources in each page of ListStackResources.
+	testPageSize = 100
+)
+
+// newTestFindTool returns a tool backed by a local stand-in for the CloudFormation API, with a
+// single stack of testStackSize security groups, sg-0 to sg-149. As with the real API,
+// DescribeStackResources only returns the first 100 of them.
+func newTestFindTool(t *testing.T) *FindTool {
+	t.Helper()
+
+	member := func(i int, timestampField string) string {
+		return fmt.Sprintf(`<member><StackName>web</StackName><StackId>%s</StackId><LogicalResourceId>Group%d</LogicalResourceId>`+
+			`<PhysicalResourceId>sg-%d</PhysicalResourceId><ResourceType>AWS::EC2::SecurityGroup</ResourceType>`+
+			`<ResourceStatus>CREATE_COMPLETE</ResourceStatus><%s>2025-05-01T12:00:00Z</%[4]s>`+
+			`<DriftInformation><StackResourceDriftStatus>IN_SYNC</StackResourceDriftStatus></DriftInformation></member>`,
+			testStackID, i, i, timestampField)
+	}
+
+	handler := awstest.QueryHandler(map[string]awstest.QueryOperation{
+		"DescribeStackResources": func(form url.Values) (string, error) {
+			id := form.Get("PhysicalResourceId")
+			n, err := strconv.Atoi(strings.TrimPrefix(id, "sg-"))
+			if !strings.HasPrefix(id, "sg-") || err != nil || n >= testStackSize {
+				return "", &awstest.Error{Code: "ValidationError", Message: "Stack for " + id + " does not exist"}
+			}
+			members := ""
+			for i := range 100 {
+				members += member(i, "Timestamp")
+			}
+			return fmt.Sprintf(`<DescribeStackResourcesResponse><DescribeStackResourcesResult><StackResources>%s</StackResources></DescribeStackResourcesResult></DescribeStackResourcesResponse>`, members), nil
+		},
+		"ListStackResources": func(form url.Values) (string, error) {
+			if name := form.Get("StackName"); name != testStackID {
+				return "", fmt.Errorf("unexpected stack %s", name)
+			}
+			start, _ := strconv.Atoi(form.Get("NextToken"))
+			end := min(start+testPageSize, testStackSize)
+			members, next := "", ""
+			for i := start; i < end; i++ {
+				members += member(i, "LastUpdatedTimestamp")
+			}
+			if end < testStackSize {
+				next = fmt.Sprintf("<NextToken>%d</NextToken>", end)
+			}
+			return fmt.Sprintf(`<ListStackResourcesResponse><ListStackResourcesResult><StackResourceSummaries>%s</StackResourceSummaries>%s</ListStackResourcesResult></ListStackResourcesResponse>`, members, next), nil
+		},
+		"DescribeStacks": func(url.Values) (string, error) {
+			return fmt.Sprintf(`<DescribeStacksResponse><DescribeStacksResult><Stacks><member><StackName>web</StackName><StackId>%s</StackId>`+
+				`<CreationTime>2025-05-01T12:00:00Z</CreationTime><StackStatus>CREATE_COMPLETE</StackStatus></member></Stacks></DescribeStacksResult></DescribeStacksResponse>`, testStackID), nil
+		},
+	})
+
+	client := cloudformation.NewFromConfig(awstest.Config(t, handler))
+	return NewFindTool(client).(*FindTool)
+}
+
+func TestFindCall(t *testing.T) {
+	tests := []struct {
+		name       string
+		identifier string
+		want       findResult
+	}{
+		{
+			name:       "in the described resources",
+			identifier: "sg-5",
+			want:       findResult{Identifier: "sg-5", Managed: true, PhysicalID: "sg-5", StackName: "web", StackID: testStackID, LogicalID: "Group5"},
+		},
+		{
+			name:       "beyond the described resources",
+			identifier: "sg-140",
+			want:       findResult{Identifier: "sg-140", Managed: true, PhysicalID: "sg-140", StackName: "web", StackID: testStackID, LogicalID: "Group140"},
+		},
+		{
+			name:       "ARN",
+			identifier: "arn:aws:ec2:eu-west-1:111111111111:security-group/sg-120",
+			want:       findResult{Identifier: "arn:aws:ec2:eu-west-1:111111111111:security-group/sg-120", Managed: true, PhysicalID: "sg-120", StackName: "web", StackID: testStackID, LogicalID: "Group120"},
+		},
+		{
+			name:       "not in a stack",
+			identifier: "sg-999",
+			want:       findResult{Identifier: "sg-999"},
+		},
+	}
+
+	tool := newTestFindTool(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tool.Call(context.Background(), map[string]any{parameterIdentifier: tt.identifier})
+			if err != nil {
+				t.Fatalf("error calling tool: %v", err)
+			}
+			var got findResult
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("error unmarshalling result: %v", err)
+			}
+
+			if got.Managed != tt.want.Managed || got.PhysicalID != tt.want.PhysicalID || got.StackName != tt.want.StackName ||
+				got.StackID != tt.want.StackID || got.LogicalID != tt.want.LogicalID {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if tt.want.Managed && (got.ResourceType != "AWS::EC2::SecurityGroup" || got.Status != "CREATE_COMPLETE" || got.DriftStatus != "IN_SYNC") {
+				t.Errorf("got type %q, status %q & drift status %q", got.ResourceType, got.Status, got.DriftStatus)
+			}
+		})
+	}
+}
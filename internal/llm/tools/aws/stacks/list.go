@@ -0,0 +1,153 @@
+package stacks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
+)
+
+const (
+	parameterNameFilter     = "name_filter"
+	parameterIncludeDeleted = "include_deleted"
+	parameterStack          = "stack"
+)
+
+type (
+	Opt func(*ListTool)
+
+	ListTool struct {
+		cloudformationClient *cloudformation.Client
+		format               format.Format
+	}
+)
+
+// WithFormat sets the default format of the list. The model can still ask for another format per call.
+func WithFormat(f format.Format) Opt {
+	return func(t *ListTool) {
+		t.format = f
+	}
+}
+
+func NewListTool(cloudformationClient *cloudformation.Client, opts ...Opt) tools.Function {
+	t := &ListTool{
+		cloudformationClient: cloudformationClient,
+		format:               format.JSON,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *ListTool) Name() string {
+	return "list_cloudformation_stacks"
+}
+
+func (t *ListTool) Description() string {
+	return fmt.Sprintf(`This tool lists the CloudFormation stacks in the current account & region.
+Each stack is returned with its status, its drift status as of the last drift check, and the stack it's nested in, if any.
+The response is returned as %s. Use the list_cloudformation_stack_resources tool to list the resources of a stack.`, t.format.Describe())
+}
+
+func (t *ListTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterNameFilter,
+			Description: "Only list stacks whose names contain this, ignoring case.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterIncludeDeleted,
+			Description: "If true, stacks deleted in the last 90 days are listed too. Defaults to false.",
+			Type:        tools.ParameterTypeBoolean,
+		},
+		format.ParameterDefinition(t.format),
+	}
+}
+
+func (t *ListTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	nameFilter, _ := parameters[parameterNameFilter].(string)
+	includeDeleted, err := tools.BoolParameter(parameters, parameterIncludeDeleted, false)
+	if err != nil {
+		return "", err
+	}
+	f, err := format.FromParameters(parameters, t.format)
+	if err != nil {
+		return "", err
+	}
+
+	// every status but DELETE_COMPLETE, unless deleted stacks are wanted
+	input := &cloudformation.ListStacksInput{}
+	if !includeDeleted {
+		for _, status := range types.StackStatus("").Values() {
+			if status != types.StackStatusDeleteComplete {
+				input.StackStatusFilter = append(input.StackStatusFilter, status)
+			}
+		}
+	}
+
+	rows := [][]any{}
+	paginator := cloudformation.NewListStacksPaginator(t.cloudformationClient, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("error listing stacks: %w", err)
+		}
+
+		for _, s := range page.StackSummaries {
+			if nameFilter != "" && !strings.Contains(strings.ToLower(deref(s.StackName)), strings.ToLower(nameFilter)) {
+				continue
+			}
+
+			var driftStatus, lastDriftCheck any
+			if s.DriftInformation != nil {
+				driftStatus = string(s.DriftInformation.StackDriftStatus)
+				lastDriftCheck = timestamp(s.DriftInformation.LastCheckTimestamp)
+			}
+			rows = append(rows, []any{
+				deref(s.StackName),
+				string(s.StackStatus),
+				driftStatus,
+				lastDriftCheck,
+				timestamp(s.CreationTime),
+				timestamp(s.LastUpdatedTime),
+				nullable(s.ParentId),
+				deref(s.TemplateDescription),
+			})
+		}
+	}
+
+	if len(rows) == 0 {
+		return "No stacks found.", nil
+	}
+
+	return f.Rows([]string{"stack_name", "status", "drift_status", "last_drift_check", "created", "last_updated", "parent_stack_id", "description"}, rows)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// nullable returns nil for a missing or empty string, so that columns without values are dropped.
+func nullable(s *string) any {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return *s
+}
+
+func timestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
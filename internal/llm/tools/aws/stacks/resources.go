@@ -0,0 +1,100 @@
+package stacks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
+)
+
+const parameterResourceType = "resource_type"
+
+type ResourcesTool struct {
+	cloudformationClient *cloudformation.Client
+}
+
+func NewResourcesTool(cloudformationClient *cloudformation.Client) tools.Function {
+	return &ResourcesTool{
+		cloudformationClient: cloudformationClient,
+	}
+}
+
+func (t *ResourcesTool) Name() string {
+	return "list_cloudformation_stack_resources"
+}
+
+func (t *ResourcesTool) Description() string {
+	return `This tool lists the resources of a CloudFormation stack.
+Each resource is returned with its logical ID in the template, its physical ID (the name, ID or ARN of the AWS resource), its type, status & drift status as of the last drift check.
+Physical IDs can be passed to get_aws_resource along with the resource type. Resources of type AWS::CloudFormation::Stack are nested stacks, whose resources can be listed by passing their physical ID to this tool.`
+}
+
+func (t *ResourcesTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterStack,
+			Description: "The name or ID of the stack.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterResourceType,
+			Description: "Only list resources of this type, e.g. AWS::EC2::SecurityGroup.",
+			Type:        tools.ParameterTypeString,
+		},
+		format.ParameterDefinition(format.JSON),
+	}
+}
+
+func (t *ResourcesTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	stack, ok := parameters[parameterStack].(string)
+	if !ok || stack == "" {
+		return "", fmt.Errorf("%s is not a valid string", parameterStack)
+	}
+	resourceType, _ := parameters[parameterResourceType].(string)
+	f, err := format.FromParameters(parameters, format.JSON)
+	if err != nil {
+		return "", err
+	}
+
+	rows := [][]any{}
+	paginator := cloudformation.NewListStackResourcesPaginator(t.cloudformationClient, &cloudformation.ListStackResourcesInput{
+		StackName: &stack,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("error listing resources of stack %s: %w", stack, err)
+		}
+
+		for _, r := range page.StackResourceSummaries {
+			if resourceType != "" && deref(r.ResourceType) != resourceType {
+				continue
+			}
+
+			var driftStatus, module any
+			if r.DriftInformation != nil {
+				driftStatus = string(r.DriftInformation.StackResourceDriftStatus)
+			}
+			if r.ModuleInfo != nil {
+				module = nullable(r.ModuleInfo.LogicalIdHierarchy)
+			}
+			rows = append(rows, []any{
+				deref(r.LogicalResourceId),
+				nullable(r.PhysicalResourceId),
+				deref(r.ResourceType),
+				string(r.ResourceStatus),
+				driftStatus,
+				module,
+			})
+		}
+	}
+
+	if len(rows) == 0 {
+		return fmt.Sprintf("No matching resources found in stack %s.", stack), nil
+	}
+
+	return f.Rows([]string{"logical_id", "physical_id", "type", "status", "drift_status", "module"}, rows)
+}
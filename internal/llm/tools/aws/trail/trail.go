@@ -0,0 +1,313 @@
+package trail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+const (
+	parameterResource        = "resource"
+	parameterEventName       = "event_name"
+	parameterUsername        = "username"
+	parameterStartTime       = "start_time"
+	parameterEndTime         = "end_time"
+	parameterIncludeReadOnly = "include_read_only"
+	parameterMaxResults      = "max_results"
+
+	defaultMaxResults = 25
+	maxMaxResults     = 100
+	// maxPages bounds how many pages are scanned when filtering client side, as LookupEvents
+	// is heavily rate limited.
+	maxPages = 10
+	// maxRequestParametersLength is the longest request parameters string returned before it's elided.
+	maxRequestParametersLength = 500
+)
+
+type (
+	Tool struct {
+		cloudtrailClient *cloudtrail.Client
+		now              func() time.Time
+	}
+
+	result struct {
+		Events []event `json:"events"`
+		// Truncated is true if there may be more matching events than were returned.
+		Truncated bool   `json:"truncated"`
+		Note      string `json:"note,omitempty"`
+	}
+
+	event struct {
+		EventTime         *time.Time `json:"event_time"`
+		EventName         string     `json:"event_name"`
+		EventSource       string     `json:"event_source"`
+		Username          string     `json:"username,omitempty"`
+		PrincipalARN      string     `json:"principal_arn,omitempty"`
+		SourceIPAddress   string     `json:"source_ip_address,omitempty"`
+		ReadOnly          bool       `json:"read_only"`
+		Resources         []resource `json:"resources,omitempty"`
+		ErrorCode         string     `json:"error_code,omitempty"`
+		ErrorMessage      string     `json:"error_message,omitempty"`
+		RequestParameters string     `json:"request_parameters,omitempty"`
+	}
+
+	resource struct {
+		Type string `json:"type,omitempty"`
+		Name string `json:"name"`
+	}
+
+	// cloudTrailEvent holds the fields of the raw CloudTrail record that aren't in the LookupEvents response.
+	cloudTrailEvent struct {
+		UserIdentity struct {
+			ARN string `json:"arn"`
+		} `json:"userIdentity"`
+		SourceIPAddress   string          `json:"sourceIPAddress"`
+		ErrorCode         string          `json:"errorCode"`
+		ErrorMessage      string          `json:"errorMessage"`
+		RequestParameters json.RawMessage `json:"requestParameters"`
+	}
+
+	filters struct {
+		resource        string
+		eventName       string
+		username        string
+		includeReadOnly bool
+	}
+)
+
+func NewTool(cloudtrailClient *cloudtrail.Client) tools.Function {
+	return &Tool{
+		cloudtrailClient: cloudtrailClient,
+		now:              time.Now,
+	}
+}
+
+func (t *Tool) Name() string {
+	return "lookup_cloudtrail_events"
+}
+
+func (t *Tool) Description() string {
+	return fmt.Sprintf(`This tool looks up CloudTrail management events, to answer questions about who changed a resource and when.
+Events can be filtered by resource, event name, username and time window. By default only events that changed something are returned: set %q to include read only events such as Describe calls.
+CloudTrail event history only covers the last 90 days. Events are returned newest first, as a JSON object.`,
+		parameterIncludeReadOnly)
+}
+
+func (t *Tool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterResource,
+			Description: "The name, ID or ARN of the resource, e.g. sg-0123456789abcdef0 or my-bucket. This must match the name recorded by CloudTrail exactly.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterEventName,
+			Description: "The API call to filter by, e.g. AuthorizeSecurityGroupIngress or PutBucketPolicy.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterUsername,
+			Description: "The username, role session name or IAM user that made the call.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterStartTime,
+			Description: "The start of the time window, either as an RFC 3339 timestamp (e.g. 2024-05-01T00:00:00Z), a date (e.g. 2024-05-01) or a duration before now (e.g. 24h or 7d). Defaults to 7d.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterEndTime,
+			Description: "The end of the time window, in the same formats as start_time. Defaults to now.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterIncludeReadOnly,
+			Description: "If true, read only events such as Describe and List calls are included. Defaults to false.",
+			Type:        tools.ParameterTypeBoolean,
+		},
+		{
+			Name:        parameterMaxResults,
+			Description: fmt.Sprintf("The maximum number of events to return, up to %d. Defaults to %d.", maxMaxResults, defaultMaxResults),
+			Type:        tools.ParameterTypeInteger,
+		},
+	}
+}
+
+func (t *Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	f := filters{}
+	f.resource, _ = parameters[parameterResource].(string)
+	f.eventName, _ = parameters[parameterEventName].(string)
+	f.username, _ = parameters[parameterUsername].(string)
+
+	var err error
+	f.includeReadOnly, err = tools.BoolParameter(parameters, parameterIncludeReadOnly, false)
+	if err != nil {
+		return "", err
+	}
+
+	maxResults, err := tools.IntParameter(parameters, parameterMaxResults, defaultMaxResults)
+	if err != nil {
+		return "", err
+	}
+	if maxResults < 1 || maxResults > maxMaxResults {
+		return "", fmt.Errorf("%s must be between 1 and %d", parameterMaxResults, maxMaxResults)
+	}
+
+	now := t.now()
+	startTime, err := parseTime(parameters[parameterStartTime], now, now.Add(-7*24*time.Hour))
+	if err != nil {
+		return "", fmt.Errorf("%s is invalid: %w", parameterStartTime, err)
+	}
+	endTime, err := parseTime(parameters[parameterEndTime], now, now)
+	if err != nil {
+		return "", fmt.Errorf("%s is invalid: %w", parameterEndTime, err)
+	}
+	if !startTime.Before(endTime) {
+		return "", fmt.Errorf("%s must be before %s", parameterStartTime, parameterEndTime)
+	}
+
+	paginator := cloudtrail.NewLookupEventsPaginator(t.cloudtrailClient, &cloudtrail.LookupEventsInput{
+		StartTime:        &startTime,
+		EndTime:          &endTime,
+		LookupAttributes: f.lookupAttributes(),
+	})
+
+	out := result{Events: []event{}}
+	for page := 0; paginator.HasMorePages(); page++ {
+		if page == maxPages || len(out.Events) >= maxResults {
+			out.Truncated = true
+			break
+		}
+
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("error looking up events: %w", err)
+		}
+
+		for _, e := range resp.Events {
+			if !f.matches(e) {
+				continue
+			}
+			if len(out.Events) >= maxResults {
+				out.Truncated = true
+				break
+			}
+			out.Events = append(out.Events, condense(e))
+		}
+	}
+
+	if out.Truncated {
+		out.Note = "There may be more matching events. Narrow the time window or add filters to see them."
+	}
+
+	outJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling events to JSON: %w", err)
+	}
+
+	return string(outJSON), nil
+}
+
+// lookupAttributes returns the single attribute CloudTrail filters on server side. Any other
+// filters are applied client side, so the most selective filter is sent to CloudTrail.
+func (f filters) lookupAttributes() []types.LookupAttribute {
+	attr := func(key types.LookupAttributeKey, value string) []types.LookupAttribute {
+		return []types.LookupAttribute{{AttributeKey: key, AttributeValue: &value}}
+	}
+
+	switch {
+	case f.resource != "":
+		return attr(types.LookupAttributeKeyResourceName, f.resource)
+	case f.eventName != "":
+		return attr(types.LookupAttributeKeyEventName, f.eventName)
+	case f.username != "":
+		return attr(types.LookupAttributeKeyUsername, f.username)
+	case !f.includeReadOnly:
+		return attr(types.LookupAttributeKeyReadOnly, "false")
+	default:
+		return nil
+	}
+}
+
+func (f filters) matches(e types.Event) bool {
+	if f.eventName != "" && !strings.EqualFold(aws.ToString(e.EventName), f.eventName) {
+		return false
+	}
+	if f.username != "" && !strings.EqualFold(aws.ToString(e.Username), f.username) {
+		return false
+	}
+	if !f.includeReadOnly && aws.ToString(e.ReadOnly) == "true" {
+		return false
+	}
+	return true
+}
+
+func condense(e types.Event) event {
+	out := event{
+		EventTime:   e.EventTime,
+		EventName:   aws.ToString(e.EventName),
+		EventSource: aws.ToString(e.EventSource),
+		Username:    aws.ToString(e.Username),
+		ReadOnly:    aws.ToString(e.ReadOnly) == "true",
+	}
+	for _, r := range e.Resources {
+		out.Resources = append(out.Resources, resource{Type: aws.ToString(r.ResourceType), Name: aws.ToString(r.ResourceName)})
+	}
+
+	// the full record is only used for the fields LookupEvents doesn't return, so a record that
+	// can't be parsed still gives a useful event
+	var raw cloudTrailEvent
+	if json.Unmarshal([]byte(aws.ToString(e.CloudTrailEvent)), &raw) == nil {
+		out.PrincipalARN = raw.UserIdentity.ARN
+		out.SourceIPAddress = raw.SourceIPAddress
+		out.ErrorCode = raw.ErrorCode
+		out.ErrorMessage = raw.ErrorMessage
+		if params := string(raw.RequestParameters); params != "" && params != "null" {
+			if len(params) > maxRequestParametersLength {
+				params = params[:maxRequestParametersLength] + "...(truncated)"
+			}
+			out.RequestParameters = params
+		}
+	}
+
+	return out
+}
+
+// parseTime parses an RFC 3339 timestamp, a date, or a duration before now such as 24h or 7d.
+func parseTime(parameter any, now, def time.Time) (time.Time, error) {
+	s, _ := parameter.(string)
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	// time.ParseDuration doesn't support days
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%q is not a valid number of days", s)
+		}
+		return now.Add(-time.Duration(n) * 24 * time.Hour), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a timestamp, date or duration", s)
+	}
+
+	return now.Add(-d), nil
+}
@@ -0,0 +1,216 @@
+package trail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/fergalhk/llm-cloud-discovery/internal/awstest"
+)
+
+var testNow = time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+type (
+	// testEvent is an event as returned by LookupEvents.
+	testEvent struct {
+		EventId         string
+		EventName       string
+		EventSource     string
+		EventTime       float64
+		Username        string
+		ReadOnly        string
+		CloudTrailEvent string
+	}
+
+	lookupRequest struct {
+		LookupAttributes []struct {
+			AttributeKey   string
+			AttributeValue string
+		}
+		NextToken string
+	}
+)
+
+// newTestTool returns a tool backed by a local stand-in for the CloudTrail API, which serves
+// pages of events in order. The requests it receives are recorded in requests.
+func newTestTool(t *testing.T, pages [][]testEvent, requests *[]lookupRequest) *Tool {
+	t.Helper()
+
+	handler := awstest.JSONHandler(map[string]awstest.JSONOperation{
+		"CloudTrail_20131101.LookupEvents": awstest.JSON(func(req lookupRequest) (any, error) {
+			if requests != nil {
+				*requests = append(*requests, req)
+			}
+
+			page := 0
+			if req.NextToken != "" {
+				page, _ = strconv.Atoi(req.NextToken)
+			}
+			resp := map[string]any{"Events": pages[page]}
+			if page+1 < len(pages) {
+				resp["NextToken"] = strconv.Itoa(page + 1)
+			}
+			return resp, nil
+		}),
+	})
+
+	client := cloudtrail.NewFromConfig(awstest.Config(t, handler))
+	tool := NewTool(client).(*Tool)
+	tool.now = func() time.Time { return testNow }
+	return tool
+}
+
+// events returns n events with the given name & username, an hour apart.
+func events(n int, name, username string) []testEvent {
+	out := []testEvent{}
+	for i := range n {
+		out = append(out, testEvent{
+			EventId:     fmt.Sprintf("%s-%s-%d", name, username, i),
+			EventName:   name,
+			EventSource: "ec2.amazonaws.com",
+			EventTime:   float64(testNow.Add(-time.Duration(i+1) * time.Hour).Unix()),
+			Username:    username,
+			ReadOnly:    "false",
+		})
+	}
+	return out
+}
+
+func call(t *testing.T, tool *Tool, parameters map[string]any) result {
+	t.Helper()
+
+	out, err := tool.Call(context.Background(), parameters)
+	if err != nil {
+		t.Fatalf("error calling tool: %v", err)
+	}
+	var r result
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("error unmarshalling result: %v", err)
+	}
+	return r
+}
+
+func TestCallTruncation(t *testing.T) {
+	tests := []struct {
+		name          string
+		pages         [][]testEvent
+		maxResults    int
+		wantEvents    int
+		wantTruncated bool
+	}{
+		{
+			name:       "every event fits",
+			pages:      [][]testEvent{events(3, "RunInstances", "alice")},
+			maxResults: 3,
+			wantEvents: 3,
+		},
+		{
+			name:          "more events on the last page",
+			pages:         [][]testEvent{events(4, "RunInstances", "alice")},
+			maxResults:    3,
+			wantEvents:    3,
+			wantTruncated: true,
+		},
+		{
+			name:          "more events on a later page",
+			pages:         [][]testEvent{events(3, "RunInstances", "alice"), events(1, "RunInstances", "alice")},
+			maxResults:    3,
+			wantEvents:    3,
+			wantTruncated: true,
+		},
+		{
+			name:       "only events that don't match are skipped",
+			pages:      [][]testEvent{append(events(2, "RunInstances", "alice"), events(2, "RunInstances", "bob")...)},
+			maxResults: 2,
+			wantEvents: 2,
+		},
+		{
+			name:          "page limit reached",
+			pages:         make([][]testEvent, maxPages+1),
+			maxResults:    3,
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := call(t, newTestTool(t, tt.pages, nil), map[string]any{
+				parameterUsername:   "alice",
+				parameterMaxResults: float64(tt.maxResults),
+			})
+
+			if len(r.Events) != tt.wantEvents {
+				t.Errorf("got %d events, want %d", len(r.Events), tt.wantEvents)
+			}
+			if r.Truncated != tt.wantTruncated {
+				t.Errorf("got truncated %v, want %v", r.Truncated, tt.wantTruncated)
+			}
+			if (r.Note != "") != tt.wantTruncated {
+				t.Errorf("got note %q with truncated %v", r.Note, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestCallFilters(t *testing.T) {
+	page := append(events(1, "AuthorizeSecurityGroupIngress", "alice"), events(1, "AuthorizeSecurityGroupIngress", "bob")...)
+	page = append(page, events(1, "RunInstances", "alice")...)
+	page = append(page, testEvent{EventName: "DescribeSecurityGroups", Username: "alice", ReadOnly: "true"})
+	page[0].CloudTrailEvent = `{
+		"userIdentity": {"arn": "arn:aws:sts::111111111111:assumed-role/admin/alice"},
+		"sourceIPAddress": "203.0.113.10",
+		"requestParameters": {"groupId": "sg-0123456789abcdef0"}
+	}`
+
+	var requests []lookupRequest
+	r := call(t, newTestTool(t, [][]testEvent{page}, &requests), map[string]any{
+		parameterEventName: "authorizesecuritygroupingress",
+		parameterUsername:  "Alice",
+	})
+
+	if len(requests) != 1 || len(requests[0].LookupAttributes) != 1 || requests[0].LookupAttributes[0].AttributeKey != "EventName" {
+		t.Errorf("got requests %+v, want one filtered by event name", requests)
+	}
+	if len(r.Events) != 1 {
+		t.Fatalf("got events %+v, want alice's AuthorizeSecurityGroupIngress only", r.Events)
+	}
+
+	e := r.Events[0]
+	if e.PrincipalARN != "arn:aws:sts::111111111111:assumed-role/admin/alice" || e.SourceIPAddress != "203.0.113.10" {
+		t.Errorf("got principal %q & source IP %q, want the values from the CloudTrail record", e.PrincipalARN, e.SourceIPAddress)
+	}
+	if e.RequestParameters != `{"groupId": "sg-0123456789abcdef0"}` {
+		t.Errorf("got request parameters %q", e.RequestParameters)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "", want: testNow},
+		{value: "2025-04-01T10:00:00Z", want: time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2025-04-01", want: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "24h", want: testNow.Add(-24 * time.Hour)},
+		{value: "7d", want: testNow.Add(-7 * 24 * time.Hour)},
+		{value: "xd", wantErr: true},
+		{value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTime(tt.value, testNow, testNow)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTime(%q) got error %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -2,48 +2,138 @@ package dns
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
+	"slices"
+	"strings"
 
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
 )
 
-type Tool struct{}
+const (
+	parameterDomain     = "domain"
+	parameterRecordType = "record_type"
+)
+
+var recordTypes = []string{"A", "AAAA", "CNAME", "MX", "TXT", "NS", "SOA", "PTR", "SRV", "CAA"}
+
+type (
+	Opt func(*Tool)
+
+	Tool struct {
+		resolver *Resolver
+	}
+
+	result struct {
+		Domain   string `json:"domain"`
+		Resolver string `json:"resolver"`
+		Answer
+	}
+)
+
+// WithServer sets the resolver to query, as an IP address or host name, with an optional port. If
+// it's empty, the system's resolver is used.
+func WithServer(server string) Opt {
+	return func(t *Tool) {
+		t.resolver = NewResolver(server)
+	}
+}
+
+func New(opts ...Opt) tools.Function {
+	t := &Tool{
+		resolver: NewResolver(""),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
 
 func (t Tool) Name() string {
 	return "dns_record"
 }
 
 func (t Tool) Description() string {
-	return "Get the DNS record for a given domain"
+	return fmt.Sprintf(`Get the DNS records of a given domain, or the domain names of an IP address.
+CNAMEs are followed, and the chain of CNAMEs from the domain is returned along with the records at the end of it. CNAME chains show which service fronts a domain, e.g. a CloudFront distribution (*.cloudfront.net) or a load balancer (*.elb.amazonaws.com).
+The response is a JSON object with the DNS status (e.g. NOERROR, or NXDOMAIN if the domain doesn't exist), the CNAME chain & the records of the requested type. Names without a dot are tried in each search domain, and when the system's resolver is used, names in the hosts file are answered from it, with the file as the source.
+Use the %q parameter to get other records than addresses, e.g. MX, TXT or NS.`, parameterRecordType)
 }
 
 func (t Tool) ParameterDefinitions() []tools.ParameterDefinition {
+	enum := make([]any, len(recordTypes))
+	for i, rt := range recordTypes {
+		enum[i] = rt
+	}
+
 	return []tools.ParameterDefinition{
 		{
-			Name:        "domain",
-			Description: "The domain to get the DNS record for, e.g. google.com",
+			Name:        parameterDomain,
+			Description: "The domain to get the DNS records of, e.g. google.com, or an IP address to get the domain names of.",
 			Required:    true,
 			Type:        tools.ParameterTypeString,
 		},
+		{
+			Name:        parameterRecordType,
+			Description: "The type of record to get. If not set, A & AAAA records are returned for a domain, and PTR records for an IP address.",
+			Type:        tools.ParameterTypeString,
+			Enum:        enum,
+		},
 	}
 }
 
 func (t Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
-	domain, ok := parameters["domain"].(string)
-	if !ok {
-		return "", fmt.Errorf("format is not a string")
+	domain, ok := parameters[parameterDomain].(string)
+	if !ok || domain == "" {
+		return "", fmt.Errorf("%s is not a valid string", parameterDomain)
 	}
+	recordType, _ := parameters[parameterRecordType].(string)
+	recordType = strings.ToUpper(strings.TrimSpace(recordType))
 
-	addrs, err := net.DefaultResolver.LookupHost(ctx, domain)
-	if err != nil {
-		return "", fmt.Errorf("error resolving host: %w", err)
+	types := []string{"A", "AAAA"}
+	switch {
+	case recordType != "":
+		if !slices.Contains(recordTypes, recordType) {
+			return "", fmt.Errorf("%q is not a valid record type, it must be one of %v", recordType, recordTypes)
+		}
+		types = []string{recordType}
+	case net.ParseIP(domain) != nil:
+		types = []string{"PTR"}
+	}
+
+	// the PTR records of an IP address are its domain names
+	reverse := types[0] == "PTR" && net.ParseIP(domain) != nil
+
+	out := result{Domain: domain, Resolver: t.resolver.Server(), Answer: Answer{Records: []Record{}}}
+	for _, recordType := range types {
+		var (
+			answer Answer
+			err    error
+		)
+		if reverse {
+			answer, err = t.resolver.LookupAddr(ctx, domain)
+		} else {
+			answer, err = t.resolver.Lookup(ctx, domain, recordType)
+		}
+		if err != nil {
+			return "", err
+		}
+		// the chain is the same for every type, as a name with a CNAME can't have other records
+		if len(answer.CNAMEChain) > len(out.CNAMEChain) {
+			out.CNAMEChain = answer.CNAMEChain
+		}
+		out.Status = answer.Status
+		if answer.Source != "" {
+			out.Source = answer.Source
+		}
+		out.Records = append(out.Records, answer.Records...)
 	}
 
-	out := ""
-	for _, addr := range addrs {
-		out += "* " + addr + "\n"
+	outJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling DNS records to JSON: %w", err)
 	}
 
-	return out, nil
+	return string(outJSON), nil
 }
@@ -0,0 +1,81 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]any
+		wantStatus string
+		wantChain  []string
+		wantValues []string
+	}{
+		{
+			name:       "addresses by default",
+			parameters: map[string]any{parameterDomain: "www.example.test"},
+			wantStatus: "NOERROR",
+			wantChain:  []string{"www.example.test>lb.example.test", "lb.example.test>web-123.elb.test"},
+			wantValues: []string{"A 192.0.2.10", "A 192.0.2.11"},
+		},
+		{
+			name:       "names of an IP address",
+			parameters: map[string]any{parameterDomain: "192.0.2.10"},
+			wantStatus: "NOERROR",
+			wantChain:  []string{},
+			wantValues: []string{"PTR web-123.elb.test."},
+		},
+		{
+			name:       "record type",
+			parameters: map[string]any{parameterDomain: "example.test", parameterRecordType: "mx"},
+			wantStatus: "NOERROR",
+			wantChain:  []string{},
+			wantValues: []string{"MX 10 mail.example.test."},
+		},
+		{
+			name:       "missing domain",
+			parameters: map[string]any{parameterDomain: "missing.example.test"},
+			wantStatus: "NXDOMAIN",
+			wantChain:  []string{},
+			wantValues: []string{},
+		},
+	}
+
+	tool := Tool{resolver: newTestResolver(t)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tool.Call(context.Background(), tt.parameters)
+			if err != nil {
+				t.Fatalf("error calling tool: %v", err)
+			}
+			var got result
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("error unmarshalling result: %v", err)
+			}
+
+			if got.Status != tt.wantStatus {
+				t.Errorf("got status %s, want %s", got.Status, tt.wantStatus)
+			}
+			if chain := chainTargets(got.Answer); !slices.Equal(chain, tt.wantChain) {
+				t.Errorf("got CNAME chain %v, want %v", chain, tt.wantChain)
+			}
+			if values := recordValues(got.Answer); !slices.Equal(values, tt.wantValues) {
+				t.Errorf("got records %v, want %v", values, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestCallInvalidRecordType(t *testing.T) {
+	tool := Tool{resolver: newTestResolver(t)}
+
+	_, err := tool.Call(context.Background(), map[string]any{parameterDomain: "example.test", parameterRecordType: "AXFR"})
+	if err == nil || !strings.Contains(err.Error(), "is not a valid record type") {
+		t.Errorf("got error %v, want an invalid record type", err)
+	}
+}
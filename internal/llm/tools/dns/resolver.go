@@ -0,0 +1,413 @@
+package dns
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// resolvConf is read for the resolver to use if none is given, and for the search domains.
+	resolvConf = "/etc/resolv.conf"
+	// systemResolver is reported as the server when lookups go through the operating system.
+	systemResolver = "system"
+	// hostsFile is checked before DNS when the system's resolver is used.
+	hostsFile   = "/etc/hosts"
+	defaultPort = "53"
+	timeout     = 5 * time.Second
+	// maxChainLength is the most CNAMEs followed, in case of a loop.
+	maxChainLength = 10
+	// udpBufferSize is advertised with EDNS0, so that large answers such as TXT records don't
+	// need a retry over TCP.
+	udpBufferSize = 4096
+)
+
+type (
+	// Resolver looks up DNS records, following CNAME chains.
+	Resolver struct {
+		// server is the resolver's address, as host:port. If empty, the system's resolver is used.
+		server string
+		// config is the system's resolver config, giving the search domains. It's read from
+		// resolvConf when it's first needed.
+		mu         sync.Mutex
+		config     *dns.ClientConfig
+		resolvConf string
+		// hosts is the hosts file checked before DNS, if any.
+		hosts string
+		// system looks records up through the operating system if there's no server to query.
+		system *net.Resolver
+	}
+
+	// Answer is the result of a lookup.
+	Answer struct {
+		// Status is the response code, e.g. NOERROR or NXDOMAIN.
+		Status string `json:"status"`
+		// Source is set if the answer came from the hosts file rather than DNS.
+		Source string `json:"source,omitempty"`
+		// CNAMEChain is each CNAME followed from the name, in order.
+		CNAMEChain []Link   `json:"cname_chain,omitempty"`
+		Records    []Record `json:"records"`
+	}
+
+	// Link is a CNAME record.
+	Link struct {
+		Name   string `json:"name"`
+		Target string `json:"target"`
+		TTL    uint32 `json:"ttl"`
+	}
+
+	Record struct {
+		Name  string `json:"name"`
+		Type  string `json:"type"`
+		TTL   uint32 `json:"ttl"`
+		Value string `json:"value"`
+	}
+)
+
+// NewResolver creates a resolver that queries server, as an IP address or host name with an
+// optional port. If server is empty, the system's resolver from resolv.conf is used, and the hosts
+// file is checked before it as the system would. Without a usable resolv.conf, e.g. on Windows,
+// lookups go through the operating system instead, which supports fewer record types.
+func NewResolver(server string) *Resolver {
+	r := &Resolver{server: server, resolvConf: resolvConf, system: net.DefaultResolver}
+	if server == "" {
+		r.hosts = hostsFile
+	} else if _, _, err := net.SplitHostPort(server); err != nil {
+		r.server = net.JoinHostPort(server, defaultPort)
+	}
+	return r
+}
+
+// Server returns the address of the resolver that's queried, or "system" if lookups go through
+// the operating system.
+func (r *Resolver) Server() string {
+	if server, ok := r.dnsServer(); ok {
+		return server
+	}
+	return systemResolver
+}
+
+// dnsServer returns the address of the DNS server to query, or false if there's none, as no
+// server was given & resolv.conf is missing or has no servers.
+func (r *Resolver) dnsServer() (string, bool) {
+	if r.server != "" {
+		return r.server, true
+	}
+	config, err := r.clientConfig()
+	if err != nil || len(config.Servers) == 0 {
+		return "", false
+	}
+	return net.JoinHostPort(config.Servers[0], config.Port), true
+}
+
+// clientConfig returns the system's resolver config.
+func (r *Resolver) clientConfig() (*dns.ClientConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.config != nil {
+		return r.config, nil
+	}
+	config, err := dns.ClientConfigFromFile(r.resolvConf)
+	if err != nil {
+		return nil, err
+	}
+	r.config = config
+	return config, nil
+}
+
+// Lookup queries the records of a type for a name, e.g. A or MX, following CNAMEs until records
+// of the type are found. Recursive resolvers usually return the whole chain in one answer, but
+// it's followed with further queries if they don't.
+//
+// As with the system's resolver, a name without a dot is tried in each search domain, and the
+// hosts file is checked for addresses first if the system's resolver is used.
+func (r *Resolver) Lookup(ctx context.Context, name, recordType string) (Answer, error) {
+	qtype, ok := dns.StringToType[strings.ToUpper(recordType)]
+	if !ok {
+		return Answer{}, fmt.Errorf("%q is not a DNS record type", recordType)
+	}
+	server, ok := r.dnsServer()
+	if !ok {
+		return r.lookupSystem(ctx, name, qtype)
+	}
+
+	names := r.names(name)
+	var out Answer
+	for i, name := range names {
+		if qtype == dns.TypeA || qtype == dns.TypeAAAA {
+			answer, ok, err := r.lookupHosts(name, qtype)
+			if err != nil {
+				return Answer{}, err
+			}
+			if ok {
+				return answer, nil
+			}
+		}
+
+		var err error
+		out, err = r.follow(ctx, server, name, qtype)
+		if err != nil {
+			return Answer{}, err
+		}
+		// the next name is only tried if this one has no records
+		if len(out.Records) > 0 || len(out.CNAMEChain) > 0 || i == len(names)-1 {
+			break
+		}
+	}
+	return out, nil
+}
+
+// names returns the fully qualified names to try for a name, in order. Names without a dot are
+// tried in each search domain of the system's resolver config first.
+func (r *Resolver) names(name string) []string {
+	name = strings.TrimSuffix(name, ".")
+	if strings.Contains(name, ".") {
+		return []string{dns.Fqdn(name)}
+	}
+	// another server may be used without a resolver config, and then there are no search domains
+	config, err := r.clientConfig()
+	if err != nil {
+		return []string{dns.Fqdn(name)}
+	}
+	return config.NameList(name)
+}
+
+// follow queries the records of a type for a fully qualified name, following CNAMEs.
+func (r *Resolver) follow(ctx context.Context, server, name string, qtype uint16) (Answer, error) {
+	out := Answer{CNAMEChain: []Link{}, Records: []Record{}}
+	for range maxChainLength {
+		resp, err := exchange(ctx, server, name, qtype)
+		if err != nil {
+			return Answer{}, err
+		}
+		out.Status = dns.RcodeToString[resp.Rcode]
+
+		// the answer is in order, but is followed by name in case it isn't
+		current := name
+		followed := len(out.CNAMEChain)
+		for progress := true; progress && len(out.CNAMEChain) < maxChainLength; {
+			progress = false
+			for _, rr := range resp.Answer {
+				if !strings.EqualFold(rr.Header().Name, current) {
+					continue
+				}
+				if cname, ok := rr.(*dns.CNAME); ok && qtype != dns.TypeCNAME {
+					out.CNAMEChain = append(out.CNAMEChain, Link{Name: trimDot(current), Target: trimDot(cname.Target), TTL: rr.Header().Ttl})
+					current = cname.Target
+					progress = true
+					break
+				}
+			}
+		}
+		if len(out.CNAMEChain) >= maxChainLength {
+			break
+		}
+
+		for _, rr := range resp.Answer {
+			if rr.Header().Rrtype == qtype && strings.EqualFold(rr.Header().Name, current) {
+				out.Records = append(out.Records, toRecord(rr))
+			}
+		}
+
+		// stop unless the chain ends in a CNAME whose target wasn't resolved
+		if len(out.Records) > 0 || len(out.CNAMEChain) == followed || resp.Rcode != dns.RcodeSuccess {
+			return out, nil
+		}
+		name = current
+	}
+
+	return Answer{}, fmt.Errorf("CNAME chain is longer than %d, it may be a loop", maxChainLength)
+}
+
+// LookupAddr queries the PTR records of an IP address, i.e. its domain names. If the system's
+// resolver is used, the hosts file is checked first.
+func (r *Resolver) LookupAddr(ctx context.Context, address string) (Answer, error) {
+	answer, ok, err := r.lookupHosts(address, dns.TypePTR)
+	if err != nil || ok {
+		return answer, err
+	}
+	if _, ok := r.dnsServer(); !ok {
+		return r.lookupSystem(ctx, address, dns.TypePTR)
+	}
+
+	// PTR records of an IP address are looked up by its reverse name, e.g. 4.3.2.1.in-addr.arpa
+	reverse, err := dns.ReverseAddr(address)
+	if err != nil {
+		return Answer{}, fmt.Errorf("error building reverse name of %s: %w", address, err)
+	}
+	return r.Lookup(ctx, reverse, "PTR")
+}
+
+// lookupSystem looks up records through the operating system, for when there's no DNS server to
+// query. The system applies the hosts file & search domains itself, but doesn't give TTLs or each
+// link of a CNAME chain, and only supports some record types. For PTR records, name is an address.
+func (r *Resolver) lookupSystem(ctx context.Context, name string, qtype uint16) (Answer, error) {
+	name = trimDot(name)
+	out := Answer{Status: dns.RcodeToString[dns.RcodeSuccess], CNAMEChain: []Link{}, Records: []Record{}}
+	add := func(recordName, value string) {
+		out.Records = append(out.Records, Record{Name: trimDot(recordName), Type: dns.TypeToString[qtype], Value: value})
+	}
+
+	var err error
+	switch qtype {
+	case dns.TypeA, dns.TypeAAAA:
+		network := "ip4"
+		if qtype == dns.TypeAAAA {
+			network = "ip6"
+		}
+		var addrs []netip.Addr
+		addrs, err = r.system.LookupNetIP(ctx, network, name)
+		if err != nil {
+			break
+		}
+		canonical := name
+		if cname, err := r.system.LookupCNAME(ctx, name); err == nil && !strings.EqualFold(trimDot(cname), name) {
+			canonical = trimDot(cname)
+			out.CNAMEChain = append(out.CNAMEChain, Link{Name: name, Target: canonical})
+		}
+		for _, addr := range addrs {
+			add(canonical, addr.Unmap().String())
+		}
+	case dns.TypeCNAME:
+		var cname string
+		cname, err = r.system.LookupCNAME(ctx, name)
+		if err == nil && !strings.EqualFold(trimDot(cname), name) {
+			add(name, dns.Fqdn(cname))
+		}
+	case dns.TypeMX:
+		var mxs []*net.MX
+		mxs, err = r.system.LookupMX(ctx, name)
+		for _, mx := range mxs {
+			add(name, fmt.Sprintf("%d %s", mx.Pref, dns.Fqdn(mx.Host)))
+		}
+	case dns.TypeTXT:
+		var txts []string
+		txts, err = r.system.LookupTXT(ctx, name)
+		for _, txt := range txts {
+			add(name, strconv.Quote(txt))
+		}
+	case dns.TypeNS:
+		var nss []*net.NS
+		nss, err = r.system.LookupNS(ctx, name)
+		for _, ns := range nss {
+			add(name, dns.Fqdn(ns.Host))
+		}
+	case dns.TypePTR:
+		if net.ParseIP(name) == nil {
+			return Answer{}, fmt.Errorf("PTR records can only be looked up by IP address with the system's resolver, not %s", name)
+		}
+		var names []string
+		names, err = r.system.LookupAddr(ctx, name)
+		for _, n := range names {
+			add(name, dns.Fqdn(n))
+		}
+	default:
+		return Answer{}, fmt.Errorf("%s records can't be looked up with the system's resolver, so a DNS server must be given to look them up", dns.TypeToString[qtype])
+	}
+
+	// the system doesn't tell a name that doesn't exist from one without records of the type
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return Answer{Status: dns.RcodeToString[dns.RcodeNameError], CNAMEChain: []Link{}, Records: []Record{}}, nil
+	}
+	if err != nil {
+		return Answer{}, fmt.Errorf("error looking up %s %s records: %w", name, dns.TypeToString[qtype], err)
+	}
+	return out, nil
+}
+
+// lookupHosts returns the addresses of a name, or with TypePTR the names of an address, from the
+// hosts file, and false if it's not in the file.
+func (r *Resolver) lookupHosts(key string, qtype uint16) (Answer, bool, error) {
+	if r.hosts == "" {
+		return Answer{}, false, nil
+	}
+	f, err := os.Open(r.hosts)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Answer{}, false, nil
+	}
+	if err != nil {
+		return Answer{}, false, fmt.Errorf("error reading hosts file: %w", err)
+	}
+	defer f.Close()
+
+	key = strings.ToLower(trimDot(key))
+	keyIP := net.ParseIP(key)
+	out := Answer{Status: dns.RcodeToString[dns.RcodeSuccess], Source: r.hosts, CNAMEChain: []Link{}, Records: []Record{}}
+	found := false
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			continue
+		}
+
+		for _, host := range fields[1:] {
+			host = strings.ToLower(host)
+			switch {
+			case qtype == dns.TypePTR && keyIP != nil && ip.Equal(keyIP):
+				found = true
+				out.Records = append(out.Records, Record{Name: key, Type: "PTR", Value: dns.Fqdn(host)})
+			case qtype != dns.TypePTR && host == key:
+				found = true
+				if (ip.To4() != nil) == (qtype == dns.TypeA) {
+					out.Records = append(out.Records, Record{Name: key, Type: dns.TypeToString[qtype], Value: ip.String()})
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Answer{}, false, fmt.Errorf("error reading hosts file: %w", err)
+	}
+
+	return out, found, nil
+}
+
+// exchange sends a single query, retrying over TCP if the answer was truncated.
+func exchange(ctx context.Context, server, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.SetEdns0(udpBufferSize, false)
+
+	client := &dns.Client{Timeout: timeout}
+	resp, _, err := client.ExchangeContext(ctx, m, server)
+	if err == nil && resp.Truncated {
+		client.Net = "tcp"
+		resp, _, err = client.ExchangeContext(ctx, m, server)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying %s for %s %s records: %w", server, trimDot(name), dns.TypeToString[qtype], err)
+	}
+	return resp, nil
+}
+
+func toRecord(rr dns.RR) Record {
+	h := rr.Header()
+	return Record{
+		Name:  trimDot(h.Name),
+		Type:  dns.TypeToString[h.Rrtype],
+		TTL:   h.Ttl,
+		Value: strings.TrimPrefix(rr.String(), h.String()),
+	}
+}
+
+func trimDot(name string) string {
+	return strings.TrimSuffix(name, ".")
+}
@@ -0,0 +1,438 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// testZone is served by the test server. Each name has its records, and CNAMEs are followed by the
+// server unless their target is in noFollow, as with a recursive resolver.
+var testZone = map[string][]string{
+	"www.example.test.":        {"www.example.test. 300 IN CNAME lb.example.test."},
+	"lb.example.test.":         {"lb.example.test. 60 IN CNAME web-123.elb.test."},
+	"web-123.elb.test.":        {"web-123.elb.test. 60 IN A 192.0.2.10", "web-123.elb.test. 60 IN A 192.0.2.11"},
+	"split.example.test.":      {"split.example.test. 300 IN CNAME cdn.other.test."},
+	"cdn.other.test.":          {"cdn.other.test. 60 IN A 192.0.2.30"},
+	"loop-a.test.":             {"loop-a.test. 60 IN CNAME loop-b.test."},
+	"loop-b.test.":             {"loop-b.test. 60 IN CNAME loop-a.test."},
+	"intranet.corp.test.":      {"intranet.corp.test. 60 IN A 192.0.2.20"},
+	"10.2.0.192.in-addr.arpa.": {"10.2.0.192.in-addr.arpa. 3600 IN PTR web-123.elb.test."},
+	"big.example.test.":        {`big.example.test. 60 IN TXT "v=spf1 -all"`, `big.example.test. 60 IN TXT "verification=abc"`},
+	"example.test.":            {"example.test. 300 IN MX 10 mail.example.test."},
+	"printer.example.test.":    {"printer.example.test. 60 IN A 192.0.2.40"},
+	"cname-only.example.test.": {"cname-only.example.test. 60 IN CNAME dangling.example.test."},
+	"dangling.example.test.":   {},
+}
+
+// noFollow are CNAME targets the server doesn't follow, so the resolver has to.
+var noFollow = map[string]bool{"cdn.other.test.": true}
+
+// newTestServer starts a DNS server on a local UDP & TCP port serving testZone, returning its
+// address. Over UDP, big.example.test is truncated so it has to be retried over TCP.
+func newTestServer(t *testing.T) string {
+	t.Helper()
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.RecursionAvailable = true
+		q := req.Question[0]
+		name := strings.ToLower(q.Name)
+
+		if _, udp := w.RemoteAddr().(*net.UDPAddr); udp && name == "big.example.test." {
+			m.Truncated = true
+			w.WriteMsg(m)
+			return
+		}
+
+		for range 20 {
+			records, ok := testZone[name]
+			if !ok {
+				if len(m.Answer) == 0 {
+					m.Rcode = dns.RcodeNameError
+				}
+				break
+			}
+			next := ""
+			for _, s := range records {
+				rr, err := dns.NewRR(s)
+				if err != nil {
+					t.Errorf("error parsing %q: %v", s, err)
+					continue
+				}
+				if cname, ok := rr.(*dns.CNAME); ok && q.Qtype != dns.TypeCNAME {
+					m.Answer = append(m.Answer, rr)
+					next = cname.Target
+				} else if rr.Header().Rrtype == q.Qtype {
+					m.Answer = append(m.Answer, rr)
+				}
+			}
+			if next == "" || noFollow[next] {
+				break
+			}
+			name = next
+		}
+		w.WriteMsg(m)
+	})
+
+	// the TCP listener is on the same port as UDP, which may be taken, so a few ports are tried
+	var (
+		pc  net.PacketConn
+		l   net.Listener
+		err error
+	)
+	for range 10 {
+		pc, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("error listening on UDP: %v", err)
+		}
+		l, err = net.Listen("tcp", pc.LocalAddr().String())
+		if err == nil {
+			break
+		}
+		pc.Close()
+	}
+	if err != nil {
+		t.Fatalf("error listening on TCP: %v", err)
+	}
+
+	udpServer := &dns.Server{PacketConn: pc, Handler: handler}
+	tcpServer := &dns.Server{Listener: l, Handler: handler}
+	for _, s := range []*dns.Server{udpServer, tcpServer} {
+		started := make(chan struct{})
+		s.NotifyStartedFunc = func() { close(started) }
+		go s.ActivateAndServe()
+		<-started
+		t.Cleanup(func() { s.Shutdown() })
+	}
+
+	return pc.LocalAddr().String()
+}
+
+// newTestResolver returns a resolver querying the test server, with corp.test as its search
+// domain & no hosts file.
+func newTestResolver(t *testing.T) *Resolver {
+	t.Helper()
+
+	r := NewResolver(newTestServer(t))
+	r.config = &dns.ClientConfig{Search: []string{"corp.test"}, Ndots: 1}
+	return r
+}
+
+// newSystemResolver returns a resolver without a usable resolv.conf, so lookups go through a Go
+// resolver, standing in for the operating system's, that queries the test server.
+func newSystemResolver(t *testing.T) *Resolver {
+	t.Helper()
+
+	server := newTestServer(t)
+	r := NewResolver("")
+	r.resolvConf = filepath.Join(t.TempDir(), "missing")
+	r.hosts = ""
+	r.system = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+	return r
+}
+
+func recordValues(a Answer) []string {
+	out := []string{}
+	for _, r := range a.Records {
+		out = append(out, r.Type+" "+r.Value)
+	}
+	return out
+}
+
+func chainTargets(a Answer) []string {
+	out := []string{}
+	for _, l := range a.CNAMEChain {
+		out = append(out, l.Name+">"+l.Target)
+	}
+	return out
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		domain     string
+		recordType string
+		wantStatus string
+		wantChain  []string
+		wantValues []string
+	}{
+		{
+			name:       "CNAME chain in one answer",
+			domain:     "www.example.test",
+			recordType: "A",
+			wantStatus: "NOERROR",
+			wantChain:  []string{"www.example.test>lb.example.test", "lb.example.test>web-123.elb.test"},
+			wantValues: []string{"A 192.0.2.10", "A 192.0.2.11"},
+		},
+		{
+			name:       "CNAME chain over several queries",
+			domain:     "split.example.test.",
+			recordType: "a",
+			wantStatus: "NOERROR",
+			wantChain:  []string{"split.example.test>cdn.other.test"},
+			wantValues: []string{"A 192.0.2.30"},
+		},
+		{
+			name:       "CNAME records aren't followed",
+			domain:     "www.example.test",
+			recordType: "CNAME",
+			wantStatus: "NOERROR",
+			wantChain:  []string{},
+			wantValues: []string{"CNAME lb.example.test."},
+		},
+		{
+			name:       "CNAME to a name without records",
+			domain:     "cname-only.example.test",
+			recordType: "A",
+			wantStatus: "NOERROR",
+			wantChain:  []string{"cname-only.example.test>dangling.example.test"},
+			wantValues: []string{},
+		},
+		{
+			name:       "NXDOMAIN",
+			domain:     "missing.example.test",
+			recordType: "A",
+			wantStatus: "NXDOMAIN",
+			wantChain:  []string{},
+			wantValues: []string{},
+		},
+		{
+			name:       "MX",
+			domain:     "example.test",
+			recordType: "MX",
+			wantStatus: "NOERROR",
+			wantChain:  []string{},
+			wantValues: []string{"MX 10 mail.example.test."},
+		},
+		{
+			name:       "truncated answer retried over TCP",
+			domain:     "big.example.test",
+			recordType: "TXT",
+			wantStatus: "NOERROR",
+			wantChain:  []string{},
+			wantValues: []string{`TXT "v=spf1 -all"`, `TXT "verification=abc"`},
+		},
+		{
+			name:       "search domain",
+			domain:     "intranet",
+			recordType: "A",
+			wantStatus: "NOERROR",
+			wantChain:  []string{},
+			wantValues: []string{"A 192.0.2.20"},
+		},
+		{
+			name:       "search domains aren't used for names with a dot",
+			domain:     "intranet.example",
+			recordType: "A",
+			wantStatus: "NXDOMAIN",
+			wantChain:  []string{},
+			wantValues: []string{},
+		},
+		{
+			name:       "name without a dot missing from the search domains",
+			domain:     "nowhere",
+			recordType: "A",
+			wantStatus: "NXDOMAIN",
+			wantChain:  []string{},
+			wantValues: []string{},
+		},
+	}
+
+	r := newTestResolver(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Lookup(context.Background(), tt.domain, tt.recordType)
+			if err != nil {
+				t.Fatalf("error looking up %s: %v", tt.domain, err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("got status %s, want %s", got.Status, tt.wantStatus)
+			}
+			if chain := chainTargets(got); !slices.Equal(chain, tt.wantChain) {
+				t.Errorf("got CNAME chain %v, want %v", chain, tt.wantChain)
+			}
+			if values := recordValues(got); !slices.Equal(values, tt.wantValues) {
+				t.Errorf("got records %v, want %v", values, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestLookupLoop(t *testing.T) {
+	_, err := newTestResolver(t).Lookup(context.Background(), "loop-a.test", "A")
+	if err == nil || !strings.Contains(err.Error(), "it may be a loop") {
+		t.Errorf("got error %v, want a loop", err)
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	r := newTestResolver(t)
+
+	if _, err := r.Lookup(context.Background(), "example.test", "BOGUS"); err == nil || !strings.Contains(err.Error(), "is not a DNS record type") {
+		t.Errorf("got error %v, want an invalid record type", err)
+	}
+	if _, err := r.LookupAddr(context.Background(), "not-an-ip"); err == nil {
+		t.Errorf("got no error for an invalid address")
+	}
+}
+
+func TestLookupAddr(t *testing.T) {
+	got, err := newTestResolver(t).LookupAddr(context.Background(), "192.0.2.10")
+	if err != nil {
+		t.Fatalf("error looking up address: %v", err)
+	}
+	if values := recordValues(got); !slices.Equal(values, []string{"PTR web-123.elb.test."}) {
+		t.Errorf("got records %v, want the load balancer's name", values)
+	}
+}
+
+func TestLookupHosts(t *testing.T) {
+	hosts := filepath.Join(t.TempDir(), "hosts")
+	err := os.WriteFile(hosts, []byte(`# comment
+127.0.0.1 localhost
+192.0.2.99 printer.example.test printer # the office printer
+2001:db8::99 printer.example.test
+`), 0o644)
+	if err != nil {
+		t.Fatalf("error writing hosts file: %v", err)
+	}
+	r := newTestResolver(t)
+	r.hosts = hosts
+
+	tests := []struct {
+		name       string
+		domain     string
+		recordType string
+		wantValues []string
+		wantSource string
+	}{
+		{name: "hosts file before DNS", domain: "printer.example.test", recordType: "A", wantValues: []string{"A 192.0.2.99"}, wantSource: hosts},
+		{name: "IPv6 from the hosts file", domain: "PRINTER.example.test.", recordType: "AAAA", wantValues: []string{"AAAA 2001:db8::99"}, wantSource: hosts},
+		{name: "short name in the hosts file", domain: "printer", recordType: "A", wantValues: []string{"A 192.0.2.99"}, wantSource: hosts},
+		{name: "other types from DNS", domain: "example.test", recordType: "MX", wantValues: []string{"MX 10 mail.example.test."}},
+		{name: "names not in the hosts file from DNS", domain: "intranet", recordType: "A", wantValues: []string{"A 192.0.2.20"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Lookup(context.Background(), tt.domain, tt.recordType)
+			if err != nil {
+				t.Fatalf("error looking up %s: %v", tt.domain, err)
+			}
+			if values := recordValues(got); !slices.Equal(values, tt.wantValues) {
+				t.Errorf("got records %v, want %v", values, tt.wantValues)
+			}
+			if got.Source != tt.wantSource {
+				t.Errorf("got source %q, want %q", got.Source, tt.wantSource)
+			}
+		})
+	}
+
+	got, err := r.LookupAddr(context.Background(), "192.0.2.99")
+	if err != nil {
+		t.Fatalf("error looking up address: %v", err)
+	}
+	if values := recordValues(got); !slices.Equal(values, []string{"PTR printer.example.test.", "PTR printer."}) {
+		t.Errorf("got records %v, want the names in the hosts file", values)
+	}
+}
+
+func TestLookupSystem(t *testing.T) {
+	tests := []struct {
+		name       string
+		domain     string
+		recordType string
+		wantStatus string
+		wantChain  []string
+		wantValues []string
+	}{
+		{
+			name:       "CNAME chain",
+			domain:     "www.example.test",
+			recordType: "A",
+			wantStatus: "NOERROR",
+			wantChain:  []string{"www.example.test>web-123.elb.test"},
+			wantValues: []string{"A 192.0.2.10", "A 192.0.2.11"},
+		},
+		{
+			name:       "CNAME",
+			domain:     "www.example.test",
+			recordType: "CNAME",
+			wantStatus: "NOERROR",
+			wantChain:  []string{},
+			wantValues: []string{"CNAME web-123.elb.test."},
+		},
+		{
+			name:       "MX",
+			domain:     "example.test",
+			recordType: "MX",
+			wantStatus: "NOERROR",
+			wantChain:  []string{},
+			wantValues: []string{"MX 10 mail.example.test."},
+		},
+		{
+			name:       "TXT",
+			domain:     "big.example.test",
+			recordType: "TXT",
+			wantStatus: "NOERROR",
+			wantChain:  []string{},
+			wantValues: []string{`TXT "v=spf1 -all"`, `TXT "verification=abc"`},
+		},
+		{
+			name:       "NXDOMAIN",
+			domain:     "missing.example.test",
+			recordType: "A",
+			wantStatus: "NXDOMAIN",
+			wantChain:  []string{},
+			wantValues: []string{},
+		},
+	}
+
+	r := newSystemResolver(t)
+	if server := r.Server(); server != "system" {
+		t.Errorf("got server %q, want the system's resolver", server)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Lookup(context.Background(), tt.domain, tt.recordType)
+			if err != nil {
+				t.Fatalf("error looking up %s: %v", tt.domain, err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("got status %s, want %s", got.Status, tt.wantStatus)
+			}
+			if chain := chainTargets(got); !slices.Equal(chain, tt.wantChain) {
+				t.Errorf("got CNAME chain %v, want %v", chain, tt.wantChain)
+			}
+			if values := recordValues(got); !slices.Equal(values, tt.wantValues) {
+				t.Errorf("got records %v, want %v", values, tt.wantValues)
+			}
+		})
+	}
+
+	got, err := r.LookupAddr(context.Background(), "192.0.2.10")
+	if err != nil {
+		t.Fatalf("error looking up address: %v", err)
+	}
+	if values := recordValues(got); !slices.Equal(values, []string{"PTR web-123.elb.test."}) {
+		t.Errorf("got records %v, want the load balancer's name", values)
+	}
+
+	if _, err := r.Lookup(context.Background(), "example.test", "SOA"); err == nil || !strings.Contains(err.Error(), "can't be looked up with the system's resolver") {
+		t.Errorf("got error %v, want an unsupported record type", err)
+	}
+}
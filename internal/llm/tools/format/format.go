@@ -0,0 +1,75 @@
+// Package format renders tool results for the model. Small models tend to reason better over
+// compact tables than over verbose JSON, so tools let the format be chosen per tool & per call.
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+type Format string
+
+const (
+	JSON Format = "json"
+	// Table is column aligned plain text.
+	Table    Format = "table"
+	CSV      Format = "csv"
+	Markdown Format = "markdown"
+
+	ParameterFormat = "format"
+
+	// MaxValueLength is the longest a single value can be before it's elided.
+	MaxValueLength = 500
+)
+
+var Formats = []Format{JSON, Table, CSV, Markdown}
+
+// Parse returns the format with the given name.
+func Parse(name string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(name, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a valid format, it must be one of %v", name, Formats)
+}
+
+// ParameterDefinition returns the definition of the per call format parameter.
+func ParameterDefinition(def Format) tools.ParameterDefinition {
+	enum := make([]any, 0, len(Formats))
+	for _, f := range Formats {
+		enum = append(enum, string(f))
+	}
+
+	return tools.ParameterDefinition{
+		Name:        ParameterFormat,
+		Description: fmt.Sprintf("The format of the response. Defaults to %s.", def),
+		Type:        tools.ParameterTypeString,
+		Enum:        enum,
+	}
+}
+
+// FromParameters returns the format requested by the format parameter, or def if it isn't set.
+func FromParameters(parameters map[string]any, def Format) (Format, error) {
+	name, _ := parameters[ParameterFormat].(string)
+	if name == "" {
+		return def, nil
+	}
+	return Parse(name)
+}
+
+// Describe returns a short description of the shape of a result in this format, for tool descriptions.
+func (f Format) Describe() string {
+	switch f {
+	case Table:
+		return "a column aligned text table"
+	case CSV:
+		return "CSV with a header row"
+	case Markdown:
+		return "a markdown table"
+	default:
+		return "JSON"
+	}
+}
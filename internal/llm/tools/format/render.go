@@ -0,0 +1,344 @@
+package format
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rows renders a result set. Columns that are null in every row are dropped, and long values are elided.
+func (f Format) Rows(columns []string, rows [][]any) (string, error) {
+	columns, rows = dropNullColumns(columns, rows)
+
+	if f == JSON {
+		// objects are built by hand to keep the column order of the query
+		var b bytes.Buffer
+		b.WriteByte('[')
+		for i, row := range rows {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('{')
+			for j, c := range columns {
+				if j > 0 {
+					b.WriteByte(',')
+				}
+				if err := writeJSON(&b, c); err != nil {
+					return "", err
+				}
+				b.WriteByte(':')
+				if err := writeJSON(&b, elide(row[j])); err != nil {
+					return "", err
+				}
+			}
+			b.WriteByte('}')
+		}
+		b.WriteByte(']')
+		return b.String(), nil
+	}
+
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([]string, len(row))
+		for j, v := range row {
+			cells[i][j] = cell(v)
+		}
+	}
+
+	return f.grid(columns, cells)
+}
+
+// Object renders a single JSON like value, such as a resource's properties. Null properties are
+// dropped, and long values are elided. Tabular formats list one property per row, with nested
+// properties flattened into dotted paths.
+func (f Format) Object(v any) (string, error) {
+	v = dropNulls(v)
+
+	if f == JSON {
+		out, err := json.Marshal(elide(v))
+		if err != nil {
+			return "", fmt.Errorf("error marshalling value to JSON: %w", err)
+		}
+		return string(out), nil
+	}
+
+	cells := [][]string{}
+	flatten("", v, func(path string, leaf any) {
+		cells = append(cells, []string{path, cell(leaf)})
+	})
+
+	return f.grid([]string{"property", "value"}, cells)
+}
+
+// Column renders a list of values, such as resource identifiers. In JSON it's an array of strings,
+// and in tabular formats it's a single column with the given name.
+func (f Format) Column(name string, values []string) (string, error) {
+	if f == JSON {
+		out, err := json.Marshal(values)
+		if err != nil {
+			return "", fmt.Errorf("error marshalling values to JSON: %w", err)
+		}
+		return string(out), nil
+	}
+
+	cells := make([][]string, len(values))
+	for i, v := range values {
+		cells[i] = []string{elideString(v)}
+	}
+
+	return f.grid([]string{name}, cells)
+}
+
+func (f Format) grid(columns []string, cells [][]string) (string, error) {
+	switch f {
+	case CSV:
+		var b bytes.Buffer
+		w := csv.NewWriter(&b)
+		if err := w.Write(columns); err != nil {
+			return "", fmt.Errorf("error writing CSV: %w", err)
+		}
+		if err := w.WriteAll(cells); err != nil {
+			return "", fmt.Errorf("error writing CSV: %w", err)
+		}
+		return b.String(), nil
+	case Markdown:
+		return markdown(columns, cells), nil
+	case Table:
+		return table(columns, cells), nil
+	default:
+		return "", fmt.Errorf("unsupported format %q", f)
+	}
+}
+
+func table(columns []string, cells [][]string) string {
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = utf8.RuneCountInString(c)
+	}
+	for _, row := range cells {
+		for i, c := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(c))
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		for i, c := range row {
+			if i == len(row)-1 {
+				// no trailing padding on the last column
+				b.WriteString(c)
+				break
+			}
+			b.WriteString(c)
+			b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c)+2))
+		}
+		b.WriteByte('\n')
+	}
+
+	writeRow(columns)
+	separators := make([]string, len(columns))
+	for i, w := range widths {
+		separators[i] = strings.Repeat("-", w)
+	}
+	writeRow(separators)
+	for _, row := range cells {
+		writeRow(row)
+	}
+
+	return b.String()
+}
+
+func markdown(columns []string, cells [][]string) string {
+	escape := func(s string) string {
+		return strings.ReplaceAll(s, "|", `\|`)
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for _, c := range row {
+			b.WriteString(" ")
+			b.WriteString(escape(c))
+			b.WriteString(" |")
+		}
+		b.WriteByte('\n')
+	}
+
+	writeRow(columns)
+	b.WriteString("|")
+	b.WriteString(strings.Repeat(" --- |", len(columns)))
+	b.WriteByte('\n')
+	for _, row := range cells {
+		writeRow(row)
+	}
+
+	return b.String()
+}
+
+// cell returns a value as a single line of text for a tabular format.
+func cell(v any) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		s = v
+	case bool:
+		s = strconv.FormatBool(v)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		out, err := json.Marshal(v)
+		if err != nil {
+			s = fmt.Sprint(v)
+		} else {
+			s = string(out)
+		}
+	}
+
+	s = strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`).Replace(s)
+	return elideString(s)
+}
+
+// flatten calls fn for each leaf of v, with the dotted path to it. Empty objects & arrays are leaves.
+func flatten(path string, v any, fn func(string, any)) {
+	join := func(k string) string {
+		if path == "" {
+			return k
+		}
+		return path + "." + k
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			fn(path, v)
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flatten(join(k), v[k], fn)
+		}
+	case []any:
+		if len(v) == 0 {
+			fn(path, v)
+			return
+		}
+		for i, child := range v {
+			flatten(join(strconv.Itoa(i)), child, fn)
+		}
+	default:
+		fn(path, v)
+	}
+}
+
+// dropNullColumns removes the columns that are null in every row.
+func dropNullColumns(columns []string, rows [][]any) ([]string, [][]any) {
+	keep := make([]bool, len(columns))
+	for _, row := range rows {
+		for i, v := range row {
+			if v != nil {
+				keep[i] = true
+			}
+		}
+	}
+	// a result that's entirely null is kept as is, so its columns are still shown
+	if !slices.Contains(keep, false) || !slices.Contains(keep, true) {
+		return columns, rows
+	}
+
+	outColumns := []string{}
+	for i, c := range columns {
+		if keep[i] {
+			outColumns = append(outColumns, c)
+		}
+	}
+	outRows := make([][]any, len(rows))
+	for r, row := range rows {
+		for i, v := range row {
+			if keep[i] {
+				outRows[r] = append(outRows[r], v)
+			}
+		}
+	}
+
+	return outColumns, outRows
+}
+
+// dropNulls removes null properties from objects, recursively.
+func dropNulls(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, child := range v {
+			if child != nil {
+				out[k] = dropNulls(child)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			out[i] = dropNulls(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// elide shortens long strings anywhere in v.
+func elide(v any) any {
+	switch v := v.(type) {
+	case string:
+		return elideString(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, child := range v {
+			out[k] = elide(child)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			out[i] = elide(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func elideString(s string) string {
+	if len(s) <= MaxValueLength {
+		return s
+	}
+
+	// cut on a rune boundary
+	cut := MaxValueLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(%d more characters)", s[:cut], utf8.RuneCountInString(s[cut:]))
+}
+
+func writeJSON(b *bytes.Buffer, v any) error {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error marshalling value to JSON: %w", err)
+	}
+	b.Write(out)
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package k8s provides tools to discover resources in Kubernetes clusters, using the clusters &
+// credentials of the contexts in a kubeconfig.
+package k8s
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+type (
+	// Clients returns a Kubernetes client for each kubeconfig context.
+	Clients interface {
+		// Contexts lists the contexts that can be used, the default first.
+		Contexts() []string
+		// Client returns the client of a context, or of the default context if it's empty.
+		Client(context string) (kubernetes.Interface, error)
+	}
+
+	kubeconfigClients struct {
+		loadingRules *clientcmd.ClientConfigLoadingRules
+		contexts     []string
+
+		mu      sync.Mutex
+		clients map[string]kubernetes.Interface
+	}
+
+	staticClients struct {
+		client kubernetes.Interface
+	}
+)
+
+// NewKubeconfigClients loads the kubeconfig at path, or if it's empty the kubeconfig kubectl would
+// use, from KUBECONFIG or ~/.kube/config. Clients are created the first time each context is used.
+func NewKubeconfigClients(path string) (Clients, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if path != "" {
+		rules.ExplicitPath = path
+	}
+
+	config, err := rules.Load()
+	if err != nil {
+		return nil, fmt.Errorf("error loading kubeconfig: %w", err)
+	}
+
+	contexts := []string{}
+	for name := range config.Contexts {
+		if name != config.CurrentContext {
+			contexts = append(contexts, name)
+		}
+	}
+	slices.Sort(contexts)
+	if _, ok := config.Contexts[config.CurrentContext]; ok {
+		contexts = append([]string{config.CurrentContext}, contexts...)
+	}
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("no contexts found in kubeconfig")
+	}
+
+	return &kubeconfigClients{
+		loadingRules: rules,
+		contexts:     contexts,
+		clients:      map[string]kubernetes.Interface{},
+	}, nil
+}
+
+// NewStaticClients uses the same client for every context, e.g. a fake clientset.
+func NewStaticClients(client kubernetes.Interface) Clients {
+	return staticClients{client: client}
+}
+
+func (c *kubeconfigClients) Contexts() []string {
+	return c.contexts
+}
+
+func (c *kubeconfigClients) Client(context string) (kubernetes.Interface, error) {
+	if context == "" {
+		context = c.contexts[0]
+	}
+	if !slices.Contains(c.contexts, context) {
+		return nil, fmt.Errorf("%q is not a kubeconfig context, it must be one of %v", context, c.contexts)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[context]; ok {
+		return client, nil
+	}
+
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(c.loadingRules, &clientcmd.ConfigOverrides{CurrentContext: context}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config of context %s: %w", context, err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating client for context %s: %w", context, err)
+	}
+
+	c.clients[context] = client
+	return client, nil
+}
+
+func (c staticClients) Contexts() []string {
+	return []string{}
+}
+
+func (c staticClients) Client(string) (kubernetes.Interface, error) {
+	return c.client, nil
+}
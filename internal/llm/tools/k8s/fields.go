@@ -0,0 +1,122 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// annotationLastApplied holds a copy of the whole resource as last applied by kubectl, so is left out.
+const annotationLastApplied = "kubectl.kubernetes.io/last-applied-configuration"
+
+// toMap converts a typed resource into its JSON form, without the fields that are noise to the model.
+func toMap(k kind, obj runtime.Object) (map[string]any, error) {
+	out, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("error converting %s: %w", k.singular, err)
+	}
+
+	// the clientset doesn't set the type of the resources it decodes
+	out["apiVersion"] = k.apiVersion
+	out["kind"] = k.kind
+
+	if metadata, ok := out["metadata"].(map[string]any); ok {
+		delete(metadata, "managedFields")
+		if annotations, ok := metadata["annotations"].(map[string]any); ok {
+			delete(annotations, annotationLastApplied)
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// listItems converts the items of a typed list into their JSON form.
+func listItems(k kind, list runtime.Object) ([]map[string]any, error) {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", k.plural, err)
+	}
+
+	out := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		m, err := toMap(k, item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// parseFields splits a comma separated list of field paths.
+func parseFields(s string) []string {
+	out := []string{}
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, strings.TrimPrefix(f, "."))
+		}
+	}
+	return out
+}
+
+// project returns the value of each field path in obj, by path. Fields that aren't set are null.
+func project(obj map[string]any, fields []string) map[string]any {
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		out[f], _ = lookup(obj, f)
+	}
+	return out
+}
+
+// lookup returns the value at a dotted path, e.g. spec.serviceAccountName. Label & annotation keys
+// often contain dots themselves, e.g. metadata.annotations.eks.amazonaws.com/role-arn, so the
+// longest key matching the start of the path is used at each level. Path elements after a list
+// are looked up in each element of it, e.g. spec.containers.image returns every container's image,
+// unless they're an index, e.g. spec.containers.0.image.
+func lookup(v any, path string) (any, bool) {
+	if path == "" {
+		return v, true
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		if value, ok := v[path]; ok {
+			return value, true
+		}
+		key := ""
+		for k := range v {
+			if len(k) > len(key) && strings.HasPrefix(path, k+".") {
+				key = k
+			}
+		}
+		if key == "" {
+			return nil, false
+		}
+		return lookup(v[key], path[len(key)+1:])
+
+	case []any:
+		head, rest, _ := strings.Cut(path, ".")
+		if i, err := strconv.Atoi(head); err == nil {
+			if i < 0 || i >= len(v) {
+				return nil, false
+			}
+			return lookup(v[i], rest)
+		}
+
+		out := []any{}
+		for _, e := range v {
+			if value, ok := lookup(e, path); ok {
+				out = append(out, value)
+			}
+		}
+		return out, len(out) > 0
+	}
+
+	return nil, false
+}
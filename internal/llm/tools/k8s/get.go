@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type GetTool struct {
+	tool
+}
+
+func NewGetTool(clients Clients, opts ...Opt) tools.Function {
+	return &GetTool{tool: newTool(clients, opts)}
+}
+
+func (t *GetTool) Name() string {
+	return "get_k8s_resource"
+}
+
+func (t *GetTool) Description() string {
+	shape := "a JSON object containing the resource's fields"
+	if t.format != format.JSON {
+		shape = fmt.Sprintf("the resource's fields as %s, with one field per row and nested fields flattened into dotted paths", t.format.Describe())
+	}
+
+	return fmt.Sprintf(`This tool gets a single resource from a Kubernetes cluster, such as a pod or a service account.
+The tool returns %s. Resources can be large, so use the %q parameter to get only the fields you need.
+Use the list_k8s_resources tool to find the names of resources.`, shape, parameterFields)
+}
+
+func (t *GetTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		t.kindParameter(),
+		{
+			Name:        parameterName,
+			Description: "The name of the resource, e.g. web-7d4b9c8f6-x2k4p.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterNamespace,
+			Description: fmt.Sprintf("The namespace of the resource. Defaults to %s. Ignored for kinds that aren't namespaced, such as nodes.", metav1.NamespaceDefault),
+			Type:        tools.ParameterTypeString,
+		},
+		t.contextParameter(),
+		t.fieldsParameter("The fields of the resource to return, rather than all of them"),
+		format.ParameterDefinition(t.format),
+	}
+}
+
+func (t *GetTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	k, client, err := t.resolve(parameters)
+	if err != nil {
+		return "", err
+	}
+
+	name, ok := parameters[parameterName].(string)
+	if !ok || name == "" {
+		return "", fmt.Errorf("%s is not a valid string", parameterName)
+	}
+	namespace, _ := parameters[parameterNamespace].(string)
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	fieldsParameter, _ := parameters[parameterFields].(string)
+
+	f, err := format.FromParameters(parameters, t.format)
+	if err != nil {
+		return "", err
+	}
+
+	obj, err := k.resources(client, namespace).get(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("error getting %s %s: %w", k.singular, name, err)
+	}
+	out, err := toMap(k, obj)
+	if err != nil {
+		return "", err
+	}
+
+	if fields := parseFields(fieldsParameter); len(fields) > 0 {
+		return f.Object(project(out, fields))
+	}
+	return f.Object(out)
+}
@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetCall(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]any
+		want       string
+	}{
+		{
+			name:       "fields",
+			parameters: map[string]any{parameterKind: "pod", parameterName: "web-1", parameterNamespace: "shop", parameterFields: "spec.nodeName,status.phase,spec.hostname"},
+			want:       `{"spec.nodeName":"node-a","status.phase":"Running"}`,
+		},
+		{
+			name:       "kind that isn't namespaced",
+			parameters: map[string]any{parameterKind: "nodes", parameterName: "node-a", parameterNamespace: "shop", parameterFields: "spec.providerID"},
+			want:       `{"spec.providerID":"aws:///eu-west-1a/i-0123456789abcdef0"}`,
+		},
+	}
+
+	tool := NewGetTool(NewStaticClients(fake.NewClientset(testObjects()...)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tool.Call(context.Background(), tt.parameters)
+			if err != nil {
+				t.Fatalf("error calling tool: %v", err)
+			}
+			if strings.TrimSpace(out) != tt.want {
+				t.Errorf("got %s, want %s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCallWholeResource(t *testing.T) {
+	tool := NewGetTool(NewStaticClients(fake.NewClientset(testObjects()...)))
+
+	out, err := tool.Call(context.Background(), map[string]any{parameterKind: "sa", parameterName: "web", parameterNamespace: "shop"})
+	if err != nil {
+		t.Fatalf("error calling tool: %v", err)
+	}
+
+	var got struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("error unmarshalling %q: %v", out, err)
+	}
+	if got.APIVersion != "v1" || got.Kind != "ServiceAccount" || got.Metadata.Name != "web" {
+		t.Errorf("got %+v, want the web service account", got)
+	}
+	if _, ok := got.Metadata.Annotations[annotationLastApplied]; ok || got.Metadata.Annotations[annotationRoleARN] == "" {
+		t.Errorf("got annotations %v, want only the role ARN", got.Metadata.Annotations)
+	}
+}
+
+func TestGetCallErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]any
+		want       string
+	}{
+		{name: "no name", parameters: map[string]any{parameterKind: "pods"}, want: "name is not a valid string"},
+		{name: "not found", parameters: map[string]any{parameterKind: "pods", parameterName: "web-1"}, want: `error getting pod web-1: pods "web-1" not found`},
+		{name: "unsupported kind", parameters: map[string]any{parameterKind: "secret", parameterName: "db"}, want: `"secret" is not a supported kind`},
+	}
+
+	tool := NewGetTool(NewStaticClients(fake.NewClientset(testObjects()...)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tool.Call(context.Background(), tt.parameters)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// annotationRoleARN is the annotation of a service account giving the IAM role its pods assume
+// through IAM roles for service accounts (IRSA).
+const annotationRoleARN = "eks.amazonaws.com/role-arn"
+
+type (
+	// kind is a type of Kubernetes resource the tools can read. Secrets are deliberately left out,
+	// so their values are never sent to the model.
+	kind struct {
+		// plural is the resource name, e.g. pods, which is how kinds are referred to in the tools.
+		plural     string
+		singular   string
+		shortNames []string
+		apiVersion string
+		kind       string
+		namespaced bool
+		// fields are the fields listed when the model doesn't choose any, besides namespace & name.
+		fields []string
+		client func(c kubernetes.Interface, namespace string) resourceClient
+	}
+
+	// resourceClient lists & gets the resources of a kind.
+	resourceClient struct {
+		list func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error)
+		get  func(ctx context.Context, name string) (runtime.Object, error)
+	}
+
+	// typedClient is implemented by the typed clients of the clientset, e.g. PodInterface.
+	typedClient[T, L runtime.Object] interface {
+		List(ctx context.Context, opts metav1.ListOptions) (L, error)
+		Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	}
+)
+
+var kinds = []kind{
+	{
+		plural: "pods", singular: "pod", shortNames: []string{"po"}, apiVersion: "v1", kind: "Pod", namespaced: true,
+		fields: []string{"status.phase", "spec.nodeName", "spec.serviceAccountName"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*corev1.Pod, *corev1.PodList](c.CoreV1().Pods(ns))
+		},
+	},
+	{
+		plural: "services", singular: "service", shortNames: []string{"svc"}, apiVersion: "v1", kind: "Service", namespaced: true,
+		fields: []string{"spec.type", "spec.clusterIP", "status.loadBalancer.ingress.hostname"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*corev1.Service, *corev1.ServiceList](c.CoreV1().Services(ns))
+		},
+	},
+	{
+		plural: "serviceaccounts", singular: "serviceaccount", shortNames: []string{"sa"}, apiVersion: "v1", kind: "ServiceAccount", namespaced: true,
+		fields: []string{"metadata.annotations." + annotationRoleARN},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*corev1.ServiceAccount, *corev1.ServiceAccountList](c.CoreV1().ServiceAccounts(ns))
+		},
+	},
+	{
+		plural: "configmaps", singular: "configmap", shortNames: []string{"cm"}, apiVersion: "v1", kind: "ConfigMap", namespaced: true,
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*corev1.ConfigMap, *corev1.ConfigMapList](c.CoreV1().ConfigMaps(ns))
+		},
+	},
+	{
+		plural: "persistentvolumeclaims", singular: "persistentvolumeclaim", shortNames: []string{"pvc"}, apiVersion: "v1", kind: "PersistentVolumeClaim", namespaced: true,
+		fields: []string{"status.phase", "spec.volumeName", "spec.storageClassName"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*corev1.PersistentVolumeClaim, *corev1.PersistentVolumeClaimList](c.CoreV1().PersistentVolumeClaims(ns))
+		},
+	},
+	{
+		plural: "persistentvolumes", singular: "persistentvolume", shortNames: []string{"pv"}, apiVersion: "v1", kind: "PersistentVolume",
+		fields: []string{"status.phase", "spec.claimRef.namespace", "spec.claimRef.name", "spec.csi.volumeHandle"},
+		client: func(c kubernetes.Interface, _ string) resourceClient {
+			return adapt[*corev1.PersistentVolume, *corev1.PersistentVolumeList](c.CoreV1().PersistentVolumes())
+		},
+	},
+	{
+		plural: "namespaces", singular: "namespace", shortNames: []string{"ns"}, apiVersion: "v1", kind: "Namespace",
+		fields: []string{"status.phase"},
+		client: func(c kubernetes.Interface, _ string) resourceClient {
+			return adapt[*corev1.Namespace, *corev1.NamespaceList](c.CoreV1().Namespaces())
+		},
+	},
+	{
+		plural: "nodes", singular: "node", shortNames: []string{"no"}, apiVersion: "v1", kind: "Node",
+		fields: []string{"spec.providerID", "metadata.labels.node.kubernetes.io/instance-type", "metadata.labels.topology.kubernetes.io/zone"},
+		client: func(c kubernetes.Interface, _ string) resourceClient {
+			return adapt[*corev1.Node, *corev1.NodeList](c.CoreV1().Nodes())
+		},
+	},
+	{
+		plural: "deployments", singular: "deployment", shortNames: []string{"deploy"}, apiVersion: "apps/v1", kind: "Deployment", namespaced: true,
+		fields: []string{"spec.replicas", "status.readyReplicas", "spec.template.spec.serviceAccountName"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*appsv1.Deployment, *appsv1.DeploymentList](c.AppsV1().Deployments(ns))
+		},
+	},
+	{
+		plural: "statefulsets", singular: "statefulset", shortNames: []string{"sts"}, apiVersion: "apps/v1", kind: "StatefulSet", namespaced: true,
+		fields: []string{"spec.replicas", "status.readyReplicas", "spec.template.spec.serviceAccountName"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*appsv1.StatefulSet, *appsv1.StatefulSetList](c.AppsV1().StatefulSets(ns))
+		},
+	},
+	{
+		plural: "daemonsets", singular: "daemonset", shortNames: []string{"ds"}, apiVersion: "apps/v1", kind: "DaemonSet", namespaced: true,
+		fields: []string{"status.desiredNumberScheduled", "status.numberReady", "spec.template.spec.serviceAccountName"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*appsv1.DaemonSet, *appsv1.DaemonSetList](c.AppsV1().DaemonSets(ns))
+		},
+	},
+	{
+		plural: "replicasets", singular: "replicaset", shortNames: []string{"rs"}, apiVersion: "apps/v1", kind: "ReplicaSet", namespaced: true,
+		fields: []string{"spec.replicas", "status.readyReplicas"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*appsv1.ReplicaSet, *appsv1.ReplicaSetList](c.AppsV1().ReplicaSets(ns))
+		},
+	},
+	{
+		plural: "jobs", singular: "job", apiVersion: "batch/v1", kind: "Job", namespaced: true,
+		fields: []string{"status.succeeded", "status.failed", "spec.template.spec.serviceAccountName"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*batchv1.Job, *batchv1.JobList](c.BatchV1().Jobs(ns))
+		},
+	},
+	{
+		plural: "cronjobs", singular: "cronjob", shortNames: []string{"cj"}, apiVersion: "batch/v1", kind: "CronJob", namespaced: true,
+		fields: []string{"spec.schedule", "status.lastScheduleTime", "spec.jobTemplate.spec.template.spec.serviceAccountName"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*batchv1.CronJob, *batchv1.CronJobList](c.BatchV1().CronJobs(ns))
+		},
+	},
+	{
+		plural: "ingresses", singular: "ingress", shortNames: []string{"ing"}, apiVersion: "networking.k8s.io/v1", kind: "Ingress", namespaced: true,
+		fields: []string{"spec.ingressClassName", "spec.rules.host", "status.loadBalancer.ingress.hostname"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*networkingv1.Ingress, *networkingv1.IngressList](c.NetworkingV1().Ingresses(ns))
+		},
+	},
+	{
+		plural: "networkpolicies", singular: "networkpolicy", shortNames: []string{"netpol"}, apiVersion: "networking.k8s.io/v1", kind: "NetworkPolicy", namespaced: true,
+		fields: []string{"spec.podSelector.matchLabels", "spec.policyTypes"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*networkingv1.NetworkPolicy, *networkingv1.NetworkPolicyList](c.NetworkingV1().NetworkPolicies(ns))
+		},
+	},
+	{
+		plural: "roles", singular: "role", apiVersion: "rbac.authorization.k8s.io/v1", kind: "Role", namespaced: true,
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*rbacv1.Role, *rbacv1.RoleList](c.RbacV1().Roles(ns))
+		},
+	},
+	{
+		plural: "rolebindings", singular: "rolebinding", apiVersion: "rbac.authorization.k8s.io/v1", kind: "RoleBinding", namespaced: true,
+		fields: []string{"roleRef.kind", "roleRef.name", "subjects.name"},
+		client: func(c kubernetes.Interface, ns string) resourceClient {
+			return adapt[*rbacv1.RoleBinding, *rbacv1.RoleBindingList](c.RbacV1().RoleBindings(ns))
+		},
+	},
+	{
+		plural: "clusterroles", singular: "clusterrole", apiVersion: "rbac.authorization.k8s.io/v1", kind: "ClusterRole",
+		client: func(c kubernetes.Interface, _ string) resourceClient {
+			return adapt[*rbacv1.ClusterRole, *rbacv1.ClusterRoleList](c.RbacV1().ClusterRoles())
+		},
+	},
+	{
+		plural: "clusterrolebindings", singular: "clusterrolebinding", apiVersion: "rbac.authorization.k8s.io/v1", kind: "ClusterRoleBinding",
+		fields: []string{"roleRef.name", "subjects.kind", "subjects.name"},
+		client: func(c kubernetes.Interface, _ string) resourceClient {
+			return adapt[*rbacv1.ClusterRoleBinding, *rbacv1.ClusterRoleBindingList](c.RbacV1().ClusterRoleBindings())
+		},
+	},
+}
+
+// adapt wraps a typed client so that resources of every kind can be handled the same way.
+func adapt[T, L runtime.Object](c typedClient[T, L]) resourceClient {
+	return resourceClient{
+		list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return c.List(ctx, opts)
+		},
+		get: func(ctx context.Context, name string) (runtime.Object, error) {
+			return c.Get(ctx, name, metav1.GetOptions{})
+		},
+	}
+}
+
+// resources returns the client of the kind's resources in a namespace, or in every namespace if
+// it's empty.
+func (k kind) resources(c kubernetes.Interface, namespace string) resourceClient {
+	if !k.namespaced {
+		namespace = ""
+	}
+	return k.client(c, namespace)
+}
+
+// findKind returns the kind with the given name, which can be plural, singular, a short name or
+// the kind itself, e.g. pods, pod, po or Pod.
+func findKind(name string) (kind, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, k := range kinds {
+		if name == k.plural || name == k.singular || name == strings.ToLower(k.kind) || slices.Contains(k.shortNames, name) {
+			return k, nil
+		}
+	}
+	return kind{}, fmt.Errorf("%q is not a supported kind, it must be one of %v", name, kindNames())
+}
+
+func kindNames() []string {
+	out := make([]string, len(kinds))
+	for i, k := range kinds {
+		out[i] = k.plural
+	}
+	return out
+}
+
+func kindEnum() []any {
+	out := make([]any, len(kinds))
+	for i, k := range kinds {
+		out[i] = k.plural
+	}
+	return out
+}
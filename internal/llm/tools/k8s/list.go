@@ -0,0 +1,263 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	parameterKind          = "kind"
+	parameterContext       = "context"
+	parameterNamespace     = "namespace"
+	parameterName          = "name"
+	parameterLabelSelector = "label_selector"
+	parameterFieldSelector = "field_selector"
+	parameterFields        = "fields"
+
+	defaultMaxRows = 100
+	// pageSize is the number of resources requested from the API server at once.
+	pageSize = 500
+)
+
+type (
+	Opt func(*tool)
+
+	tool struct {
+		clients Clients
+		format  format.Format
+		maxRows int
+	}
+
+	ListTool struct {
+		tool
+	}
+)
+
+// WithFormat sets the default format of the response. The model can still ask for another format per call.
+func WithFormat(f format.Format) Opt {
+	return func(t *tool) {
+		t.format = f
+	}
+}
+
+// WithMaxRows sets the most resources listed in a response.
+func WithMaxRows(maxRows int) Opt {
+	return func(t *tool) {
+		t.maxRows = maxRows
+	}
+}
+
+func newTool(clients Clients, opts []Opt) tool {
+	t := tool{
+		clients: clients,
+		format:  format.JSON,
+		maxRows: defaultMaxRows,
+	}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	return t
+}
+
+func NewListTool(clients Clients, opts ...Opt) tools.Function {
+	return &ListTool{tool: newTool(clients, opts)}
+}
+
+func (t *ListTool) Name() string {
+	return "list_k8s_resources"
+}
+
+func (t *ListTool) Description() string {
+	return fmt.Sprintf(`This tool lists the resources of a kind in a Kubernetes cluster, such as pods or service accounts.
+Each resource is returned with its name, and its namespace if the kind is namespaced, along with the fields given by the %q parameter. If no fields are given, a few useful fields of the kind are returned, e.g. the node & service account of pods, or the IAM role of service accounts.
+The response is returned as %s, with at most %d resources. Narrow the list with the %q, %q or %q parameters to see more.
+Use the get_k8s_resource tool to get every field of a resource.`,
+		parameterFields, t.format.Describe(), t.maxRows, parameterNamespace, parameterLabelSelector, parameterFieldSelector)
+}
+
+func (t *ListTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		t.kindParameter(),
+		t.contextParameter(),
+		{
+			Name:        parameterNamespace,
+			Description: "The namespace to list resources in. If not set, resources in every namespace are listed. Ignored for kinds that aren't namespaced, such as nodes.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterLabelSelector,
+			Description: "Only list resources with matching labels, in the same form as kubectl's --selector, e.g. app=web,tier!=cache or environment in (production, staging).",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterFieldSelector,
+			Description: "Only list resources with matching fields, in the same form as kubectl's --field-selector, e.g. spec.nodeName=ip-10-0-1-23.ec2.internal or status.phase!=Running. Only a few fields of each kind can be selected on.",
+			Type:        tools.ParameterTypeString,
+		},
+		t.fieldsParameter("The fields to return for each resource"),
+		format.ParameterDefinition(t.format),
+	}
+}
+
+func (t *ListTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	k, client, err := t.resolve(parameters)
+	if err != nil {
+		return "", err
+	}
+
+	namespace, _ := parameters[parameterNamespace].(string)
+	labelSelector, _ := parameters[parameterLabelSelector].(string)
+	fieldSelector, _ := parameters[parameterFieldSelector].(string)
+	fieldsParameter, _ := parameters[parameterFields].(string)
+
+	f, err := format.FromParameters(parameters, t.format)
+	if err != nil {
+		return "", err
+	}
+
+	// parsed here to give the model a clearer error than the API server would
+	if _, err := labels.Parse(labelSelector); err != nil {
+		return "", fmt.Errorf("%s is not a valid label selector: %w", parameterLabelSelector, err)
+	}
+
+	fields := parseFields(fieldsParameter)
+	if len(fields) == 0 {
+		fields = k.fields
+	}
+
+	rc := k.resources(client, namespace)
+	opts := metav1.ListOptions{LabelSelector: labelSelector, FieldSelector: fieldSelector, Limit: pageSize}
+	items := []map[string]any{}
+	// omitted counts the resources left out of the response, and uncounted is true if there are
+	// more that the API server didn't count
+	omitted, uncounted := 0, false
+	for {
+		list, err := rc.list(ctx, opts)
+		if err != nil {
+			return "", fmt.Errorf("error listing %s: %w", k.plural, err)
+		}
+		page, err := listItems(k, list)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, page...)
+
+		accessor, err := meta.ListAccessor(list)
+		if err != nil || accessor.GetContinue() == "" {
+			break
+		}
+		if len(items) >= t.maxRows {
+			// the remaining items aren't counted for some lists, e.g. those with a field selector
+			if remaining := accessor.GetRemainingItemCount(); remaining != nil {
+				omitted += int(*remaining)
+			} else {
+				uncounted = true
+			}
+			break
+		}
+		opts.Continue = accessor.GetContinue()
+	}
+	if len(items) > t.maxRows {
+		omitted += len(items) - t.maxRows
+		items = items[:t.maxRows]
+	}
+
+	columns := append([]string{parameterName}, fields...)
+	if k.namespaced {
+		columns = append([]string{parameterNamespace}, columns...)
+	}
+	rows := [][]any{}
+	for _, item := range items {
+		row := []any{}
+		if k.namespaced {
+			namespace, _ := lookup(item, "metadata.namespace")
+			row = append(row, namespace)
+		}
+		name, _ := lookup(item, "metadata.name")
+		row = append(row, name)
+		values := project(item, fields)
+		for _, field := range fields {
+			row = append(row, values[field])
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return fmt.Sprintf("No %s found.", k.plural), nil
+	}
+
+	out, err := f.Rows(columns, rows)
+	if err != nil {
+		return "", err
+	}
+	if omitted > 0 || uncounted {
+		count := fmt.Sprintf("%d more", omitted)
+		if uncounted {
+			count = "more"
+		}
+		out = strings.TrimRight(out, "\n") + fmt.Sprintf("\n\nThe result was truncated: %s %s were omitted. Narrow the list with a namespace, label selector or field selector to see them.", count, k.plural)
+	}
+	return out, nil
+}
+
+// resolve returns the kind named by the kind parameter, and the client of the context named by
+// the context parameter.
+func (t *tool) resolve(parameters map[string]any) (kind, kubernetes.Interface, error) {
+	kindName, ok := parameters[parameterKind].(string)
+	if !ok {
+		return kind{}, nil, fmt.Errorf("%s is not a valid string", parameterKind)
+	}
+	k, err := findKind(kindName)
+	if err != nil {
+		return kind{}, nil, err
+	}
+
+	contextName, _ := parameters[parameterContext].(string)
+	client, err := t.clients.Client(contextName)
+	if err != nil {
+		return kind{}, nil, err
+	}
+
+	return k, client, nil
+}
+
+func (t *tool) kindParameter() tools.ParameterDefinition {
+	return tools.ParameterDefinition{
+		Name:        parameterKind,
+		Description: "The kind of resource, as its plural name, e.g. pods or serviceaccounts. Secrets can't be read.",
+		Required:    true,
+		Type:        tools.ParameterTypeString,
+		Enum:        kindEnum(),
+	}
+}
+
+func (t *tool) contextParameter() tools.ParameterDefinition {
+	p := tools.ParameterDefinition{
+		Name:        parameterContext,
+		Description: "The kubeconfig context of the cluster to use. If not set, the current context is used.",
+		Type:        tools.ParameterTypeString,
+	}
+	if contexts := t.clients.Contexts(); len(contexts) > 0 {
+		p.Description = fmt.Sprintf("The kubeconfig context of the cluster to use. If not set, %s is used.", contexts[0])
+		for _, c := range contexts {
+			p.Enum = append(p.Enum, c)
+		}
+	}
+	return p
+}
+
+func (t *tool) fieldsParameter(description string) tools.ParameterDefinition {
+	return tools.ParameterDefinition{
+		Name:        parameterFields,
+		Description: description + ", as a comma separated list of dotted paths, e.g. spec.serviceAccountName,metadata.labels.app,metadata.annotations.eks.amazonaws.com/role-arn. Paths through a list return the field of every element, e.g. spec.containers.image.",
+		Type:        tools.ParameterTypeString,
+	}
+}
@@ -0,0 +1,199 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testObjects() []runtime.Object {
+	return []runtime.Object{
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "shop", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: "node-a", ServiceAccountName: "web"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Namespace: "shop", Labels: map[string]string{"app": "worker"}},
+			Spec:       corev1.PodSpec{NodeName: "node-b", ServiceAccountName: "worker"},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system", Labels: map[string]string{"app": "dns"}},
+			Spec:       corev1.PodSpec{NodeName: "node-a"},
+		},
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "web",
+				Namespace: "shop",
+				Annotations: map[string]string{
+					annotationRoleARN:     "arn:aws:iam::111111111111:role/web",
+					annotationLastApplied: "{}",
+				},
+			},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"topology.kubernetes.io/zone": "eu-west-1a"}},
+			Spec:       corev1.NodeSpec{ProviderID: "aws:///eu-west-1a/i-0123456789abcdef0"},
+		},
+	}
+}
+
+func TestListCall(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]any
+		want       string
+	}{
+		{
+			name:       "every namespace",
+			parameters: map[string]any{parameterKind: "pods"},
+			want: `[{"namespace":"kube-system","name":"coredns","status.phase":null,"spec.nodeName":"node-a","spec.serviceAccountName":null},` +
+				`{"namespace":"shop","name":"web-1","status.phase":"Running","spec.nodeName":"node-a","spec.serviceAccountName":"web"},` +
+				`{"namespace":"shop","name":"worker-1","status.phase":"Pending","spec.nodeName":"node-b","spec.serviceAccountName":"worker"}]`,
+		},
+		{
+			name:       "namespace & label selector",
+			parameters: map[string]any{parameterKind: "po", parameterNamespace: "shop", parameterLabelSelector: "app in (web, dns)"},
+			want:       `[{"namespace":"shop","name":"web-1","status.phase":"Running","spec.nodeName":"node-a","spec.serviceAccountName":"web"}]`,
+		},
+		{
+			name:       "fields",
+			parameters: map[string]any{parameterKind: "serviceaccounts", parameterFields: "metadata.annotations.eks.amazonaws.com/role-arn, metadata.annotations.kubectl.kubernetes.io/last-applied-configuration"},
+			want:       `[{"namespace":"shop","name":"web","metadata.annotations.eks.amazonaws.com/role-arn":"arn:aws:iam::111111111111:role/web"}]`,
+		},
+		{
+			name:       "kind that isn't namespaced",
+			parameters: map[string]any{parameterKind: "Node", parameterNamespace: "shop"},
+			want:       `[{"name":"node-a","spec.providerID":"aws:///eu-west-1a/i-0123456789abcdef0","metadata.labels.topology.kubernetes.io/zone":"eu-west-1a"}]`,
+		},
+	}
+
+	tool := NewListTool(NewStaticClients(fake.NewClientset(testObjects()...))).(*ListTool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tool.Call(context.Background(), tt.parameters)
+			if err != nil {
+				t.Fatalf("error calling tool: %v", err)
+			}
+			if out != tt.want {
+				t.Errorf("got %s, want %s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestListCallNoResources(t *testing.T) {
+	tool := NewListTool(NewStaticClients(fake.NewClientset(testObjects()...)))
+
+	out, err := tool.Call(context.Background(), map[string]any{parameterKind: "deployments"})
+	if err != nil {
+		t.Fatalf("error calling tool: %v", err)
+	}
+	if out != "No deployments found." {
+		t.Errorf("got %q, want no deployments", out)
+	}
+}
+
+func TestListCallErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]any
+		want       string
+	}{
+		{name: "no kind", parameters: map[string]any{}, want: "kind is not a valid string"},
+		{name: "secrets", parameters: map[string]any{parameterKind: "secrets"}, want: `"secrets" is not a supported kind`},
+		{name: "invalid label selector", parameters: map[string]any{parameterKind: "pods", parameterLabelSelector: "app in web"}, want: "is not a valid label selector"},
+	}
+
+	tool := NewListTool(NewStaticClients(fake.NewClientset()))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tool.Call(context.Background(), tt.parameters)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// pagedClientset returns a clientset that lists total pods in pages of pageSize, as the API
+// server does, counting the remaining pods if counted is true. The number of list requests is
+// recorded in requests.
+func pagedClientset(total int, counted bool, requests *int) *fake.Clientset {
+	client := fake.NewClientset()
+	client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		*requests++
+
+		start, _ := strconv.Atoi(action.(k8stesting.ListActionImpl).ListOptions.Continue)
+		end := min(start+pageSize, total)
+		list := &corev1.PodList{}
+		for i := start; i < end; i++ {
+			list.Items = append(list.Items, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: "default"}})
+		}
+		if end < total {
+			list.Continue = strconv.Itoa(end)
+			if counted {
+				remaining := int64(total - end)
+				list.RemainingItemCount = &remaining
+			}
+		}
+		return true, list, nil
+	})
+	return client
+}
+
+func TestListCallTruncation(t *testing.T) {
+	tests := []struct {
+		name         string
+		total        int
+		maxRows      int
+		counted      bool
+		wantRows     int
+		wantRequests int
+		wantNote     string
+	}{
+		{name: "fits in a page", total: 10, maxRows: 10, counted: true, wantRows: 10, wantRequests: 1},
+		{name: "fits in several pages", total: 1200, maxRows: 1200, counted: true, wantRows: 1200, wantRequests: 3},
+		{name: "truncated in the last page", total: 20, maxRows: 10, counted: true, wantRows: 10, wantRequests: 1, wantNote: "10 more pods were omitted"},
+		{name: "stops at max rows", total: 2000, maxRows: 100, counted: true, wantRows: 100, wantRequests: 1, wantNote: "1900 more pods were omitted"},
+		{name: "stops after the page reaching max rows", total: 2000, maxRows: 600, counted: true, wantRows: 600, wantRequests: 2, wantNote: "1400 more pods were omitted"},
+		{name: "remaining items not counted", total: 2000, maxRows: 100, wantRows: 100, wantRequests: 1, wantNote: "The result was truncated: more pods were omitted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			tool := NewListTool(NewStaticClients(pagedClientset(tt.total, tt.counted, &requests)), WithMaxRows(tt.maxRows))
+
+			out, err := tool.Call(context.Background(), map[string]any{parameterKind: "pods"})
+			if err != nil {
+				t.Fatalf("error calling tool: %v", err)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("got %d list requests, want %d", requests, tt.wantRequests)
+			}
+
+			rows, note, _ := strings.Cut(out, "\n\n")
+			var got []map[string]any
+			if err := json.Unmarshal([]byte(rows), &got); err != nil {
+				t.Fatalf("error unmarshalling rows: %v", err)
+			}
+			if len(got) != tt.wantRows {
+				t.Errorf("got %d rows, want %d", len(got), tt.wantRows)
+			}
+			if !strings.Contains(note, tt.wantNote) || (tt.wantNote == "") != (note == "") {
+				t.Errorf("got note %q, want one containing %q", note, tt.wantNote)
+			}
+		})
+	}
+}
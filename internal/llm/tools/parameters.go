@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// IntParameter returns the named integer parameter, or def if it was not provided.
+// Models frequently pass numbers as strings, so both forms are accepted.
+func IntParameter(parameters map[string]any, name string, def int) (int, error) {
+	switch v := parameters[name].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	case string:
+		if v == "" {
+			return def, nil
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid integer: %w", name, err)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("%s is not a valid integer", name)
+	}
+}
+
+// BoolParameter returns the named boolean parameter, or def if it was not provided.
+func BoolParameter(parameters map[string]any, name string, def bool) (bool, error) {
+	switch v := parameters[name].(type) {
+	case nil:
+		return def, nil
+	case bool:
+		return v, nil
+	case string:
+		if v == "" {
+			return def, nil
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("%s is not a valid boolean: %w", name, err)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("%s is not a valid boolean", name)
+	}
+}
@@ -60,3 +60,12 @@ func (r *Registry) Call(ctx context.Context, name string, parameters map[string]
 
 	return tool.Call(ctx, parameters)
 }
+
+// Reset clears the cached state of any tools that implement Resetter.
+func (r *Registry) Reset() {
+	for _, tool := range r.nameToTool {
+		if resetter, ok := tool.(Resetter); ok {
+			resetter.Reset()
+		}
+	}
+}
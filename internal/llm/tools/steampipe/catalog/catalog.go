@@ -0,0 +1,397 @@
+// Package catalog describes the tables steampipe exposes, using the PostgreSQL system catalogs &
+// the key column configuration steampipe publishes for each plugin.
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// RequireRequired, RequireOptional & RequireAnyOf are the values of KeyColumn.Require.
+	RequireRequired = "required"
+	RequireOptional = "optional"
+	RequireAnyOf    = "any_of"
+
+	// tableQuery finds a table by name. If no schema is given, the table is found in the plugin
+	// schemas in search path order, which is the table an unqualified name refers to.
+	tableQuery = `
+select
+  c.oid,
+  n.nspname,
+  coalesce(obj_description(c.oid, 'pg_class'), '')
+from
+  pg_catalog.pg_class c
+  join pg_catalog.pg_namespace n on n.oid = c.relnamespace
+where
+  c.relname = $2
+  and ($1::text = '' or n.nspname = $1::text)
+  and n.nspname not like 'steampipe\_%'
+order by
+  array_position(current_schemas(false)::text[], n.nspname::text) nulls last,
+  n.nspname
+limit 1
+`
+
+	columnsQuery = `
+select
+  a.attname,
+  format_type(a.atttypid, a.atttypmod),
+  coalesce(col_description(a.attrelid, a.attnum), '')
+from
+  pg_catalog.pg_attribute a
+where
+  a.attrelid = $1 and a.attnum > 0 and not a.attisdropped
+order by
+  a.attnum
+`
+
+	// keyColumnsQuery reads the key columns of a table, from the plugin of the connection whose
+	// schema the table is in, as plugins can have tables of the same name. Older versions of
+	// steampipe don't have these tables, in which case key columns are left out.
+	keyColumnsQuery = `
+select distinct on (pc.name)
+  pc.name,
+  coalesce(pc.list_config::text, ''),
+  coalesce(pc.get_config::text, '')
+from
+  steampipe_internal.steampipe_plugin_column pc
+  join steampipe_internal.steampipe_connection sc on sc.plugin = pc.plugin
+where
+  sc.name = $1
+  and pc.table_name = $2
+order by
+  pc.name
+`
+
+	// tablesQuery lists the tables in a schema, or the distinct table names in every plugin schema
+	// if no schema is given.
+	tablesQuery = `
+select distinct
+  foreign_table_name::text
+from
+  information_schema.foreign_tables
+where
+  ($1::text = '' or foreign_table_schema = $1::text)
+  and foreign_table_schema not like 'steampipe\_%'
+order by
+  1
+`
+
+	// schemasQuery lists the schemas of the installed plugins' connections. Steampipe's own
+	// schemas, such as steampipe_internal, are left out.
+	schemasQuery = `
+select
+  foreign_table_schema::text,
+  count(*),
+  min(foreign_table_name::text),
+  max(foreign_table_name::text)
+from
+  information_schema.foreign_tables
+where
+  foreign_table_schema not like 'steampipe\_%'
+group by
+  foreign_table_schema
+order by
+  array_position(current_schemas(false)::text[], foreign_table_schema::text) nulls last,
+  foreign_table_schema
+`
+
+	// connectionsQuery maps connection schemas to the plugins that provide them. Older versions of
+	// steampipe don't have this table, in which case plugins aren't known.
+	connectionsQuery = `
+select
+  name,
+  plugin
+from
+  steampipe_internal.steampipe_connection
+`
+)
+
+// joinColumns are the columns most steampipe tables share, which are commonly used to join them.
+var joinColumns = []string{"arn", "account_id", "region", "partition", "akas", "title", "tags"}
+
+var ErrTableNotFound = errors.New("table not found")
+
+type (
+	Catalog struct {
+		db *pgxpool.Pool
+	}
+
+	// Schema is the schema of a steampipe connection, which holds the tables of one plugin.
+	Schema struct {
+		Name string `json:"name"`
+		// Plugin is the plugin the connection uses, e.g. hub.steampipe.io/plugins/turbot/aws@latest, if
+		// steampipe publishes it.
+		Plugin string `json:"plugin,omitempty"`
+		Tables int    `json:"tables"`
+		// TablePrefix is the prefix the schema's table names share, e.g. aws_.
+		TablePrefix string `json:"table_prefix,omitempty"`
+	}
+
+	Table struct {
+		Schema      string   `json:"schema"`
+		Name        string   `json:"name"`
+		Description string   `json:"description,omitempty"`
+		Columns     []Column `json:"columns"`
+		// KeyColumnsKnown is false if steampipe doesn't publish key columns, so none are listed.
+		KeyColumnsKnown bool `json:"-"`
+	}
+
+	Column struct {
+		Name        string `json:"name"`
+		Type        string `json:"type"`
+		Description string `json:"description,omitempty"`
+		// List is set if the column is a key column of the API call that lists the table's resources.
+		List *KeyColumn `json:"list_key,omitempty"`
+		// Get is set if the column is a key column of the API call that gets a single resource.
+		Get *KeyColumn `json:"get_key,omitempty"`
+	}
+
+	// KeyColumn is a column that steampipe passes to the underlying API when it's used in a WHERE clause.
+	KeyColumn struct {
+		Operators []string `json:"operators,omitempty"`
+		// Require says whether the column must be in the WHERE clause: required, optional or any_of.
+		Require string `json:"require,omitempty"`
+	}
+)
+
+func New(db *pgxpool.Pool) *Catalog {
+	return &Catalog{db: db}
+}
+
+// Table describes a table. If schema is empty, the table is looked for in every plugin schema. It
+// returns ErrTableNotFound if the table doesn't exist.
+func (c *Catalog) Table(ctx context.Context, schema, name string) (Table, error) {
+	if schema == "" {
+		// a qualified name such as aws.aws_s3_bucket is split into its schema & table
+		if s, n, ok := strings.Cut(name, "."); ok {
+			schema, name = s, n
+		}
+	}
+
+	t := Table{Name: name}
+	var oid uint32
+	err := c.db.QueryRow(ctx, tableQuery, schema, name).Scan(&oid, &t.Schema, &t.Description)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Table{}, fmt.Errorf("%w: %s", ErrTableNotFound, qualified(schema, name))
+	}
+	if err != nil {
+		return Table{}, fmt.Errorf("error querying table %s: %w", qualified(schema, name), err)
+	}
+
+	rows, err := c.db.Query(ctx, columnsQuery, oid)
+	if err != nil {
+		return Table{}, fmt.Errorf("error querying columns of %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var col Column
+		if err := rows.Scan(&col.Name, &col.Type, &col.Description); err != nil {
+			return Table{}, fmt.Errorf("error scanning column: %w", err)
+		}
+		t.Columns = append(t.Columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return Table{}, fmt.Errorf("error reading columns of %s: %w", name, err)
+	}
+
+	keyColumns, err := c.keyColumns(ctx, t.Schema, name)
+	if err == nil {
+		t.KeyColumnsKnown = true
+		for i, col := range t.Columns {
+			if k, ok := keyColumns[col.Name]; ok {
+				t.Columns[i].List, t.Columns[i].Get = k[0], k[1]
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// Tables lists the tables in a schema, or the table names in every plugin schema if schema is empty.
+func (c *Catalog) Tables(ctx context.Context, schema string) ([]string, error) {
+	rows, err := c.db.Query(ctx, tablesQuery, schema)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("error scanning table: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading tables: %w", err)
+	}
+
+	return tables, nil
+}
+
+// Schemas lists the schemas of the installed plugins, in search path order.
+func (c *Catalog) Schemas(ctx context.Context) ([]Schema, error) {
+	rows, err := c.db.Query(ctx, schemasQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error querying schemas: %w", err)
+	}
+	defer rows.Close()
+
+	schemas := []Schema{}
+	for rows.Next() {
+		var (
+			s           Schema
+			first, last string
+		)
+		if err := rows.Scan(&s.Name, &s.Tables, &first, &last); err != nil {
+			return nil, fmt.Errorf("error scanning schema: %w", err)
+		}
+		s.TablePrefix = tablePrefix(first, last)
+		schemas = append(schemas, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading schemas: %w", err)
+	}
+
+	plugins, err := c.plugins(ctx)
+	if err == nil {
+		for i, s := range schemas {
+			schemas[i].Plugin = plugins[s.Name]
+		}
+	}
+
+	return schemas, nil
+}
+
+// plugins maps connection names, which are also their schema names, to their plugins.
+func (c *Catalog) plugins(ctx context.Context) (map[string]string, error) {
+	rows, err := c.db.Query(ctx, connectionsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]string{}
+	for rows.Next() {
+		var name, plugin string
+		if err := rows.Scan(&name, &plugin); err != nil {
+			return nil, err
+		}
+		out[name] = plugin
+	}
+
+	return out, rows.Err()
+}
+
+// tablePrefix returns the prefix up to & including the last underscore shared by the first & last
+// table names of a schema in sorted order, which is shared by every table in between.
+func tablePrefix(first, last string) string {
+	n := 0
+	for n < len(first) && n < len(last) && first[n] == last[n] {
+		n++
+	}
+	return first[:strings.LastIndex(first[:n], "_")+1]
+}
+
+func qualified(schema, name string) string {
+	if schema == "" {
+		return name
+	}
+	return schema + "." + name
+}
+
+// keyColumns returns the list & get key column configuration of each key column of a table.
+func (c *Catalog) keyColumns(ctx context.Context, schema, table string) (map[string][2]*KeyColumn, error) {
+	rows, err := c.db.Query(ctx, keyColumnsQuery, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string][2]*KeyColumn{}
+	for rows.Next() {
+		var name, listConfig, getConfig string
+		if err := rows.Scan(&name, &listConfig, &getConfig); err != nil {
+			return nil, err
+		}
+
+		list, get := parseKeyColumn(listConfig), parseKeyColumn(getConfig)
+		if list != nil || get != nil {
+			out[name] = [2]*KeyColumn{list, get}
+		}
+	}
+
+	return out, rows.Err()
+}
+
+func parseKeyColumn(config string) *KeyColumn {
+	if config == "" || config == "null" {
+		return nil
+	}
+	var k KeyColumn
+	if err := json.Unmarshal([]byte(config), &k); err != nil {
+		return nil
+	}
+	return &k
+}
+
+// RequiredQuals returns the columns that must be in the WHERE clause of any query on the table.
+// If the table has any_of key columns, at least one of them must be used, so they're returned
+// separately.
+func (t Table) RequiredQuals() (required, anyOf []string) {
+	for _, c := range t.Columns {
+		if c.List == nil {
+			continue
+		}
+		switch c.List.Require {
+		case RequireRequired:
+			required = append(required, c.Name)
+		case RequireAnyOf:
+			anyOf = append(anyOf, c.Name)
+		}
+	}
+	return required, anyOf
+}
+
+// JoinColumns returns the columns that are commonly used to join the table to others: the
+// columns shared by most tables, along with any that hold the ARNs or IDs of other resources.
+func (t Table) JoinColumns() []string {
+	out := []string{}
+	for _, c := range t.Columns {
+		if slices.Contains(joinColumns, c.Name) || IsReferenceColumn(c.Name) {
+			out = append(out, c.Name)
+		}
+	}
+	return out
+}
+
+// Column returns the column with the given name.
+func (t Table) Column(name string) (Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// IsReferenceColumn returns true if a column's name says it holds the ARN or ID of a resource.
+func IsReferenceColumn(name string) bool {
+	for _, suffix := range []string{"_arn", "_arns", "_id", "_ids"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package dml
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+const (
+	// maxCursors is the number of results kept for paging. The oldest is dropped when a new
+	// result needs a cursor.
+	maxCursors = 10
+	// maxCursorRows is the most rows read for a single result. Rows beyond this are never read, so
+	// they can't be fetched without narrowing the query.
+	maxCursorRows = 5000
+)
+
+type (
+	// cursorCache holds the unreturned rows of truncated results, so the model can page through them.
+	cursorCache struct {
+		mu      sync.Mutex
+		nextID  int
+		order   []string
+		cursors map[string]*cursor
+	}
+
+	cursor struct {
+		columns []string
+		rows    [][]any
+		// more is true if the result had rows beyond maxCursorRows, which were never read.
+		more bool
+	}
+
+	// page is the part of a result returned by a single call.
+	page struct {
+		columns []string
+		rows    [][]any
+		// remaining is the number of stored rows left after this page.
+		remaining int
+		// more is true if there are rows beyond the stored ones, which can't be fetched.
+		more     bool
+		cursorID string
+	}
+)
+
+func newCursorCache() *cursorCache {
+	return &cursorCache{cursors: map[string]*cursor{}}
+}
+
+// add stores rows for later pages, returning the cursor ID to fetch them with.
+func (c *cursorCache) add(columns []string, rows [][]any, more bool) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.order) == maxCursors {
+		delete(c.cursors, c.order[0])
+		c.order = c.order[1:]
+	}
+
+	c.nextID++
+	id := "c" + strconv.Itoa(c.nextID)
+	c.cursors[id] = &cursor{columns: columns, rows: rows, more: more}
+	c.order = append(c.order, id)
+
+	return id
+}
+
+// next removes & returns the next page of rows for a cursor. The cursor is dropped once all of its
+// stored rows have been returned.
+func (c *cursorCache) next(id string, b budget) (page, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cur, ok := c.cursors[id]
+	if !ok {
+		return page{}, fmt.Errorf("cursor %q does not exist or has expired, run the query again", id)
+	}
+
+	n := b.fit(cur.rows)
+	p := page{columns: cur.columns, rows: cur.rows[:n], remaining: len(cur.rows) - n, more: cur.more}
+	cur.rows = cur.rows[n:]
+
+	if len(cur.rows) == 0 {
+		c.remove(id)
+	} else {
+		p.cursorID = id
+	}
+
+	return p, nil
+}
+
+func (c *cursorCache) remove(id string) {
+	delete(c.cursors, id)
+	for i, o := range c.order {
+		if o == id {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *cursorCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cursors = map[string]*cursor{}
+	c.order = nil
+}
+
+// budget limits how much of a result is returned to the model at once.
+type budget struct {
+	maxRows  int
+	maxBytes int
+}
+
+// fit returns how many of rows can be returned within the budget. At least one row is always
+// returned, so that a single large row doesn't stop the result being read. Row sizes are measured
+// as JSON, which is the largest of the formats.
+func (b budget) fit(rows [][]any) int {
+	size := 0
+	for i, r := range rows {
+		rowJSON, _ := json.Marshal(r)
+		size += len(rowJSON)
+		if i == b.maxRows || (i > 0 && size > b.maxBytes) {
+			return i
+		}
+	}
+	return len(rows)
+}
@@ -2,25 +2,95 @@ package dml
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/sqlerror"
+	"github.com/fergalhk/llm-cloud-discovery/internal/pgsql"
+	"github.com/fergalhk/llm-cloud-discovery/internal/pgvalue"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 const (
-	parameterQuery = "query"
+	parameterQuery  = "query"
+	parameterCursor = "cursor"
+
+	// defaultStatementTimeout is generous, as steampipe queries call the APIs behind each table.
+	defaultStatementTimeout = 2 * time.Minute
+	defaultMaxRows          = 100
+	defaultMaxBytes         = 32 * 1024
+
+	// declarePrefix declares a cursor for the query, so only the rows fetched from it are read &
+	// the rest of the result is discarded with the transaction. Error positions are offset by its
+	// length.
+	declarePrefix = "declare query_rows no scroll cursor for "
 )
 
-type Tool struct {
-	db *pgx.Conn
+type (
+	Opt func(*Tool)
+
+	Tool struct {
+		db               *pgxpool.Pool
+		statementTimeout time.Duration
+		format           format.Format
+		budget           budget
+		cursors          *cursorCache
+		describer        *sqlerror.Describer
+	}
+)
+
+// WithStatementTimeout sets how long a query may run before the database cancels it.
+func WithStatementTimeout(timeout time.Duration) Opt {
+	return func(t *Tool) {
+		t.statementTimeout = timeout
+	}
+}
+
+// WithFormat sets the default format of results. The model can still ask for another format per call.
+func WithFormat(f format.Format) Opt {
+	return func(t *Tool) {
+		t.format = f
+	}
+}
+
+// WithMaxRows sets the most rows returned by a single call. Further rows are fetched with a cursor.
+func WithMaxRows(maxRows int) Opt {
+	return func(t *Tool) {
+		t.budget.maxRows = maxRows
+	}
+}
+
+// WithMaxBytes sets the approximate size limit of the rows returned by a single call. Further rows
+// are fetched with a cursor.
+func WithMaxBytes(maxBytes int) Opt {
+	return func(t *Tool) {
+		t.budget.maxBytes = maxBytes
+	}
 }
 
-func New(db *pgx.Conn) tools.Function {
-	return &Tool{
-		db: db,
+func New(db *pgxpool.Pool, opts ...Opt) tools.Function {
+	t := &Tool{
+		db:               db,
+		statementTimeout: defaultStatementTimeout,
+		format:           format.JSON,
+		budget: budget{
+			maxRows:  defaultMaxRows,
+			maxBytes: defaultMaxBytes,
+		},
+		cursors:   newCursorCache(),
+		describer: sqlerror.NewDescriber(db),
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 func (t Tool) Name() string {
@@ -28,66 +98,155 @@ func (t Tool) Name() string {
 }
 
 func (t Tool) Description() string {
-	return "Executes arbitrary SQL queries against the AWS resources tables. The response is returned as a JSON array of objects, with each object representing a row in the result set."
+	return fmt.Sprintf(`Executes a read only SQL query against the resource tables of the installed steampipe plugins. Only a single SELECT, WITH or EXPLAIN statement can be run. The response is returned as %s by default, with one row per row in the result set. Columns that are null in every row are left out, and long values are shortened.
+If the query fails, the error is returned as a JSON object with its SQLSTATE & position in the query. For unknown columns & tables it also has the nearest matching names in did_you_mean, and the schema of the tables involved.
+Large results are truncated, and a note after the rows says how many were omitted. To get the next page of rows, call this tool again with the %q from the note instead of a query.`, t.format.Describe(), parameterCursor)
 }
 
 func (t Tool) ParameterDefinitions() []tools.ParameterDefinition {
 	return []tools.ParameterDefinition{
 		{
 			Name:        parameterQuery,
-			Description: "The SQL query to execute.",
-			Required:    true,
+			Description: fmt.Sprintf("The SQL query to execute. Required unless %s is set.", parameterCursor),
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterCursor,
+			Description: "The cursor returned with a truncated result, to get its next page of rows.",
 			Type:        tools.ParameterTypeString,
 		},
+		format.ParameterDefinition(t.format),
 	}
 }
 
 func (t Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	f, err := format.FromParameters(parameters, t.format)
+	if err != nil {
+		return "", err
+	}
+
+	if cursorID, _ := parameters[parameterCursor].(string); cursorID != "" {
+		p, err := t.cursors.next(cursorID, t.budget)
+		if err != nil {
+			return "", err
+		}
+		return render(p, f)
+	}
+
 	query, _ := parameters[parameterQuery].(string)
 	if query == "" {
 		return "", fmt.Errorf("query is required")
 	}
 
-	rows, err := t.db.Query(ctx, query)
-	if err != nil {
-		return "", fmt.Errorf("error executing query: %w", err)
+	if err := pgsql.CheckReadOnly(query); err != nil {
+		return "", fmt.Errorf("query rejected: %w", err)
 	}
 
-	data := []map[string]any{}
-	for rows.Next() {
-		row, err := scanArbitraryRow(rows)
+	var (
+		columns []string
+		data    = [][]any{}
+		more    bool
+	)
+	err = steampipe.ReadOnly(ctx, t.db, t.statementTimeout, func(tx pgx.Tx) error {
+		rows, err := queryRows(ctx, tx, query)
 		if err != nil {
-			return "", fmt.Errorf("error scanning row: %w", err)
+			return fmt.Errorf("error executing query: %w", err)
+		}
+		defer rows.Close()
+
+		columns = make([]string, len(rows.FieldDescriptions()))
+		for i, field := range rows.FieldDescriptions() {
+			columns[i] = field.Name
+		}
+
+		for rows.Next() {
+			if len(data) == maxCursorRows {
+				more = true
+				break
+			}
+
+			row, err := rows.Values()
+			if err != nil {
+				return fmt.Errorf("error scanning row: %w", err)
+			}
+			data = append(data, pgvalue.Row(rows.FieldDescriptions(), row))
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error reading rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// errors from PostgreSQL, such as unknown columns, are returned as JSON with the nearest
+		// matching names, so the model can fix the query
+		if sqlErr, ok := t.describer.Describe(ctx, err, query); ok {
+			return "", fmt.Errorf("query failed: %w", sqlErr)
 		}
-		data = append(data, row)
+		return "", err
 	}
 
 	if len(data) == 0 {
 		return "", fmt.Errorf("no rows returned")
 	}
 
-	dataJSON, err := json.Marshal(data)
-	if err != nil {
-		return "", fmt.Errorf("error marshalling data to JSON: %w", err)
+	n := t.budget.fit(data)
+	p := page{columns: columns, rows: data[:n], remaining: len(data) - n, more: more}
+	if n < len(data) {
+		p.cursorID = t.cursors.add(columns, data[n:], more)
 	}
 
-	return string(dataJSON), nil
+	return render(p, f)
 }
 
-func scanArbitraryRow(rows pgx.Rows) (map[string]any, error) {
-	values := make([]any, len(rows.FieldDescriptions()))
-	for i := range values {
-		values[i] = new(any)
+// Reset drops the cursors of previous results.
+func (t Tool) Reset() {
+	t.cursors.reset()
+}
+
+// queryRows runs a query through a cursor, fetching at most one row more than maxCursorRows so
+// that it's known whether rows were omitted. EXPLAIN can't be declared as a cursor, but its output
+// is small, so it's run directly.
+func queryRows(ctx context.Context, tx pgx.Tx, query string) (pgx.Rows, error) {
+	tokens, err := pgsql.Tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) > 0 && tokens[0].Is("explain") {
+		return tx.Query(ctx, query)
+	}
+
+	if _, err := tx.Exec(ctx, declarePrefix+query); err != nil {
+		// positions count from the start of the DECLARE, not of the query
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Position > 0 {
+			pgErr.Position = max(pgErr.Position-int32(len(declarePrefix)), 1)
+		}
+		return nil, err
 	}
-	err := rows.Scan(values...)
+	return tx.Query(ctx, fmt.Sprintf("fetch forward %d from query_rows", maxCursorRows+1))
+}
+
+// render formats a page, followed by a note if rows were omitted.
+func render(p page, f format.Format) (string, error) {
+	out, err := f.Rows(p.columns, p.rows)
 	if err != nil {
-		return nil, fmt.Errorf("error scanning row: %w", err)
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(out, "\n"))
+
+	omitted := fmt.Sprintf("%d more rows were", p.remaining)
+	if p.more {
+		omitted = fmt.Sprintf("more than %d rows were", p.remaining)
 	}
 
-	valuesMap := make(map[string]any)
-	for i, field := range rows.FieldDescriptions() {
-		valuesMap[field.Name] = values[i]
+	switch {
+	case p.cursorID != "":
+		fmt.Fprintf(&b, "\n\nThe result was truncated: %s omitted. To get the next page, call execute_aws_query with %s %q. If you don't need every row, narrow the query with a WHERE clause or select fewer columns instead.", omitted, parameterCursor, p.cursorID)
+	case p.more:
+		fmt.Fprintf(&b, "\n\nThe result was truncated: only the first %d rows can be fetched, and the rest were omitted. Narrow the query with a WHERE clause or an aggregate to see them.", maxCursorRows)
 	}
 
-	return valuesMap, nil
+	return b.String(), nil
 }
@@ -0,0 +1,54 @@
+package dml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
+)
+
+func TestRenderNotes(t *testing.T) {
+	tests := []struct {
+		name string
+		page page
+		want string
+	}{
+		{
+			name: "every row returned",
+			page: page{},
+		},
+		{
+			name: "more pages",
+			page: page{remaining: 40, cursorID: "c1"},
+			want: `40 more rows were omitted. To get the next page, call execute_aws_query with cursor "c1"`,
+		},
+		{
+			name: "more pages & unread rows",
+			page: page{remaining: 40, more: true, cursorID: "c1"},
+			want: `more than 40 rows were omitted. To get the next page, call execute_aws_query with cursor "c1"`,
+		},
+		{
+			name: "last page of unread rows",
+			page: page{more: true},
+			want: "only the first 5000 rows can be fetched",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.page.columns = []string{"name"}
+			tt.page.rows = [][]any{{"users"}}
+
+			got, err := render(tt.page, format.JSON)
+			if err != nil {
+				t.Fatalf("error rendering page: %v", err)
+			}
+			if tt.want == "" && strings.Contains(got, "truncated") {
+				t.Errorf("got %q, want no note", got)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("got %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package explain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/catalog"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/sqlerror"
+	"github.com/fergalhk/llm-cloud-discovery/internal/pgsql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	parameterQuery = "query"
+
+	// statementTimeout is short, as planning a query doesn't call the plugins' APIs.
+	statementTimeout = 30 * time.Second
+
+	nodeTypeForeignScan = "Foreign Scan"
+
+	// explainPrefix is prepended to the query, so error positions are offset by its length.
+	explainPrefix = "explain (verbose, format json) "
+)
+
+type (
+	Tool struct {
+		db        *pgxpool.Pool
+		catalog   *catalog.Catalog
+		describer *sqlerror.Describer
+	}
+
+	result struct {
+		Valid bool            `json:"valid"`
+		Error *sqlerror.Error `json:"error,omitempty"`
+		// APITables are the tables whose APIs will be called when the query runs.
+		APITables []apiTable `json:"api_tables,omitempty"`
+		// MissingQuals are the required key columns the query doesn't filter on, which will make it fail.
+		MissingQuals []missingQual `json:"missing_required_quals,omitempty"`
+		Note         string        `json:"note,omitempty"`
+	}
+
+	apiTable struct {
+		Table         string   `json:"table"`
+		Aliases       []string `json:"aliases,omitempty"`
+		Scans         int      `json:"scans"`
+		EstimatedRows float64  `json:"estimated_rows"`
+	}
+
+	missingQual struct {
+		Table string `json:"table"`
+		// Columns must all be in the WHERE clause, unless AnyOf is set, when one of them must be.
+		Columns []string `json:"columns"`
+		AnyOf   bool     `json:"any_of,omitempty"`
+	}
+
+	// plan is a node of the output of EXPLAIN (VERBOSE, FORMAT JSON). Schema is only set in verbose output.
+	plan struct {
+		NodeType     string  `json:"Node Type"`
+		RelationName string  `json:"Relation Name"`
+		Schema       string  `json:"Schema"`
+		Alias        string  `json:"Alias"`
+		PlanRows     float64 `json:"Plan Rows"`
+		Plans        []plan  `json:"Plans"`
+	}
+)
+
+func New(db *pgxpool.Pool) tools.Function {
+	return &Tool{
+		db:        db,
+		catalog:   catalog.New(db),
+		describer: sqlerror.NewDescriber(db),
+	}
+}
+
+func (t Tool) Name() string {
+	return "explain_aws_query"
+}
+
+func (t Tool) Description() string {
+	return `Checks a SQL query without running it, using EXPLAIN. No APIs are called, so it's fast, and should be used before running large or complex queries with the execute_aws_query tool.
+The response is a JSON object. valid is false if the query has an error, such as a syntax error or an unknown table or column, in which case error says what went wrong & where, along with the nearest matching names for unknown columns & tables.
+api_tables lists the tables whose APIs the query will call, with the planner's rough estimate of how many rows each returns. missing_required_quals lists key columns that the query must filter on in its WHERE clause, otherwise it fails when run.`
+}
+
+func (t Tool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterQuery,
+			Description: "The SQL query to check, without EXPLAIN. The same restrictions apply as for the execute_aws_query tool.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	query, _ := parameters[parameterQuery].(string)
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+
+	tokens, err := pgsql.Tokenize(query)
+	if err != nil {
+		return marshal(result{Error: &sqlerror.Error{Message: err.Error()}})
+	}
+	if len(tokens) > 0 && tokens[0].Is("explain") {
+		return "", fmt.Errorf("pass the query without EXPLAIN")
+	}
+	if err := pgsql.CheckReadOnly(query); err != nil {
+		return "", fmt.Errorf("query rejected: %w", err)
+	}
+
+	var planJSON string
+	err = steampipe.ReadOnly(ctx, t.db, statementTimeout, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, explainPrefix+query).Scan(&planJSON)
+	})
+	if err != nil {
+		// positions count from the start of the EXPLAIN, not of the query
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Position > 0 {
+			pgErr.Position = max(pgErr.Position-int32(len(explainPrefix)), 1)
+		}
+		if sqlErr, ok := t.describer.Describe(ctx, err, query); ok {
+			return marshal(result{Error: &sqlErr})
+		}
+		return "", fmt.Errorf("error explaining query: %w", err)
+	}
+
+	var plans []struct {
+		Plan plan `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(planJSON), &plans); err != nil {
+		return "", fmt.Errorf("error parsing query plan: %w", err)
+	}
+
+	out := result{Valid: true, APITables: []apiTable{}}
+	for _, p := range plans {
+		out.APITables = foreignScans(p.Plan, out.APITables)
+	}
+
+	out.MissingQuals, err = t.missingQuals(ctx, out.APITables, filteredNames(tokens))
+	if err != nil {
+		return "", err
+	}
+	if len(out.MissingQuals) > 0 {
+		out.Valid = false
+		out.Note = "The query will fail unless the missing required quals are added to its WHERE clause. Required quals are checked by column name only, so a column used for another table may hide a missing one."
+	}
+
+	return marshal(out)
+}
+
+// missingQuals returns the required key columns of each table that aren't among the filtered names.
+func (t Tool) missingQuals(ctx context.Context, scans []apiTable, filtered map[string]bool) ([]missingQual, error) {
+	out := []missingQual{}
+	for _, scan := range scans {
+		table, err := t.catalog.Table(ctx, "", scan.Table)
+		if errors.Is(err, catalog.ErrTableNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		required, anyOf := table.RequiredQuals()
+		missing := []string{}
+		for _, c := range required {
+			if !filtered[c] {
+				missing = append(missing, c)
+			}
+		}
+		if len(missing) > 0 {
+			out = append(out, missingQual{Table: scan.Table, Columns: missing})
+		}
+		if len(anyOf) > 0 && !slices.ContainsFunc(anyOf, func(c string) bool { return filtered[c] }) {
+			out = append(out, missingQual{Table: scan.Table, Columns: anyOf, AnyOf: true})
+		}
+	}
+	return out, nil
+}
+
+// foreignScans adds the foreign tables scanned by a plan node & its children to scans. Every
+// steampipe table is a foreign table, and scanning one calls its plugin's APIs.
+func foreignScans(p plan, scans []apiTable) []apiTable {
+	if p.NodeType == nodeTypeForeignScan && p.RelationName != "" {
+		name := p.RelationName
+		if p.Schema != "" {
+			name = p.Schema + "." + name
+		}
+
+		i := slices.IndexFunc(scans, func(s apiTable) bool { return s.Table == name })
+		if i < 0 {
+			scans = append(scans, apiTable{Table: name})
+			i = len(scans) - 1
+		}
+		scans[i].Scans++
+		scans[i].EstimatedRows += p.PlanRows
+		if p.Alias != "" && p.Alias != p.RelationName && !slices.Contains(scans[i].Aliases, p.Alias) {
+			scans[i].Aliases = append(scans[i].Aliases, p.Alias)
+		}
+	}
+
+	for _, child := range p.Plans {
+		scans = foreignScans(child, scans)
+	}
+	return scans
+}
+
+// filteredNames returns the names used in WHERE, ON & HAVING clauses anywhere in the query, which
+// are the columns it may filter on. Qualified names such as i.instance_id are reduced to
+// the column name.
+func filteredNames(tokens []pgsql.Token) map[string]bool {
+	out := map[string]bool{}
+	filtering := false
+	for _, tok := range tokens {
+		switch {
+		case tok.Is("where") || tok.Is("on") || tok.Is("having"):
+			filtering = true
+		case tok.Is("group") || tok.Is("order") || tok.Is("limit") || tok.Is("select") || tok.Is("from"):
+			filtering = false
+		case filtering && tok.IsName():
+			out[tok.Value] = true
+		}
+	}
+	return out
+}
+
+func marshal(out result) (string, error) {
+	dataJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling result to JSON: %w", err)
+	}
+	return string(dataJSON), nil
+}
@@ -0,0 +1,341 @@
+package join
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/catalog"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	parameterLeftTable  = "left_table"
+	parameterRightTable = "right_table"
+
+	confidenceHigh   = "high"
+	confidenceMedium = "medium"
+	confidenceLow    = "low"
+
+	sourceLibrary   = "known relationship"
+	sourceReference = "reference column"
+	sourceShared    = "shared column"
+)
+
+// keywords are the short SQL keywords that can't be used as table aliases.
+var keywords = []string{"as", "at", "by", "do", "if", "in", "is", "of", "on", "or", "to"}
+
+type (
+	Tool struct {
+		catalog *catalog.Catalog
+	}
+
+	result struct {
+		Suggestions []suggestion `json:"suggestions"`
+		Note        string       `json:"note,omitempty"`
+	}
+
+	suggestion struct {
+		Condition  string `json:"condition"`
+		Source     string `json:"source"`
+		Confidence string `json:"confidence"`
+		Note       string `json:"note,omitempty"`
+		ExampleSQL string `json:"example_sql"`
+	}
+
+	// side is one of the tables being joined, along with its alias in the suggested SQL.
+	side struct {
+		table catalog.Table
+		alias string
+	}
+)
+
+func New(db *pgxpool.Pool) tools.Function {
+	return &Tool{
+		catalog: catalog.New(db),
+	}
+}
+
+func (t Tool) Name() string {
+	return "suggest_join"
+}
+
+func (t Tool) Description() string {
+	return `Suggests how to join two resource tables, for example which column of aws_ecs_service refers to aws_ecs_task_definition.
+Suggestions come from a library of known relationships, from columns holding the ARNs or IDs of the other table's resources, and from columns the tables share. Each has a confidence, and example SQL that can be adapted and run with the execute_aws_query tool.
+The response is a JSON object, with the most likely suggestions first.`
+}
+
+func (t Tool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterLeftTable,
+			Description: "The exact name of the first table, e.g. aws_ecs_service.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterRightTable,
+			Description: "The exact name of the second table, e.g. aws_ecs_task_definition.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	leftName, _ := parameters[parameterLeftTable].(string)
+	if leftName == "" {
+		return "", fmt.Errorf("%s is required", parameterLeftTable)
+	}
+	rightName, _ := parameters[parameterRightTable].(string)
+	if rightName == "" {
+		return "", fmt.Errorf("%s is required", parameterRightTable)
+	}
+
+	left, err := t.side(ctx, leftName, "")
+	if err != nil {
+		return "", err
+	}
+	right, err := t.side(ctx, rightName, left.alias)
+	if err != nil {
+		return "", err
+	}
+
+	out := result{Suggestions: suggest(left, right)}
+	if len(out.Suggestions) == 0 {
+		out.Note = "No join conditions were found. Check the schemas of both tables with the get_aws_table_schema tool, as the tables may need to be joined through a third table, or by a value inside a JSON column."
+	}
+
+	// SQL operators such as ->> are left unescaped, so the example SQL can be copied as is
+	var outJSON strings.Builder
+	enc := json.NewEncoder(&outJSON)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(out); err != nil {
+		return "", fmt.Errorf("error marshalling suggestions to JSON: %w", err)
+	}
+
+	return strings.TrimSpace(outJSON.String()), nil
+}
+
+func (t Tool) side(ctx context.Context, name, otherAlias string) (side, error) {
+	table, err := t.catalog.Table(ctx, "", name)
+	if errors.Is(err, catalog.ErrTableNotFound) {
+		return side{}, fmt.Errorf("table %s does not exist, use the list_aws_tables tool to find its exact name", name)
+	}
+	if err != nil {
+		return side{}, err
+	}
+	return newSide(table, otherAlias), nil
+}
+
+// newSide returns a side for a table, with an alias that differs from the other side's.
+func newSide(table catalog.Table, otherAlias string) side {
+	a := alias(table.Name)
+	if a == otherAlias {
+		a += "2"
+	}
+	return side{table: table, alias: a}
+}
+
+// suggest returns the join conditions between two tables, most likely first.
+func suggest(left, right side) []suggestion {
+	out := []suggestion{}
+	seen := map[string]bool{}
+	add := func(s suggestion) {
+		key := canonical(s.Condition)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		s.ExampleSQL = exampleSQL(left, right, s.Condition)
+		out = append(out, s)
+	}
+
+	for _, r := range library {
+		for _, dir := range [][2]side{{left, right}, {right, left}} {
+			from, to := dir[0], dir[1]
+			if r.fromTable != from.table.Name || r.toTable != to.table.Name {
+				continue
+			}
+			add(suggestion{
+				Condition:  condition(r.kind, from.alias, r.fromColumn, r.fromKey, to.alias, r.toColumn),
+				Source:     sourceLibrary,
+				Confidence: confidenceHigh,
+				Note:       r.note,
+			})
+		}
+	}
+
+	for _, dir := range [][2]side{{left, right}, {right, left}} {
+		for _, s := range references(dir[0], dir[1]) {
+			add(s)
+		}
+	}
+
+	for _, s := range shared(left, right) {
+		add(s)
+	}
+
+	return out
+}
+
+// references finds columns of from that are named after to's resources, e.g. a vpc_id column
+// referring to aws_vpc, or a role_arn column referring to aws_iam_role.
+func references(from, to side) []suggestion {
+	out := []suggestion{}
+	for _, noun := range nouns(to.table.Name) {
+		for _, suffix := range []string{"_arn", "_arns", "_id", "_ids", "_name"} {
+			fromCol, ok := from.table.Column(noun + suffix)
+			if !ok {
+				continue
+			}
+
+			// the referenced column is the target's own column of the same name, e.g. cluster_arn,
+			// otherwise its generic arn, id or name column
+			singular := strings.TrimSuffix(suffix, "s")
+			toColumn := noun + singular
+			if _, ok := to.table.Column(toColumn); !ok {
+				toColumn = strings.TrimPrefix(singular, "_")
+				if _, ok := to.table.Column(toColumn); !ok {
+					continue
+				}
+			}
+			if from.table.Name == to.table.Name && fromCol.Name == toColumn {
+				continue
+			}
+
+			kind := referenceScalar
+			if singular != suffix || fromCol.Type == "jsonb" {
+				kind = referenceArray
+			}
+
+			s := suggestion{
+				Condition:  condition(kind, from.alias, fromCol.Name, "", to.alias, toColumn),
+				Source:     sourceReference,
+				Confidence: confidenceHigh,
+			}
+			if singular != "_arn" {
+				s.Confidence = confidenceMedium
+				if scope := scopeCondition(from, to); scope != "" {
+					s.Condition += scope
+					s.Note = "IDs & names are only unique within an account & region, so those are matched too"
+				}
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// shared finds reference columns both tables have, such as vpc_id. These mean the resources
+// refer to the same thing, e.g. are in the same VPC, rather than to each other.
+func shared(left, right side) []suggestion {
+	out := []suggestion{}
+	for _, c := range left.table.Columns {
+		if !catalog.IsReferenceColumn(c.Name) || strings.HasSuffix(c.Name, "s") || c.Name == "account_id" {
+			continue
+		}
+		if _, ok := right.table.Column(c.Name); !ok {
+			continue
+		}
+		out = append(out, suggestion{
+			Condition:  fmt.Sprintf("%s.%s = %s.%s", left.alias, c.Name, right.alias, c.Name) + scopeCondition(left, right),
+			Source:     sourceShared,
+			Confidence: confidenceLow,
+			Note:       fmt.Sprintf("both resources refer to the same %s, rather than to each other", strings.TrimSuffix(strings.TrimSuffix(c.Name, "_id"), "_arn")),
+		})
+	}
+	return out
+}
+
+// scopeCondition matches the account & region of both tables, for joins on values that are only
+// unique within them.
+func scopeCondition(a, b side) string {
+	out := ""
+	for _, c := range []string{"account_id", "region"} {
+		_, okA := a.table.Column(c)
+		_, okB := b.table.Column(c)
+		if okA && okB {
+			out += fmt.Sprintf(" and %s.%s = %s.%s", a.alias, c, b.alias, c)
+		}
+	}
+	return out
+}
+
+func exampleSQL(left, right side, condition string) string {
+	return fmt.Sprintf(`select
+  %s,
+  %s
+from
+  %s as %s
+  join %s as %s on %s
+limit 10`,
+		displayColumn(left), displayColumn(right),
+		left.table.Name, left.alias,
+		right.table.Name, right.alias, condition)
+}
+
+// displayColumn selects a column that identifies a table's resources, named after the table.
+func displayColumn(s side) string {
+	col := "title"
+	if _, ok := s.table.Column(col); !ok && len(s.table.Columns) > 0 {
+		col = s.table.Columns[0].Name
+	}
+	name := unprefixed(s.table.Name)
+	return fmt.Sprintf("%s.%s as %s", s.alias, col, name)
+}
+
+// nouns returns the names a table's resources may be referred to by in other tables' columns,
+// longest first. For example aws_ecs_task_definition gives ecs_task_definition, task_definition
+// and definition.
+func nouns(table string) []string {
+	parts := strings.Split(unprefixed(table), "_")
+	out := []string{}
+	for i := range parts {
+		out = append(out, strings.Join(parts[i:], "_"))
+	}
+	slices.SortStableFunc(out, func(a, b string) int { return len(b) - len(a) })
+	return out
+}
+
+// alias returns a short alias for a table from the initials of its name, e.g. etd for aws_ecs_task_definition.
+func alias(table string) string {
+	a := ""
+	for _, part := range strings.Split(unprefixed(table), "_") {
+		if part != "" {
+			a += part[:1]
+		}
+	}
+	// single letters are hard to read, and short aliases can clash with keywords such as "is" or "on"
+	if len(a) < 2 || slices.Contains(keywords, a) {
+		a = unprefixed(table)
+	}
+	return a
+}
+
+// unprefixed returns a table's name without its plugin prefix, e.g. ec2_instance for aws_ec2_instance.
+func unprefixed(table string) string {
+	if _, rest, ok := strings.Cut(table, "_"); ok {
+		return rest
+	}
+	return table
+}
+
+// canonical returns the first part of a condition with the operands of its equality sorted, so
+// that the same join found from either table is only suggested once.
+func canonical(condition string) string {
+	first, _, _ := strings.Cut(condition, " and ")
+	a, b, ok := strings.Cut(first, " = ")
+	if !ok {
+		return first
+	}
+	if b < a {
+		a, b = b, a
+	}
+	return a + " = " + b
+}
@@ -0,0 +1,165 @@
+package join
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/catalog"
+)
+
+// loadTables returns the fixture tables in testdata/tables.json by name.
+func loadTables(t *testing.T) map[string]catalog.Table {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/tables.json")
+	if err != nil {
+		t.Fatalf("error reading fixture: %v", err)
+	}
+	var tables []catalog.Table
+	if err := json.Unmarshal(data, &tables); err != nil {
+		t.Fatalf("error unmarshalling fixture: %v", err)
+	}
+
+	out := map[string]catalog.Table{}
+	for _, table := range tables {
+		out[table.Name] = table
+	}
+	return out
+}
+
+func TestSuggest(t *testing.T) {
+	const scopeNote = "IDs & names are only unique within an account & region, so those are matched too"
+
+	tests := []struct {
+		name  string
+		left  string
+		right string
+		want  []suggestion
+	}{
+		{
+			name:  "known relationship",
+			left:  "aws_ecs_service",
+			right: "aws_ecs_task_definition",
+			want: []suggestion{
+				{Condition: "es.task_definition = etd.task_definition_arn", Source: sourceLibrary, Confidence: confidenceHigh, Note: "the service's current task definition"},
+			},
+		},
+		{
+			name:  "known relationship from the right table",
+			left:  "aws_ecs_task_definition",
+			right: "aws_ecs_service",
+			want: []suggestion{
+				{Condition: "es.task_definition = etd.task_definition_arn", Source: sourceLibrary, Confidence: confidenceHigh, Note: "the service's current task definition"},
+			},
+		},
+		{
+			name:  "reference column in the library suggested once",
+			left:  "aws_ecs_cluster",
+			right: "aws_ecs_service",
+			want: []suggestion{
+				{Condition: "es.cluster_arn = ec.cluster_arn", Source: sourceLibrary, Confidence: confidenceHigh},
+			},
+		},
+		{
+			name:  "JSON array of references",
+			left:  "aws_iam_role",
+			right: "aws_iam_policy",
+			want: []suggestion{
+				{Condition: "ir.attached_policy_arns ? ip.arn", Source: sourceLibrary, Confidence: confidenceHigh},
+			},
+		},
+		{
+			name:  "JSON array of objects & a shared column",
+			left:  "aws_ec2_instance",
+			right: "aws_vpc_security_group",
+			want: []suggestion{
+				{Condition: "vsg.group_id in (select jsonb_array_elements(ei.security_groups) ->> 'GroupId')", Source: sourceLibrary, Confidence: confidenceHigh},
+				{Condition: "ei.vpc_id = vsg.vpc_id and ei.account_id = vsg.account_id and ei.region = vsg.region", Source: sourceShared, Confidence: confidenceLow, Note: "both resources refer to the same vpc, rather than to each other"},
+			},
+		},
+		{
+			name:  "ID column scoped to the account & region",
+			left:  "aws_vpc_subnet",
+			right: "aws_ec2_instance",
+			want: []suggestion{
+				{Condition: "ei.subnet_id = vs.subnet_id and ei.account_id = vs.account_id and ei.region = vs.region", Source: sourceReference, Confidence: confidenceMedium, Note: scopeNote},
+				{Condition: "vs.vpc_id = ei.vpc_id and vs.account_id = ei.account_id and vs.region = ei.region", Source: sourceShared, Confidence: confidenceLow, Note: "both resources refer to the same vpc, rather than to each other"},
+			},
+		},
+		{
+			name:  "IDs column",
+			left:  "aws_rds_db_subnet_group",
+			right: "aws_vpc_subnet",
+			want: []suggestion{
+				{Condition: "rdsg.subnet_ids ? vs.subnet_id and rdsg.account_id = vs.account_id and rdsg.region = vs.region", Source: sourceReference, Confidence: confidenceMedium, Note: scopeNote},
+				{Condition: "rdsg.vpc_id = vs.vpc_id and rdsg.account_id = vs.account_id and rdsg.region = vs.region", Source: sourceShared, Confidence: confidenceLow, Note: "both resources refer to the same vpc, rather than to each other"},
+			},
+		},
+		{
+			name:  "same table",
+			left:  "aws_vpc_subnet",
+			right: "aws_vpc_subnet",
+			want: []suggestion{
+				{Condition: "vs.subnet_id = vs2.subnet_id and vs.account_id = vs2.account_id and vs.region = vs2.region", Source: sourceShared, Confidence: confidenceLow, Note: "both resources refer to the same subnet, rather than to each other"},
+				{Condition: "vs.vpc_id = vs2.vpc_id and vs.account_id = vs2.account_id and vs.region = vs2.region", Source: sourceShared, Confidence: confidenceLow, Note: "both resources refer to the same vpc, rather than to each other"},
+			},
+		},
+		{
+			name:  "unrelated",
+			left:  "aws_iam_policy",
+			right: "aws_vpc_subnet",
+			want:  []suggestion{},
+		},
+	}
+
+	tables := loadTables(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left := newSide(tables[tt.left], "")
+			right := newSide(tables[tt.right], left.alias)
+
+			got := suggest(left, right)
+			for i := range got {
+				got[i].ExampleSQL = ""
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExampleSQL(t *testing.T) {
+	tables := loadTables(t)
+	left := newSide(tables["aws_vpc_subnet"], "")
+	right := newSide(tables["aws_vpc_subnet"], left.alias)
+
+	want := `select
+  vs.title as vpc_subnet,
+  vs2.title as vpc_subnet
+from
+  aws_vpc_subnet as vs
+  join aws_vpc_subnet as vs2 on vs.vpc_id = vs2.vpc_id
+limit 10`
+	if got := exampleSQL(left, right, "vs.vpc_id = vs2.vpc_id"); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	tests := map[string]string{
+		"aws_ecs_task_definition": "etd",
+		"aws_s3_bucket":           "sb",
+		// single letters & keywords are replaced by the unprefixed name
+		"aws_vpc":            "vpc",
+		"aws_inspector_scan": "inspector_scan",
+	}
+
+	for table, want := range tests {
+		if got := alias(table); got != want {
+			t.Errorf("alias(%s) = %s, want %s", table, got, want)
+		}
+	}
+}
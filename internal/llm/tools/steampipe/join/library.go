@@ -0,0 +1,67 @@
+package join
+
+import "fmt"
+
+type (
+	// relationship is a known link from a column of one table to a column of another.
+	relationship struct {
+		fromTable  string
+		fromColumn string
+		// fromKey is set if fromColumn is a JSON array of objects, and the reference is in this key of each object.
+		fromKey  string
+		kind     referenceKind
+		toTable  string
+		toColumn string
+		note     string
+	}
+
+	referenceKind int
+)
+
+const (
+	// referenceScalar is a column holding a single reference.
+	referenceScalar referenceKind = iota
+	// referenceArray is a JSON array of references.
+	referenceArray
+	// referenceObjectArray is a JSON array of objects, each of which holds a reference in a key.
+	referenceObjectArray
+)
+
+// library holds relationships that can't be inferred from column names alone.
+var library = []relationship{
+	{fromTable: "aws_ecs_service", fromColumn: "task_definition", toTable: "aws_ecs_task_definition", toColumn: "task_definition_arn", note: "the service's current task definition"},
+	{fromTable: "aws_ecs_service", fromColumn: "cluster_arn", toTable: "aws_ecs_cluster", toColumn: "cluster_arn"},
+	{fromTable: "aws_ecs_service", fromColumn: "load_balancers", fromKey: "TargetGroupArn", kind: referenceObjectArray, toTable: "aws_ec2_target_group", toColumn: "target_group_arn", note: "the target groups the service registers its tasks with"},
+	{fromTable: "aws_ecs_task", fromColumn: "task_definition_arn", toTable: "aws_ecs_task_definition", toColumn: "task_definition_arn"},
+	{fromTable: "aws_ecs_task", fromColumn: "cluster_arn", toTable: "aws_ecs_cluster", toColumn: "cluster_arn"},
+	{fromTable: "aws_ecs_container_instance", fromColumn: "cluster_arn", toTable: "aws_ecs_cluster", toColumn: "cluster_arn"},
+	{fromTable: "aws_ecs_task_definition", fromColumn: "task_role_arn", toTable: "aws_iam_role", toColumn: "arn", note: "the role the task's containers assume"},
+	{fromTable: "aws_ecs_task_definition", fromColumn: "execution_role_arn", toTable: "aws_iam_role", toColumn: "arn", note: "the role ECS uses to pull images & write logs"},
+	{fromTable: "aws_ec2_instance", fromColumn: "security_groups", fromKey: "GroupId", kind: referenceObjectArray, toTable: "aws_vpc_security_group", toColumn: "group_id"},
+	{fromTable: "aws_iam_role", fromColumn: "instance_profile_arns", kind: referenceArray, toTable: "aws_ec2_instance", toColumn: "iam_instance_profile_arn", note: "instances use the role through its instance profile"},
+	{fromTable: "aws_ebs_volume", fromColumn: "attachments", fromKey: "InstanceId", kind: referenceObjectArray, toTable: "aws_ec2_instance", toColumn: "instance_id"},
+	{fromTable: "aws_ec2_network_interface", fromColumn: "attached_instance_id", toTable: "aws_ec2_instance", toColumn: "instance_id"},
+	{fromTable: "aws_ec2_target_group", fromColumn: "load_balancer_arns", kind: referenceArray, toTable: "aws_ec2_application_load_balancer", toColumn: "arn"},
+	{fromTable: "aws_ec2_target_group", fromColumn: "load_balancer_arns", kind: referenceArray, toTable: "aws_ec2_network_load_balancer", toColumn: "arn"},
+	{fromTable: "aws_lambda_function", fromColumn: "role", toTable: "aws_iam_role", toColumn: "arn", note: "the function's execution role"},
+	{fromTable: "aws_rds_db_instance", fromColumn: "kms_key_id", toTable: "aws_kms_key", toColumn: "arn"},
+	{fromTable: "aws_eks_cluster", fromColumn: "role_arn", toTable: "aws_iam_role", toColumn: "arn"},
+	{fromTable: "aws_eks_node_group", fromColumn: "cluster_name", toTable: "aws_eks_cluster", toColumn: "name"},
+	{fromTable: "aws_iam_role", fromColumn: "attached_policy_arns", kind: referenceArray, toTable: "aws_iam_policy", toColumn: "arn"},
+	{fromTable: "aws_iam_user", fromColumn: "attached_policy_arns", kind: referenceArray, toTable: "aws_iam_policy", toColumn: "arn"},
+	{fromTable: "aws_iam_group", fromColumn: "attached_policy_arns", kind: referenceArray, toTable: "aws_iam_policy", toColumn: "arn"},
+	{fromTable: "aws_sns_topic_subscription", fromColumn: "topic_arn", toTable: "aws_sns_topic", toColumn: "topic_arn"},
+	{fromTable: "aws_route53_record", fromColumn: "zone_id", toTable: "aws_route53_zone", toColumn: "id"},
+}
+
+// condition returns the SQL join condition of a reference from fromAlias.fromColumn to toAlias.toColumn.
+func condition(kind referenceKind, fromAlias, fromColumn, fromKey, toAlias, toColumn string) string {
+	switch kind {
+	case referenceArray:
+		return fmt.Sprintf("%s.%s ? %s.%s", fromAlias, fromColumn, toAlias, toColumn)
+	case referenceObjectArray:
+		return fmt.Sprintf("%s.%s in (select jsonb_array_elements(%s.%s) ->> '%s')", toAlias, toColumn, fromAlias, fromColumn, fromKey)
+	default:
+		return fmt.Sprintf("%s.%s = %s.%s", fromAlias, fromColumn, toAlias, toColumn)
+	}
+}
@@ -0,0 +1,43 @@
+// Package steampipe holds the database access shared by the steampipe tools.
+package steampipe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// healthCheckPeriod is how often idle connections are checked, so that connections broken by
+	// the steampipe service restarting are replaced before a tool needs them.
+	healthCheckPeriod = 15 * time.Second
+	// maxConnIdleTime closes connections that haven't been used for a while, as the model can
+	// spend a long time between tool calls.
+	maxConnIdleTime = 5 * time.Minute
+)
+
+// NewPool creates a connection pool for the steampipe database. The pool is safe for concurrent
+// use by tools, and replaces broken connections, so it recovers once a restarted steampipe
+// service is available again.
+func NewPool(ctx context.Context, connString string) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing connection string: %w", err)
+	}
+	cfg.HealthCheckPeriod = healthCheckPeriod
+	cfg.MaxConnIdleTime = maxConnIdleTime
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating connection pool: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("error connecting to steampipe: %w", err)
+	}
+
+	return pool, nil
+}
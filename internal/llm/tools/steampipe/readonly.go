@@ -0,0 +1,27 @@
+package steampipe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReadOnly runs fn in a read only transaction, with a statement timeout. The transaction is never
+// committed, so nothing fn does can persist even if a statement gets past the checks in pgsql.
+func ReadOnly(ctx context.Context, db *pgxpool.Pool, statementTimeout time.Duration, fn func(pgx.Tx) error) error {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, fmt.Sprintf("set local statement_timeout = %d", statementTimeout.Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("error setting statement timeout: %w", err)
+	}
+
+	return fn(tx)
+}
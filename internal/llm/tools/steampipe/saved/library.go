@@ -0,0 +1,310 @@
+// Package saved runs documented, parameterized SQL queries from a library, so that the model can
+// use vetted queries for common questions instead of writing its own.
+//
+// Each query is a .sql file named after the query, e.g. public_s3_buckets.sql. The comment lines at
+// the top of the file describe it: plain lines are its description, and @param lines declare its
+// parameters as
+//
+//	-- @param <name> <string|integer|boolean> [required|default=<value>] <description>
+//
+// Parameters are optional unless they're required, and are null if they're not given & have no
+// default. They're referenced in the SQL as :name, and are substituted as SQL literals, e.g.
+//
+//	-- Lists the EC2 instances that don't require IMDSv2.
+//	-- @param region string Only list instances in this region.
+//	select instance_id from aws_ec2_instance where (:region is null or region = :region)
+package saved
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/pgsql"
+)
+
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+
+	queryExtension = ".sql"
+	paramDirective = "@param"
+)
+
+//go:embed queries/*.sql
+var defaultQueries embed.FS
+
+type (
+	// Library holds saved queries by name.
+	Library struct {
+		queries map[string]Query
+	}
+
+	Query struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description"`
+		Parameters  []Parameter `json:"parameters,omitempty"`
+		SQL         string      `json:"-"`
+	}
+
+	Parameter struct {
+		Name        string `json:"name"`
+		Type        string `json:"type"`
+		Required    bool   `json:"required,omitempty"`
+		Default     string `json:"default,omitempty"`
+		Description string `json:"description"`
+		hasDefault  bool
+	}
+)
+
+// Load reads the default queries, then those in dir if it's set. Queries in dir replace default
+// queries of the same name.
+func Load(dir string) (*Library, error) {
+	l := &Library{queries: map[string]Query{}}
+
+	defaults, err := fs.Sub(defaultQueries, "queries")
+	if err != nil {
+		return nil, fmt.Errorf("error reading default queries: %w", err)
+	}
+	if err := l.load(defaults); err != nil {
+		return nil, err
+	}
+
+	if dir != "" {
+		if err := l.load(os.DirFS(dir)); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+func (l *Library) load(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return fmt.Errorf("error reading saved queries: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != queryExtension {
+			continue
+		}
+
+		src, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return fmt.Errorf("error reading saved query %s: %w", entry.Name(), err)
+		}
+
+		q, err := parse(strings.TrimSuffix(entry.Name(), queryExtension), string(src))
+		if err != nil {
+			return fmt.Errorf("error parsing saved query %s: %w", entry.Name(), err)
+		}
+		l.queries[q.Name] = q
+	}
+
+	return nil
+}
+
+// Queries returns the queries in the library, sorted by name.
+func (l *Library) Queries() []Query {
+	out := make([]Query, 0, len(l.queries))
+	for _, q := range l.queries {
+		out = append(out, q)
+	}
+	slices.SortFunc(out, func(a, b Query) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// Query returns the query with the given name.
+func (l *Library) Query(name string) (Query, bool) {
+	q, ok := l.queries[name]
+	return q, ok
+}
+
+// parse reads a query's description & parameters from its header comments.
+func parse(name, src string) (Query, error) {
+	q := Query{Name: name}
+	description := []string{}
+	body := []string{}
+
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	header := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		comment, isComment := strings.CutPrefix(strings.TrimSpace(line), "--")
+		if !header || !isComment {
+			header = header && strings.TrimSpace(line) == ""
+			body = append(body, line)
+			continue
+		}
+
+		comment = strings.TrimSpace(comment)
+		if rest, ok := strings.CutPrefix(comment, paramDirective); ok {
+			p, err := parseParameter(rest)
+			if err != nil {
+				return Query{}, err
+			}
+			if slices.ContainsFunc(q.Parameters, func(other Parameter) bool { return other.Name == p.Name }) {
+				return Query{}, fmt.Errorf("parameter %s is declared more than once", p.Name)
+			}
+			q.Parameters = append(q.Parameters, p)
+		} else if comment != "" {
+			description = append(description, comment)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Query{}, err
+	}
+
+	q.Description = strings.Join(description, " ")
+	q.SQL = strings.TrimSpace(strings.Join(body, "\n"))
+	if q.Description == "" {
+		return Query{}, fmt.Errorf("query has no description")
+	}
+	if q.SQL == "" {
+		return Query{}, fmt.Errorf("query has no SQL")
+	}
+
+	// the defaults are rendered to check that they're valid for their type
+	for _, p := range q.Parameters {
+		if !p.hasDefault {
+			continue
+		}
+		if _, err := p.literal(nil); err != nil {
+			return Query{}, err
+		}
+	}
+	// references aren't SQL, so they're replaced by nulls for the check
+	nulls := map[string]string{}
+	for _, p := range q.Parameters {
+		nulls[p.Name] = "null"
+	}
+	sql, err := q.substitute(nulls)
+	if err != nil {
+		return Query{}, err
+	}
+	if err := pgsql.CheckReadOnly(sql); err != nil {
+		return Query{}, err
+	}
+
+	return q, nil
+}
+
+// parseParameter parses the rest of a @param line: name, type, an optional required or default=
+// flag, and a description.
+func parseParameter(s string) (Parameter, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return Parameter{}, fmt.Errorf("%s must have a name & type", paramDirective)
+	}
+
+	p := Parameter{Name: fields[0], Type: fields[1]}
+	if !slices.Contains([]string{TypeString, TypeInteger, TypeBoolean}, p.Type) {
+		return Parameter{}, fmt.Errorf("parameter %s has unknown type %s", p.Name, p.Type)
+	}
+
+	rest := fields[2:]
+	if len(rest) > 0 {
+		if rest[0] == "required" {
+			p.Required = true
+			rest = rest[1:]
+		} else if def, ok := strings.CutPrefix(rest[0], "default="); ok {
+			p.Default, p.hasDefault = def, true
+			rest = rest[1:]
+		}
+	}
+	p.Description = strings.Join(rest, " ")
+
+	return p, nil
+}
+
+// Render substitutes parameter values into the query. Values are given by parameter name, and
+// may be strings for any type, as models often pass numbers & booleans as strings.
+func (q Query) Render(values map[string]any) (string, error) {
+	for name := range values {
+		if !slices.ContainsFunc(q.Parameters, func(p Parameter) bool { return p.Name == name }) {
+			return "", fmt.Errorf("query %s has no parameter %s", q.Name, name)
+		}
+	}
+
+	literals := map[string]string{}
+	for _, p := range q.Parameters {
+		lit, err := p.literal(values)
+		if err != nil {
+			return "", err
+		}
+		literals[p.Name] = lit
+	}
+
+	return q.substitute(literals)
+}
+
+// substitute replaces each parameter reference with its literal.
+func (q Query) substitute(literals map[string]string) (string, error) {
+	tokens, err := pgsql.Tokenize(q.SQL)
+	if err != nil {
+		return "", err
+	}
+
+	// a reference is a colon immediately followed by a parameter's name; casts are tokenized as
+	// :: operators, so aren't mistaken for references, but array slices such as a[1:n] need a
+	// space after the colon if n is also a parameter name
+	var out strings.Builder
+	last := 0
+	for i := 0; i+1 < len(tokens); i++ {
+		colon, name := tokens[i], tokens[i+1]
+		lit, ok := literals[name.Value]
+		if !colon.IsPunctuation(':') || name.Kind != pgsql.TokenIdentifier || name.Pos != colon.Pos+1 || !ok {
+			continue
+		}
+		out.WriteString(q.SQL[last:colon.Pos])
+		out.WriteString(lit)
+		last = name.Pos + len(name.Value)
+		i++
+	}
+	out.WriteString(q.SQL[last:])
+
+	return out.String(), nil
+}
+
+// literal returns the parameter's value in values as a SQL literal, or its default if it isn't set.
+func (p Parameter) literal(values map[string]any) (string, error) {
+	if _, ok := values[p.Name]; !ok || values[p.Name] == nil || values[p.Name] == "" {
+		if p.Required {
+			return "", fmt.Errorf("parameter %s is required", p.Name)
+		}
+		if !p.hasDefault {
+			return "null", nil
+		}
+		values = map[string]any{p.Name: p.Default}
+	}
+
+	switch p.Type {
+	case TypeInteger:
+		i, err := tools.IntParameter(values, p.Name, 0)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(i), nil
+	case TypeBoolean:
+		b, err := tools.BoolParameter(values, p.Name, false)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	default:
+		s, ok := values[p.Name].(string)
+		if !ok {
+			s = fmt.Sprint(values[p.Name])
+		}
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'", nil
+	}
+}
@@ -0,0 +1,209 @@
+package saved
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+const testQuery = `-- Lists the instances of a type.
+-- @param instance_type string required The instance type.
+-- @param region string Only list instances in this region.
+-- @param max_results integer default=10 The most instances to list.
+-- @param running boolean default=true Whether to only list running instances.
+select instance_id, launch_time::date
+from aws_ec2_instance
+where instance_type = :instance_type
+  and (:region is null or region = :region::text)
+  and (not :running or instance_state = 'running')
+  and tags ->> 'note' <> ':region'
+limit :max_results`
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]any
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "defaults & nulls",
+			values: map[string]any{"instance_type": "t3.micro"},
+			want: []string{
+				"instance_type = 't3.micro'",
+				"(null is null or region = null::text)",
+				"(not true or instance_state = 'running')",
+				"limit 10",
+			},
+		},
+		{
+			name:   "quotes escaped",
+			values: map[string]any{"instance_type": "t3.micro' or '1'='1", "region": "eu-west-1"},
+			want: []string{
+				"instance_type = 't3.micro'' or ''1''=''1'",
+				"('eu-west-1' is null or region = 'eu-west-1'::text)",
+			},
+		},
+		{
+			name:   "casts & strings left alone",
+			values: map[string]any{"instance_type": "t3.micro"},
+			want:   []string{"launch_time::date", "tags ->> 'note' <> ':region'"},
+		},
+		{
+			name:   "numbers & booleans given as strings",
+			values: map[string]any{"instance_type": "t3.micro", "max_results": "5", "running": "false"},
+			want:   []string{"limit 5", "(not false or"},
+		},
+		{
+			name:    "required parameter missing",
+			values:  map[string]any{"region": "eu-west-1"},
+			wantErr: "parameter instance_type is required",
+		},
+		{
+			name:    "unknown parameter",
+			values:  map[string]any{"instance_type": "t3.micro", "zone": "eu-west-1a"},
+			wantErr: "query instances has no parameter zone",
+		},
+		{
+			name:    "invalid integer",
+			values:  map[string]any{"instance_type": "t3.micro", "max_results": "ten"},
+			wantErr: "max_results is not a valid integer",
+		},
+	}
+
+	q, err := parse("instances", testQuery)
+	if err != nil {
+		t.Fatalf("error parsing query: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := q.Render(tt.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error rendering query: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("got %s, want it to contain %s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	q, err := parse("instances", testQuery)
+	if err != nil {
+		t.Fatalf("error parsing query: %v", err)
+	}
+	if q.Description != "Lists the instances of a type." {
+		t.Errorf("got description %q", q.Description)
+	}
+	want := []Parameter{
+		{Name: "instance_type", Type: TypeString, Required: true, Description: "The instance type."},
+		{Name: "region", Type: TypeString, Description: "Only list instances in this region."},
+		{Name: "max_results", Type: TypeInteger, Default: "10", Description: "The most instances to list.", hasDefault: true},
+		{Name: "running", Type: TypeBoolean, Default: "true", Description: "Whether to only list running instances.", hasDefault: true},
+	}
+	if len(q.Parameters) != len(want) {
+		t.Fatalf("got parameters %+v, want %+v", q.Parameters, want)
+	}
+	for i := range want {
+		if q.Parameters[i] != want[i] {
+			t.Errorf("got parameter %+v, want %+v", q.Parameters[i], want[i])
+		}
+	}
+	if !strings.HasPrefix(q.SQL, "select") {
+		t.Errorf("got SQL %q, want the header comments removed", q.SQL)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "duplicate parameter",
+			src:  "-- Lists.\n-- @param region string A region.\n-- @param region string Another region.\nselect :region",
+			want: "parameter region is declared more than once",
+		},
+		{
+			name: "unknown type",
+			src:  "-- Lists.\n-- @param since timestamp The start.\nselect :since",
+			want: "parameter since has unknown type timestamp",
+		},
+		{
+			name: "no type",
+			src:  "-- Lists.\n-- @param region\nselect :region",
+			want: "@param must have a name & type",
+		},
+		{
+			name: "invalid integer default",
+			src:  "-- Lists.\n-- @param limit integer default=ten The most rows.\nselect 1 limit :limit",
+			want: "limit is not a valid integer",
+		},
+		{
+			name: "invalid boolean default",
+			src:  "-- Lists.\n-- @param all boolean default=maybe Whether to list all.\nselect :all",
+			want: "all is not a valid boolean",
+		},
+		{
+			name: "no description",
+			src:  "select 1",
+			want: "query has no description",
+		},
+		{
+			name: "no SQL",
+			src:  "-- Lists.",
+			want: "query has no SQL",
+		},
+		{
+			name: "not read only",
+			src:  "-- Deletes.\n-- @param id string The ID.\ndelete from aws_ec2_instance where instance_id = :id",
+			want: "DELETE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse("test", tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultQueries(t *testing.T) {
+	files, err := fs.Glob(defaultQueries, "queries/*.sql")
+	if err != nil {
+		t.Fatalf("error listing default queries: %v", err)
+	}
+
+	l, err := Load("")
+	if err != nil {
+		t.Fatalf("error loading default queries: %v", err)
+	}
+	if len(l.Queries()) != len(files) || len(files) == 0 {
+		t.Fatalf("got %d queries, want %d", len(l.Queries()), len(files))
+	}
+
+	for _, q := range l.Queries() {
+		values := map[string]any{}
+		for _, p := range q.Parameters {
+			if p.Required {
+				values[p.Name] = "1"
+			}
+		}
+		if _, err := q.Render(values); err != nil {
+			t.Errorf("error rendering %s: %v", q.Name, err)
+		}
+	}
+}
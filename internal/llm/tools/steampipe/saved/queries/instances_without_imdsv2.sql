@@ -0,0 +1,23 @@
+-- Lists the EC2 instances that don't require IMDSv2, so allow requests to the instance metadata
+-- service without a session token.
+-- @param region string Only list instances in this region, e.g. eu-west-1.
+-- @param include_stopped boolean default=false Whether to include instances that aren't running.
+select
+  instance_id,
+  tags ->> 'Name' as name,
+  instance_type,
+  instance_state,
+  metadata_options ->> 'HttpTokens' as http_tokens,
+  metadata_options ->> 'HttpEndpoint' as http_endpoint,
+  region,
+  account_id
+from
+  aws_ec2_instance
+where
+  coalesce(metadata_options ->> 'HttpTokens', '') <> 'required'
+  and (:include_stopped or instance_state = 'running')
+  and (:region is null or region = :region)
+order by
+  account_id,
+  region,
+  instance_id
@@ -0,0 +1,24 @@
+-- Lists the S3 buckets that may be public: those whose bucket policy allows public access, or that
+-- don't block public access with all four public access block settings.
+-- @param region string Only list buckets in this region, e.g. eu-west-1.
+select
+  name,
+  region,
+  account_id,
+  bucket_policy_is_public,
+  block_public_acls,
+  block_public_policy,
+  ignore_public_acls,
+  restrict_public_buckets
+from
+  aws_s3_bucket
+where
+  (
+    bucket_policy_is_public
+    or not coalesce(block_public_acls and block_public_policy and ignore_public_acls and restrict_public_buckets, false)
+  )
+  and (:region is null or region = :region)
+order by
+  account_id,
+  region,
+  name
@@ -0,0 +1,22 @@
+-- Lists the EBS volumes that aren't attached to any instance, which are still billed for their
+-- provisioned size.
+-- @param region string Only list volumes in this region, e.g. eu-west-1.
+-- @param min_size_gib integer default=0 Only list volumes of at least this size in GiB.
+select
+  volume_id,
+  tags ->> 'Name' as name,
+  volume_type,
+  size as size_gib,
+  create_time,
+  snapshot_id,
+  region,
+  account_id
+from
+  aws_ebs_volume
+where
+  state = 'available'
+  and size >= :min_size_gib
+  and (:region is null or region = :region)
+order by
+  size desc,
+  volume_id
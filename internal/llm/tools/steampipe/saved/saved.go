@@ -0,0 +1,152 @@
+package saved
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+)
+
+const (
+	parameterFilter     = "filter"
+	parameterName       = "name"
+	parameterParameters = "parameters"
+
+	// executorParameterQuery is the parameter of the executor tool that takes the SQL to run.
+	executorParameterQuery = "query"
+)
+
+type (
+	ListTool struct {
+		library *Library
+	}
+
+	RunTool struct {
+		library *Library
+		// executor runs the rendered SQL. It's the execute_aws_query tool, so that saved queries are
+		// subject to the same checks & limits, and their results are formatted & paged the same way.
+		executor tools.Function
+	}
+)
+
+func NewListTool(library *Library) tools.Function {
+	return &ListTool{library: library}
+}
+
+func NewRunTool(library *Library, executor tools.Function) tools.Function {
+	return &RunTool{library: library, executor: executor}
+}
+
+func (t ListTool) Name() string {
+	return "list_saved_queries"
+}
+
+func (t ListTool) Description() string {
+	return `Lists the saved queries, which are vetted SQL queries that answer common questions about AWS resources, such as which S3 buckets are public. Prefer a saved query over writing your own if one answers the question.
+The response is a JSON array with the name, description & parameters of each query. Run a query with the run_saved_query tool.`
+}
+
+func (t ListTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterFilter,
+			Description: "If set, only queries whose name or description contains this text are listed, e.g. s3 or ebs.",
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t ListTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	filter, _ := parameters[parameterFilter].(string)
+	filter = strings.ToLower(filter)
+
+	out := []Query{}
+	for _, q := range t.library.Queries() {
+		if strings.Contains(strings.ToLower(q.Name), filter) || strings.Contains(strings.ToLower(q.Description), filter) {
+			out = append(out, q)
+		}
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("no saved queries match %q", filter)
+	}
+
+	dataJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling saved queries to JSON: %w", err)
+	}
+
+	return string(dataJSON), nil
+}
+
+func (t RunTool) Name() string {
+	return "run_saved_query"
+}
+
+func (t RunTool) Description() string {
+	return fmt.Sprintf(`Runs a saved query, with the given parameter values. Use the list_saved_queries tool to find the saved queries & their parameters.
+The results are returned the same way as by the %[1]s tool, and further pages of large results are fetched with the %[1]s tool's cursor.`, t.executor.Name())
+}
+
+func (t RunTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterName,
+			Description: "The exact name of the saved query to run.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterParameters,
+			Description: `A JSON object of the values of the query's parameters by name, e.g. {"region": "eu-west-1"}. Parameters that aren't given use their defaults.`,
+			Type:        tools.ParameterTypeString,
+		},
+	}
+}
+
+func (t RunTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	name, _ := parameters[parameterName].(string)
+	if name == "" {
+		return "", fmt.Errorf("%s is required", parameterName)
+	}
+
+	q, ok := t.library.Query(name)
+	if !ok {
+		return "", fmt.Errorf("saved query %s does not exist, use the list_saved_queries tool to find its exact name", name)
+	}
+
+	values, err := parameterValues(parameters[parameterParameters])
+	if err != nil {
+		return "", err
+	}
+
+	sql, err := q.Render(values)
+	if err != nil {
+		return "", err
+	}
+
+	return t.executor.Call(ctx, map[string]any{executorParameterQuery: sql})
+}
+
+// parameterValues reads the values of a query's parameters, which models may pass as a JSON
+// string or as an object.
+func parameterValues(v any) (map[string]any, error) {
+	switch v := v.(type) {
+	case nil:
+		return map[string]any{}, nil
+	case map[string]any:
+		return v, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return map[string]any{}, nil
+		}
+		values := map[string]any{}
+		if err := json.Unmarshal([]byte(v), &values); err != nil {
+			return nil, fmt.Errorf("%s is not a valid JSON object: %w", parameterParameters, err)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("%s must be a JSON object", parameterParameters)
+	}
+}
@@ -3,32 +3,41 @@ package schema
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
-	"github.com/jackc/pgx/v5"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/catalog"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 const (
 	parameterResourceType = "resource_type"
-
-	getSchemaQuery = `
-select
-  column_name,
-  data_type
-from
-  information_schema.columns
-where table_name = $1 and table_schema = 'aws'
-`
+	parameterSchema       = "schema"
 )
 
-type Tool struct {
-	db *pgx.Conn
-}
+type (
+	Tool struct {
+		catalog *catalog.Catalog
+	}
 
-func New(db *pgx.Conn) tools.Function {
+	result struct {
+		Schema      string           `json:"schema"`
+		Table       string           `json:"table"`
+		Description string           `json:"description,omitempty"`
+		Columns     []catalog.Column `json:"columns"`
+		// RequiredQuals must be in the WHERE clause of every query on the table.
+		RequiredQuals []string `json:"required_quals,omitempty"`
+		// AnyOfQuals must have at least one member in the WHERE clause of every query on the table.
+		AnyOfQuals  []string `json:"any_of_quals,omitempty"`
+		JoinColumns []string `json:"join_columns,omitempty"`
+		Note        string   `json:"note,omitempty"`
+	}
+)
+
+func New(db *pgxpool.Pool) tools.Function {
 	return &Tool{
-		db: db,
+		catalog: catalog.New(db),
 	}
 }
 
@@ -37,7 +46,9 @@ func (t Tool) Name() string {
 }
 
 func (t Tool) Description() string {
-	return "Returns the schema for a given AWS resource. The schema is returned as a JSON object, with the key representing the column name and the value representing the column type."
+	return `Returns the schema for a given resource table, from any installed steampipe plugin, as a JSON object. Each column has its type & a description of what it holds.
+Key columns are passed to the AWS API when used in a WHERE clause, which makes queries much faster. Columns listed in required_quals must always be in the WHERE clause, otherwise the query fails, and at least one of any_of_quals must be.
+join_columns lists the columns commonly used to join the table to other tables, such as arn, account_id, region and columns holding the ARNs or IDs of other resources.`
 }
 
 func (t Tool) ParameterDefinitions() []tools.ParameterDefinition {
@@ -48,6 +59,11 @@ func (t Tool) ParameterDefinitions() []tools.ParameterDefinition {
 			Required:    true,
 			Type:        tools.ParameterTypeString,
 		},
+		{
+			Name:        parameterSchema,
+			Description: "The schema of the table, e.g. aws or kubernetes. If not set, the table is found in the first schema that has it.",
+			Type:        tools.ParameterTypeString,
+		},
 	}
 }
 
@@ -57,26 +73,29 @@ func (t Tool) Call(ctx context.Context, parameters map[string]any) (string, erro
 		return "", fmt.Errorf("resource type is required")
 	}
 
-	rows, err := t.db.Query(ctx, getSchemaQuery, resourceType)
+	schema, _ := parameters[parameterSchema].(string)
+
+	table, err := t.catalog.Table(ctx, schema, resourceType)
+	if errors.Is(err, catalog.ErrTableNotFound) {
+		return "", fmt.Errorf("no columns found for resource type %s", resourceType)
+	}
 	if err != nil {
-		return "", fmt.Errorf("error querying tables: %w", err)
+		return "", err
 	}
 
-	columnToDataType := make(map[string]string)
-	for rows.Next() {
-		var columnName, dataType string
-		err := rows.Scan(&columnName, &dataType)
-		if err != nil {
-			return "", fmt.Errorf("error scanning table: %w", err)
-		}
-		columnToDataType[columnName] = dataType
+	out := result{
+		Schema:      table.Schema,
+		Table:       table.Name,
+		Description: table.Description,
+		Columns:     table.Columns,
+		JoinColumns: table.JoinColumns(),
 	}
-
-	if len(columnToDataType) == 0 {
-		return "", fmt.Errorf("no columns found for resource type %s", resourceType)
+	out.RequiredQuals, out.AnyOfQuals = table.RequiredQuals()
+	if !table.KeyColumnsKnown {
+		out.Note = "Key columns are not available in this version of steampipe, so required quals are not listed."
 	}
 
-	dataJSON, err := json.Marshal(columnToDataType)
+	dataJSON, err := json.Marshal(out)
 	if err != nil {
 		return "", fmt.Errorf("error marshalling columns to JSON: %w", err)
 	}
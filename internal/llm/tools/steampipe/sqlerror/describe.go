@@ -0,0 +1,262 @@
+package sqlerror
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/catalog"
+	"github.com/fergalhk/llm-cloud-discovery/internal/pgsql"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	codeUndefinedColumn = "42703"
+	codeUndefinedTable  = "42P01"
+
+	// maxSuggestions is the most names suggested for an unknown one.
+	maxSuggestions = 3
+	// maxSchemaColumns is the most columns of each table included in a schema snippet.
+	maxSchemaColumns = 100
+)
+
+// aliasStopWords are the keywords that can follow a table name in a FROM clause, so aren't its alias.
+var aliasStopWords = []string{
+	"where", "join", "inner", "left", "right", "full", "cross", "natural", "on", "using",
+	"group", "order", "limit", "offset", "union", "intersect", "except", "having", "window", "for",
+}
+
+type (
+	// Describer adds suggestions & schema snippets to the errors of queries on steampipe tables.
+	Describer struct {
+		catalog *catalog.Catalog
+	}
+
+	// TableSchema is the part of a table's schema relevant to an error.
+	TableSchema struct {
+		Table string `json:"table"`
+		// Columns are the table's columns, each as its name followed by its type.
+		Columns []string `json:"columns"`
+	}
+
+	// tableRef is a table used by a query, along with its schema & alias if it has them.
+	tableRef struct {
+		schema string
+		name   string
+		alias  string
+	}
+)
+
+func NewDescriber(db *pgxpool.Pool) *Describer {
+	return &Describer{catalog: catalog.New(db)}
+}
+
+// Describe describes err if it's an error returned by PostgreSQL for query. Unknown column &
+// table errors are given the nearest matching names, and the schema of the tables involved.
+func (d *Describer) Describe(ctx context.Context, err error, query string) (Error, bool) {
+	out, ok := FromError(err, query)
+	if !ok {
+		return out, false
+	}
+
+	switch out.SQLState {
+	case codeUndefinedColumn:
+		d.describeUndefinedColumn(ctx, &out, query)
+	case codeUndefinedTable:
+		d.describeUndefinedTable(ctx, &out, query)
+	}
+
+	return out, true
+}
+
+// describeUndefinedColumn suggests the nearest columns of the tables the query uses, or of the
+// table the column was qualified with, e.g. i.instanceid.
+func (d *Describer) describeUndefinedColumn(ctx context.Context, e *Error, query string) {
+	name, ok := quotedName(e.Message, "column ")
+	if !ok {
+		return
+	}
+	qualifier := ""
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		qualifier, name = name[:i], name[i+1:]
+	}
+
+	refs := d.tableRefs(ctx, query)
+	if qualifier != "" {
+		qualified := slices.DeleteFunc(slices.Clone(refs), func(r tableRef) bool {
+			return r.alias != qualifier && r.name != qualifier
+		})
+		if len(qualified) > 0 {
+			refs = qualified
+		}
+	}
+
+	candidates := []string{}
+	for _, ref := range refs {
+		table, err := d.catalog.Table(ctx, ref.schema, ref.name)
+		if err != nil {
+			continue
+		}
+		for _, c := range table.Columns {
+			candidates = append(candidates, c.Name)
+		}
+		e.Schema = append(e.Schema, tableSchema(table))
+	}
+
+	e.Suggestions = nearest(name, candidates)
+}
+
+// describeUndefinedTable suggests the nearest table names, or for a missing FROM clause entry
+// the nearest table names & aliases the query uses.
+func (d *Describer) describeUndefinedTable(ctx context.Context, e *Error, query string) {
+	if name, ok := quotedName(e.Message, "missing FROM-clause entry for table "); ok {
+		candidates := []string{}
+		for _, ref := range d.tableRefs(ctx, query) {
+			candidates = append(candidates, ref.name)
+			if ref.alias != "" {
+				candidates = append(candidates, ref.alias)
+			}
+		}
+		e.Suggestions = nearest(name, candidates)
+		return
+	}
+
+	name, ok := quotedName(e.Message, "relation ")
+	if !ok {
+		return
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+
+	tables, err := d.catalog.Tables(ctx, "")
+	if err != nil {
+		return
+	}
+	e.Suggestions = nearest(name, tables)
+
+	if len(e.Suggestions) > 0 {
+		table, err := d.catalog.Table(ctx, "", e.Suggestions[0])
+		if err == nil {
+			e.Schema = append(e.Schema, tableSchema(table))
+		}
+	}
+}
+
+// tableRefs returns the steampipe tables a query uses, found by matching its names against the
+// tables in the catalog.
+func (d *Describer) tableRefs(ctx context.Context, query string) []tableRef {
+	tokens, err := pgsql.Tokenize(query)
+	if err != nil {
+		return nil
+	}
+	tables, err := d.catalog.Tables(ctx, "")
+	if err != nil {
+		return nil
+	}
+
+	refs := []tableRef{}
+	for i, tok := range tokens {
+		if !tok.IsName() || !slices.Contains(tables, tok.Value) {
+			continue
+		}
+		// a column of the same name as a table would be qualified or followed by an operator, so
+		// only names after FROM, JOIN, a comma or a schema are tables
+		if i == 0 || !(tokens[i-1].Is("from") || tokens[i-1].Is("join") || tokens[i-1].IsPunctuation(',') || tokens[i-1].IsPunctuation('.')) {
+			continue
+		}
+
+		ref := tableRef{name: tok.Value}
+		if i >= 2 && tokens[i-1].IsPunctuation('.') && tokens[i-2].IsName() {
+			ref.schema = tokens[i-2].Value
+		}
+		j := i + 1
+		if j < len(tokens) && tokens[j].Is("as") {
+			j++
+		}
+		if j < len(tokens) && tokens[j].IsName() && !slices.ContainsFunc(aliasStopWords, tokens[j].Is) {
+			ref.alias = tokens[j].Value
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+func tableSchema(t catalog.Table) TableSchema {
+	out := TableSchema{Table: t.Name, Columns: []string{}}
+	for i, c := range t.Columns {
+		if i == maxSchemaColumns {
+			break
+		}
+		out.Columns = append(out.Columns, c.Name+" "+c.Type)
+	}
+	return out
+}
+
+// quotedName returns the name following prefix at the start of a PostgreSQL error message, without
+// the quotes PostgreSQL puts around it, e.g. instanceid from `column "instanceid" does not exist`.
+func quotedName(message, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(message, prefix)
+	if !ok {
+		return "", false
+	}
+	if before, _, ok := strings.Cut(rest, " does not exist"); ok {
+		rest = before
+	}
+	return strings.ReplaceAll(strings.TrimSpace(rest), `"`, ""), true
+}
+
+// nearest returns the candidates closest to name by edit distance, closest first. Candidates that
+// are too far from name to be a typo are left out.
+func nearest(name string, candidates []string) []string {
+	type scored struct {
+		name     string
+		distance int
+	}
+
+	limit := max(2, len(name)/3)
+	matches := []scored{}
+	for _, c := range candidates {
+		if c == name || slices.ContainsFunc(matches, func(s scored) bool { return s.name == c }) {
+			continue
+		}
+		d := min(distance(name, c), distance(strings.ReplaceAll(name, "_", ""), strings.ReplaceAll(c, "_", "")))
+		if d <= limit {
+			matches = append(matches, scored{name: c, distance: d})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b scored) int { return a.distance - b.distance })
+	out := []string{}
+	for i, m := range matches {
+		if i == maxSuggestions {
+			break
+		}
+		out = append(out, m.name)
+	}
+	return out
+}
+
+// distance returns the Levenshtein distance between two strings, ignoring case.
+func distance(a, b string) int {
+	ra, rb := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,116 @@
+// Package sqlerror describes the errors PostgreSQL returns for a query in a form the model can act
+// on: what kind of error it is, where in the query it happened, and for unknown names, the names
+// that were probably meant.
+package sqlerror
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// nearLength is the most characters of the query quoted from the error position.
+const nearLength = 40
+
+// conditions names the SQLSTATE codes the model is most likely to cause.
+var conditions = map[string]string{
+	"42601": "syntax_error",
+	"42703": "undefined_column",
+	"42P01": "undefined_table",
+	"42883": "undefined_function",
+	"42702": "ambiguous_column",
+	"42725": "ambiguous_function",
+	"42804": "datatype_mismatch",
+	"42846": "cannot_coerce",
+	"42803": "grouping_error",
+	"42P10": "invalid_column_reference",
+	"22P02": "invalid_text_representation",
+	"22007": "invalid_datetime_format",
+	"22008": "datetime_field_overflow",
+	"22012": "division_by_zero",
+	"25006": "read_only_sql_transaction",
+	"42501": "insufficient_privilege",
+	"57014": "query_canceled",
+	"XX000": "internal_error",
+}
+
+type Error struct {
+	SQLState string `json:"sqlstate,omitempty"`
+	// Condition is the PostgreSQL name of the SQLSTATE, e.g. undefined_column.
+	Condition string `json:"condition,omitempty"`
+	Message   string `json:"message"`
+	Detail    string `json:"detail,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+	// Position is the 1-based character offset of the error in the query, or 0 if it's not known.
+	Position int `json:"position,omitempty"`
+	// Line & Column locate Position in the query, both 1-based.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Near is the text of the query starting at Position.
+	Near string `json:"near,omitempty"`
+	// Suggestions are the known names nearest to an unknown column or table.
+	Suggestions []string `json:"did_you_mean,omitempty"`
+	// Schema holds the schemas of the tables involved in an unknown column or table error.
+	Schema []TableSchema `json:"schema,omitempty"`
+}
+
+// Error returns the error as JSON, so it can be returned to the model as is.
+func (e Error) Error() string {
+	out, err := json.Marshal(e)
+	if err != nil {
+		return e.Message
+	}
+	return string(out)
+}
+
+// FromError describes err if it's an error returned by PostgreSQL for query.
+func FromError(err error, query string) (Error, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return Error{}, false
+	}
+
+	out := Error{
+		SQLState:  pgErr.Code,
+		Condition: conditions[pgErr.Code],
+		Message:   pgErr.Message,
+		Detail:    pgErr.Detail,
+		Hint:      pgErr.Hint,
+		Position:  int(pgErr.Position),
+	}
+	out.Line, out.Column, out.Near = locate(query, out.Position)
+
+	return out, true
+}
+
+// locate returns the line & column of a 1-based character position in a query, along with the
+// rest of that line from the position. It returns zero values if the position is out of range.
+func locate(query string, position int) (line, column int, near string) {
+	if position <= 0 || position > utf8.RuneCountInString(query) {
+		return 0, 0, ""
+	}
+
+	line, column = 1, 1
+	offset := 0
+	for range position - 1 {
+		r, size := utf8.DecodeRuneInString(query[offset:])
+		offset += size
+		if r == '\n' {
+			line++
+			column = 1
+			continue
+		}
+		column++
+	}
+
+	near, _, _ = strings.Cut(query[offset:], "\n")
+	near = strings.TrimSpace(near)
+	if utf8.RuneCountInString(near) > nearLength {
+		near = string([]rune(near)[:nearLength]) + "..."
+	}
+
+	return line, column, near
+}
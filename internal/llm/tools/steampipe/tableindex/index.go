@@ -0,0 +1,225 @@
+// Package tableindex is a local vector index of steampipe tables, used to find tables by meaning
+// rather than by name, e.g. aws_ec2_application_load_balancer for "load balancer". Each table's
+// name, description & columns are embedded with an Ollama embedding model, and the index is saved
+// to a file so that it's only rebuilt when the installed plugins change.
+package tableindex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/catalog"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	DefaultModel = "nomic-embed-text"
+
+	// batchSize is the number of tables embedded per request.
+	batchSize = 32
+	// maxDocumentLength is the most characters of a table's document that are embedded.
+	maxDocumentLength = 4000
+)
+
+type (
+	// Embedder turns texts into vectors.
+	Embedder interface {
+		Embed(ctx context.Context, inputs []string) ([][]float32, error)
+	}
+
+	// OllamaEmbedder embeds texts with an Ollama embedding model.
+	OllamaEmbedder struct {
+		client *api.Client
+		model  string
+	}
+
+	Index struct {
+		// Model is the embedding model the index was built with. Queries must be embedded with the same model.
+		Model   string    `json:"model"`
+		Built   time.Time `json:"built"`
+		Entries []Entry   `json:"entries"`
+	}
+
+	Entry struct {
+		Schema string    `json:"schema"`
+		Table  string    `json:"table"`
+		Vector []float32 `json:"vector"`
+	}
+)
+
+func NewOllamaEmbedder(client *api.Client, model string) *OllamaEmbedder {
+	return &OllamaEmbedder{client: client, model: model}
+}
+
+func (e *OllamaEmbedder) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	truncate := true
+	resp, err := e.client.Embed(ctx, &api.EmbedRequest{Model: e.model, Input: inputs, Truncate: &truncate})
+	if err != nil {
+		return nil, fmt.Errorf("error calling ollama embed API: %w", err)
+	}
+	if len(resp.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(resp.Embeddings))
+	}
+	return resp.Embeddings, nil
+}
+
+// DefaultPath returns the path the index is saved to if no other is given, in the user's cache directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding cache directory: %w", err)
+	}
+	return filepath.Join(dir, "llm-cloud-discovery", "steampipe-tables.json"), nil
+}
+
+// Build embeds every table in every plugin schema. progress, if set, is called after each batch
+// with the number of tables embedded so far & the total.
+func Build(ctx context.Context, cat *catalog.Catalog, embedder Embedder, model string, progress func(done, total int)) (*Index, error) {
+	schemas, err := cat.Schemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// connections of the same plugin have the same tables, which are only embedded once
+	pending := []catalog.Table{}
+	seen := map[string]bool{}
+	for _, s := range schemas {
+		names, err := cat.Tables(ctx, s.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			table, err := cat.Table(ctx, s.Name, name)
+			if errors.Is(err, catalog.ErrTableNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, table)
+		}
+	}
+
+	idx := &Index{Model: model, Built: time.Now().UTC(), Entries: make([]Entry, 0, len(pending))}
+	for start := 0; start < len(pending); start += batchSize {
+		batch := pending[start:min(start+batchSize, len(pending))]
+		docs := make([]string, len(batch))
+		for i, table := range batch {
+			docs[i] = Document(table)
+		}
+
+		vectors, err := embedder.Embed(ctx, docs)
+		if err != nil {
+			return nil, err
+		}
+		for i, table := range batch {
+			idx.Entries = append(idx.Entries, Entry{Schema: table.Schema, Table: table.Name, Vector: normalize(vectors[i])})
+		}
+
+		if progress != nil {
+			progress(len(idx.Entries), len(pending))
+		}
+	}
+
+	return idx, nil
+}
+
+// Document is the text embedded for a table: its name as words, its description, and its columns
+// with their descriptions.
+func Document(t catalog.Table) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s)\n%s\nColumns:\n", strings.ReplaceAll(t.Name, "_", " "), t.Name, t.Description)
+	for _, c := range t.Columns {
+		fmt.Fprintf(&b, "- %s: %s\n", c.Name, c.Description)
+	}
+
+	doc := b.String()
+	if len(doc) > maxDocumentLength {
+		doc = strings.ToValidUTF8(doc[:maxDocumentLength], "")
+	}
+	return doc
+}
+
+// Load reads an index saved by Save.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading table index: %w", err)
+	}
+
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("error parsing table index %s: %w", path, err)
+	}
+	return &idx, nil
+}
+
+// Save writes the index to path, creating its directory if needed. The file is replaced atomically,
+// so a running agent never reads a partly written index.
+func (idx *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating table index directory: %w", err)
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("error marshalling table index to JSON: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing table index: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing table index: %w", err)
+	}
+	return nil
+}
+
+// Similarities returns the cosine similarity of each indexed table to the query vector, by table
+// name.
+func (idx *Index) Similarities(query []float32) map[string]float64 {
+	query = normalize(query)
+	out := make(map[string]float64, len(idx.Entries))
+	for _, e := range idx.Entries {
+		if len(e.Vector) != len(query) {
+			continue
+		}
+		var dot float64
+		for i := range query {
+			dot += float64(query[i]) * float64(e.Vector[i])
+		}
+		out[e.Table] = dot
+	}
+	return out
+}
+
+// normalize scales a vector to unit length, so that cosine similarity is a dot product.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package tableindex
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	// semanticWeight is the share of a table's score that comes from its similarity to the search,
+	// with the rest coming from the search's words appearing in its name.
+	semanticWeight = 0.6
+	// minSemanticScore is the similarity a table needs to match on meaning alone, without any of
+	// the search's words in its name.
+	minSemanticScore = 0.5
+)
+
+type Match struct {
+	Table string
+	Score float64
+}
+
+// Rank scores tables against a search & returns those that match, best first. A table matches if
+// the search's words are in its name, or if it's similar enough to the search by meaning. If
+// similarities is nil, tables are matched by name alone.
+func Rank(search string, tables []string, similarities map[string]float64) []Match {
+	words := searchWords(search)
+	phrase := strings.Join(words, "_")
+
+	out := []Match{}
+	for _, table := range tables {
+		lexical := lexicalScore(table, phrase, words)
+		semantic, ok := similarities[table]
+
+		var score float64
+		switch {
+		case !ok:
+			score = lexical
+		case lexical > 0 || semantic >= minSemanticScore:
+			score = semanticWeight*semantic + (1-semanticWeight)*lexical
+		}
+		if score > 0 {
+			out = append(out, Match{Table: table, Score: score})
+		}
+	}
+
+	slices.SortStableFunc(out, func(a, b Match) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return strings.Compare(a.Table, b.Table)
+		}
+	})
+	return out
+}
+
+// lexicalScore is 1 if the search is in the table's name as a phrase, e.g. load_balancer, or
+// otherwise the share of the search's words that are in its name.
+func lexicalScore(table, phrase string, words []string) float64 {
+	if phrase == "" {
+		return 1
+	}
+	if strings.Contains(table, phrase) {
+		return 1
+	}
+
+	matched := 0
+	for _, w := range words {
+		if strings.Contains(table, w) {
+			matched++
+		}
+	}
+	// a single matching word of a longer search scores less than the whole phrase
+	return 0.9 * float64(matched) / float64(len(words))
+}
+
+// searchWords splits a search into lower case words, e.g. "Load Balancer" into load & balancer.
+func searchWords(search string) []string {
+	return strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
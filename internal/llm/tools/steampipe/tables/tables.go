@@ -6,35 +6,47 @@ import (
 	"fmt"
 
 	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
-	"github.com/jackc/pgx/v5"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/catalog"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/steampipe/tableindex"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 const (
 	parameterResourceTypeFilter = "resource_type_filter"
+	parameterSchema             = "schema"
 
-	listTablesQuery = `
-select
-  foreign_table_name
-from 
-  information_schema.foreign_tables
-where
-  foreign_table_schema = 'aws'
-`
-
-	filteredListTablesQuery = listTablesQuery + `
-and
-  foreign_table_name LIKE $1;
-`
+	// maxSemanticResults is the most tables returned by a search using the index, as every table is
+	// similar to the search to some degree.
+	maxSemanticResults = 25
 )
 
-type Tool struct {
-	db *pgx.Conn
+type (
+	Opt func(*Tool)
+
+	Tool struct {
+		catalog  *catalog.Catalog
+		index    *tableindex.Index
+		embedder tableindex.Embedder
+	}
+)
+
+// WithIndex ranks tables by their similarity to the resource type filter, as well as by name. The
+// embedder must use the model the index was built with.
+func WithIndex(index *tableindex.Index, embedder tableindex.Embedder) Opt {
+	return func(t *Tool) {
+		t.index = index
+		t.embedder = embedder
+	}
 }
 
-func New(db *pgx.Conn) tools.Function {
-	return &Tool{
-		db: db,
+func New(db *pgxpool.Pool, opts ...Opt) tools.Function {
+	t := &Tool{
+		catalog: catalog.New(db),
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 func (t Tool) Name() string {
@@ -42,14 +54,20 @@ func (t Tool) Name() string {
 }
 
 func (t Tool) Description() string {
-	return "Returns the list of tables containing AWS resources. The list of tables is returned as a JSON array of strings."
+	return "Returns the list of tables containing resources, from every installed steampipe plugin unless a schema is given. Table names are prefixed with their plugin, e.g. aws_ or kubernetes_. The list of tables is returned as a JSON array of strings."
 }
 
 func (t Tool) ParameterDefinitions() []tools.ParameterDefinition {
 	return []tools.ParameterDefinition{
 		{
 			Name:        parameterResourceTypeFilter,
-			Description: "The type of resource to filter by, e.g. \"ec2\" or \"s3\". This is used in a fuzzy search, with the query returning all tables containing the resource type in the name.",
+			Description: "The type of resource to filter by, e.g. \"ec2\", \"s3\" or \"load balancer\". This is used in a fuzzy search, with the query returning the tables with the words of the resource type in their name, or that hold similar resources, most relevant first.",
+			Required:    false,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterSchema,
+			Description: "The schema to list the tables of, e.g. aws or kubernetes. If not set, the tables of every schema are listed.",
 			Required:    false,
 			Type:        tools.ParameterTypeString,
 		},
@@ -58,27 +76,20 @@ func (t Tool) ParameterDefinitions() []tools.ParameterDefinition {
 
 func (t Tool) Call(ctx context.Context, parameters map[string]any) (string, error) {
 	resourceTypeFilter, _ := parameters[parameterResourceTypeFilter].(string)
+	schema, _ := parameters[parameterSchema].(string)
 
-	query := listTablesQuery
-	args := []any{}
-	if resourceTypeFilter != "" {
-		query = filteredListTablesQuery
-		args = append(args, "%"+resourceTypeFilter+"%")
-	}
-
-	rows, err := t.db.Query(ctx, query, args...)
+	tables, err := t.catalog.Tables(ctx, schema)
 	if err != nil {
-		return "", fmt.Errorf("error querying tables: %w", err)
+		return "", err
 	}
 
+	similarities := t.similarities(ctx, resourceTypeFilter)
 	resources := []string{}
-	for rows.Next() {
-		var resource string
-		err := rows.Scan(&resource)
-		if err != nil {
-			return "", fmt.Errorf("error scanning table: %w", err)
+	for _, match := range tableindex.Rank(resourceTypeFilter, tables, similarities) {
+		if similarities != nil && len(resources) == maxSemanticResults {
+			break
 		}
-		resources = append(resources, resource)
+		resources = append(resources, match.Table)
 	}
 
 	if len(resources) == 0 {
@@ -92,3 +103,17 @@ func (t Tool) Call(ctx context.Context, parameters map[string]any) (string, erro
 
 	return string(dataJSON), nil
 }
+
+// similarities returns the similarity of each table to the filter, or nil if there's no index or
+// filter. If the filter can't be embedded, tables are matched by name alone.
+func (t Tool) similarities(ctx context.Context, filter string) map[string]float64 {
+	if t.index == nil || filter == "" {
+		return nil
+	}
+
+	vectors, err := t.embedder.Embed(ctx, []string{filter})
+	if err != nil {
+		return nil
+	}
+	return t.index.Similarities(vectors[0])
+}
@@ -0,0 +1,124 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/tfstate"
+)
+
+const (
+	parameterIdentifiers       = "identifiers"
+	parameterIncludeAttributes = "include_attributes"
+
+	// maxIdentifiers is the most identifiers looked up in one call.
+	maxIdentifiers = 200
+)
+
+type (
+	FindTool struct {
+		source *tfstate.Source
+	}
+
+	findResult struct {
+		Identifier string     `json:"identifier"`
+		Managed    bool       `json:"managed"`
+		Resources  []resource `json:"resources,omitempty"`
+	}
+
+	resource struct {
+		tfstate.Match
+		Attributes map[string]any `json:"attributes,omitempty"`
+	}
+)
+
+func NewFindTool(source *tfstate.Source) tools.Function {
+	return &FindTool{
+		source: source,
+	}
+}
+
+func (t *FindTool) Name() string {
+	return "find_terraform_resources"
+}
+
+func (t *FindTool) Description() string {
+	return fmt.Sprintf(`This tool finds out whether AWS resources are managed by Terraform, and if so which Terraform resource manages each of them.
+Pass the IDs or ARNs of the resources in the %q parameter, e.g. the identifiers returned by list_aws_resources. Many identifiers can be checked at once, to find the unmanaged resources of a type.
+The response is a JSON array with an entry for each identifier. Each entry says whether the resource is managed, and if so gives the address & module of the Terraform resource, the state file it's in, and how it matched.
+Matches on arn_suffix or arn_resource_id are less certain than matches on id or arn, so check them if it matters.`,
+		parameterIdentifiers)
+}
+
+func (t *FindTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterIdentifiers,
+			Description: "A comma separated list of the IDs or ARNs of the resources to find, e.g. i-0abc123,arn:aws:iam::123456789012:role/web.",
+			Required:    true,
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterIncludeAttributes,
+			Description: "If true, the attributes of each Terraform resource are included, as recorded in the state when it was last applied. Compare them with the live resource to find drift. Sensitive values are redacted. Defaults to false.",
+			Type:        tools.ParameterTypeBoolean,
+		},
+	}
+}
+
+func (t *FindTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	identifiersParameter, ok := parameters[parameterIdentifiers].(string)
+	if !ok {
+		return "", fmt.Errorf("%s is not a valid string", parameterIdentifiers)
+	}
+	includeAttributes, err := tools.BoolParameter(parameters, parameterIncludeAttributes, false)
+	if err != nil {
+		return "", err
+	}
+
+	identifiers := []string{}
+	for _, id := range strings.Split(identifiersParameter, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			identifiers = append(identifiers, id)
+		}
+	}
+	if len(identifiers) == 0 {
+		return "", fmt.Errorf("%s must contain at least one identifier", parameterIdentifiers)
+	}
+	if len(identifiers) > maxIdentifiers {
+		return "", fmt.Errorf("%s can contain at most %d identifiers", parameterIdentifiers, maxIdentifiers)
+	}
+
+	out := []findResult{}
+	for _, id := range identifiers {
+		matches, err := t.source.Find(ctx, id)
+		if err != nil {
+			return "", err
+		}
+
+		result := findResult{Identifier: id, Managed: len(matches) > 0}
+		for _, m := range matches {
+			r := resource{Match: m}
+			if includeAttributes {
+				r.Attributes = m.Attributes
+			}
+			result.Resources = append(result.Resources, r)
+		}
+		out = append(out, result)
+	}
+
+	outJSON, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling Terraform resources to JSON: %w", err)
+	}
+
+	return string(outJSON), nil
+}
+
+// Reset reloads the state files on next use, in case they've changed.
+func (t *FindTool) Reset() {
+	t.source.Reset()
+}
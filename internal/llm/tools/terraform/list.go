@@ -0,0 +1,160 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools"
+	"github.com/fergalhk/llm-cloud-discovery/internal/llm/tools/format"
+	"github.com/fergalhk/llm-cloud-discovery/internal/tfstate"
+)
+
+const (
+	parameterResourceType = "resource_type"
+	parameterModule       = "module"
+	parameterState        = "state"
+
+	defaultMaxRows = 200
+)
+
+type (
+	Opt func(*ListTool)
+
+	ListTool struct {
+		source  *tfstate.Source
+		format  format.Format
+		maxRows int
+	}
+)
+
+// WithFormat sets the default format of the list. The model can still ask for another format per call.
+func WithFormat(f format.Format) Opt {
+	return func(t *ListTool) {
+		t.format = f
+	}
+}
+
+// WithMaxRows sets the most resources listed in a response.
+func WithMaxRows(maxRows int) Opt {
+	return func(t *ListTool) {
+		t.maxRows = maxRows
+	}
+}
+
+func NewListTool(source *tfstate.Source, opts ...Opt) tools.Function {
+	t := &ListTool{
+		source:  source,
+		format:  format.JSON,
+		maxRows: defaultMaxRows,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *ListTool) Name() string {
+	return "list_terraform_resources"
+}
+
+func (t *ListTool) Description() string {
+	return fmt.Sprintf(`This tool lists the resources managed by Terraform, from the Terraform state files it has been given.
+Each resource is returned with its Terraform address (e.g. module.network.aws_subnet.private[0]), its ID & ARN, and the state file it's in.
+Terraform resource types are named after the provider, e.g. aws_instance or aws_s3_bucket, rather than AWS::EC2::Instance.
+The response is returned as %s, with at most %d resources. Filter by %q, %q or %q to see more.`,
+		t.format.Describe(), t.maxRows, parameterResourceType, parameterModule, parameterState)
+}
+
+func (t *ListTool) ParameterDefinitions() []tools.ParameterDefinition {
+	return []tools.ParameterDefinition{
+		{
+			Name:        parameterResourceType,
+			Description: "Only list resources of this Terraform type, e.g. aws_iam_role.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterModule,
+			Description: "Only list resources in this module or its child modules, e.g. module.network. Use \"root\" for resources outside any module.",
+			Type:        tools.ParameterTypeString,
+		},
+		{
+			Name:        parameterState,
+			Description: fmt.Sprintf("Only list resources in state files whose location contains this, e.g. network. The state files are %s.", strings.Join(t.source.Locations(), ", ")),
+			Type:        tools.ParameterTypeString,
+		},
+		format.ParameterDefinition(t.format),
+	}
+}
+
+func (t *ListTool) Call(ctx context.Context, parameters map[string]any) (string, error) {
+	resourceType, _ := parameters[parameterResourceType].(string)
+	module, _ := parameters[parameterModule].(string)
+	state, _ := parameters[parameterState].(string)
+
+	f, err := format.FromParameters(parameters, t.format)
+	if err != nil {
+		return "", err
+	}
+
+	resources, err := t.source.Resources(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	matched := []tfstate.Resource{}
+	for _, r := range resources {
+		if resourceType != "" && r.Type != resourceType {
+			continue
+		}
+		if module != "" && !inModule(r.Module, module) {
+			continue
+		}
+		if state != "" && !strings.Contains(r.Location, state) {
+			continue
+		}
+		matched = append(matched, r)
+	}
+
+	if len(matched) == 0 {
+		return "No matching resources are managed by Terraform.", nil
+	}
+
+	rows := [][]any{}
+	for i, r := range matched {
+		if i == t.maxRows {
+			break
+		}
+		rows = append(rows, []any{r.Address, r.Type, nullable(r.ID), nullable(r.ARN), r.Location})
+	}
+
+	out, err := f.Rows([]string{"address", "type", "id", "arn", "state"}, rows)
+	if err != nil {
+		return "", err
+	}
+	if omitted := len(matched) - len(rows); omitted > 0 {
+		out = strings.TrimRight(out, "\n") + fmt.Sprintf("\n\nThe result was truncated: %d more resources were omitted. Filter by resource type, module or state to see them.", omitted)
+	}
+	return out, nil
+}
+
+// inModule returns true if a resource in resourceModule is in module or one of its child modules.
+func inModule(resourceModule, module string) bool {
+	if module == "root" {
+		return resourceModule == ""
+	}
+	return resourceModule == module || strings.HasPrefix(resourceModule, module+".")
+}
+
+// nullable returns nil for an empty string, so that columns without values are dropped.
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// Reset reloads the state files on next use, in case they've changed.
+func (t *ListTool) Reset() {
+	t.source.Reset()
+}
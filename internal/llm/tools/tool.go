@@ -13,6 +13,12 @@ type (
 		Call(ctx context.Context, parameters map[string]any) (string, error)
 	}
 
+	// Resetter is implemented by tools that cache state between calls. Reset is called
+	// whenever the conversation history is cleared.
+	Resetter interface {
+		Reset()
+	}
+
 	// ParameterDefinition defines an input parameter for a tool.
 	ParameterDefinition struct {
 		// The name of the parameter.
@@ -31,7 +37,9 @@ type (
 )
 
 const (
-	ParameterTypeString ParameterType = "string"
+	ParameterTypeString  ParameterType = "string"
+	ParameterTypeInteger ParameterType = "integer"
+	ParameterTypeBoolean ParameterType = "boolean"
 )
 
 func (p ParameterType) String() string {
@@ -0,0 +1,408 @@
+// Package pgsql checks & tokenizes PostgreSQL statements before they're sent to the database.
+// Statements are checked with PostgreSQL's own parser. The tokenizer is lighter, for finding names
+// & positions in a statement without being fooled by comments, string literals or quoted
+// identifiers.
+package pgsql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type (
+	TokenKind int
+
+	Token struct {
+		Kind TokenKind
+		// Value is the token's text. Keywords & unquoted identifiers are lower cased, as
+		// PostgreSQL folds them, and quoted identifiers are unquoted.
+		Value string
+		// Pos is the byte offset of the token in the statement.
+		Pos int
+	}
+)
+
+const (
+	// TokenIdentifier is a keyword or an unquoted identifier.
+	TokenIdentifier TokenKind = iota
+	TokenQuotedIdentifier
+	TokenString
+	TokenNumber
+	// TokenParameter is a positional parameter such as $1.
+	TokenParameter
+	TokenOperator
+	// TokenPunctuation is one of ( ) [ ] , ; : . and is always a single character.
+	TokenPunctuation
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenIdentifier:
+		return "identifier"
+	case TokenQuotedIdentifier:
+		return "quoted identifier"
+	case TokenString:
+		return "string"
+	case TokenNumber:
+		return "number"
+	case TokenParameter:
+		return "parameter"
+	case TokenOperator:
+		return "operator"
+	case TokenPunctuation:
+		return "punctuation"
+	default:
+		return fmt.Sprintf("TokenKind(%d)", int(k))
+	}
+}
+
+// Is returns true if the token is the unquoted keyword or identifier word, ignoring case.
+func (t Token) Is(word string) bool {
+	return t.Kind == TokenIdentifier && t.Value == strings.ToLower(word)
+}
+
+// IsPunctuation returns true if the token is the punctuation character c.
+func (t Token) IsPunctuation(c byte) bool {
+	return t.Kind == TokenPunctuation && t.Value == string(c)
+}
+
+// IsName returns true if the token can name a table, column or function.
+func (t Token) IsName() bool {
+	return t.Kind == TokenIdentifier || t.Kind == TokenQuotedIdentifier
+}
+
+// Tokenize splits a statement into tokens, dropping whitespace & comments. It returns an error if
+// a string, quoted identifier or comment isn't terminated.
+func Tokenize(s string) ([]Token, error) {
+	l := lexer{src: s}
+	for {
+		l.skipSpaceAndComments()
+		if l.err != nil {
+			return nil, l.err
+		}
+		if l.pos >= len(l.src) {
+			return l.tokens, nil
+		}
+		l.next()
+		if l.err != nil {
+			return nil, l.err
+		}
+	}
+}
+
+type lexer struct {
+	src    string
+	pos    int
+	tokens []Token
+	err    error
+}
+
+func (l *lexer) peek(offset int) byte {
+	if l.pos+offset >= len(l.src) {
+		return 0
+	}
+	return l.src[l.pos+offset]
+}
+
+func (l *lexer) emit(kind TokenKind, value string, start int) {
+	l.tokens = append(l.tokens, Token{Kind: kind, Value: value, Pos: start})
+}
+
+func (l *lexer) skipSpaceAndComments() {
+	for l.pos < len(l.src) {
+		switch c := l.peek(0); {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+		case c == '-' && l.peek(1) == '-':
+			end := strings.IndexByte(l.src[l.pos:], '\n')
+			if end < 0 {
+				l.pos = len(l.src)
+			} else {
+				l.pos += end + 1
+			}
+		case c == '/' && l.peek(1) == '*':
+			l.skipBlockComment()
+			if l.err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipBlockComment skips a /* */ comment. Unlike most SQL dialects, PostgreSQL block comments nest.
+func (l *lexer) skipBlockComment() {
+	start := l.pos
+	depth := 0
+	for l.pos < len(l.src) {
+		switch {
+		case l.peek(0) == '/' && l.peek(1) == '*':
+			depth++
+			l.pos += 2
+		case l.peek(0) == '*' && l.peek(1) == '/':
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return
+			}
+		default:
+			l.pos++
+		}
+	}
+	l.err = fmt.Errorf("unterminated comment at position %d", start+1)
+}
+
+func (l *lexer) next() {
+	start := l.pos
+	c := l.peek(0)
+
+	switch {
+	case c == '\'':
+		l.quoted('\'', false, TokenString)
+	case (c == 'e' || c == 'E') && l.peek(1) == '\'':
+		l.pos++
+		l.quoted('\'', true, TokenString)
+		l.tokens[len(l.tokens)-1].Pos = start
+	case (c == 'b' || c == 'B' || c == 'x' || c == 'X' || c == 'n' || c == 'N') && l.peek(1) == '\'':
+		// bit string, hex string & national character literals
+		l.pos++
+		l.quoted('\'', false, TokenString)
+		l.tokens[len(l.tokens)-1].Pos = start
+	case (c == 'u' || c == 'U') && l.peek(1) == '&' && (l.peek(2) == '\'' || l.peek(2) == '"'):
+		l.unicodeQuoted()
+	case c == '"':
+		l.quoted('"', false, TokenQuotedIdentifier)
+	case c == '$' && isDigit(l.peek(1)):
+		l.pos++
+		for isDigit(l.peek(0)) {
+			l.pos++
+		}
+		l.emit(TokenParameter, l.src[start:l.pos], start)
+	case c == '$':
+		l.dollarQuoted()
+	case isDigit(c) || (c == '.' && isDigit(l.peek(1))):
+		l.number()
+	case isIdentifierStart(l.src[l.pos:]):
+		l.identifier()
+	case strings.IndexByte("()[],;:.", c) >= 0:
+		// :: is a cast, which is treated as an operator
+		if c == ':' && l.peek(1) == ':' {
+			l.pos += 2
+			l.emit(TokenOperator, "::", start)
+			return
+		}
+		l.pos++
+		l.emit(TokenPunctuation, string(c), start)
+	case isOperatorChar(c):
+		for l.pos < len(l.src) && isOperatorChar(l.peek(0)) {
+			// comments can start in the middle of an operator
+			if (l.peek(0) == '-' && l.peek(1) == '-') || (l.peek(0) == '/' && l.peek(1) == '*') {
+				if l.pos > start {
+					break
+				}
+			}
+			l.pos++
+		}
+		l.emit(TokenOperator, l.src[start:l.pos], start)
+	default:
+		l.err = fmt.Errorf("unexpected character %q at position %d", c, start+1)
+	}
+}
+
+// quoted reads a string or identifier enclosed in quote, where a doubled quote is an escaped quote.
+// If backslashEscapes is set, a backslash escapes the following character.
+func (l *lexer) quoted(quote byte, backslashEscapes bool, kind TokenKind) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.peek(0)
+		switch {
+		case backslashEscapes && c == '\\' && l.pos+1 < len(l.src):
+			b.WriteByte(l.src[l.pos+1])
+			l.pos += 2
+		case c == quote && l.peek(1) == quote:
+			b.WriteByte(quote)
+			l.pos += 2
+		case c == quote:
+			l.pos++
+			l.emit(kind, b.String(), start)
+			return
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	what := "string"
+	if kind == TokenQuotedIdentifier {
+		what = "quoted identifier"
+	}
+	l.err = fmt.Errorf("unterminated %s at position %d", what, start+1)
+}
+
+// unicodeQuoted reads a string or identifier with unicode escapes, such as U&"d\0061ta", decoding
+// the escapes so the value is what PostgreSQL sees. The escape character is a backslash, unless
+// another is given by a following UESCAPE clause.
+func (l *lexer) unicodeQuoted() {
+	start := l.pos
+	l.pos += 2
+	kind := TokenString
+	if l.peek(0) == '"' {
+		kind = TokenQuotedIdentifier
+	}
+	l.quoted(l.peek(0), false, kind)
+	if l.err != nil {
+		return
+	}
+
+	escape := byte('\\')
+	end := l.pos
+	l.skipSpaceAndComments()
+	rest := l.src[l.pos:]
+	if l.err == nil && len(rest) >= len("uescape") && strings.EqualFold(rest[:len("uescape")], "uescape") && !isIdentifierPart(rest[len("uescape"):]) {
+		l.pos += len("uescape")
+		l.skipSpaceAndComments()
+		if l.err != nil {
+			return
+		}
+		if l.peek(0) != '\'' || l.peek(2) != '\'' || isHexDigit(l.peek(1)) || strings.IndexByte("+'\" \t\n\r", l.peek(1)) >= 0 {
+			l.err = fmt.Errorf("invalid UESCAPE at position %d", l.pos+1)
+			return
+		}
+		escape = l.peek(1)
+		l.pos += 3
+	} else {
+		l.pos, l.err = end, nil
+	}
+
+	token := &l.tokens[len(l.tokens)-1]
+	value, err := decodeUnicodeEscapes(token.Value, escape)
+	if err != nil {
+		l.err = fmt.Errorf("%w at position %d", err, start+1)
+		return
+	}
+	token.Value, token.Pos = value, start
+}
+
+// decodeUnicodeEscapes decodes the escapes of a unicode escaped string: the escape character
+// followed by 4 hex digits, or by + and 6 hex digits, or doubled for the character itself.
+func decodeUnicodeEscapes(s string, escape byte) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != escape {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		digits := 4
+		switch {
+		case i+1 < len(s) && s[i+1] == escape:
+			b.WriteByte(escape)
+			i++
+			continue
+		case i+1 < len(s) && s[i+1] == '+':
+			digits = 6
+			i++
+		}
+		if i+digits >= len(s) {
+			return "", fmt.Errorf("invalid unicode escape")
+		}
+		r, err := strconv.ParseUint(s[i+1:i+1+digits], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return "", fmt.Errorf("invalid unicode escape")
+		}
+		b.WriteRune(rune(r))
+		i += digits
+	}
+	return b.String(), nil
+}
+
+// dollarQuoted reads a dollar quoted string such as $$text$$ or $tag$text$tag$.
+func (l *lexer) dollarQuoted() {
+	start := l.pos
+	end := l.pos + 1
+	for end < len(l.src) && l.src[end] != '$' {
+		r, size := utf8.DecodeRuneInString(l.src[end:])
+		if !(r == '_' || unicode.IsLetter(r) || (end > l.pos+1 && unicode.IsDigit(r))) {
+			l.err = fmt.Errorf("unexpected character %q at position %d", l.src[l.pos], start+1)
+			return
+		}
+		end += size
+	}
+	if end >= len(l.src) {
+		l.err = fmt.Errorf("unexpected character %q at position %d", l.src[l.pos], start+1)
+		return
+	}
+
+	tag := l.src[l.pos : end+1]
+	body := end + 1
+	closing := strings.Index(l.src[body:], tag)
+	if closing < 0 {
+		l.err = fmt.Errorf("unterminated dollar quoted string at position %d", start+1)
+		return
+	}
+
+	l.pos = body + closing + len(tag)
+	l.emit(TokenString, l.src[body:body+closing], start)
+}
+
+func (l *lexer) number() {
+	start := l.pos
+	for l.pos < len(l.src) {
+		c := l.peek(0)
+		switch {
+		case isDigit(c) || c == '.' || c == '_':
+			l.pos++
+		case (c == 'e' || c == 'E') && (isDigit(l.peek(1)) || ((l.peek(1) == '+' || l.peek(1) == '-') && isDigit(l.peek(2)))):
+			l.pos += 2
+		case (c == 'x' || c == 'X' || c == 'o' || c == 'O' || c == 'b' || c == 'B') && l.pos == start+1 && l.src[start] == '0':
+			l.pos++
+		case l.pos > start+1 && (l.src[start+1] == 'x' || l.src[start+1] == 'X') && isHexDigit(c):
+			l.pos++
+		default:
+			l.emit(TokenNumber, l.src[start:l.pos], start)
+			return
+		}
+	}
+	l.emit(TokenNumber, l.src[start:l.pos], start)
+}
+
+func (l *lexer) identifier() {
+	start := l.pos
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		l.pos += size
+	}
+	l.emit(TokenIdentifier, strings.ToLower(l.src[start:l.pos]), start)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIdentifierStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// isIdentifierPart returns true if s starts with a character that can continue an identifier.
+func isIdentifierPart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte("+-*/<>=~!@#%^&|`?", c) >= 0
+}
@@ -0,0 +1,112 @@
+package pgsql
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []Token
+	}{
+		{
+			name:  "keywords are lower cased",
+			query: "SELECT Name FROM t",
+			want: []Token{
+				{Kind: TokenIdentifier, Value: "select", Pos: 0},
+				{Kind: TokenIdentifier, Value: "name", Pos: 7},
+				{Kind: TokenIdentifier, Value: "from", Pos: 12},
+				{Kind: TokenIdentifier, Value: "t", Pos: 17},
+			},
+		},
+		{
+			name:  "comments are dropped",
+			query: "a /* b /* c */ d */ -- e\nf",
+			want: []Token{
+				{Kind: TokenIdentifier, Value: "a", Pos: 0},
+				{Kind: TokenIdentifier, Value: "f", Pos: 25},
+			},
+		},
+		{
+			name:  "quoted identifiers keep their case",
+			query: `"My ""Table"""`,
+			want:  []Token{{Kind: TokenQuotedIdentifier, Value: `My "Table"`, Pos: 0}},
+		},
+		{
+			name:  "E strings",
+			query: `E'it\'s'`,
+			want:  []Token{{Kind: TokenString, Value: "it's", Pos: 0}},
+		},
+		{
+			name:  "dollar quoted strings",
+			query: "$fn$ it's $$ $fn$",
+			want:  []Token{{Kind: TokenString, Value: " it's $$ ", Pos: 0}},
+		},
+		{
+			name:  "unicode escaped identifiers are decoded",
+			query: `U&"pg\005fsleep"(`,
+			want: []Token{
+				{Kind: TokenQuotedIdentifier, Value: "pg_sleep", Pos: 0},
+				{Kind: TokenPunctuation, Value: "(", Pos: 16},
+			},
+		},
+		{
+			name:  "unicode escaped strings with 6 digit escapes",
+			query: `u&'\+01F600 \\'`,
+			want:  []Token{{Kind: TokenString, Value: "\U0001F600 \\", Pos: 0}},
+		},
+		{
+			name:  "UESCAPE sets the escape character",
+			query: `U&"d!0061t!0061" UESCAPE '!' x`,
+			want: []Token{
+				{Kind: TokenQuotedIdentifier, Value: "data", Pos: 0},
+				{Kind: TokenIdentifier, Value: "x", Pos: 29},
+			},
+		},
+		{
+			name:  "casts and parameters",
+			query: "$1::int",
+			want: []Token{
+				{Kind: TokenParameter, Value: "$1", Pos: 0},
+				{Kind: TokenOperator, Value: "::", Pos: 2},
+				{Kind: TokenIdentifier, Value: "int", Pos: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Tokenize(tt.query)
+			if err != nil {
+				t.Fatalf("error tokenizing: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr string
+	}{
+		{query: "select 'abc", wantErr: "unterminated string at position 8"},
+		{query: `select "abc`, wantErr: "unterminated quoted identifier at position 8"},
+		{query: "select /* abc", wantErr: "unterminated comment at position 8"},
+		{query: "select $a$ abc", wantErr: "unterminated dollar quoted string at position 8"},
+		{query: `select U&"\00zz"`, wantErr: "invalid unicode escape at position 8"},
+		{query: `select U&"x" UESCAPE 'ab'`, wantErr: "invalid UESCAPE"},
+	}
+
+	for _, tt := range tests {
+		_, err := Tokenize(tt.query)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Tokenize(%q) got error %v, want one containing %q", tt.query, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package pgsql
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// allowedStatements are the statement kinds CheckReadOnly accepts.
+	allowedStatements = []string{"select", "with", "values", "explain"}
+
+	// deniedKeywords modify data even when nested inside an allowed statement, e.g. in a data
+	// modifying WITH query. Other statements are rejected by their first keyword.
+	deniedKeywords = map[string]struct{}{
+		"delete": {},
+		"insert": {},
+		"merge":  {},
+		"update": {},
+	}
+
+	// statementNames are the names of statements that aren't named by their first keyword, for
+	// clearer errors.
+	statementNames = map[string]string{
+		"abort":     "transaction control",
+		"begin":     "transaction control",
+		"commit":    "transaction control",
+		"end":       "transaction control",
+		"release":   "transaction control",
+		"rollback":  "transaction control",
+		"savepoint": "transaction control",
+		"start":     "transaction control",
+	}
+
+	// deniedFunctions read server files, run other SQL, change server state or block the session.
+	deniedFunctions = map[string]struct{}{
+		"cursor_to_xml":              {},
+		"nextval":                    {},
+		"pg_cancel_backend":          {},
+		"pg_file_rename":             {},
+		"pg_file_unlink":             {},
+		"pg_file_write":              {},
+		"pg_notify":                  {},
+		"pg_promote":                 {},
+		"pg_reload_conf":             {},
+		"pg_rotate_logfile":          {},
+		"pg_stat_file":               {},
+		"pg_switch_wal":              {},
+		"pg_terminate_backend":       {},
+		"query_to_xml":               {},
+		"query_to_xml_and_xmlschema": {},
+		"query_to_xmlschema":         {},
+		"set_config":                 {},
+		"setval":                     {},
+	}
+
+	// deniedFunctionPrefixes cover families of functions that are all denied.
+	deniedFunctionPrefixes = []string{
+		"dblink",
+		"lo_",
+		"pg_advisory",
+		"pg_create_",
+		"pg_drop_",
+		"pg_logical_",
+		"pg_ls_",
+		"pg_read_",
+		"pg_replication_",
+		"pg_sleep",
+		"pg_stat_reset",
+		"pg_try_advisory",
+	}
+)
+
+// CheckReadOnly returns an error explaining why a statement is rejected unless it's a single
+// SELECT, WITH, VALUES or EXPLAIN statement that doesn't modify data, create a table, lock rows or
+// call a denied function.
+//
+// The statement is tokenized as PostgreSQL reads it, so comments, quoting & escapes, including
+// unicode escapes, can't hide a statement or function call from the checks. Functions that aren't
+// denied may still have side effects, so statements should also be run in a read only transaction.
+func CheckReadOnly(query string) error {
+	tokens, err := Tokenize(query)
+	if err != nil {
+		return fmt.Errorf("the query could not be parsed: %w", err)
+	}
+
+	// a trailing semicolon is fine, but more than one statement isn't
+	for len(tokens) > 0 && tokens[len(tokens)-1].IsPunctuation(';') {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("the query is empty")
+	}
+	for _, t := range tokens {
+		if t.IsPunctuation(';') {
+			return fmt.Errorf("only a single statement can be run at a time, but there is a semicolon at position %d", t.Pos+1)
+		}
+	}
+
+	if err := checkBrackets(tokens); err != nil {
+		return fmt.Errorf("the query could not be parsed: %w", err)
+	}
+	if err := checkStatementKind(tokens); err != nil {
+		return err
+	}
+
+	for i, t := range tokens {
+		if t.Kind != TokenIdentifier {
+			continue
+		}
+		// qualified names such as t.update are column references, not keywords
+		if i > 0 && tokens[i-1].IsPunctuation('.') {
+			continue
+		}
+
+		if _, ok := deniedKeywords[t.Value]; ok {
+			return fmt.Errorf("%s is not allowed, as only read only queries can be run (position %d). If this is a column, qualify it with its table name or quote it", strings.ToUpper(t.Value), t.Pos+1)
+		}
+		if t.Value == "into" {
+			return fmt.Errorf("SELECT INTO is not allowed, as it creates a table (position %d)", t.Pos+1)
+		}
+		if t.Value == "for" && isLockingClause(tokens[i+1:]) {
+			return fmt.Errorf("row locking clauses such as FOR UPDATE & FOR SHARE are not allowed, as only read only queries can be run (position %d)", t.Pos+1)
+		}
+	}
+
+	for i, t := range tokens {
+		if !t.IsName() || i+1 == len(tokens) || !tokens[i+1].IsPunctuation('(') {
+			continue
+		}
+		if isDeniedFunction(strings.ToLower(t.Value)) {
+			return fmt.Errorf("the function %s is not allowed (position %d)", t.Value, t.Pos+1)
+		}
+	}
+
+	return nil
+}
+
+// checkBrackets checks parentheses & square brackets are balanced, so a statement can't end
+// inside a group the other checks think is still open.
+func checkBrackets(tokens []Token) error {
+	open := []Token{}
+	for _, t := range tokens {
+		switch {
+		case t.IsPunctuation('(') || t.IsPunctuation('['):
+			open = append(open, t)
+		case t.IsPunctuation(')') || t.IsPunctuation(']'):
+			want := "("
+			if t.Value == "]" {
+				want = "["
+			}
+			if len(open) == 0 || open[len(open)-1].Value != want {
+				return fmt.Errorf("unbalanced %q at position %d", t.Value, t.Pos+1)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		t := open[len(open)-1]
+		return fmt.Errorf("unclosed %q at position %d", t.Value, t.Pos+1)
+	}
+	return nil
+}
+
+// checkStatementKind checks the statement starts with an allowed keyword, looking through any
+// opening parentheses & EXPLAIN options.
+func checkStatementKind(tokens []Token) error {
+	i := 0
+	for i < len(tokens) && tokens[i].IsPunctuation('(') {
+		i++
+	}
+	if i == len(tokens) {
+		return fmt.Errorf("the query has no statement")
+	}
+
+	first := tokens[i]
+	if !isAllowedStatement(first) {
+		return fmt.Errorf("only SELECT, WITH & EXPLAIN statements can be run, not %s", describeStatement(first))
+	}
+	if !first.Is("explain") {
+		return nil
+	}
+
+	// EXPLAIN is followed by either a parenthesised option list, or the legacy ANALYZE & VERBOSE
+	// keywords, before the statement being explained
+	i++
+	if i < len(tokens) && tokens[i].IsPunctuation('(') {
+		depth := 0
+		for ; i < len(tokens); i++ {
+			if tokens[i].IsPunctuation('(') {
+				depth++
+			} else if tokens[i].IsPunctuation(')') {
+				depth--
+				if depth == 0 {
+					i++
+					break
+				}
+			}
+		}
+	}
+	for i < len(tokens) && (tokens[i].Is("analyze") || tokens[i].Is("analyse") || tokens[i].Is("verbose")) {
+		i++
+	}
+
+	return checkStatementKind(tokens[i:])
+}
+
+// isLockingClause returns true if the tokens after FOR are a row locking strength, i.e. UPDATE,
+// NO KEY UPDATE, SHARE or KEY SHARE.
+func isLockingClause(tokens []Token) bool {
+	words := []string{}
+	for _, t := range tokens {
+		if t.Kind != TokenIdentifier || len(words) == 3 {
+			break
+		}
+		words = append(words, t.Value)
+	}
+	clause := strings.Join(words, " ")
+	for _, strength := range []string{"update", "no key update", "share", "key share"} {
+		if clause == strength || strings.HasPrefix(clause, strength+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func isAllowedStatement(t Token) bool {
+	for _, s := range allowedStatements {
+		if t.Is(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDeniedFunction(name string) bool {
+	if _, ok := deniedFunctions[name]; ok {
+		return true
+	}
+	for _, prefix := range deniedFunctionPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeStatement names the statement starting with t, e.g. "DROP statements".
+func describeStatement(t Token) string {
+	if t.Kind != TokenIdentifier {
+		return fmt.Sprintf("a statement starting with the %s %q", t.Kind, t.Value)
+	}
+	if name, ok := statementNames[t.Value]; ok {
+		return name + " statements"
+	}
+	return strings.ToUpper(t.Value) + " statements"
+}
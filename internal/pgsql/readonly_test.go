@@ -0,0 +1,83 @@
+package pgsql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		// wantErr is part of the expected error, or empty if the query is accepted.
+		wantErr string
+	}{
+		{name: "select", query: "select instance_id from aws_ec2_instance where region = 'eu-west-1'"},
+		{name: "trailing semicolon", query: "select 1;"},
+		{name: "with", query: "with running as (select * from aws_ec2_instance where instance_state = 'running') select count(*) from running"},
+		{name: "explain", query: "explain select * from aws_s3_bucket"},
+		{name: "explain with options", query: "explain (analyze, format json) select * from aws_s3_bucket"},
+		{name: "parenthesised select", query: "(select 1) union (select 2)"},
+		{name: "values", query: "values (1), (2)"},
+		{name: "allowed function", query: "select jsonb_array_length(tags) from aws_s3_bucket"},
+		{name: "keyword as quoted column", query: `select "update", t.delete from t`},
+		{name: "denied function name in a string", query: "select 'pg_sleep(10)'"},
+		{name: "denied function name in a comment", query: "select 1 -- pg_sleep(10)"},
+		{name: "for in a function call", query: "select substring(name for 3) from t"},
+		{name: "parameter reference", query: "select * from t where region = :region"},
+
+		{name: "empty", query: " -- nothing\n", wantErr: "the query is empty"},
+		{name: "unterminated string", query: "select 'abc", wantErr: "could not be parsed: unterminated string at position 8"},
+		{name: "unclosed parenthesis", query: "select (1", wantErr: `could not be parsed: unclosed "(" at position 8`},
+		{name: "unbalanced parenthesis", query: "select 1) union (select 2", wantErr: `could not be parsed: unbalanced ")" at position 9`},
+		{name: "insert", query: "insert into t values (1)", wantErr: "not INSERT statements"},
+		{name: "update", query: "update t set a = 1", wantErr: "not UPDATE statements"},
+		{name: "drop", query: "drop table t", wantErr: "not DROP statements"},
+		{name: "set", query: "set statement_timeout = 0", wantErr: "not SET statements"},
+		{name: "copy to program", query: "copy (select 1) to program 'curl http://example.com'", wantErr: "not COPY statements"},
+		{name: "copy from program", query: "COPY t FROM PROGRAM 'id'", wantErr: "not COPY statements"},
+		{name: "do block", query: "do $$ begin perform 1; end $$", wantErr: "not DO statements"},
+		{name: "commit", query: "commit", wantErr: "not transaction control statements"},
+		{name: "statement in parentheses", query: "(delete from t)", wantErr: "not DELETE statements"},
+		{name: "string statement", query: "'select 1'", wantErr: `not a statement starting with the string "select 1"`},
+		{name: "data modifying CTE", query: "with gone as (delete from t returning *) select * from gone", wantErr: "DELETE is not allowed"},
+		{name: "nested data modifying CTE", query: "select * from (with x as (insert into t values (1) returning *) select * from x) s", wantErr: "INSERT is not allowed"},
+		{name: "select into", query: "select * into copy_of_t from t", wantErr: "SELECT INTO is not allowed"},
+		{name: "for update", query: "select * from t for update", wantErr: "row locking clauses"},
+		{name: "for key share", query: "select * from t for key share", wantErr: "row locking clauses"},
+		{name: "for no key update", query: "select * from t for no key update of t", wantErr: "row locking clauses"},
+		{name: "for share in a subquery", query: "select * from (select * from t for share) s", wantErr: "row locking clauses"},
+		{name: "multiple statements", query: "select 1; drop table t", wantErr: "only a single statement"},
+		{name: "multiple selects", query: "select 1; select 2", wantErr: "only a single statement"},
+		{name: "explain analyze of delete", query: "explain analyze delete from t", wantErr: "not DELETE statements"},
+		{name: "explain of create table as", query: "explain create table t2 as select 1", wantErr: "not CREATE statements"},
+		{name: "denied function", query: "select pg_sleep(10)", wantErr: "the function pg_sleep is not allowed (position 8)"},
+		{name: "denied function prefix", query: "select pg_read_file('/etc/passwd')", wantErr: "the function pg_read_file is not allowed"},
+		{name: "qualified denied function", query: "select pg_catalog.pg_sleep(1)", wantErr: "the function pg_sleep is not allowed"},
+		{name: "denied function in from", query: "select * from dblink('host=x', 'select 1') as t(a int)", wantErr: "the function dblink is not allowed"},
+		{name: "denied function in a where clause", query: "select * from t where set_config('statement_timeout', '0', false) is not null", wantErr: "the function set_config is not allowed"},
+		{name: "denied function after a comment", query: "select /* pg_sleep */ pg_sleep /* ( */ (1)", wantErr: "the function pg_sleep is not allowed"},
+		{name: "denied function after a nested comment", query: "select /* /* */ 'x' */ pg_sleep(1)", wantErr: "the function pg_sleep is not allowed"},
+		{name: "denied function after a dollar quote", query: "select $x$ '; $x$, pg_sleep(1)", wantErr: "the function pg_sleep is not allowed"},
+		{name: "denied function after an E string", query: `select E'\' pg_sleep(0)', pg_sleep(1)`, wantErr: "the function pg_sleep is not allowed (position 27)"},
+		{name: "quoted denied function", query: `select "pg_sleep"(1)`, wantErr: "the function pg_sleep is not allowed"},
+		{name: "unicode escaped denied function", query: `select U&"pg\005fsleep"(1)`, wantErr: "the function pg_sleep is not allowed"},
+		{name: "unicode escaped denied function with UESCAPE", query: `select U&"pg!005fsleep" UESCAPE '!' (1)`, wantErr: "the function pg_sleep is not allowed"},
+		{name: "denied function in explain", query: "explain analyze select pg_sleep(1)", wantErr: "the function pg_sleep is not allowed"},
+		{name: "nextval", query: "select nextval('s')", wantErr: "the function nextval is not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckReadOnly(tt.query)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("got error %q, want none", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("got no error, want one containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("got error %q, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// Package pgvalue turns the values pgx decodes from PostgreSQL into values that marshal to
+// stable, readable JSON. Without it, UUIDs marshal as arrays of bytes, numerics & intervals as
+// structs, and network addresses with a redundant prefix length.
+package pgvalue
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	dateLayout      = "2006-01-02"
+	timestampLayout = "2006-01-02T15:04:05.999999"
+)
+
+// arrayElementOIDs maps array types to their element types, for the types whose values can't be
+// told apart by their Go type alone.
+var arrayElementOIDs = map[uint32]uint32{
+	pgtype.DateArrayOID:        pgtype.DateOID,
+	pgtype.TimestampArrayOID:   pgtype.TimestampOID,
+	pgtype.TimestamptzArrayOID: pgtype.TimestamptzOID,
+	pgtype.InetArrayOID:        pgtype.InetOID,
+	pgtype.CIDRArrayOID:        pgtype.CIDROID,
+}
+
+// Row normalizes each of the values of a row, as returned by pgx.Rows.Values.
+func Row(fields []pgconn.FieldDescription, values []any) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		var oid uint32
+		if i < len(fields) {
+			oid = fields[i].DataTypeOID
+		}
+		out[i] = Normalize(oid, v)
+	}
+	return out
+}
+
+// Normalize converts a value of the PostgreSQL type oid, as decoded by pgx, to a string, number,
+// boolean, nil, or a map or slice of these. The oid is needed to format dates, timestamps &
+// network addresses the way PostgreSQL does, as pgx decodes them to the same Go types.
+func Normalize(oid uint32, v any) any {
+	switch v := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return v
+	case float32:
+		return normalizeFloat(float64(v))
+	case float64:
+		return normalizeFloat(v)
+	case time.Time:
+		return normalizeTime(oid, v)
+	case pgtype.InfinityModifier:
+		return v.String()
+	case netip.Prefix:
+		// inet values are host addresses unless they have a shorter prefix, but cidr values are always networks
+		if oid != pgtype.CIDROID && v.IsSingleIP() {
+			return v.Addr().String()
+		}
+		return v.String()
+	case netip.Addr:
+		return v.String()
+	case net.HardwareAddr:
+		return v.String()
+	case [16]byte:
+		return formatUUID(v)
+	case []byte:
+		return normalizeBytes(v)
+	case pgtype.Numeric:
+		return normalizeNumeric(v)
+	case pgtype.Interval:
+		return formatInterval(v)
+	case pgtype.Time:
+		return formatTimeOfDay(v)
+	case map[string]any:
+		// json & jsonb
+		out := make(map[string]any, len(v))
+		for k, child := range v {
+			out[k] = Normalize(0, child)
+		}
+		return out
+	case []any:
+		elementOID := arrayElementOIDs[oid]
+		out := make([]any, len(v))
+		for i, child := range v {
+			out[i] = Normalize(elementOID, child)
+		}
+		return out
+	case fmt.Stringer:
+		return v.String()
+	case driver.Valuer:
+		// most other pgtype values, such as ranges & geometric types, have a text representation
+		dv, err := v.Value()
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return Normalize(oid, dv)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// normalizeFloat returns special values as strings, as JSON can't represent them.
+func normalizeFloat(f float64) any {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	default:
+		return f
+	}
+}
+
+func normalizeTime(oid uint32, t time.Time) string {
+	switch oid {
+	case pgtype.DateOID:
+		return t.Format(dateLayout)
+	case pgtype.TimestampOID:
+		return t.Format(timestampLayout)
+	default:
+		// timestamptz values are decoded in the local time zone, so are converted to UTC for
+		// results that don't depend on where the tool runs
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+func formatUUID(b [16]byte) string {
+	s := hex.EncodeToString(b[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+// normalizeBytes returns text as a string, and anything else in PostgreSQL's hex format.
+func normalizeBytes(b []byte) string {
+	if utf8.Valid(b) && !strings.ContainsFunc(string(b), isControl) {
+		return string(b)
+	}
+	return `\x` + hex.EncodeToString(b)
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 && r != '\n' && r != '\r' && r != '\t'
+}
+
+// normalizeNumeric returns a JSON number that keeps the numeric's full precision, or a string for
+// special values.
+func normalizeNumeric(n pgtype.Numeric) any {
+	if !n.Valid {
+		return nil
+	}
+	switch {
+	case n.NaN:
+		return "NaN"
+	case n.InfinityModifier == pgtype.Infinity:
+		return "Infinity"
+	case n.InfinityModifier == pgtype.NegativeInfinity:
+		return "-Infinity"
+	}
+
+	s := n.Int.String()
+	if n.Exp > 0 {
+		s += strings.Repeat("0", int(n.Exp))
+	} else if n.Exp < 0 {
+		negative := strings.HasPrefix(s, "-")
+		digits := strings.TrimPrefix(s, "-")
+		scale := int(-n.Exp)
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		s = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+		if negative {
+			s = "-" + s
+		}
+	}
+
+	return json.Number(s)
+}
+
+// formatInterval formats an interval the way PostgreSQL does by default, e.g. 1 year 2 mons 3 days 04:05:06.
+func formatInterval(i pgtype.Interval) any {
+	if !i.Valid {
+		return nil
+	}
+
+	parts := []string{}
+	plural := func(n int64, unit string) {
+		if n == 0 {
+			return
+		}
+		if n == 1 || n == -1 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, unit))
+			return
+		}
+		parts = append(parts, fmt.Sprintf("%d %ss", n, unit))
+	}
+	plural(int64(i.Months/12), "year")
+	plural(int64(i.Months%12), "mon")
+	plural(int64(i.Days), "day")
+
+	if i.Microseconds != 0 || len(parts) == 0 {
+		parts = append(parts, formatClock(i.Microseconds))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func formatTimeOfDay(t pgtype.Time) any {
+	if !t.Valid {
+		return nil
+	}
+	return formatClock(t.Microseconds)
+}
+
+// formatClock formats a number of microseconds as hh:mm:ss, with a fraction only if there is one.
+func formatClock(microseconds int64) string {
+	sign := ""
+	if microseconds < 0 {
+		sign = "-"
+		microseconds = -microseconds
+	}
+
+	seconds := microseconds / 1e6
+	s := fmt.Sprintf("%s%02d:%02d:%02d", sign, seconds/3600, seconds/60%60, seconds%60)
+	if fraction := microseconds % 1e6; fraction != 0 {
+		s += strings.TrimRight("."+strconv.FormatInt(fraction+1e6, 10)[1:], "0")
+	}
+
+	return s
+}
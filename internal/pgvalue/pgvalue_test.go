@@ -0,0 +1,185 @@
+package pgvalue
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		oid   uint32
+		value any
+		want  string
+	}{
+		{
+			name:  "numeric",
+			oid:   pgtype.NumericOID,
+			value: pgtype.Numeric{Int: big.NewInt(-12345), Exp: -3, Valid: true},
+			want:  `-12.345`,
+		},
+		{
+			name:  "numeric with more precision than a float",
+			oid:   pgtype.NumericOID,
+			value: pgtype.Numeric{Int: mustBigInt(t, "123456789012345678901234567890"), Exp: -10, Valid: true},
+			want:  `12345678901234567890.1234567890`,
+		},
+		{
+			name:  "numeric below one",
+			oid:   pgtype.NumericOID,
+			value: pgtype.Numeric{Int: big.NewInt(5), Exp: -3, Valid: true},
+			want:  `0.005`,
+		},
+		{
+			name:  "numeric with positive exponent",
+			oid:   pgtype.NumericOID,
+			value: pgtype.Numeric{Int: big.NewInt(12), Exp: 3, Valid: true},
+			want:  `12000`,
+		},
+		{
+			name:  "numeric NaN",
+			oid:   pgtype.NumericOID,
+			value: pgtype.Numeric{NaN: true, Valid: true},
+			want:  `"NaN"`,
+		},
+		{
+			name:  "int8 over 2^53",
+			oid:   pgtype.Int8OID,
+			value: int64(9007199254740993),
+			want:  `9007199254740993`,
+		},
+		{
+			name:  "timestamptz",
+			oid:   pgtype.TimestamptzOID,
+			value: time.Date(2024, 3, 1, 14, 30, 0, 123000000, time.FixedZone("CET", 3600)),
+			want:  `"2024-03-01T13:30:00.123Z"`,
+		},
+		{
+			name:  "timestamp",
+			oid:   pgtype.TimestampOID,
+			value: time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC),
+			want:  `"2024-03-01T14:30:00"`,
+		},
+		{
+			name:  "date",
+			oid:   pgtype.DateOID,
+			value: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			want:  `"2024-03-01"`,
+		},
+		{
+			name:  "interval",
+			oid:   pgtype.IntervalOID,
+			value: pgtype.Interval{Months: 14, Days: 3, Microseconds: 4*3600e6 + 5*60e6 + 6.5e6, Valid: true},
+			want:  `"1 year 2 mons 3 days 04:05:06.5"`,
+		},
+		{
+			name:  "zero interval",
+			oid:   pgtype.IntervalOID,
+			value: pgtype.Interval{Valid: true},
+			want:  `"00:00:00"`,
+		},
+		{
+			name:  "inet host",
+			oid:   pgtype.InetOID,
+			value: netip.MustParsePrefix("10.0.0.1/32"),
+			want:  `"10.0.0.1"`,
+		},
+		{
+			name:  "inet network",
+			oid:   pgtype.InetOID,
+			value: netip.MustParsePrefix("10.0.0.1/24"),
+			want:  `"10.0.0.1/24"`,
+		},
+		{
+			name:  "cidr",
+			oid:   pgtype.CIDROID,
+			value: netip.MustParsePrefix("10.0.0.1/32"),
+			want:  `"10.0.0.1/32"`,
+		},
+		{
+			name: "jsonb",
+			oid:  pgtype.JSONBOID,
+			value: map[string]any{
+				"name": "web",
+				"tags": []any{"a", "b"},
+				"port": float64(443),
+			},
+			want: `{"name":"web","port":443,"tags":["a","b"]}`,
+		},
+		{
+			name:  "text array",
+			oid:   pgtype.TextArrayOID,
+			value: []any{"a", nil, "c"},
+			want:  `["a",null,"c"]`,
+		},
+		{
+			name:  "inet array",
+			oid:   pgtype.InetArrayOID,
+			value: []any{netip.MustParsePrefix("10.0.0.1/32"), netip.MustParsePrefix("10.0.0.0/8")},
+			want:  `["10.0.0.1","10.0.0.0/8"]`,
+		},
+		{
+			name:  "timestamp array",
+			oid:   pgtype.TimestampArrayOID,
+			value: []any{time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)},
+			want:  `["2024-03-01T14:30:00"]`,
+		},
+		{
+			name:  "bytea",
+			oid:   pgtype.ByteaOID,
+			value: []byte{0xde, 0xad, 0xbe, 0xef},
+			want:  `"\\xdeadbeef"`,
+		},
+		{
+			name:  "bytea of text",
+			oid:   pgtype.ByteaOID,
+			value: []byte("hello"),
+			want:  `"hello"`,
+		},
+		{
+			name:  "NULL",
+			oid:   pgtype.TextOID,
+			value: nil,
+			want:  `null`,
+		},
+		{
+			name:  "uuid",
+			oid:   pgtype.UUIDOID,
+			value: [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00},
+			want:  `"123e4567-e89b-12d3-a456-426614174000"`,
+		},
+		{
+			name:  "float infinity",
+			oid:   pgtype.Float8OID,
+			value: math.Inf(1),
+			want:  `"Infinity"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(Normalize(tt.oid, tt.value))
+			if err != nil {
+				t.Fatalf("error marshalling value: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustBigInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("%q is not an integer", s)
+	}
+	return n
+}
@@ -0,0 +1,241 @@
+package reachability
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+)
+
+const (
+	// ephemeralPortFrom & ephemeralPortTo are the range of ports replies are sent to. Network
+	// ACLs are stateless, so must allow this range for replies to get back to the source.
+	ephemeralPortFrom = 1024
+	ephemeralPortTo   = 65535
+)
+
+type (
+	// Query is the traffic to analyse.
+	Query struct {
+		Source      Endpoint
+		Destination Endpoint
+		Protocol    Protocol
+		Port        int32
+	}
+
+	// Result is the outcome of an analysis.
+	Result struct {
+		Reachable bool  `json:"reachable"`
+		Hops      []Hop `json:"hops"`
+	}
+
+	// Hop is a single control on the path between the endpoints, and whether it allows the traffic.
+	Hop struct {
+		Step    string `json:"step"`
+		Allowed bool   `json:"allowed"`
+		// Resource is the ID of the security group, network ACL or route table evaluated.
+		Resource string `json:"resource,omitempty"`
+		Detail   string `json:"detail"`
+	}
+)
+
+// Analyze walks the path from source to destination, evaluating every control on it. All hops
+// are evaluated even once one blocks the traffic, so that every problem is reported at once.
+func Analyze(q Query, n Network) Result {
+	hops := []Hop{
+		securityGroupHop("source security group egress", q.Source, q.Destination, q, n, true),
+	}
+
+	// traffic within a subnet doesn't pass through its network ACL
+	sameSubnet := q.Source.SubnetID == q.Destination.SubnetID
+	if !sameSubnet {
+		hops = append(hops, networkACLHop("source subnet network ACL outbound", q.Source, q.Destination.Address, q.Protocol, q.Port, true, n))
+	}
+
+	hops = append(hops, routeHop(q, n))
+
+	if !sameSubnet {
+		hops = append(hops, networkACLHop("destination subnet network ACL inbound", q.Destination, q.Source.Address, q.Protocol, q.Port, false, n))
+	}
+
+	hops = append(hops, securityGroupHop("destination security group ingress", q.Destination, q.Source, q, n, false))
+
+	// security groups are stateful, so replies are always allowed by them, but network ACLs aren't
+	if !sameSubnet && q.Protocol.hasPorts() {
+		hops = append(hops,
+			networkACLHop("destination subnet network ACL outbound (replies)", q.Destination, q.Source.Address, q.Protocol, -1, true, n),
+			networkACLHop("source subnet network ACL inbound (replies)", q.Source, q.Destination.Address, q.Protocol, -1, false, n),
+		)
+	}
+
+	result := Result{Reachable: true, Hops: hops}
+	for _, h := range hops {
+		if !h.Allowed {
+			result.Reachable = false
+		}
+	}
+
+	return result
+}
+
+// securityGroupHop checks whether any security group of self allows traffic to (egress) or from (ingress) peer.
+func securityGroupHop(step string, self, peer Endpoint, q Query, n Network, egress bool) Hop {
+	hop := Hop{Step: step}
+	if len(self.SecurityGroupIDs) == 0 {
+		hop.Detail = fmt.Sprintf("%s has no security groups", self.NetworkInterfaceID)
+		return hop
+	}
+
+	unknownPrefixLists := []string{}
+	for _, sgID := range self.SecurityGroupIDs {
+		sg, ok := n.SecurityGroups[sgID]
+		if !ok {
+			hop.Detail = fmt.Sprintf("security group %s could not be found", sgID)
+			continue
+		}
+
+		rules := sg.Ingress
+		if egress {
+			rules = sg.Egress
+		}
+
+		for _, rule := range rules {
+			if !rule.Protocol.Matches(q.Protocol) || !portInRange(rule.Protocol, rule.FromPort, rule.ToPort, q.Port) {
+				continue
+			}
+
+			for _, cidr := range rule.CIDRs {
+				if cidr.Contains(peer.Address) {
+					hop.Allowed = true
+					hop.Resource = sgID
+					hop.Detail = fmt.Sprintf("rule allowing %s %s %s%s", rule.ports(), direction(egress), cidr, describe(rule.Description))
+					return hop
+				}
+			}
+
+			for _, peerSG := range rule.SecurityGroupIDs {
+				if slices.Contains(peer.SecurityGroupIDs, peerSG) {
+					hop.Allowed = true
+					hop.Resource = sgID
+					hop.Detail = fmt.Sprintf("rule allowing %s %s security group %s, which %s is a member of%s", rule.ports(), direction(egress), peerSG, peer.NetworkInterfaceID, describe(rule.Description))
+					return hop
+				}
+			}
+
+			unknownPrefixLists = append(unknownPrefixLists, rule.PrefixListIDs...)
+		}
+	}
+
+	hop.Resource = fmt.Sprint(self.SecurityGroupIDs)
+	hop.Detail = fmt.Sprintf("no rule in security groups %v allows %s/%d %s %s", self.SecurityGroupIDs, q.Protocol, q.Port, direction(egress), peer.Address)
+	if len(unknownPrefixLists) > 0 {
+		hop.Detail += fmt.Sprintf(", unless the address is in prefix lists %v, which were not evaluated", unknownPrefixLists)
+	}
+
+	return hop
+}
+
+// networkACLHop checks whether the network ACL of self's subnet allows traffic to (egress) or
+// from (ingress) the peer address. A port of -1 checks the ephemeral port range used by replies.
+func networkACLHop(step string, self Endpoint, peer netip.Addr, protocol Protocol, port int32, egress bool, n Network) Hop {
+	hop := Hop{Step: step}
+	acl, ok := n.NetworkACLFor(self.SubnetID, self.VpcID)
+	if !ok {
+		hop.Detail = fmt.Sprintf("no network ACL found for subnet %s", self.SubnetID)
+		return hop
+	}
+	hop.Resource = acl.ID
+
+	entries := make([]NetworkACLRule, 0, len(acl.Entries))
+	for _, e := range acl.Entries {
+		if e.Egress == egress {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].RuleNumber < entries[j].RuleNumber })
+
+	portDesc := fmt.Sprintf("%s/%d", protocol, port)
+	if port < 0 {
+		portDesc = fmt.Sprintf("%s/%d-%d", protocol, ephemeralPortFrom, ephemeralPortTo)
+	}
+
+	for _, e := range entries {
+		if !e.Protocol.Matches(protocol) || !e.CIDR.Contains(peer) {
+			continue
+		}
+		if port < 0 && e.Protocol.hasPorts() && (e.FromPort > ephemeralPortFrom || e.ToPort < ephemeralPortTo) {
+			// every ephemeral port must be allowed for replies to be reliable, so a rule covering
+			// only some of them decides nothing if it allows, but blocks some replies if it denies
+			if e.Allow || e.ToPort < ephemeralPortFrom || e.FromPort > ephemeralPortTo {
+				continue
+			}
+			hop.Detail = fmt.Sprintf("rule %d denies %s/%d-%d %s %s, so replies sent to those ephemeral ports are dropped",
+				e.RuleNumber, protocol, e.FromPort, e.ToPort, direction(egress), e.CIDR)
+			return hop
+		}
+		if port >= 0 && !portInRange(e.Protocol, e.FromPort, e.ToPort, port) {
+			continue
+		}
+
+		hop.Allowed = e.Allow
+		action := "denies"
+		if e.Allow {
+			action = "allows"
+		}
+		hop.Detail = fmt.Sprintf("rule %d %s %s %s %s", e.RuleNumber, action, portDesc, direction(egress), e.CIDR)
+		return hop
+	}
+
+	hop.Detail = fmt.Sprintf("no rule matches %s %s %s, so the default rule denies it", portDesc, direction(egress), peer)
+	return hop
+}
+
+// routeHop checks that the source subnet's route table has a route to the destination.
+func routeHop(q Query, n Network) Hop {
+	hop := Hop{Step: "source subnet route table"}
+	rt, ok := n.RouteTableFor(q.Source.SubnetID, q.Source.VpcID)
+	if !ok {
+		hop.Detail = fmt.Sprintf("no route table found for subnet %s", q.Source.SubnetID)
+		return hop
+	}
+	hop.Resource = rt.ID
+
+	// the most specific route wins
+	var best *Route
+	for i, r := range rt.Routes {
+		if r.Destination.Contains(q.Destination.Address) && (best == nil || r.Destination.Bits() > best.Destination.Bits()) {
+			best = &rt.Routes[i]
+		}
+	}
+
+	switch {
+	case best == nil:
+		hop.Detail = fmt.Sprintf("no route to %s", q.Destination.Address)
+	case best.Blackhole:
+		hop.Detail = fmt.Sprintf("route %s to %s is a blackhole, as its target no longer exists", best.Destination, best.Target)
+	case best.Target == routeTargetLocal && q.Source.VpcID == q.Destination.VpcID:
+		hop.Allowed = true
+		hop.Detail = fmt.Sprintf("local route %s within %s", best.Destination, q.Source.VpcID)
+	case best.Target == routeTargetLocal:
+		hop.Detail = fmt.Sprintf("the local route %s matches, but the destination is in a different VPC (%s)", best.Destination, q.Destination.VpcID)
+	default:
+		hop.Allowed = true
+		hop.Detail = fmt.Sprintf("route %s via %s. The return route on the other side of %s was not evaluated", best.Destination, best.Target, best.Target)
+	}
+
+	return hop
+}
+
+func direction(egress bool) string {
+	if egress {
+		return "to"
+	}
+	return "from"
+}
+
+func describe(description string) string {
+	if description == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%q)", description)
+}
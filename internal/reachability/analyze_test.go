@@ -0,0 +1,170 @@
+package reachability
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fixture is a network of two VPCs, loaded from testdata/network.json. vpc-a has app, db, batch &
+// legacy subnets, where the db subnet's network ACL denies PostgreSQL from the batch subnet, the
+// legacy subnet's network ACL denies replies to some ephemeral ports & the batch subnet's route to
+// vpc-b is a blackhole. vpc-a's main route table has no route to vpc-b.
+type fixture struct {
+	Endpoints map[string]Endpoint `json:"endpoints"`
+	Network   Network             `json:"network"`
+}
+
+func loadFixture(t *testing.T) fixture {
+	t.Helper()
+
+	b, err := os.ReadFile("testdata/network.json")
+	if err != nil {
+		t.Fatalf("error reading fixture: %v", err)
+	}
+	var f fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		t.Fatalf("error unmarshalling fixture: %v", err)
+	}
+	return f
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name          string
+		source        string
+		destination   string
+		protocol      Protocol
+		port          int32
+		wantReachable bool
+		wantSteps     int
+		// wantBlocked are the steps that deny the traffic, with a substring of their detail.
+		wantBlocked map[string]string
+	}{
+		{
+			name:          "allowed",
+			source:        "app",
+			destination:   "db",
+			protocol:      ProtocolTCP,
+			port:          5432,
+			wantReachable: true,
+			wantSteps:     7,
+		},
+		{
+			name:          "same subnet skips network ACLs",
+			source:        "app",
+			destination:   "app-2",
+			protocol:      ProtocolTCP,
+			port:          8080,
+			wantReachable: true,
+			wantSteps:     3,
+		},
+		{
+			name:        "denied by security group",
+			source:      "app",
+			destination: "db",
+			protocol:    ProtocolTCP,
+			port:        6379,
+			wantSteps:   7,
+			wantBlocked: map[string]string{
+				"destination security group ingress": "no rule in security groups [sg-db] allows tcp/6379 from 10.0.1.10",
+			},
+		},
+		{
+			name:        "denied by network ACL",
+			source:      "batch",
+			destination: "db",
+			protocol:    ProtocolTCP,
+			port:        5432,
+			wantSteps:   7,
+			wantBlocked: map[string]string{
+				"destination subnet network ACL inbound": "rule 90 denies tcp/5432 from 10.0.4.0/24",
+			},
+		},
+		{
+			name:        "replies denied by network ACL",
+			source:      "app",
+			destination: "db",
+			protocol:    ProtocolUDP,
+			port:        5432,
+			wantSteps:   7,
+			wantBlocked: map[string]string{
+				"destination security group ingress":                "no rule in security groups [sg-db] allows udp/5432",
+				"destination subnet network ACL outbound (replies)": "no rule matches udp/1024-65535 to 10.0.1.10",
+			},
+		},
+		{
+			name:        "replies to some ephemeral ports denied by network ACL",
+			source:      "app",
+			destination: "legacy",
+			protocol:    ProtocolTCP,
+			port:        80,
+			wantSteps:   7,
+			wantBlocked: map[string]string{
+				"destination subnet network ACL outbound (replies)": "rule 90 denies tcp/32768-65535 to 0.0.0.0/0",
+			},
+		},
+		{
+			name:        "no route",
+			source:      "app",
+			destination: "other",
+			protocol:    ProtocolTCP,
+			port:        443,
+			wantSteps:   7,
+			wantBlocked: map[string]string{
+				"source subnet route table": "no route to 10.1.1.10",
+			},
+		},
+		{
+			name:        "blackhole route",
+			source:      "batch",
+			destination: "other",
+			protocol:    ProtocolTCP,
+			port:        443,
+			wantSteps:   7,
+			wantBlocked: map[string]string{
+				"source subnet route table": "route 10.1.0.0/16 to pcx-0123 is a blackhole",
+			},
+		},
+	}
+
+	f := loadFixture(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Analyze(Query{
+				Source:      f.Endpoints[tt.source],
+				Destination: f.Endpoints[tt.destination],
+				Protocol:    tt.protocol,
+				Port:        tt.port,
+			}, f.Network)
+
+			if got.Reachable != tt.wantReachable {
+				t.Errorf("got reachable %t, want %t", got.Reachable, tt.wantReachable)
+			}
+			if len(got.Hops) != tt.wantSteps {
+				t.Errorf("got %d hops, want %d", len(got.Hops), tt.wantSteps)
+			}
+
+			blocked := []string{}
+			for _, h := range got.Hops {
+				if h.Allowed {
+					continue
+				}
+				blocked = append(blocked, h.Step)
+				want, ok := tt.wantBlocked[h.Step]
+				if !ok {
+					t.Errorf("got step %q blocked: %s", h.Step, h.Detail)
+				} else if !strings.Contains(h.Detail, want) {
+					t.Errorf("got detail %q for step %q, want one containing %q", h.Detail, h.Step, want)
+				}
+			}
+			for step := range tt.wantBlocked {
+				if !slices.Contains(blocked, step) {
+					t.Errorf("got step %q allowed, want it blocked", step)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// Package reachability decides whether network traffic can flow between two network
+// interfaces in AWS, by evaluating the security groups, network ACLs and route tables
+// on the path between them.
+//
+// The types in this package are plain data, so networks can be built from EC2 API
+// responses or loaded from JSON fixtures.
+package reachability
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Endpoint is one end of a connection: a network interface & the address used on it.
+	Endpoint struct {
+		NetworkInterfaceID string     `json:"network_interface_id"`
+		Description        string     `json:"description,omitempty"`
+		Address            netip.Addr `json:"address"`
+		SubnetID           string     `json:"subnet_id"`
+		VpcID              string     `json:"vpc_id"`
+		SecurityGroupIDs   []string   `json:"security_group_ids"`
+	}
+
+	// Network holds the configuration that controls traffic between endpoints.
+	Network struct {
+		SecurityGroups map[string]SecurityGroup `json:"security_groups"`
+		NetworkACLs    []NetworkACL             `json:"network_acls"`
+		RouteTables    []RouteTable             `json:"route_tables"`
+	}
+
+	SecurityGroup struct {
+		ID      string              `json:"id"`
+		VpcID   string              `json:"vpc_id"`
+		Ingress []SecurityGroupRule `json:"ingress"`
+		Egress  []SecurityGroupRule `json:"egress"`
+	}
+
+	// SecurityGroupRule allows traffic to or from any of its peers.
+	SecurityGroupRule struct {
+		Protocol         Protocol       `json:"protocol"`
+		FromPort         int32          `json:"from_port"`
+		ToPort           int32          `json:"to_port"`
+		CIDRs            []netip.Prefix `json:"cidrs,omitempty"`
+		SecurityGroupIDs []string       `json:"security_group_ids,omitempty"`
+		PrefixListIDs    []string       `json:"prefix_list_ids,omitempty"`
+		Description      string         `json:"description,omitempty"`
+	}
+
+	NetworkACL struct {
+		ID        string           `json:"id"`
+		VpcID     string           `json:"vpc_id"`
+		IsDefault bool             `json:"is_default"`
+		SubnetIDs []string         `json:"subnet_ids"`
+		Entries   []NetworkACLRule `json:"entries"`
+	}
+
+	// NetworkACLRule is a numbered network ACL entry. Rules are evaluated in number order and
+	// the first match decides.
+	NetworkACLRule struct {
+		RuleNumber int32        `json:"rule_number"`
+		Egress     bool         `json:"egress"`
+		Protocol   Protocol     `json:"protocol"`
+		Allow      bool         `json:"allow"`
+		CIDR       netip.Prefix `json:"cidr"`
+		FromPort   int32        `json:"from_port"`
+		ToPort     int32        `json:"to_port"`
+	}
+
+	RouteTable struct {
+		ID        string   `json:"id"`
+		VpcID     string   `json:"vpc_id"`
+		IsMain    bool     `json:"is_main"`
+		SubnetIDs []string `json:"subnet_ids"`
+		Routes    []Route  `json:"routes"`
+	}
+
+	Route struct {
+		Destination netip.Prefix `json:"destination"`
+		// Target is the ID of the route's target, e.g. local, pcx-0123 or tgw-0123.
+		Target string `json:"target"`
+		// Blackhole is true if the route's target no longer exists.
+		Blackhole bool `json:"blackhole,omitempty"`
+	}
+
+	// Protocol is an IP protocol, normalised to tcp, udp, icmp, icmpv6, all (-1) or a protocol number.
+	Protocol string
+)
+
+const (
+	ProtocolAll    Protocol = "all"
+	ProtocolTCP    Protocol = "tcp"
+	ProtocolUDP    Protocol = "udp"
+	ProtocolICMP   Protocol = "icmp"
+	ProtocolICMPv6 Protocol = "icmpv6"
+
+	routeTargetLocal = "local"
+)
+
+// ParseProtocol normalises the protocol names & numbers used by the EC2 API.
+func ParseProtocol(s string) Protocol {
+	switch strings.ToLower(s) {
+	case "-1", "all", "":
+		return ProtocolAll
+	case "6", "tcp":
+		return ProtocolTCP
+	case "17", "udp":
+		return ProtocolUDP
+	case "1", "icmp":
+		return ProtocolICMP
+	case "58", "icmpv6":
+		return ProtocolICMPv6
+	default:
+		return Protocol(s)
+	}
+}
+
+// Matches returns true if a rule for protocol p applies to traffic of protocol other.
+func (p Protocol) Matches(other Protocol) bool {
+	return p == ProtocolAll || p == other
+}
+
+// hasPorts returns true if rules for the protocol are restricted by port.
+func (p Protocol) hasPorts() bool {
+	return p == ProtocolTCP || p == ProtocolUDP
+}
+
+// portInRange returns true if port is within the rule's range. Rules for protocols without
+// ports, and rules for all protocols, match any port.
+func portInRange(ruleProtocol Protocol, from, to int32, port int32) bool {
+	if !ruleProtocol.hasPorts() {
+		return true
+	}
+	return port >= from && port <= to
+}
+
+func (r SecurityGroupRule) ports() string {
+	if !r.Protocol.hasPorts() {
+		return string(r.Protocol)
+	}
+	if r.FromPort == r.ToPort {
+		return string(r.Protocol) + "/" + strconv.Itoa(int(r.FromPort))
+	}
+	return string(r.Protocol) + "/" + strconv.Itoa(int(r.FromPort)) + "-" + strconv.Itoa(int(r.ToPort))
+}
+
+// NetworkACLFor returns the network ACL associated with a subnet, falling back to the VPC's default ACL.
+func (n Network) NetworkACLFor(subnetID, vpcID string) (NetworkACL, bool) {
+	var fallback *NetworkACL
+	for i, acl := range n.NetworkACLs {
+		for _, s := range acl.SubnetIDs {
+			if s == subnetID {
+				return acl, true
+			}
+		}
+		if acl.IsDefault && acl.VpcID == vpcID {
+			fallback = &n.NetworkACLs[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return NetworkACL{}, false
+}
+
+// RouteTableFor returns the route table associated with a subnet, falling back to the VPC's main route table.
+func (n Network) RouteTableFor(subnetID, vpcID string) (RouteTable, bool) {
+	var fallback *RouteTable
+	for i, rt := range n.RouteTables {
+		for _, s := range rt.SubnetIDs {
+			if s == subnetID {
+				return rt, true
+			}
+		}
+		if rt.IsMain && rt.VpcID == vpcID {
+			fallback = &n.RouteTables[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return RouteTable{}, false
+}
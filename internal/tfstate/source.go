@@ -0,0 +1,243 @@
+package tfstate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/fergalhk/llm-cloud-discovery/internal/arn"
+)
+
+const (
+	stateSuffix = ".tfstate"
+	s3Scheme    = "s3://"
+)
+
+type (
+	// Source loads the state files at a set of locations, and caches them until it's reset.
+	Source struct {
+		locations []string
+		s3Client  *s3.Client
+
+		mu     sync.Mutex
+		states []*State
+	}
+
+	// Match is a resource found by its ID or ARN.
+	Match struct {
+		Resource
+		// MatchedOn is how the resource matched: id, arn, arn_suffix (the ARN ends with the
+		// identifier) or arn_resource_id (the resource's ID is the resource part of the ARN).
+		MatchedOn string `json:"matched_on"`
+	}
+)
+
+// NewSource creates a source for the given locations. Each location is one of:
+//   - a local state file, e.g. terraform.tfstate
+//   - a local directory, searched recursively for *.tfstate files
+//   - an S3 object, e.g. s3://my-state-bucket/network/terraform.tfstate
+//   - an S3 prefix ending in "/", searched for *.tfstate objects, which includes workspaces
+//
+// The S3 client is only used for S3 locations, and can be nil if there aren't any.
+func NewSource(s3Client *s3.Client, locations ...string) *Source {
+	return &Source{
+		locations: locations,
+		s3Client:  s3Client,
+	}
+}
+
+// Locations returns the locations the source was created with.
+func (s *Source) Locations() []string {
+	return s.locations
+}
+
+// States returns every state, loading them on first use.
+func (s *Source) States(ctx context.Context) ([]*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states != nil {
+		return s.states, nil
+	}
+
+	states := []*State{}
+	for _, location := range s.locations {
+		var (
+			loaded []*State
+			err    error
+		)
+		if strings.HasPrefix(location, s3Scheme) {
+			loaded, err = s.loadS3(ctx, location)
+		} else {
+			loaded, err = loadLocal(location)
+		}
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, loaded...)
+	}
+
+	s.states = states
+	return states, nil
+}
+
+// Reset discards the loaded states, so they're loaded again on next use.
+func (s *Source) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states = nil
+}
+
+// Resources returns the resources of every state.
+func (s *Source) Resources(ctx context.Context) ([]Resource, error) {
+	states, err := s.States(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []Resource{}
+	for _, state := range states {
+		out = append(out, state.Resources...)
+	}
+	return out, nil
+}
+
+// Find returns the resources whose ID or ARN is the identifier. If none match exactly, resources
+// whose ARN ends with the identifier are returned, e.g. an ECS service by name, or for an ARN the
+// resources whose ID is the ARN's resource ID, e.g. an IAM role by its ARN.
+func (s *Source) Find(ctx context.Context, identifier string) ([]Match, error) {
+	resources, err := s.Resources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []Match{}
+	for _, r := range resources {
+		switch identifier {
+		case r.ID:
+			out = append(out, Match{Resource: r, MatchedOn: "id"})
+		case r.ARN:
+			out = append(out, Match{Resource: r, MatchedOn: "arn"})
+		}
+	}
+	if len(out) > 0 {
+		return out, nil
+	}
+
+	resourceID := ""
+	if parsed, err := arn.Parse(identifier); err == nil {
+		resourceID = parsed.ResourceID
+	}
+	for _, r := range resources {
+		// some resources, such as ECS services, are identified by their ARN
+		resourceARN := r.ARN
+		if resourceARN == "" && arn.IsARN(r.ID) {
+			resourceARN = r.ID
+		}
+
+		switch {
+		case resourceID != "" && r.ID != "" && (r.ID == resourceID || strings.HasSuffix(resourceID, "/"+r.ID)):
+			out = append(out, Match{Resource: r, MatchedOn: "arn_resource_id"})
+		case resourceID == "" && resourceARN != "" && (strings.HasSuffix(resourceARN, "/"+identifier) || strings.HasSuffix(resourceARN, ":"+identifier)):
+			out = append(out, Match{Resource: r, MatchedOn: "arn_suffix"})
+		}
+	}
+	return out, nil
+}
+
+func (s *Source) loadS3(ctx context.Context, location string) ([]*State, error) {
+	if s.s3Client == nil {
+		return nil, fmt.Errorf("error loading state %s: no S3 client", location)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing state location %s: %w", location, err)
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+
+	keys := []string{key}
+	if key == "" || strings.HasSuffix(key, "/") {
+		keys = []string{}
+		paginator := s3.NewListObjectsV2Paginator(s.s3Client, &s3.ListObjectsV2Input{Bucket: &bucket, Prefix: &key})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("error listing states in %s: %w", location, err)
+			}
+			for _, obj := range page.Contents {
+				if obj.Key != nil && strings.HasSuffix(*obj.Key, stateSuffix) {
+					keys = append(keys, *obj.Key)
+				}
+			}
+		}
+	}
+
+	out := []*State{}
+	for _, key := range keys {
+		objectLocation := s3Scheme + bucket + "/" + key
+		resp, err := s.s3Client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+		if err != nil {
+			return nil, fmt.Errorf("error getting state %s: %w", objectLocation, err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading state %s: %w", objectLocation, err)
+		}
+
+		state, err := Parse(objectLocation, data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, state)
+	}
+	return out, nil
+}
+
+func loadLocal(location string) ([]*State, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, fmt.Errorf("error loading state: %w", err)
+	}
+
+	paths := []string{location}
+	if info.IsDir() {
+		paths = []string{}
+		err := filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// .terraform holds copies of remote state & modules rather than the state of this configuration
+			if d.IsDir() && d.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && strings.HasSuffix(path, stateSuffix) {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error finding states in %s: %w", location, err)
+		}
+	}
+
+	out := []*State{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading state: %w", err)
+		}
+		state, err := Parse(path, data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, state)
+	}
+	return out, nil
+}
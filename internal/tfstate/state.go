@@ -0,0 +1,205 @@
+// Package tfstate reads Terraform state files, to find out which resources Terraform manages and
+// the address of the resource block that manages each of them. Only version 4 state files,
+// written by Terraform 0.12 and later, are supported.
+package tfstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	supportedVersion = 4
+
+	modeManaged = "managed"
+
+	// Redacted replaces the values of sensitive attributes.
+	Redacted = "(sensitive)"
+)
+
+// secretAttributes are the attributes of resource types that are known to hold secrets. The
+// providers mark them as sensitive, but states only record sensitive attributes from Terraform
+// 0.15, so they're redacted whatever the state says.
+var secretAttributes = map[string][]string{
+	"aws_api_gateway_api_key":             {"value"},
+	"aws_appsync_api_key":                 {"key"},
+	"aws_cognito_user_pool_client":        {"client_secret"},
+	"aws_db_instance":                     {"password"},
+	"aws_docdb_cluster":                   {"master_password"},
+	"aws_elasticache_replication_group":   {"auth_token"},
+	"aws_elasticache_user":                {"passwords"},
+	"aws_iam_access_key":                  {"secret", "ses_smtp_password_v4"},
+	"aws_iam_service_specific_credential": {"service_password"},
+	"aws_iam_user_login_profile":          {"password"},
+	"aws_kms_ciphertext":                  {"plaintext"},
+	"aws_mq_broker":                       {"user"},
+	"aws_rds_cluster":                     {"master_password"},
+	"aws_redshift_cluster":                {"master_password"},
+	"aws_secretsmanager_secret_version":   {"secret_string", "secret_binary"},
+	"aws_ssm_parameter":                   {"value"},
+	"random_password":                     {"result", "bcrypt_hash"},
+	"tls_private_key":                     {"private_key_pem", "private_key_pem_pkcs8", "private_key_openssh"},
+}
+
+type (
+	// State is a parsed state file.
+	State struct {
+		// Location is where the state was loaded from, e.g. a path or an s3:// URL.
+		Location         string
+		TerraformVersion string
+		Serial           int64
+		Lineage          string
+		Resources        []Resource
+	}
+
+	// Resource is a single instance of a managed resource, e.g. one element of a resource block
+	// using count or for_each.
+	Resource struct {
+		// Location is where the state holding the resource was loaded from.
+		Location string `json:"state"`
+		// Address is the resource's address, as used by terraform state show, e.g.
+		// module.network.aws_subnet.private["eu-west-1a"].
+		Address string `json:"address"`
+		// Module is the address of the module the resource is in, or empty for the root module.
+		Module   string `json:"module,omitempty"`
+		Type     string `json:"type"`
+		Name     string `json:"name"`
+		Provider string `json:"provider"`
+		ID       string `json:"id,omitempty"`
+		ARN      string `json:"arn,omitempty"`
+		// Attributes are the resource's attributes, with sensitive values redacted.
+		Attributes map[string]any `json:"-"`
+	}
+
+	stateFile struct {
+		Version          int             `json:"version"`
+		TerraformVersion string          `json:"terraform_version"`
+		Serial           int64           `json:"serial"`
+		Lineage          string          `json:"lineage"`
+		Resources        []stateResource `json:"resources"`
+	}
+
+	stateResource struct {
+		Module    string          `json:"module"`
+		Mode      string          `json:"mode"`
+		Type      string          `json:"type"`
+		Name      string          `json:"name"`
+		Provider  string          `json:"provider"`
+		Instances []stateInstance `json:"instances"`
+	}
+
+	stateInstance struct {
+		IndexKey            any            `json:"index_key"`
+		Attributes          map[string]any `json:"attributes"`
+		SensitiveAttributes any            `json:"sensitive_attributes"`
+	}
+)
+
+// Parse parses a state file loaded from location. Data sources are left out, as they only read
+// resources that are managed elsewhere. Sensitive attributes, & those in secretAttributes, are
+// redacted.
+func Parse(location string, data []byte) (*State, error) {
+	var f stateFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error parsing state %s: %w", location, err)
+	}
+	if f.Version != supportedVersion {
+		return nil, fmt.Errorf("state %s is version %d, only version %d is supported", location, f.Version, supportedVersion)
+	}
+
+	s := &State{
+		Location:         location,
+		TerraformVersion: f.TerraformVersion,
+		Serial:           f.Serial,
+		Lineage:          f.Lineage,
+		Resources:        []Resource{},
+	}
+	for _, r := range f.Resources {
+		if r.Mode != modeManaged {
+			continue
+		}
+
+		for _, instance := range r.Instances {
+			address := r.Type + "." + r.Name + indexSuffix(instance.IndexKey)
+			if r.Module != "" {
+				address = r.Module + "." + address
+			}
+
+			attributes := instance.Attributes
+			sensitive := append(sensitiveAttributes(instance.SensitiveAttributes), secretAttributes[r.Type]...)
+			for _, name := range sensitive {
+				if v, ok := attributes[name]; ok && v != nil {
+					attributes[name] = Redacted
+				}
+			}
+
+			id, _ := attributes["id"].(string)
+			arn, _ := attributes["arn"].(string)
+			s.Resources = append(s.Resources, Resource{
+				Location:   location,
+				Address:    address,
+				Module:     r.Module,
+				Type:       r.Type,
+				Name:       r.Name,
+				Provider:   providerName(r.Provider),
+				ID:         id,
+				ARN:        arn,
+				Attributes: attributes,
+			})
+		}
+	}
+
+	return s, nil
+}
+
+// indexSuffix returns the part of an address that picks an instance of a resource using count or
+// for_each, e.g. [0] or ["eu-west-1a"].
+func indexSuffix(key any) string {
+	switch key := key.(type) {
+	case float64:
+		return "[" + strconv.FormatFloat(key, 'f', -1, 64) + "]"
+	case string:
+		return "[" + strconv.Quote(key) + "]"
+	default:
+		return ""
+	}
+}
+
+// providerName shortens a provider configuration address, e.g.
+// provider["registry.terraform.io/hashicorp/aws"].us_east_1 to hashicorp/aws.us_east_1.
+func providerName(provider string) string {
+	inner, ok := strings.CutPrefix(provider, `provider["`)
+	if !ok {
+		return provider
+	}
+	source, alias, ok := strings.Cut(inner, `"]`)
+	if !ok {
+		return provider
+	}
+	return strings.TrimPrefix(source, "registry.terraform.io/") + alias
+}
+
+// sensitiveAttributes returns the top level attributes that contain sensitive values. Each path
+// in the state is a list of steps, the first of which names the attribute.
+func sensitiveAttributes(paths any) []string {
+	list, ok := paths.([]any)
+	if !ok {
+		return nil
+	}
+
+	out := []string{}
+	for _, path := range list {
+		step := path
+		if steps, ok := path.([]any); ok && len(steps) > 0 {
+			step = steps[0]
+		}
+		if m, ok := step.(map[string]any); ok && m["type"] == "get_attr" {
+			if name, ok := m["value"].(string); ok {
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,171 @@
+package tfstate
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseFixture(t *testing.T, path string) *State {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading fixture: %v", err)
+	}
+	s, err := Parse(path, data)
+	if err != nil {
+		t.Fatalf("error parsing %s: %v", path, err)
+	}
+	return s
+}
+
+func TestParse(t *testing.T) {
+	s := parseFixture(t, "testdata/network/terraform.tfstate")
+
+	if s.TerraformVersion != "1.5.7" || s.Serial != 12 || s.Lineage != "6f1c2d3e-0000-4000-8000-000000000001" {
+		t.Errorf("got version %s, serial %d & lineage %s", s.TerraformVersion, s.Serial, s.Lineage)
+	}
+
+	// data sources are left out
+	got := []string{}
+	for _, r := range s.Resources {
+		got = append(got, r.Address)
+	}
+	want := []string{
+		"aws_vpc.main",
+		"aws_subnet.private[0]",
+		"aws_subnet.private[1]",
+		`module.edge.aws_subnet.public["us-east-1a"]`,
+		"aws_db_instance.orders",
+		"aws_ecs_service.web",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got addresses %v, want %v", got, want)
+	}
+
+	public := s.Resources[3]
+	if public.Module != "module.edge" || public.Provider != "hashicorp/aws.us_east_1" || public.Type != "aws_subnet" || public.Name != "public" {
+		t.Errorf("got %+v, want the module's subnet", public)
+	}
+	if public.ID != "subnet-22222222" || public.ARN != "arn:aws:ec2:us-east-1:111111111111:subnet/subnet-22222222" || public.Location != "testdata/network/terraform.tfstate" {
+		t.Errorf("got ID %s, ARN %s & location %s", public.ID, public.ARN, public.Location)
+	}
+}
+
+func TestParseRedacts(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		address   string
+		attribute string
+		want      any
+	}{
+		{
+			name:      "sensitive attribute",
+			path:      "testdata/network/terraform.tfstate",
+			address:   "aws_db_instance.orders",
+			attribute: "tags",
+			want:      Redacted,
+		},
+		{
+			name:      "known secret",
+			path:      "testdata/network/terraform.tfstate",
+			address:   "aws_db_instance.orders",
+			attribute: "password",
+			want:      Redacted,
+		},
+		{
+			name:      "other attribute",
+			path:      "testdata/network/terraform.tfstate",
+			address:   "aws_db_instance.orders",
+			attribute: "parameter_group_name",
+			want:      "orders",
+		},
+		{
+			name:      "known secret in a state without sensitive attributes",
+			path:      "testdata/iam/terraform.tfstate",
+			address:   "aws_iam_access_key.deploy",
+			attribute: "secret",
+			want:      Redacted,
+		},
+		{
+			name:      "unset secret",
+			path:      "testdata/iam/terraform.tfstate",
+			address:   "aws_iam_access_key.deploy",
+			attribute: "ses_smtp_password_v4",
+			want:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parseFixture(t, tt.path)
+			for _, r := range s.Resources {
+				if r.Address != tt.address {
+					continue
+				}
+				if got := r.Attributes[tt.attribute]; got != tt.want {
+					t.Errorf("got %s %v, want %v", tt.attribute, got, tt.want)
+				}
+				return
+			}
+			t.Errorf("resource %s not found", tt.address)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "invalid JSON", data: "{", want: "error parsing state"},
+		{name: "old version", data: `{"version": 3, "terraform_version": "0.11.14", "modules": []}`, want: "is version 3, only version 4 is supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("terraform.tfstate", []byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		identifier string
+		// want is the address & how each match matched.
+		want []string
+	}{
+		{identifier: "vpc-0a1b2c3d", want: []string{"aws_vpc.main id"}},
+		{identifier: "arn:aws:ec2:eu-west-1:111111111111:subnet/subnet-11111111", want: []string{"aws_subnet.private[1] arn"}},
+		{identifier: "arn:aws:iam::111111111111:role/app", want: []string{"aws_iam_role.app arn_resource_id"}},
+		{identifier: "arn:aws:ec2:eu-west-1:111111111111:vpc/vpc-0a1b2c3d", want: []string{"aws_vpc.main arn"}},
+		{identifier: "web", want: []string{"aws_ecs_service.web arn_suffix"}},
+		{identifier: "orders", want: []string{"aws_db_instance.orders arn_suffix"}},
+		{identifier: "vpc-99999999", want: []string{}},
+	}
+
+	source := NewSource(nil, "testdata")
+	for _, tt := range tests {
+		t.Run(tt.identifier, func(t *testing.T) {
+			matches, err := source.Find(context.Background(), tt.identifier)
+			if err != nil {
+				t.Fatalf("error finding resources: %v", err)
+			}
+			got := []string{}
+			for _, m := range matches {
+				got = append(got, m.Address+" "+m.MatchedOn)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}